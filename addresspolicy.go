@@ -0,0 +1,86 @@
+package orderedheaders
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"strings"
+)
+
+// AddressPolicy adds checks to the RFC 5322 syntax check that Set makes
+// of the addresses in mailbox headers, such as From and To, for
+// submission services that enforce a stricter policy. The zero value
+// accepts any syntactically valid address.
+type AddressPolicy struct {
+	// DotAtom rejects local parts that need quoting, such as
+	// "john smith"@example.com
+	// https://tools.wordtothewise.com/rfc5322#section-3.4.1
+	DotAtom bool
+	// NoDomainLiterals rejects IP address literals in place of a domain,
+	// such as user@[192.0.2.1]
+	NoDomainLiterals bool
+	// LookupMX, if set, is used to check that each domain has MX records
+	// and doesn't publish a null MX. net.LookupMX, or the LookupMX
+	// method of a net.Resolver wrapped with a context, can be used.
+	// https://tools.wordtothewise.com/rfc7505#section-3
+	LookupMX func(domain string) ([]*net.MX, error)
+}
+
+// check applies the policy to a mailbox or mailbox list value that has
+// already passed the syntax check
+func (p AddressPolicy) check(headerType HeaderType, value string) error {
+	if !p.DotAtom && !p.NoDomainLiterals && p.LookupMX == nil {
+		return nil
+	}
+	var addrs []*mail.Address
+	switch headerType {
+	case HeaderTypeMailbox:
+		addr, err := mail.ParseAddress(value)
+		if err != nil {
+			return err
+		}
+		addrs = []*mail.Address{addr}
+	case HeaderTypeMailboxList:
+		var err error
+		addrs, err = mail.ParseAddressList(value)
+		if err != nil {
+			return err
+		}
+	default:
+		return nil
+	}
+	for _, addr := range addrs {
+		if err := p.checkAddress(addr.Address); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkAddress applies the policy to a single addr-spec
+func (p AddressPolicy) checkAddress(addr string) error {
+	at := strings.LastIndexByte(addr, '@')
+	local, domain := addr[:at], addr[at+1:]
+	if p.DotAtom && !isDotAtom(local) {
+		return fmt.Errorf("'%s' has a local part that isn't a dot-atom", addr)
+	}
+	literal := strings.HasPrefix(domain, "[")
+	if p.NoDomainLiterals && literal {
+		return fmt.Errorf("'%s' has an address literal rather than a domain", addr)
+	}
+	if p.LookupMX == nil || literal {
+		return nil
+	}
+	domain = strings.ToLower(domain)
+	mxs, err := p.LookupMX(domain)
+	if err != nil {
+		return fmt.Errorf("looking up MX for '%s': %w", domain, err)
+	}
+	if len(mxs) == 0 {
+		return fmt.Errorf("'%s' has no MX records", domain)
+	}
+	if len(mxs) == 1 && strings.TrimSuffix(mxs[0].Host, ".") == "" {
+		return fmt.Errorf("'%s' has a null MX, so doesn't accept mail", domain)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package orderedheaders
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestAddressPolicy(t *testing.T) {
+	mx := map[string][]*net.MX{
+		"example.com": {{Host: "mx.example.com.", Pref: 10}},
+		"null.test":   {{Host: ".", Pref: 0}},
+		"empty.test":  {},
+	}
+	lookupMX := func(domain string) ([]*net.MX, error) {
+		if records, ok := mx[domain]; ok {
+			return records, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	tests := map[string]struct {
+		Key     string
+		Value   string
+		Policy  AddressPolicy
+		WantErr string
+	}{
+		"syntax only":     {Key: "To", Value: `"john smith"@example.com, a@[192.0.2.1]`},
+		"dot-atom":        {Key: "To", Value: "John <john.smith@example.com>, a@example.com", Policy: AddressPolicy{DotAtom: true}},
+		"quoted":          {Key: "To", Value: `a@example.com, "john smith"@example.com`, Policy: AddressPolicy{DotAtom: true}, WantErr: "local part that isn't a dot-atom"},
+		"needless quotes": {Key: "Sender", Value: `"john"@example.com`, Policy: AddressPolicy{DotAtom: true}},
+		"double dot":      {Key: "Sender", Value: `"john..smith"@example.com`, Policy: AddressPolicy{DotAtom: true}, WantErr: "local part that isn't a dot-atom"},
+		"literal":         {Key: "From", Value: "a@[192.0.2.1]", Policy: AddressPolicy{NoDomainLiterals: true}, WantErr: "address literal"},
+		"literal allowed": {Key: "From", Value: "a@[192.0.2.1]", Policy: AddressPolicy{LookupMX: lookupMX}},
+		"mx":              {Key: "Cc", Value: "a@example.com, b@EXAMPLE.com", Policy: AddressPolicy{LookupMX: lookupMX}},
+		"null mx":         {Key: "Cc", Value: "a@example.com, b@null.test", Policy: AddressPolicy{LookupMX: lookupMX}, WantErr: "null MX"},
+		"no mx":           {Key: "Cc", Value: "b@empty.test", Policy: AddressPolicy{LookupMX: lookupMX}, WantErr: "no MX records"},
+		"lookup error":    {Key: "Cc", Value: "b@nowhere.test", Policy: AddressPolicy{LookupMX: lookupMX}, WantErr: "no such host"},
+		"group":           {Key: "To", Value: "undisclosed-recipients:;", Policy: AddressPolicy{DotAtom: true, LookupMX: lookupMX}},
+		"not an address":  {Key: "Subject", Value: `"john smith"@example.com`, Policy: AddressPolicy{DotAtom: true}},
+		"invalid anyway":  {Key: "To", Value: "not an address", Policy: AddressPolicy{DotAtom: true}, WantErr: "not a valid 5322 list"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var h Header
+			err := h.SetWithOptions(test.Key, test.Value, SetOptions{Addresses: test.Policy})
+			if test.WantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.WantErr) {
+				t.Errorf("want error containing '%s', got %v", test.WantErr, err)
+			}
+			if h.Has(test.Key) {
+				t.Errorf("%s was set despite the error", test.Key)
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package orderedheaders
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// https://tools.wordtothewise.com/rfc8617#section-4.1
+
+const (
+	HdrARCSeal                  = "Arc-Seal"
+	HdrARCMessageSignature      = "Arc-Message-Signature"
+	HdrARCAuthenticationResults = "Arc-Authentication-Results"
+)
+
+// maxARCInstance is the largest instance number permitted in an ARC chain
+const maxARCInstance = 50
+
+// ARC chain validation states, as used in the ARC-Seal cv= tag
+const (
+	ARCNone = "none"
+	ARCPass = "pass"
+	ARCFail = "fail"
+)
+
+// ARCSeal is a parsed ARC-Seal header
+type ARCSeal struct {
+	// Instance is the i= tag
+	Instance int
+	// Algorithm is the a= tag, e.g. rsa-sha256
+	Algorithm string
+	// Domain is the sealing domain, d=
+	Domain string
+	// Selector is the s= tag
+	Selector string
+	// Signature is the base64 encoded signature, b=, with whitespace removed
+	Signature string
+	// ChainValidation is the cv= tag, one of none, pass or fail
+	ChainValidation string
+	// Timestamp is the t= tag, or the zero time if absent
+	Timestamp time.Time
+	// Tags is every tag in the seal, in the order they appear
+	Tags []Tag
+}
+
+// ARCMessageSignature is a parsed ARC-Message-Signature header. It has the
+// same tags as a DKIM-Signature, other than v= being replaced by i=.
+type ARCMessageSignature struct {
+	Instance int
+	DKIMSignature
+}
+
+// ARCAuthenticationResults is a parsed ARC-Authentication-Results header
+type ARCAuthenticationResults struct {
+	Instance int
+	// Results is the remainder of the header, in Authentication-Results syntax
+	Results string
+}
+
+// An ARCSet is the three headers added by a single ARC intermediary
+type ARCSet struct {
+	Instance              int
+	Seal                  ARCSeal
+	MessageSignature      ARCMessageSignature
+	AuthenticationResults ARCAuthenticationResults
+}
+
+// ParseARCSeal parses the value of an ARC-Seal header
+func ParseARCSeal(value string) (ARCSeal, error) {
+	tags, err := parseTagList(value)
+	if err != nil {
+		return ARCSeal{}, fmt.Errorf("invalid ARC-Seal: %w", err)
+	}
+	seal := ARCSeal{Tags: tags}
+	for _, required := range []string{"i", "a", "b", "cv", "d", "s"} {
+		if _, ok := lookupTag(tags, required); !ok {
+			return ARCSeal{}, fmt.Errorf("invalid ARC-Seal: missing required tag %s=", required)
+		}
+	}
+	for _, tag := range tags {
+		switch tag.Name {
+		case "i":
+			seal.Instance, err = parseARCInstance(tag.Value)
+		case "a":
+			seal.Algorithm = strings.ToLower(tag.Value)
+		case "d":
+			seal.Domain = tag.Value
+		case "s":
+			seal.Selector = tag.Value
+		case "b":
+			seal.Signature = stripFWS(tag.Value)
+		case "cv":
+			seal.ChainValidation = strings.ToLower(tag.Value)
+			switch seal.ChainValidation {
+			case ARCNone, ARCPass, ARCFail:
+			default:
+				err = fmt.Errorf("'%s' is not a valid chain validation status", tag.Value)
+			}
+		case "t":
+			seal.Timestamp, err = parseUnixTag(tag)
+		case "h":
+			err = errors.New("h= tag is not permitted")
+		}
+		if err != nil {
+			return ARCSeal{}, fmt.Errorf("invalid ARC-Seal: %w", err)
+		}
+	}
+	return seal, nil
+}
+
+// ParseARCMessageSignature parses the value of an ARC-Message-Signature header
+func ParseARCMessageSignature(value string) (ARCMessageSignature, error) {
+	sig, err := parseSignature(value, []string{"i", "a", "b", "bh", "d", "h", "s"})
+	if err != nil {
+		return ARCMessageSignature{}, fmt.Errorf("invalid ARC-Message-Signature: %w", err)
+	}
+	i, _ := sig.Tag("i")
+	instance, err := parseARCInstance(i)
+	if err != nil {
+		return ARCMessageSignature{}, fmt.Errorf("invalid ARC-Message-Signature: %w", err)
+	}
+	return ARCMessageSignature{
+		Instance:      instance,
+		DKIMSignature: sig,
+	}, nil
+}
+
+// ParseARCAuthenticationResults parses the value of an
+// ARC-Authentication-Results header
+func ParseARCAuthenticationResults(value string) (ARCAuthenticationResults, error) {
+	i := strings.IndexByte(value, ';')
+	if i < 0 {
+		return ARCAuthenticationResults{}, errors.New("invalid ARC-Authentication-Results: missing instance")
+	}
+	tags, err := parseTagList(value[:i])
+	if err != nil || len(tags) != 1 || tags[0].Name != "i" {
+		return ARCAuthenticationResults{}, fmt.Errorf("invalid ARC-Authentication-Results: '%s' is not an instance tag", strings.TrimSpace(value[:i]))
+	}
+	instance, err := parseARCInstance(tags[0].Value)
+	if err != nil {
+		return ARCAuthenticationResults{}, fmt.Errorf("invalid ARC-Authentication-Results: %w", err)
+	}
+	return ARCAuthenticationResults{
+		Instance: instance,
+		Results:  strings.TrimSpace(value[i+1:]),
+	}, nil
+}
+
+func parseARCInstance(s string) (int, error) {
+	i, err := strconv.Atoi(s)
+	if err != nil || i < 1 || i > maxARCInstance {
+		return 0, fmt.Errorf("'%s' is not a valid ARC instance", s)
+	}
+	return i, nil
+}
+
+// ARCSets parses the ARC headers into sets, ordered by instance. It
+// returns an error if any header fails to parse, if an instance has
+// missing or duplicate headers, if the instances are not continuous
+// from 1, or if a newer set appears below an older one.
+func (h *Header) ARCSets() ([]ARCSet, error) {
+	h.CanonicalizeKeys()
+	sets := map[int]*ARCSet{}
+	get := func(i int) *ARCSet {
+		set, ok := sets[i]
+		if !ok {
+			set = &ARCSet{Instance: i}
+			sets[i] = set
+		}
+		return set
+	}
+	last := map[string]int{}
+	for _, kv := range h.Headers {
+		var instance int
+		var dup bool
+		switch kv.Key {
+		case HdrARCSeal:
+			seal, err := ParseARCSeal(kv.Value)
+			if err != nil {
+				return nil, err
+			}
+			instance = seal.Instance
+			set := get(instance)
+			dup = set.Seal.Instance != 0
+			set.Seal = seal
+		case HdrARCMessageSignature:
+			sig, err := ParseARCMessageSignature(kv.Value)
+			if err != nil {
+				return nil, err
+			}
+			instance = sig.Instance
+			set := get(instance)
+			dup = set.MessageSignature.Instance != 0
+			set.MessageSignature = sig
+		case HdrARCAuthenticationResults:
+			ar, err := ParseARCAuthenticationResults(kv.Value)
+			if err != nil {
+				return nil, err
+			}
+			instance = ar.Instance
+			set := get(instance)
+			dup = set.AuthenticationResults.Instance != 0
+			set.AuthenticationResults = ar
+		default:
+			continue
+		}
+		if dup {
+			return nil, fmt.Errorf("duplicate %s for ARC instance %d", kv.Key, instance)
+		}
+		if prev, ok := last[kv.Key]; ok && prev < instance {
+			return nil, fmt.Errorf("%s for ARC instance %d is below instance %d", kv.Key, instance, prev)
+		}
+		last[kv.Key] = instance
+	}
+	ret := make([]ARCSet, len(sets))
+	for i := 1; i <= len(sets); i++ {
+		set, ok := sets[i]
+		if !ok {
+			return nil, fmt.Errorf("missing ARC instance %d", i)
+		}
+		switch {
+		case set.Seal.Instance == 0:
+			return nil, fmt.Errorf("missing ARC-Seal for instance %d", i)
+		case set.MessageSignature.Instance == 0:
+			return nil, fmt.Errorf("missing ARC-Message-Signature for instance %d", i)
+		case set.AuthenticationResults.Instance == 0:
+			return nil, fmt.Errorf("missing ARC-Authentication-Results for instance %d", i)
+		}
+		ret[i-1] = *set
+	}
+	return ret, nil
+}
+
+// ARCChainValidation returns the cv= status of the most recent ARC-Seal,
+// or "none" if there is no ARC chain. A structurally invalid chain is
+// reported as "fail", along with the reason.
+func (h *Header) ARCChainValidation() (string, error) {
+	sets, err := h.ARCSets()
+	if err != nil {
+		return ARCFail, err
+	}
+	if len(sets) == 0 {
+		return ARCNone, nil
+	}
+	return sets[len(sets)-1].Seal.ChainValidation, nil
+}
+
+// AddARCSet adds a new ARC set at the top of the header, above any
+// existing ARC sets and trace headers. The three values must all carry
+// the next instance number in the chain, and the seal's cv= must be
+// none for the first instance and pass or fail for later ones.
+func (h *Header) AddARCSet(seal, messageSignature, authenticationResults string) error {
+	sets, err := h.ARCSets()
+	if err != nil {
+		return err
+	}
+	next := len(sets) + 1
+	as, err := ParseARCSeal(seal)
+	if err != nil {
+		return err
+	}
+	ams, err := ParseARCMessageSignature(messageSignature)
+	if err != nil {
+		return err
+	}
+	aar, err := ParseARCAuthenticationResults(authenticationResults)
+	if err != nil {
+		return err
+	}
+	if as.Instance != next || ams.Instance != next || aar.Instance != next {
+		return fmt.Errorf("new ARC set must have instance %d", next)
+	}
+	if (next == 1) != (as.ChainValidation == ARCNone) {
+		return fmt.Errorf("cv=%s is not valid for ARC instance %d", as.ChainValidation, next)
+	}
+	h.insert(0,
+		KV{Key: HdrARCSeal, Value: seal},
+		KV{Key: HdrARCMessageSignature, Value: messageSignature},
+		KV{Key: HdrARCAuthenticationResults, Value: authenticationResults},
+	)
+	return nil
+}
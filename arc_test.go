@@ -0,0 +1,75 @@
+package orderedheaders
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func arcSet(i, cv string) []KV {
+	return []KV{
+		{Key: HdrARCSeal, Value: "i=" + i + "; a=rsa-sha256; cv=" + cv + "; d=example.org; s=sel; b=c2lnbmF0dXJl"},
+		{Key: HdrARCMessageSignature, Value: "i=" + i + "; a=rsa-sha256; c=relaxed/relaxed; d=example.org; s=sel; h=from:to; bh=aGFzaA==; b=c2ln"},
+		{Key: HdrARCAuthenticationResults, Value: "i=" + i + "; mx.example.org; spf=pass smtp.mailfrom=example.com"},
+	}
+}
+
+func TestARCSets(t *testing.T) {
+	tests := map[string]struct {
+		Headers   [][]KV
+		WantError bool
+		WantCV    string
+	}{
+		"none":      {nil, false, ARCNone},
+		"single":    {[][]KV{arcSet("1", "none")}, false, ARCNone},
+		"chain":     {[][]KV{arcSet("2", "pass"), arcSet("1", "none")}, false, ARCPass},
+		"gap":       {[][]KV{arcSet("3", "pass"), arcSet("1", "none")}, true, ARCFail},
+		"order":     {[][]KV{arcSet("1", "none"), arcSet("2", "pass")}, true, ARCFail},
+		"duplicate": {[][]KV{arcSet("1", "none"), arcSet("1", "none")}, true, ARCFail},
+		"partial":   {[][]KV{arcSet("1", "none")[:2]}, true, ARCFail},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := &Header{}
+			h.Add("From", "a@example.com")
+			for _, set := range test.Headers {
+				h.Headers = append(h.Headers, set...)
+			}
+			cv, err := h.ARCChainValidation()
+			if test.WantError != (err != nil) {
+				t.Errorf("want error %v, got %v", test.WantError, err)
+			}
+			if cv != test.WantCV {
+				t.Errorf("want cv=%s, got cv=%s", test.WantCV, cv)
+			}
+		})
+	}
+}
+
+func TestAddARCSet(t *testing.T) {
+	h := &Header{}
+	h.Add("Received", "from somewhere")
+	one := arcSet("1", "none")
+	if err := h.AddARCSet(one[0].Value, one[1].Value, one[2].Value); err != nil {
+		t.Fatal(err)
+	}
+	bad := arcSet("3", "pass")
+	if err := h.AddARCSet(bad[0].Value, bad[1].Value, bad[2].Value); err == nil {
+		t.Errorf("expected error adding instance 3 to a chain of 1")
+	}
+	two := arcSet("2", "pass")
+	if err := h.AddARCSet(two[0].Value, two[1].Value, two[2].Value); err != nil {
+		t.Fatal(err)
+	}
+	want := append(append(two, one...), KV{Key: "Received", Value: "from somewhere"})
+	if diff := cmp.Diff(want, h.Headers); diff != "" {
+		t.Errorf("AddARCSet mismatch (-want +got):\n%s", diff)
+	}
+	sets, err := h.ARCSets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sets) != 2 || sets[1].MessageSignature.Domain != "example.org" || sets[0].AuthenticationResults.Results != "mx.example.org; spf=pass smtp.mailfrom=example.com" {
+		t.Errorf("unexpected ARC sets %#v", sets)
+	}
+}
@@ -0,0 +1,311 @@
+// Package arf builds and parses Abuse Reporting Format feedback reports,
+// as described in RFC 5965, with the machine readable feedback fields
+// held as an ordered header.
+package arf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wttw/orderedheaders"
+)
+
+// https://tools.wordtothewise.com/rfc5965#section-3.5
+
+const (
+	HdrFeedbackType          = "Feedback-Type"
+	HdrUserAgent             = "User-Agent"
+	HdrVersion               = "Version"
+	HdrOriginalEnvelopeID    = "Original-Envelope-Id"
+	HdrOriginalMailFrom      = "Original-Mail-From"
+	HdrArrivalDate           = "Arrival-Date"
+	HdrReportingMTA          = "Reporting-Mta"
+	HdrSourceIP              = "Source-Ip"
+	HdrIncidents             = "Incidents"
+	HdrAuthenticationResults = "Authentication-Results"
+	HdrOriginalRcptTo        = "Original-Rcpt-To"
+	HdrReportedDomain        = "Reported-Domain"
+	HdrReportedURI           = "Reported-Uri"
+)
+
+// Feedback types
+// https://tools.wordtothewise.com/rfc5965#section-7.3
+const (
+	FeedbackAbuse   = "abuse"
+	FeedbackFraud   = "fraud"
+	FeedbackOther   = "other"
+	FeedbackVirus   = "virus"
+	FeedbackNotSpam = "not-spam"
+)
+
+// ReportType is the report-type of a multipart/report containing a
+// feedback report
+const ReportType = "feedback-report"
+
+// ErrNotARF is returned by Parse for a message that isn't a feedback
+// report
+var ErrNotARF = errors.New("message is not a feedback report")
+
+// Report is an ARF feedback report
+type Report struct {
+	// FeedbackType is one of the Feedback constants, or an extension
+	FeedbackType string
+	// UserAgent identifies the software generating the report
+	UserAgent string
+	// Version is the report format version, always 1
+	Version int
+	// OriginalEnvelopeID is the ENVID of the reported message
+	OriginalEnvelopeID string
+	// OriginalMailFrom is the envelope sender of the reported message,
+	// without angle brackets
+	OriginalMailFrom string
+	// OriginalRcptTo are the envelope recipients of the reported message,
+	// without angle brackets
+	OriginalRcptTo []string
+	// ArrivalDate is when the reported message was received, or the zero
+	// time
+	ArrivalDate time.Time
+	// ReportingMTA is the name of the MTA that received the message,
+	// without its "dns;" type
+	ReportingMTA string
+	// SourceIP is the address the reported message came from
+	SourceIP net.IP
+	// Incidents is the number of incidents this report covers, 1 if not
+	// given
+	Incidents int
+	// AuthenticationResults are the receiver's authentication results
+	AuthenticationResults []string
+	// ReportedDomain and ReportedURI identify what's being reported
+	ReportedDomain []string
+	ReportedURI    []string
+	// Fields holds every feedback field, in order, including extensions.
+	// When building it's used for fields not set above.
+	Fields orderedheaders.Header
+
+	// Text is the human readable explanation
+	Text string
+	// Original is the reported message, or just its header
+	Original *orderedheaders.Message
+	// HeadersOnly returns only the header of Original, as
+	// text/rfc822-headers, when building. The header is copied exactly
+	// as it was read if Original has a RawHeaderBlock.
+	HeadersOnly bool
+}
+
+// Parse parses a multipart/report feedback report
+func Parse(m *orderedheaders.Message) (*Report, error) {
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get(orderedheaders.HdrContentType))
+	if err != nil || mediaType != "multipart/report" || !strings.EqualFold(params["report-type"], ReportType) {
+		return nil, ErrNotARF
+	}
+	parts, err := m.Parts()
+	if err != nil {
+		return nil, err
+	}
+	r := &Report{}
+	found := false
+	for i, part := range parts {
+		mediaType, _, _ := mime.ParseMediaType(part.Header.Get(orderedheaders.HdrContentType))
+		switch {
+		case i == 0 && strings.HasPrefix(mediaType, "text/"):
+			body, err := part.TextBody()
+			if err != nil {
+				return nil, err
+			}
+			text, err := io.ReadAll(body)
+			if err != nil {
+				return nil, err
+			}
+			r.Text = string(text)
+		case mediaType == "message/feedback-report":
+			fields, err := orderedheaders.ReadMessage(part.Body)
+			if err != nil {
+				return nil, err
+			}
+			if err := r.parseFields(fields.Header); err != nil {
+				return nil, err
+			}
+			found = true
+		case part.IsEmbedded():
+			if r.Original, err = part.Embedded(); err != nil {
+				return nil, err
+			}
+		case mediaType == "text/rfc822-headers":
+			r.Original, err = orderedheaders.ReadMessageWithOptions(part.Body, orderedheaders.MessageOptions{KeepRawHeader: true})
+			if err != nil {
+				return nil, err
+			}
+			r.HeadersOnly = true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: no feedback report part", ErrNotARF)
+	}
+	return r, nil
+}
+
+// ParseFields parses the fields of a message/feedback-report part
+func ParseFields(h orderedheaders.Header) (*Report, error) {
+	r := &Report{}
+	if err := r.parseFields(h); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Report) parseFields(h orderedheaders.Header) error {
+	h.CanonicalizeKeys()
+	r.Fields = h
+	r.FeedbackType = strings.ToLower(strings.TrimSpace(h.Get(HdrFeedbackType)))
+	r.UserAgent = strings.TrimSpace(h.Get(HdrUserAgent))
+	if r.FeedbackType == "" || r.UserAgent == "" || !h.Has(HdrVersion) {
+		return errors.New("feedback report requires Feedback-Type, User-Agent and Version")
+	}
+	var err error
+	if r.Version, err = strconv.Atoi(strings.TrimSpace(h.Get(HdrVersion))); err != nil {
+		return fmt.Errorf("'%s' is not a valid feedback report version", h.Get(HdrVersion))
+	}
+	r.OriginalEnvelopeID = strings.TrimSpace(h.Get(HdrOriginalEnvelopeID))
+	r.OriginalMailFrom = unbracket(h.Get(HdrOriginalMailFrom))
+	r.ArrivalDate, _, _ = orderedheaders.ParseDateLenient(h.Get(HdrArrivalDate))
+	r.ReportingMTA = strings.TrimSpace(h.Get(HdrReportingMTA))
+	if semi := strings.IndexByte(r.ReportingMTA, ';'); semi >= 0 {
+		r.ReportingMTA = strings.TrimSpace(r.ReportingMTA[semi+1:])
+	}
+	r.SourceIP = net.ParseIP(strings.TrimSpace(h.Get(HdrSourceIP)))
+	r.Incidents = 1
+	if h.Has(HdrIncidents) {
+		if r.Incidents, err = strconv.Atoi(strings.TrimSpace(h.Get(HdrIncidents))); err != nil {
+			return fmt.Errorf("'%s' is not a valid incident count", h.Get(HdrIncidents))
+		}
+	}
+	for _, kv := range h.Headers {
+		value := strings.TrimSpace(kv.Value)
+		switch kv.Key {
+		case HdrOriginalRcptTo:
+			r.OriginalRcptTo = append(r.OriginalRcptTo, unbracket(value))
+		case HdrAuthenticationResults:
+			r.AuthenticationResults = append(r.AuthenticationResults, value)
+		case HdrReportedDomain:
+			r.ReportedDomain = append(r.ReportedDomain, value)
+		case HdrReportedURI:
+			r.ReportedURI = append(r.ReportedURI, value)
+		}
+	}
+	return nil
+}
+
+// unbracket removes whitespace and angle brackets around an address
+func unbracket(s string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(s), "<"), ">")
+}
+
+// header renders the feedback fields, in the order used by the RFC 5965
+// examples, followed by any extension fields from Fields
+func (r *Report) header() (orderedheaders.Header, error) {
+	if r.FeedbackType == "" || r.UserAgent == "" {
+		return orderedheaders.Header{}, errors.New("feedback report requires Feedback-Type and User-Agent")
+	}
+	version := r.Version
+	if version == 0 {
+		version = 1
+	}
+	var h orderedheaders.Header
+	set := map[string]struct{}{}
+	add := func(key string, values ...string) {
+		set[key] = struct{}{}
+		for _, v := range values {
+			h.Add(key, v)
+		}
+	}
+	add(HdrFeedbackType, r.FeedbackType)
+	add(HdrUserAgent, r.UserAgent)
+	add(HdrVersion, strconv.Itoa(version))
+	add(HdrOriginalEnvelopeID, optional(r.OriginalEnvelopeID)...)
+	add(HdrOriginalMailFrom, bracket(optional(r.OriginalMailFrom))...)
+	add(HdrOriginalRcptTo, bracket(r.OriginalRcptTo)...)
+	if !r.ArrivalDate.IsZero() {
+		add(HdrArrivalDate, orderedheaders.FormatDate(r.ArrivalDate, true))
+	}
+	if r.ReportingMTA != "" {
+		add(HdrReportingMTA, "dns; "+r.ReportingMTA)
+	}
+	if r.SourceIP != nil {
+		add(HdrSourceIP, r.SourceIP.String())
+	}
+	if r.Incidents > 1 {
+		add(HdrIncidents, strconv.Itoa(r.Incidents))
+	}
+	add(HdrAuthenticationResults, r.AuthenticationResults...)
+	add(HdrReportedDomain, r.ReportedDomain...)
+	add(HdrReportedURI, r.ReportedURI...)
+	for _, key := range []string{HdrArrivalDate, HdrReportingMTA, HdrSourceIP, HdrIncidents} {
+		set[key] = struct{}{}
+	}
+	for _, kv := range r.Fields.Headers {
+		if _, ok := set[kv.Key]; !ok {
+			h.Add(kv.Key, kv.Value)
+		}
+	}
+	return h, nil
+}
+
+// optional returns s as a single value, or none if it's empty
+func optional(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return []string{s}
+}
+
+// bracket adds angle brackets to each address
+func bracket(addrs []string) []string {
+	ret := make([]string, len(addrs))
+	for i, a := range addrs {
+		ret[i] = "<" + a + ">"
+	}
+	return ret
+}
+
+// Build creates a feedback report message, using b for the header of the
+// report itself, such as From, To and Subject
+func (r *Report) Build(b *orderedheaders.Builder) (*orderedheaders.Message, error) {
+	fields, err := r.header()
+	if err != nil {
+		return nil, err
+	}
+	var report bytes.Buffer
+	if err := fields.WriteTo(&report, orderedheaders.Options{}); err != nil {
+		return nil, err
+	}
+	var reportHeader orderedheaders.Header
+	reportHeader.Add(orderedheaders.HdrContentType, "message/feedback-report")
+	b.Report(ReportType).Text(r.Text).Part(reportHeader, report.Bytes())
+
+	if r.Original != nil {
+		var original bytes.Buffer
+		var originalHeader orderedheaders.Header
+		if r.HeadersOnly {
+			originalHeader.Add(orderedheaders.HdrContentType, "text/rfc822-headers")
+			if raw := r.Original.RawHeaderBlock(); raw != nil {
+				original.Write(raw)
+			} else if err := r.Original.Header.WriteTo(&original, orderedheaders.Options{}); err != nil {
+				return nil, err
+			}
+		} else {
+			originalHeader.Add(orderedheaders.HdrContentType, "message/rfc822")
+			if err := r.Original.Rewrite(&original, orderedheaders.Options{}); err != nil {
+				return nil, err
+			}
+		}
+		b.Part(originalHeader, original.Bytes())
+	}
+	return b.Build()
+}
@@ -0,0 +1,183 @@
+package arf
+
+import (
+	"errors"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/wttw/orderedheaders"
+)
+
+// based on https://tools.wordtothewise.com/rfc5965#appendix-B.2
+const example = "From: <abusedesk@example.com>\r\n" +
+	"Date: Thu, 8 Mar 2005 17:40:36 EDT\r\n" +
+	"Subject: FW: Earn money\r\n" +
+	"To: <abuse@example.net>\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/report; report-type=feedback-report;\r\n" +
+	"     boundary=\"part1_13d.2e68ed54_boundary\"\r\n" +
+	"\r\n" +
+	"--part1_13d.2e68ed54_boundary\r\n" +
+	"Content-Type: text/plain; charset=\"US-ASCII\"\r\n" +
+	"Content-Transfer-Encoding: 7bit\r\n" +
+	"\r\n" +
+	"This is an email abuse report for an email message received from IP\r\n" +
+	"192.0.2.1 on Thu, 8 Mar 2005 14:00:00 EDT.\r\n" +
+	"--part1_13d.2e68ed54_boundary\r\n" +
+	"Content-Type: message/feedback-report\r\n" +
+	"\r\n" +
+	"Feedback-Type: abuse\r\n" +
+	"User-Agent: SomeGenerator/1.0\r\n" +
+	"Version: 1\r\n" +
+	"Original-Mail-From: <somespammer@example.net>\r\n" +
+	"Original-Rcpt-To: <user@example.com>\r\n" +
+	"Arrival-Date: Thu, 8 Mar 2005 14:00:00 EDT\r\n" +
+	"Reporting-MTA: dns; mail.example.com\r\n" +
+	"Source-IP: 192.0.2.1\r\n" +
+	"Authentication-Results: mail.example.com;\r\n" +
+	"               spf=fail smtp.mail=somespammer@example.com\r\n" +
+	"Reported-Domain: example.net\r\n" +
+	"Reported-Uri: http://example.net/earn_money.html\r\n" +
+	"Removal-Recipient: user@example.com\r\n" +
+	"\r\n" +
+	"--part1_13d.2e68ed54_boundary\r\n" +
+	"Content-Type: message/rfc822\r\n" +
+	"Content-Disposition: inline\r\n" +
+	"\r\n" +
+	"From: <somespammer@example.net>\r\n" +
+	"Received: from mailserver.example.net (mailserver.example.net\r\n" +
+	"        [192.0.2.1]) by example.com with ESMTP id M63d4137594e46;\r\n" +
+	"        Thu, 08 Mar 2005 14:00:00 -0400\r\n" +
+	"To: <Undisclosed Recipients>\r\n" +
+	"Subject: Earn money\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-type: text/plain\r\n" +
+	"Message-ID: 8787KJKJ3K4J3K4J3K4J3.mail@example.net\r\n" +
+	"Date: Thu, 02 Sep 2004 12:31:03 -0500\r\n" +
+	"\r\n" +
+	"Spam Spam Spam\r\n" +
+	"--part1_13d.2e68ed54_boundary--\r\n"
+
+func TestParse(t *testing.T) {
+	msg, err := orderedheaders.ReadMessage(strings.NewReader(example))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := Parse(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Report{
+		FeedbackType:          FeedbackAbuse,
+		UserAgent:             "SomeGenerator/1.0",
+		Version:               1,
+		OriginalMailFrom:      "somespammer@example.net",
+		OriginalRcptTo:        []string{"user@example.com"},
+		ArrivalDate:           time.Date(2005, 3, 8, 18, 0, 0, 0, time.UTC),
+		ReportingMTA:          "mail.example.com",
+		SourceIP:              net.ParseIP("192.0.2.1"),
+		Incidents:             1,
+		AuthenticationResults: []string{"mail.example.com; spf=fail smtp.mail=somespammer@example.com"},
+		ReportedDomain:        []string{"example.net"},
+		ReportedURI:           []string{"http://example.net/earn_money.html"},
+		Text: "This is an email abuse report for an email message received from IP\r\n" +
+			"192.0.2.1 on Thu, 8 Mar 2005 14:00:00 EDT.",
+	}
+	if diff := cmp.Diff(want, r, cmpopts.IgnoreFields(Report{}, "Fields", "Original")); diff != "" {
+		t.Errorf("report mismatch (-want +got):\n%s", diff)
+	}
+	if r.Fields.Get("Removal-Recipient") != "user@example.com" {
+		t.Errorf("extension field missing")
+	}
+	if r.Original == nil || r.Original.Header.Get("Subject") != "Earn money" {
+		t.Errorf("original message not parsed")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for name, in := range map[string]string{
+		"nottype":  "Content-Type: multipart/report; report-type=delivery-status; boundary=b\r\n\r\n--b--\r\n",
+		"nopart":   "Content-Type: multipart/report; report-type=feedback-report; boundary=b\r\n\r\n--b\r\n\r\nx\r\n--b--\r\n",
+		"required": "Content-Type: multipart/report; report-type=feedback-report; boundary=b\r\n\r\n--b\r\nContent-Type: message/feedback-report\r\n\r\nFeedback-Type: abuse\r\n--b--\r\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			msg, err := orderedheaders.ReadMessage(strings.NewReader(in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = Parse(msg)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if name != "required" && !errors.Is(err, ErrNotARF) {
+				t.Errorf("expected ErrNotARF, got %v", err)
+			}
+		})
+	}
+}
+
+func TestBuild(t *testing.T) {
+	original, err := orderedheaders.ReadMessageWithOptions(strings.NewReader("From:  spammer@example.net\r\nSubject: spam\r\n\r\nspam\r\n"),
+		orderedheaders.MessageOptions{ReadOptions: orderedheaders.ReadOptions{KeepRaw: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var extra orderedheaders.Header
+	extra.Add("X-Extension", "yes")
+	extra.Add("Feedback-Type", "ignored")
+	report := &Report{
+		FeedbackType:     FeedbackAbuse,
+		UserAgent:        "orderedheaders/1",
+		OriginalMailFrom: "spammer@example.net",
+		OriginalRcptTo:   []string{"a@example.com", "b@example.com"},
+		SourceIP:         net.ParseIP("192.0.2.1"),
+		Incidents:        3,
+		Fields:           extra,
+		Text:             "Spam report",
+		Original:         original,
+		HeadersOnly:      true,
+	}
+	b := orderedheaders.NewBuilder().
+		From(&mail.Address{Address: "abuse@example.com"}).
+		To(&mail.Address{Address: "abuse@example.net"})
+	msg, err := report.Build(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := Parse(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, kv := range parsed.Fields.Headers {
+		keys = append(keys, kv.Key+": "+kv.Value)
+	}
+	want := []string{
+		"Feedback-Type: abuse",
+		"User-Agent: orderedheaders/1",
+		"Version: 1",
+		"Original-Mail-From: <spammer@example.net>",
+		"Original-Rcpt-To: <a@example.com>",
+		"Original-Rcpt-To: <b@example.com>",
+		"Source-Ip: 192.0.2.1",
+		"Incidents: 3",
+		"X-Extension: yes",
+	}
+	if diff := cmp.Diff(want, keys); diff != "" {
+		t.Errorf("fields mismatch (-want +got):\n%s", diff)
+	}
+	if !parsed.HeadersOnly || parsed.Original.Header.Get("Subject") != "spam" {
+		t.Errorf("original headers not included")
+	}
+	if raw := string(parsed.Original.RawHeaderBlock()); raw != "From:  spammer@example.net\r\nSubject: spam\r\n\r\n" {
+		t.Errorf("original header not preserved, got %q", raw)
+	}
+	if _, err := (&Report{}).Build(orderedheaders.NewBuilder()); err == nil {
+		t.Errorf("expected error for empty report")
+	}
+}
@@ -0,0 +1,131 @@
+package orderedheaders
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"path/filepath"
+	"strings"
+)
+
+// https://tools.wordtothewise.com/rfc2183#section-2
+// https://tools.wordtothewise.com/rfc2231#section-4
+// https://tools.wordtothewise.com/rfc2387#section-3
+// https://tools.wordtothewise.com/rfc2392#section-2
+
+// base64LineLength is the maximum encoded line length for base64 content
+// https://tools.wordtothewise.com/rfc2045#section-6.8
+const base64LineLength = 76
+
+// Attach adds an attachment, read from r, encoded as
+// SuggestTransferEncoding recommends. If contentType is empty it is
+// guessed from the filename's extension. The filename is given in
+// Content-Disposition, and as the name parameter of Content-Type for
+// older clients, RFC 2231 encoded if needed.
+func (b *Builder) Attach(filename string, contentType string, r io.Reader) *Builder {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		b.setErr(fmt.Errorf("reading attachment %s: %w", filename, err))
+		return b
+	}
+	filename = filepath.Base(filepath.ToSlash(filename))
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(filename))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		b.setErr(fmt.Errorf("'%s' is not a valid content type: %w", contentType, err))
+		return b
+	}
+	delete(params, "name")
+	var p builderPart
+	p.header.Add(HdrContentType, formatParams(mime.FormatMediaType(mediaType, params), "name", filename))
+	p.header.Add(HdrContentDisposition, formatParams("attachment", "filename", filename))
+	cte, body := encodeBody(mediaType, content)
+	p.header.Add(HdrContentTransferEncoding, cte)
+	p.body = body
+	b.parts = append(b.parts, p)
+	return b
+}
+
+// AttachInline adds a part, such as an image, to be referenced from the
+// HTML body. It's given a Content-ID of cid, with angle brackets added if
+// needed, or a generated one if cid is empty. It returns the cid: URL to
+// use in the HTML. The part is combined with the HTML in a
+// multipart/related.
+func (b *Builder) AttachInline(cid, contentType string, r io.Reader) (string, error) {
+	cid = strings.TrimSpace(cid)
+	if cid == "" {
+		cid = NewMessageID("")
+	}
+	if !strings.HasPrefix(cid, "<") {
+		cid = "<" + cid + ">"
+	}
+	if err := validMessageId(cid); err != nil {
+		return "", fmt.Errorf("invalid Content-ID: %w", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", fmt.Errorf("'%s' is not a valid content type: %w", contentType, err)
+	}
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("reading inline part %s: %w", cid, err)
+	}
+	var p builderPart
+	p.header.Add(HdrContentType, mime.FormatMediaType(mediaType, params))
+	p.header.Add(HdrContentID, cid)
+	p.header.Add(HdrContentDisposition, "inline")
+	cte, body := encodeBody(mediaType, content)
+	p.header.Add(HdrContentTransferEncoding, cte)
+	p.body = body
+	b.inline = append(b.inline, p)
+	return ContentIDURL(cid), nil
+}
+
+// formatParams appends a parameter to a header value, quoting it, or
+// using RFC 2231 extended notation if it isn't ASCII
+func formatParams(value, name, param string) string {
+	if param == "" {
+		return value
+	}
+	if isAscii(param) && !strings.ContainsAny(param, "\r\n") {
+		return value + "; " + name + "=" + quoteString(param)
+	}
+	return value + "; " + name + "*=" + utf8 + "''" + percentEncode(param)
+}
+
+// percentEncode encodes everything other than attribute-char as %XX
+func percentEncode(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c > ' ' && c < 0x7f && strings.IndexByte(`*'%()<>@,;:\"/[]?=`, c) < 0 {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&0x0f])
+	}
+	return b.String()
+}
+
+// encodeBase64Lines base64 encodes content, wrapped into CRLF terminated
+// lines of base64LineLength characters
+func encodeBase64Lines(content []byte) []byte {
+	encoded := base64.StdEncoding.EncodeToString(content)
+	var b strings.Builder
+	for len(encoded) > base64LineLength {
+		b.WriteString(encoded[:base64LineLength])
+		b.WriteString("\r\n")
+		encoded = encoded[base64LineLength:]
+	}
+	b.WriteString(encoded)
+	return []byte(b.String())
+}
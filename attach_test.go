@@ -0,0 +1,170 @@
+package orderedheaders
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("read failed")
+}
+
+func TestAttach(t *testing.T) {
+	content := bytes.Repeat([]byte{0, 1, 2, 0xff}, 100)
+	msg, err := NewBuilder().
+		From(&mail.Address{Address: "alice@example.com"}).
+		Text("see attached").
+		Attach("/tmp/report.pdf", "", bytes.NewReader(content)).
+		Attach("résumé 2023.txt", "text/plain; charset=utf-8; name=ignored", strings.NewReader("cv")).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts, err := msg.Parts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 3 {
+		t.Fatalf("expected 3 parts, got %d", len(parts))
+	}
+
+	pdf := parts[1]
+	want := []KV{
+		{Key: "Content-Type", Value: `application/pdf; name="report.pdf"`},
+		{Key: "Content-Disposition", Value: `attachment; filename="report.pdf"`},
+		{Key: "Content-Transfer-Encoding", Value: "base64"},
+	}
+	for i := range pdf.Header.Headers {
+		pdf.Header.Headers[i].Raw = ""
+	}
+	if diff := cmp.Diff(want, pdf.Header.Headers); diff != "" {
+		t.Errorf("header mismatch (-want +got):\n%s", diff)
+	}
+	body, _ := io.ReadAll(pdf.Body)
+	for _, line := range strings.Split(string(body), "\r\n") {
+		if len(line) > 76 {
+			t.Errorf("line too long: %d", len(line))
+		}
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.Replace(string(body), "\r\n", "", -1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, content) {
+		t.Errorf("attachment content mismatch")
+	}
+
+	cv := parts[2]
+	_, params, err := mime.ParseMediaType(cv.Header.Get("Content-Disposition"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params["filename"] != "résumé 2023.txt" {
+		t.Errorf("unexpected filename '%s' from '%s'", params["filename"], cv.Header.Get("Content-Disposition"))
+	}
+	mediaType, params, err := mime.ParseMediaType(cv.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mediaType != "text/plain" || params["charset"] != "utf-8" || params["name"] != "résumé 2023.txt" {
+		t.Errorf("unexpected Content-Type '%s'", cv.Header.Get("Content-Type"))
+	}
+}
+
+func TestAttachErrors(t *testing.T) {
+	from := &mail.Address{Address: "alice@example.com"}
+	if _, err := NewBuilder().From(from).Attach("x", "", failingReader{}).Build(); err == nil {
+		t.Errorf("expected read error")
+	}
+	if _, err := NewBuilder().From(from).Attach("x", "not a type", strings.NewReader("x")).Build(); err == nil {
+		t.Errorf("expected content type error")
+	}
+}
+
+func TestFormatParams(t *testing.T) {
+	tests := map[string]struct {
+		In   string
+		Want string
+	}{
+		"plain":   {"a.txt", `attachment; filename="a.txt"`},
+		"quote":   {`a "b".txt`, `attachment; filename="a \"b\".txt"`},
+		"utf8":    {"ü 1%.txt", `attachment; filename*=utf-8''%C3%BC%201%25.txt`},
+		"missing": {"", "attachment"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := formatParams("attachment", "filename", test.In); got != test.Want {
+				t.Errorf("want %s, got %s", test.Want, got)
+			}
+		})
+	}
+}
+
+func TestAttachInline(t *testing.T) {
+	b := NewBuilder().
+		From(&mail.Address{Address: "alice@example.com"}).
+		Text("logo")
+	url, err := b.AttachInline("logo@example.com", "image/png", bytes.NewReader([]byte{0x89, 'P', 'N', 'G'}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "cid:logo@example.com" {
+		t.Errorf("unexpected URL %s", url)
+	}
+	generated, err := b.AttachInline("", "image/gif", strings.NewReader("GIF89a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(generated, "cid:") || len(generated) < 10 {
+		t.Errorf("unexpected generated URL %s", generated)
+	}
+	msg, err := b.HTML(`<img src="` + url + `">`).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	err = msg.Walk(func(part *Message, depth int) error {
+		s := strings.Repeat(" ", depth) + strings.SplitN(part.Header.Get("Content-Type"), ";", 2)[0]
+		if id := part.Header.Get("Content-ID"); id != "" {
+			if err := validMessageId(id); err != nil {
+				t.Errorf("invalid Content-ID: %v", err)
+			}
+			s += " " + part.Header.Get("Content-Disposition")
+		}
+		got = append(got, s)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"multipart/alternative",
+		" text/plain",
+		" multipart/related",
+		"  text/html",
+		"  image/png inline",
+		"  image/gif inline",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("structure mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, cid := range []string{"not a cid", "<two@@example.com>"} {
+		if _, err := b.AttachInline(cid, "image/png", strings.NewReader("")); err == nil {
+			t.Errorf("expected error for Content-ID %s", cid)
+		}
+	}
+	if _, err := b.AttachInline("x@example.com", "", strings.NewReader("")); err == nil {
+		t.Errorf("expected error for missing content type")
+	}
+}
@@ -0,0 +1,53 @@
+package orderedheaders
+
+import (
+	"strings"
+)
+
+// https://tools.wordtothewise.com/rfc3834#section-2
+
+const (
+	HdrPrecedence            = "Precedence"
+	HdrXAutoResponseSuppress = "X-Auto-Response-Suppress"
+)
+
+// ShouldAutoRespond reports whether an automatic response, such as a
+// vacation message or ticket acknowledgement, may be sent to this
+// message. If not, it also returns the reason.
+func (h *Header) ShouldAutoRespond() (bool, string) {
+	if auto := h.autoSubmitted(); auto != "" {
+		return false, "Auto-Submitted is " + auto
+	}
+	switch precedence := strings.ToLower(strings.TrimSpace(h.Get(HdrPrecedence))); precedence {
+	case "bulk", "list", "junk":
+		return false, "Precedence is " + precedence
+	}
+	for _, key := range []string{HdrListID, HdrListUnsubscribe} {
+		if h.Has(key) {
+			return false, "message has a " + key + " header"
+		}
+	}
+	for _, v := range strings.Split(h.Get(HdrXAutoResponseSuppress), ",") {
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "all", "autoreply", "oof":
+			return false, "X-Auto-Response-Suppress is " + strings.TrimSpace(v)
+		}
+	}
+	if _, isNull, err := h.ReturnPath(); err == nil && isNull {
+		return false, "Return-Path is null"
+	}
+	return true, ""
+}
+
+// autoSubmitted returns the keyword of the Auto-Submitted field, without
+// any parameters or comments, or "" if there is no field or it is "no"
+func (h *Header) autoSubmitted() string {
+	auto := strings.TrimSpace(h.Get(HdrAutoSubmitted))
+	if i := strings.IndexAny(auto, "; \t("); i >= 0 {
+		auto = auto[:i]
+	}
+	if strings.EqualFold(auto, "no") {
+		return ""
+	}
+	return auto
+}
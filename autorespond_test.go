@@ -0,0 +1,29 @@
+package orderedheaders
+
+import "testing"
+
+func TestShouldAutoRespond(t *testing.T) {
+	tests := map[string]struct {
+		Headers []KV
+		Want    bool
+	}{
+		"personal":      {[]KV{{Key: "From", Value: "bob@example.com"}, {Key: "Return-Path", Value: "<bob@example.com>"}}, true},
+		"autono":        {[]KV{{Key: "Auto-Submitted", Value: "no"}}, true},
+		"autonocomment": {[]KV{{Key: "Auto-Submitted", Value: "No (manual)"}}, true},
+		"autoreplied":   {[]KV{{Key: "Auto-Submitted", Value: "auto-replied; owner-email=bob@example.com"}}, false},
+		"bulk":          {[]KV{{Key: "Precedence", Value: " Bulk"}}, false},
+		"listid":        {[]KV{{Key: "List-Id", Value: "<announce.example.com>"}}, false},
+		"suppress":      {[]KV{{Key: "X-Auto-Response-Suppress", Value: "DR, OOF"}}, false},
+		"suppressdr":    {[]KV{{Key: "X-Auto-Response-Suppress", Value: "DR, RN"}}, true},
+		"null":          {[]KV{{Key: "Return-Path", Value: "< >"}}, false},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := &Header{Headers: test.Headers}
+			got, reason := h.ShouldAutoRespond()
+			if got != test.Want {
+				t.Errorf("want %v, got %v (%s)", test.Want, got, reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package orderedheaders
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/textproto"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// batchKeys is the number of keys each ParseBatch worker shares between
+// the headers it reads
+const batchKeys = 1024
+
+// batchReader reads a header from a byte slice, and is reused between
+// inputs
+type batchReader struct {
+	src bytes.Reader
+	buf *bufio.Reader
+	tp  textproto.Reader
+}
+
+var batchReaderPool = sync.Pool{
+	New: func() interface{} {
+		b := &batchReader{}
+		b.buf = bufio.NewReader(&b.src)
+		b.tp.R = b.buf
+		return b
+	},
+}
+
+// read reads the header at the start of input
+func (b *batchReader) read(input []byte, o ReadOptions) (Header, error) {
+	b.src.Reset(input)
+	b.buf.Reset(&b.src)
+	h, err := ReadHeaderWithOptions(&b.tp, o)
+	if err == io.EOF {
+		// a header with nothing after it
+		err = nil
+	}
+	return h, err
+}
+
+// ParseBatch reads the header at the start of each input, which may be a
+// whole message or just its header, using up to workers goroutines, or
+// one per CPU if workers isn't positive. The headers and errors are
+// returned in the same order as the inputs. Readers and buffers are
+// reused, and each worker shares key strings between the headers it
+// reads.
+func ParseBatch(inputs [][]byte, workers int) ([]Header, []error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(inputs) {
+		workers = len(inputs)
+	}
+	headers := make([]Header, len(inputs))
+	errs := make([]error, len(inputs))
+	var next int64 = -1
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b := batchReaderPool.Get().(*batchReader)
+			defer func() {
+				b.src.Reset(nil)
+				b.buf.Reset(&b.src)
+				batchReaderPool.Put(b)
+			}()
+			o := ReadOptions{Keys: NewKeyCache(batchKeys)}
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= len(inputs) {
+					return
+				}
+				headers[i], errs[i] = b.read(inputs[i], o)
+			}
+		}()
+	}
+	wg.Wait()
+	return headers, errs
+}
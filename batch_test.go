@@ -0,0 +1,51 @@
+package orderedheaders
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestParseBatch(t *testing.T) {
+	var inputs [][]byte
+	for i := 0; i < 200; i++ {
+		switch i % 4 {
+		case 0:
+			inputs = append(inputs, []byte(fmt.Sprintf("Subject: message %d\r\nX-Custom: %d\r\n\r\nbody\r\n", i, i)))
+		case 1:
+			inputs = append(inputs, []byte(fmt.Sprintf("Subject: message %d\r\n  folded\r\n", i)))
+		case 2:
+			inputs = append(inputs, []byte("not a header\r\n\r\n"))
+		case 3:
+			inputs = append(inputs, nil)
+		}
+	}
+	for _, workers := range []int{0, 1, 7, 1000} {
+		headers, errs := ParseBatch(inputs, workers)
+		if len(headers) != len(inputs) || len(errs) != len(inputs) {
+			t.Fatalf("workers %d: got %d headers and %d errors", workers, len(headers), len(errs))
+		}
+		for i := range inputs {
+			var want []KV
+			switch i % 4 {
+			case 0:
+				want = []KV{{Key: "Subject", Value: fmt.Sprintf("message %d", i)}, {Key: "X-Custom", Value: fmt.Sprint(i)}}
+			case 1:
+				want = []KV{{Key: "Subject", Value: fmt.Sprintf("message %d folded", i)}}
+			}
+			if (errs[i] != nil) != (i%4 == 2) {
+				t.Errorf("workers %d, input %d: unexpected error %v", workers, i, errs[i])
+			}
+			if !reflect.DeepEqual(headers[i].Headers, want) && len(headers[i].Headers)+len(want) > 0 {
+				t.Errorf("workers %d, input %d: got %q, want %q", workers, i, headers[i].Headers, want)
+			}
+		}
+	}
+}
+
+func TestParseBatchEmpty(t *testing.T) {
+	headers, errs := ParseBatch(nil, 4)
+	if len(headers) != 0 || len(errs) != 0 {
+		t.Errorf("got %d headers and %d errors", len(headers), len(errs))
+	}
+}
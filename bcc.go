@@ -0,0 +1,94 @@
+package orderedheaders
+
+import (
+	"bytes"
+	"io"
+	"net/mail"
+)
+
+// https://tools.wordtothewise.com/rfc5322#section-3.6.3
+
+// RenderPerBccRecipient renders the message once for the visible
+// recipients and once more for each Bcc recipient, calling fn with each
+// rendering, as a submission agent does when sending blind copies
+// separately. The first call has a nil recipient and no Bcc field. The
+// following calls are for each Bcc address in turn: if o.RenderBCC is
+// set each has a Bcc field naming just that recipient, in place of the
+// first Bcc field, otherwise none. Other fields are written as Rewrite
+// writes them, so fields read with KeepRaw are unchanged. The body is
+// read into memory unless it can be seeked back to its start. If fn
+// returns an error rendering stops and it's returned.
+func (m *Message) RenderPerBccRecipient(o Options, fn func(recipient *mail.Address, rendered []byte) error) error {
+	m.Header.CanonicalizeKeys()
+	var recipients []*mail.Address
+	first := -1
+	var others []KV
+	for _, kv := range m.Header.Headers {
+		if kv.Key != HdrBcc {
+			others = append(others, kv)
+			continue
+		}
+		if first < 0 {
+			first = len(others)
+		}
+		if StripComments(kv.Value) == "" {
+			continue
+		}
+		addrs, err := mail.ParseAddressList(kv.Value)
+		if err != nil {
+			return err
+		}
+		recipients = append(recipients, addrs...)
+	}
+
+	body, err := m.rewindableBody()
+	if err != nil {
+		return err
+	}
+	start, err := body.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	render := func(recipient *mail.Address) error {
+		headers := others
+		if recipient != nil && o.RenderBCC {
+			headers = make([]KV, 0, len(others)+1)
+			headers = append(headers, others[:first]...)
+			headers = append(headers, KV{Key: HdrBcc, Value: recipient.String()})
+			headers = append(headers, others[first:]...)
+		}
+		if _, err := body.Seek(start, io.SeekStart); err != nil {
+			return err
+		}
+		copy := Message{Header: Header{Headers: headers}, Body: body, separator: m.separator}
+		var buf bytes.Buffer
+		if err := copy.Rewrite(&buf, o); err != nil {
+			return err
+		}
+		return fn(recipient, buf.Bytes())
+	}
+	if err := render(nil); err != nil {
+		return err
+	}
+	for _, r := range recipients {
+		if err := render(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewindableBody returns the body as an io.ReadSeeker, buffering it in
+// memory if it isn't one already
+func (m *Message) rewindableBody() (io.ReadSeeker, error) {
+	if m.Body == nil {
+		m.Body = bytes.NewReader(nil)
+	}
+	if rs, ok := m.Body.(io.ReadSeeker); ok {
+		return rs, nil
+	}
+	if err := m.buffer(MessageOptions{}); err != nil {
+		return nil, err
+	}
+	return m.Body.(io.ReadSeeker), nil
+}
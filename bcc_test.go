@@ -0,0 +1,95 @@
+package orderedheaders
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/mail"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRenderPerBccRecipient(t *testing.T) {
+	in := "From: a@example.com\r\n" +
+		"To: b@example.com\r\n" +
+		"Bcc: c@example.com, \"Dee\" <d@example.com>\r\n" +
+		"Subject:  Hello\r\n" +
+		"Bcc: e@example.com\r\n" +
+		"\r\n" +
+		"body\r\n"
+	visible := "From: a@example.com\r\nTo: b@example.com\r\nSubject:  Hello\r\n\r\nbody\r\n"
+	withBcc := func(bcc string) string {
+		return strings.Replace(visible, "Subject:", "Bcc: "+bcc+"\r\nSubject:", 1)
+	}
+	tests := map[string]struct {
+		In      string
+		Options Options
+		Want    []string
+	}{
+		"none": {In: in, Want: []string{visible, visible, visible, visible}},
+		"own": {In: in, Options: Options{RenderBCC: true}, Want: []string{
+			visible,
+			withBcc("<c@example.com>"),
+			withBcc("\"Dee\" <d@example.com>"),
+			withBcc("<e@example.com>"),
+		}},
+		"nobcc":    {In: visible, Options: Options{RenderBCC: true}, Want: []string{visible}},
+		"emptybcc": {In: "From: a@example.com\r\nBcc:\r\n\r\nbody\r\n", Options: Options{RenderBCC: true}, Want: []string{"From: a@example.com\r\n\r\nbody\r\n"}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m, err := ReadMessageWithOptions(strings.NewReader(test.In), MessageOptions{ReadOptions: ReadOptions{KeepRaw: true}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// hide the body's Seek, so it has to be read into memory
+			m.Body = ioutil.NopCloser(m.Body)
+			var got []string
+			var recipients []*mail.Address
+			err = m.RenderPerBccRecipient(test.Options, func(recipient *mail.Address, rendered []byte) error {
+				recipients = append(recipients, recipient)
+				got = append(got, string(rendered))
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.Want) {
+				t.Errorf("got %q, want %q", got, test.Want)
+			}
+			if recipients[0] != nil {
+				t.Errorf("first recipient is %v, want nil", recipients[0])
+			}
+		})
+	}
+}
+
+func TestRenderPerBccRecipientError(t *testing.T) {
+	m, err := ReadMessage(strings.NewReader("To: a@example.com\r\nBcc: b@example.com, c@example.com\r\n\r\nbody\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := errors.New("stop")
+	calls := 0
+	err = m.RenderPerBccRecipient(Options{}, func(recipient *mail.Address, rendered []byte) error {
+		calls++
+		if recipient != nil {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Errorf("got error %v, want %v", err, stop)
+	}
+	if calls != 2 {
+		t.Errorf("got %d calls, want 2", calls)
+	}
+
+	m, err = ReadMessage(strings.NewReader("Bcc: not an address\r\n\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.RenderPerBccRecipient(Options{}, func(*mail.Address, []byte) error { return nil }); err == nil {
+		t.Error("expected an error for an invalid Bcc")
+	}
+}
@@ -0,0 +1,100 @@
+package orderedheaders
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+)
+
+// https://datatracker.ietf.org/doc/html/draft-brand-indicators-for-message-identification#section-5.1
+
+const HdrBIMISelector = "Bimi-Selector"
+
+// bimiVersion is the only defined value of the v= tag
+const bimiVersion = "BIMI1"
+
+// DefaultBIMISelector is used by receivers when there's no BIMI-Selector
+const DefaultBIMISelector = "default"
+
+// BIMISelector is a parsed BIMI-Selector header, which tells receivers
+// which BIMI assertion record to use for a message
+type BIMISelector struct {
+	// Selector is the s= tag
+	Selector string
+	// Tags is every tag in the header, in the order they appear
+	Tags []Tag
+}
+
+// String renders the BIMI-Selector value
+func (b BIMISelector) String() string {
+	return "v=" + bimiVersion + "; s=" + b.Selector
+}
+
+// ParseBIMISelector parses and validates a BIMI-Selector value. The v=
+// tag must come first and be BIMI1, and s= is required.
+func ParseBIMISelector(value string) (BIMISelector, error) {
+	tags, err := parseTagList(value)
+	if err != nil {
+		return BIMISelector{}, fmt.Errorf("invalid BIMI-Selector: %w", err)
+	}
+	if len(tags) == 0 || tags[0].Name != "v" {
+		return BIMISelector{}, errors.New("invalid BIMI-Selector: v= must be the first tag")
+	}
+	if tags[0].Value != bimiVersion {
+		return BIMISelector{}, fmt.Errorf("invalid BIMI-Selector: unsupported version '%s'", tags[0].Value)
+	}
+	s, ok := lookupTag(tags, "s")
+	if !ok {
+		return BIMISelector{}, errors.New("invalid BIMI-Selector: missing s= tag")
+	}
+	if err := validSelector(s); err != nil {
+		return BIMISelector{}, fmt.Errorf("invalid BIMI-Selector: %w", err)
+	}
+	return BIMISelector{Selector: s, Tags: tags}, nil
+}
+
+// ValidateBIMISelector checks the syntax of a BIMI-Selector value
+func ValidateBIMISelector(value string) error {
+	_, err := ParseBIMISelector(value)
+	return err
+}
+
+// validSelector checks selector = sub-domain *( "." sub-domain ), where
+// each sub-domain is letters, digits and interior hyphens
+func validSelector(s string) error {
+	for _, label := range strings.Split(s, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return fmt.Errorf("'%s' is not a valid selector", s)
+		}
+		for i := 0; i < len(label); i++ {
+			c := label[i]
+			switch {
+			case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-':
+			default:
+				return fmt.Errorf("'%s' is not a valid selector", s)
+			}
+		}
+	}
+	return nil
+}
+
+// BIMISelector parses the BIMI-Selector header
+func (h *Header) BIMISelector() (BIMISelector, error) {
+	value := h.Get(HdrBIMISelector)
+	if value == "" {
+		return BIMISelector{}, mail.ErrHeaderNotPresent
+	}
+	return ParseBIMISelector(value)
+}
+
+// SetBIMISelector sets the BIMI-Selector header to use the given
+// selector. An empty selector removes the header, so receivers use the
+// default.
+func (h *Header) SetBIMISelector(selector string) error {
+	if selector == "" {
+		h.RemoveAll(HdrBIMISelector)
+		return nil
+	}
+	return h.Set(HdrBIMISelector, BIMISelector{Selector: selector}.String())
+}
@@ -0,0 +1,73 @@
+package orderedheaders
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseBIMISelector(t *testing.T) {
+	tests := map[string]struct {
+		In        string
+		WantError bool
+		Want      BIMISelector
+	}{
+		"simple": {In: "v=BIMI1; s=brand;", Want: BIMISelector{Selector: "brand",
+			Tags: []Tag{{Name: "v", Value: "BIMI1"}, {Name: "s", Value: "brand"}}}},
+		"dotted": {In: " v=BIMI1;\r\n\ts=spring-2023.brand", Want: BIMISelector{Selector: "spring-2023.brand",
+			Tags: []Tag{{Name: "v", Value: "BIMI1"}, {Name: "s", Value: "spring-2023.brand"}}}},
+		"noversion":  {In: "s=brand", WantError: true},
+		"notfirst":   {In: "s=brand; v=BIMI1", WantError: true},
+		"badversion": {In: "v=BIMI2; s=brand", WantError: true},
+		"noselector": {In: "v=BIMI1", WantError: true},
+		"badselect":  {In: "v=BIMI1; s=-brand", WantError: true},
+		"empty":      {In: "v=BIMI1; s=a..b", WantError: true},
+		"duplicate":  {In: "v=BIMI1; s=a; s=b", WantError: true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseBIMISelector(test.In)
+			if test.WantError {
+				if err == nil {
+					t.Errorf("expected error, didn't get one")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.Want, got); diff != "" {
+				t.Errorf("BIMISelector mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSetBIMISelector(t *testing.T) {
+	h := &Header{}
+	if err := h.SetBIMISelector("brand"); err != nil {
+		t.Fatal(err)
+	}
+	if got := h.Get(HdrBIMISelector); got != "v=BIMI1; s=brand" {
+		t.Errorf("unexpected BIMI-Selector '%s'", got)
+	}
+	sel, err := h.BIMISelector()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sel.Selector != "brand" {
+		t.Errorf("unexpected selector '%s'", sel.Selector)
+	}
+	if err := h.SetBIMISelector("not valid"); err == nil {
+		t.Errorf("expected error setting invalid selector")
+	}
+	if err := h.Set("BIMI-Selector", "v=BIMI1"); err == nil {
+		t.Errorf("expected Set to validate BIMI-Selector")
+	}
+	if err := h.SetBIMISelector(""); err != nil {
+		t.Fatal(err)
+	}
+	if h.Has(HdrBIMISelector) {
+		t.Errorf("expected BIMI-Selector to be removed")
+	}
+}
@@ -0,0 +1,361 @@
+package orderedheaders
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"mime"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// https://tools.wordtothewise.com/rfc2046#section-5.1
+
+// maxLineLength is the longest line, excluding CRLF, permitted in 7bit
+// and 8bit content
+// https://tools.wordtothewise.com/rfc5322#section-2.1.1
+const maxLineLength = 998
+
+// Builder constructs a MIME message. Methods can be chained, and any
+// error is reported by Build.
+//
+//	msg, err := NewBuilder().
+//		From(&mail.Address{Address: "alice@example.com"}).
+//		To(&mail.Address{Address: "bob@example.com"}).
+//		Subject("Hello").
+//		Text("Hello, world").
+//		HTML("<p>Hello, world</p>").
+//		Build()
+//
+// With both Text and HTML the body is multipart/alternative, inline
+// parts are combined with the HTML in multipart/related, and adding
+// further parts wraps that in multipart/mixed.
+type Builder struct {
+	from    []*mail.Address
+	to      []*mail.Address
+	cc      []*mail.Address
+	bcc     []*mail.Address
+	replyTo []*mail.Address
+	subject string
+	date    time.Time
+	extra   Header
+	text    *string
+	html    *string
+	inline  []builderPart
+	parts   []builderPart
+	// reportType is the report-type of a multipart/report
+	reportType string
+	err        error
+}
+
+// builderPart is a body part that's ready to render
+type builderPart struct {
+	header Header
+	body   []byte
+	// raw is set when body is the complete part, header included, and
+	// must be written unchanged
+	raw bool
+}
+
+// NewBuilder returns an empty Builder
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// setErr records the first error seen
+func (b *Builder) setErr(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// From sets the author addresses
+func (b *Builder) From(addrs ...*mail.Address) *Builder {
+	b.from = addrs
+	return b
+}
+
+// To sets the primary recipients
+func (b *Builder) To(addrs ...*mail.Address) *Builder {
+	b.to = addrs
+	return b
+}
+
+// Cc sets the carbon copy recipients
+func (b *Builder) Cc(addrs ...*mail.Address) *Builder {
+	b.cc = addrs
+	return b
+}
+
+// Bcc sets the blind carbon copy recipients. The Bcc header isn't
+// rendered by WriteTo unless Options.RenderBCC is set.
+func (b *Builder) Bcc(addrs ...*mail.Address) *Builder {
+	b.bcc = addrs
+	return b
+}
+
+// ReplyTo sets the addresses replies should be sent to
+func (b *Builder) ReplyTo(addrs ...*mail.Address) *Builder {
+	b.replyTo = addrs
+	return b
+}
+
+// Subject sets the subject
+func (b *Builder) Subject(s string) *Builder {
+	b.subject = s
+	return b
+}
+
+// Date sets the date of the message. If it's not set Build uses the
+// current time.
+func (b *Builder) Date(t time.Time) *Builder {
+	b.date = t
+	return b
+}
+
+// Header sets an additional header, using SetWithOptions, so it's
+// validated. Fields that aren't registered, such as X- fields, are
+// allowed as long as the name is valid. These are rendered after the
+// standard headers, in the order they were set.
+func (b *Builder) Header(key, value string) *Builder {
+	b.setErr(b.extra.SetWithOptions(key, value, SetOptions{AllowUnknown: true}))
+	return b
+}
+
+// Text sets the plain text body
+func (b *Builder) Text(s string) *Builder {
+	b.text = &s
+	return b
+}
+
+// HTML sets the HTML body
+func (b *Builder) HTML(s string) *Builder {
+	b.html = &s
+	return b
+}
+
+// Report makes the message a multipart/report with the given
+// report-type, such as delivery-status, rather than multipart/mixed. The
+// text body is the human readable first part, and parts added with Part
+// follow it.
+// https://tools.wordtothewise.com/rfc6522#section-3
+func (b *Builder) Report(reportType string) *Builder {
+	b.reportType = reportType
+	return b
+}
+
+// Part adds a body part with the given header and already encoded body,
+// after the text and HTML bodies
+func (b *Builder) Part(h Header, body []byte) *Builder {
+	b.parts = append(b.parts, builderPart{header: h, body: body})
+	return b
+}
+
+// Build assembles the message. The header has Date, From, Reply-To, To,
+// Cc, Bcc, Subject, Message-Id, any additional headers and then the
+// MIME headers, in that order. A Message-Id is generated using the
+// domain of the first From address.
+func (b *Builder) Build() (*Message, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if len(b.from) == 0 {
+		return nil, errors.New("a message requires a From address")
+	}
+	msg := &Message{}
+	h := &msg.Header
+	date := b.date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	h.SetDate(date)
+	for _, f := range []struct {
+		key   string
+		addrs []*mail.Address
+	}{
+		{HdrFrom, b.from},
+		{HdrReplyTo, b.replyTo},
+		{HdrTo, b.to},
+		{HdrCc, b.cc},
+		{HdrBcc, b.bcc},
+	} {
+		if len(f.addrs) == 0 {
+			continue
+		}
+		if err := h.Set(f.key, formatAddressList(f.addrs)); err != nil {
+			return nil, err
+		}
+	}
+	if b.subject != "" {
+		if err := h.Set(HdrSubject, b.subject); err != nil {
+			return nil, err
+		}
+	}
+	domain := b.from[0].Address[strings.LastIndexByte(b.from[0].Address, '@')+1:]
+	if err := h.EnsureMessageID(domain); err != nil {
+		return nil, err
+	}
+	h.Headers = append(h.Headers, b.extra.Headers...)
+
+	root, err := b.body()
+	if err != nil {
+		return nil, err
+	}
+	if err := h.Set(HdrMimeVersion, "1.0"); err != nil {
+		return nil, err
+	}
+	h.Headers = append(h.Headers, root.header.Headers...)
+	msg.Body = bytes.NewReader(root.body)
+	return msg, nil
+}
+
+// body builds the top level body part
+func (b *Builder) body() (builderPart, error) {
+	var alternatives []builderPart
+	if b.text != nil {
+		alternatives = append(alternatives, textPart("text/plain", *b.text))
+	}
+	if b.html != nil {
+		alternatives = append(alternatives, textPart("text/html", *b.html))
+	}
+	if len(b.inline) > 0 {
+		// inline parts are related to the richest alternative
+		var root []builderPart
+		if len(alternatives) > 0 {
+			root = alternatives[len(alternatives)-1:]
+			alternatives = alternatives[:len(alternatives)-1]
+		}
+		related, err := multipartPart("related", nil, append(root, b.inline...))
+		if err != nil {
+			return builderPart{}, err
+		}
+		alternatives = append(alternatives, related)
+	}
+	var main []builderPart
+	switch len(alternatives) {
+	case 0:
+		if len(b.parts) == 0 {
+			main = []builderPart{textPart("text/plain", "")}
+		}
+	case 1:
+		main = alternatives
+	default:
+		alt, err := multipartPart("alternative", nil, alternatives)
+		if err != nil {
+			return builderPart{}, err
+		}
+		main = []builderPart{alt}
+	}
+	all := append(main, b.parts...)
+	if b.reportType != "" {
+		return multipartPart("report", map[string]string{"report-type": b.reportType}, all)
+	}
+	if len(all) == 1 {
+		return all[0], nil
+	}
+	return multipartPart("mixed", nil, all)
+}
+
+// textPart creates a UTF-8 text part, with whichever transfer encoding
+// suits the text
+func textPart(mediaType, text string) builderPart {
+	var p builderPart
+	p.header.Add(HdrContentType, mime.FormatMediaType(mediaType, map[string]string{"charset": utf8}))
+	text = strings.Replace(strings.Replace(text, "\r\n", "\n", -1), "\n", "\r\n", -1)
+	cte, body := encodeBody(mediaType, []byte(text))
+	p.header.Add(HdrContentTransferEncoding, cte)
+	p.body = body
+	return p
+}
+
+// is7bit checks whether text is ASCII with no NULs, no bare CR or LF and
+// short enough lines to be sent as 7bit
+func is7bit(text string) bool {
+	for _, line := range strings.Split(text, "\r\n") {
+		if len(line) > maxLineLength {
+			return false
+		}
+		for i := 0; i < len(line); i++ {
+			if line[i] == 0 || line[i] > 127 || line[i] == '\r' || line[i] == '\n' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+var boundaryEncoding = base32.NewEncoding("0123456789abcdefghijklmnopqrstuv").WithPadding(base32.NoPadding)
+
+// boundaryAttempts is how many boundaries multipartPart tries before
+// giving up, which only content crafted to collide could cause
+const boundaryAttempts = 4
+
+// GenerateBoundary returns a random multipart boundary. It's 26
+// characters, well within the 70 allowed, and has 120 bits of
+// randomness from crypto/rand. The "=_" prefix can't occur in base64 or
+// quoted-printable encoded content. '=' is a tspecial, so the boundary
+// parameter of a Content-Type has to be quoted.
+// https://tools.wordtothewise.com/rfc2046#section-5.1.1
+func GenerateBoundary() string {
+	var random [15]byte
+	if _, err := rand.Read(random[:]); err != nil {
+		// crypto/rand can't fail on supported platforms
+		panic(fmt.Sprintf("reading random bytes: %v", err))
+	}
+	return "=_" + boundaryEncoding.EncodeToString(random[:])
+}
+
+// generateBoundary is used by multipartPart, and replaced in tests
+var generateBoundary = GenerateBoundary
+
+// multipartPart combines parts into a multipart body part, using a
+// boundary that doesn't occur in any of them
+func multipartPart(subtype string, params map[string]string, parts []builderPart) (builderPart, error) {
+	rendered := make([][]byte, len(parts))
+	for i, part := range parts {
+		if part.raw {
+			rendered[i] = part.body
+			continue
+		}
+		var buf bytes.Buffer
+		if err := part.header.WriteTo(&buf, Options{}); err != nil {
+			return builderPart{}, err
+		}
+		buf.WriteString("\r\n")
+		buf.Write(part.body)
+		rendered[i] = buf.Bytes()
+	}
+
+	var boundary string
+	for attempt := 0; boundary == ""; attempt++ {
+		if attempt == boundaryAttempts {
+			return builderPart{}, errors.New("couldn't generate a boundary that isn't in the content")
+		}
+		boundary = generateBoundary()
+		for _, r := range rendered {
+			if bytes.Contains(r, []byte("--"+boundary)) {
+				boundary = ""
+				break
+			}
+		}
+	}
+
+	ctParams := map[string]string{"boundary": boundary}
+	for k, v := range params {
+		ctParams[k] = v
+	}
+	var p builderPart
+	p.header.Add(HdrContentType, mime.FormatMediaType("multipart/"+subtype, ctParams))
+	var buf bytes.Buffer
+	for _, r := range rendered {
+		buf.WriteString("--" + boundary + "\r\n")
+		buf.Write(r)
+		buf.WriteString("\r\n")
+	}
+	buf.WriteString("--" + boundary + "--\r\n")
+	p.body = buf.Bytes()
+	return p, nil
+}
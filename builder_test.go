@@ -0,0 +1,183 @@
+package orderedheaders
+
+import (
+	"io"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func keys(h Header) []string {
+	var ret []string
+	for _, kv := range h.Headers {
+		ret = append(ret, kv.Key)
+	}
+	return ret
+}
+
+func TestBuilder(t *testing.T) {
+	date := time.Date(2023, 5, 22, 10, 0, 0, 0, time.UTC)
+	msg, err := NewBuilder().
+		Subject("Hello").
+		To(&mail.Address{Name: "Bob", Address: "bob@example.com"}).
+		From(&mail.Address{Address: "alice@example.com"}).
+		Date(date).
+		Header("Keywords", "greeting").
+		Text("Hello, world\n").
+		HTML("<p>Hello, wörld</p>").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantKeys := []string{"Date", "From", "To", "Subject", "Message-Id", "Keywords", "Mime-Version", "Content-Type"}
+	if diff := cmp.Diff(wantKeys, keys(msg.Header)); diff != "" {
+		t.Errorf("header order mismatch (-want +got):\n%s", diff)
+	}
+	if got := msg.Header.Get("Date"); got != "Mon, 22 May 2023 10:00:00 +0000" {
+		t.Errorf("unexpected Date '%s'", got)
+	}
+	if !strings.HasSuffix(msg.Header.Get("Message-Id"), "@example.com>") {
+		t.Errorf("unexpected Message-Id '%s'", msg.Header.Get("Message-Id"))
+	}
+	if !strings.HasPrefix(msg.Header.Get("Content-Type"), "multipart/alternative;") {
+		t.Errorf("unexpected Content-Type '%s'", msg.Header.Get("Content-Type"))
+	}
+	parts, err := msg.Parts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 2 {
+		t.Fatalf("expected 2 parts, got %d", len(parts))
+	}
+	want := [][]KV{
+		{
+			{Key: "Content-Type", Value: "text/plain; charset=utf-8"},
+			{Key: "Content-Transfer-Encoding", Value: "7bit"},
+		},
+		{
+			{Key: "Content-Type", Value: "text/html; charset=utf-8"},
+			{Key: "Content-Transfer-Encoding", Value: "quoted-printable"},
+		},
+	}
+	wantBody := []string{"Hello, world\r\n", "<p>Hello, w=C3=B6rld</p>"}
+	for i, p := range parts {
+		for j := range p.Header.Headers {
+			p.Header.Headers[j].Raw = ""
+		}
+		if diff := cmp.Diff(want[i], p.Header.Headers); diff != "" {
+			t.Errorf("part %d header mismatch (-want +got):\n%s", i, diff)
+		}
+		body, _ := io.ReadAll(p.Body)
+		if string(body) != wantBody[i] {
+			t.Errorf("part %d: want body %q, got %q", i, wantBody[i], body)
+		}
+	}
+}
+
+func TestBuilderStructure(t *testing.T) {
+	from := &mail.Address{Address: "alice@example.com"}
+	var extra Header
+	extra.Add("Content-Type", "application/octet-stream")
+	tests := map[string]struct {
+		Builder *Builder
+		Want    []string
+	}{
+		"empty": {NewBuilder().From(from), []string{"text/plain"}},
+		"text":  {NewBuilder().From(from).Text("hi"), []string{"text/plain"}},
+		"html":  {NewBuilder().From(from).HTML("hi"), []string{"text/html"}},
+		"mixed": {NewBuilder().From(from).Text("hi").HTML("hi").Part(extra, []byte("x")),
+			[]string{"multipart/mixed", " multipart/alternative", "  text/plain", "  text/html", " application/octet-stream"}},
+		"textpart": {NewBuilder().From(from).Text("hi").Part(extra, []byte("x")),
+			[]string{"multipart/mixed", " text/plain", " application/octet-stream"}},
+		"partonly": {NewBuilder().From(from).Part(extra, []byte("x")),
+			[]string{"application/octet-stream"}},
+		"report": {NewBuilder().From(from).Report("delivery-status").Text("hi").Part(extra, []byte("x")),
+			[]string{"multipart/report", " text/plain", " application/octet-stream"}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			msg, err := test.Builder.Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			err = msg.Walk(func(part *Message, depth int) error {
+				got = append(got, strings.Repeat(" ", depth)+strings.SplitN(part.Header.Get("Content-Type"), ";", 2)[0])
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.Want, got); diff != "" {
+				t.Errorf("structure mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	if _, err := NewBuilder().Text("hi").Build(); err == nil {
+		t.Errorf("expected error without From")
+	}
+	from := &mail.Address{Address: "alice@example.com"}
+	if _, err := NewBuilder().From(from).Header("X Unknown", "x").Build(); err == nil {
+		t.Errorf("expected error setting invalid field name")
+	}
+	if _, err := NewBuilder().From(from).Header("In-Reply-To", "nope").Build(); err == nil {
+		t.Errorf("expected error setting invalid In-Reply-To")
+	}
+	msg, err := NewBuilder().From(from).Header("X-Unknown", "x").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := msg.Header.Get("X-Unknown"); got != "x" {
+		t.Errorf("want X-Unknown %q, got %q", "x", got)
+	}
+}
+
+func TestGenerateBoundary(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		b := GenerateBoundary()
+		if len(b) == 0 || len(b) > 70 {
+			t.Fatalf("boundary '%s' has invalid length", b)
+		}
+		for _, c := range b {
+			if !strings.ContainsRune("0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ'()+_,-./:=?", c) {
+				t.Fatalf("boundary '%s' has invalid character %q", b, c)
+			}
+		}
+		if seen[b] {
+			t.Fatalf("boundary '%s' generated twice", b)
+		}
+		seen[b] = true
+	}
+}
+
+func TestBoundaryCollision(t *testing.T) {
+	defer func(f func() string) { generateBoundary = f }(generateBoundary)
+	candidates := []string{"clash", "clash", "=_ok"}
+	generateBoundary = func() string {
+		b := candidates[0]
+		if len(candidates) > 1 {
+			candidates = candidates[1:]
+		}
+		return b
+	}
+	parts := []builderPart{textPart("text/plain", "--clash\r\n")}
+	p, err := multipartPart("mixed", nil, parts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `multipart/mixed; boundary="=_ok"`; p.header.Get(HdrContentType) != want {
+		t.Errorf("want %s, got %s", want, p.header.Get(HdrContentType))
+	}
+
+	candidates = []string{"clash"}
+	if _, err := multipartPart("mixed", nil, parts); err == nil {
+		t.Errorf("expected error when every boundary collides")
+	}
+}
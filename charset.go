@@ -0,0 +1,100 @@
+package orderedheaders
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// https://tools.wordtothewise.com/rfc2046#section-4.1.2
+
+// CharsetReaders maps lower case charset names to a function that
+// converts text in that charset to UTF-8. Only UTF-8, US-ASCII,
+// ISO-8859-1 and Windows-1252 are supported by default; others, such as
+// those provided by golang.org/x/text, can be added with RegisterCharset.
+var CharsetReaders = map[string]func(io.Reader) (io.Reader, error){
+	"utf-8":        identityCharset,
+	"utf8":         identityCharset,
+	"us-ascii":     identityCharset,
+	"ascii":        identityCharset,
+	"iso-8859-1":   latin1Charset,
+	"iso8859-1":    latin1Charset,
+	"latin1":       latin1Charset,
+	"windows-1252": windows1252Charset,
+	"cp1252":       windows1252Charset,
+}
+
+// RegisterCharset adds a converter from a charset to UTF-8, for use by
+// TextBody
+func RegisterCharset(name string, reader func(io.Reader) (io.Reader, error)) {
+	CharsetReaders[strings.ToLower(name)] = reader
+}
+
+// ErrNotText is returned by TextBody for a part that isn't text/*
+var ErrNotText = errors.New("message is not text")
+
+// TextBody returns the body of a text/* message or part, with the
+// Content-Transfer-Encoding decoded and converted from its charset to
+// UTF-8.
+func (m *Message) TextBody() (io.Reader, error) {
+	mediaType, params := m.mediaType()
+	if !strings.HasPrefix(mediaType, "text/") {
+		return nil, ErrNotText
+	}
+	charset := strings.ToLower(strings.TrimSpace(params["charset"]))
+	if charset == "" {
+		charset = "us-ascii"
+	}
+	convert, ok := CharsetReaders[charset]
+	if !ok {
+		return nil, fmt.Errorf("unsupported charset '%s'", charset)
+	}
+	return convert(decodeTransferEncoding(m.Header.Get(HdrContentTransferEncoding), m.Body))
+}
+
+func identityCharset(r io.Reader) (io.Reader, error) {
+	return r, nil
+}
+
+func latin1Charset(r io.Reader) (io.Reader, error) {
+	return &singleByteReader{r: bufio.NewReader(r)}, nil
+}
+
+func windows1252Charset(r io.Reader) (io.Reader, error) {
+	return &singleByteReader{r: bufio.NewReader(r), high: &windows1252}, nil
+}
+
+// windows1252 maps 0x80-0x9f, where Windows-1252 differs from ISO-8859-1
+var windows1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8d, 'Ž', 0x8f,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9d, 'ž', 'Ÿ',
+}
+
+// singleByteReader converts a single byte charset, which matches
+// ISO-8859-1 other than optionally in the range 0x80-0x9f, to UTF-8
+type singleByteReader struct {
+	r       *bufio.Reader
+	high    *[32]rune
+	pending bytes.Buffer
+}
+
+func (s *singleByteReader) Read(p []byte) (int, error) {
+	for s.pending.Len() < len(p) {
+		c, err := s.r.ReadByte()
+		if err != nil {
+			if s.pending.Len() > 0 {
+				break
+			}
+			return 0, err
+		}
+		r := rune(c)
+		if s.high != nil && c >= 0x80 && c < 0xa0 {
+			r = s.high[c-0x80]
+		}
+		s.pending.WriteRune(r)
+	}
+	return s.pending.Read(p)
+}
@@ -0,0 +1,82 @@
+package orderedheaders
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestTextBody(t *testing.T) {
+	tests := map[string]struct {
+		Header    string
+		Body      string
+		Want      string
+		WantError bool
+	}{
+		"default":     {Header: "X-Test: 1", Body: "plain", Want: "plain"},
+		"utf8":        {Header: "Content-Type: text/plain; charset=UTF-8", Body: "caf\xc3\xa9", Want: "café"},
+		"latin1":      {Header: "Content-Type: text/plain; charset=iso-8859-1", Body: "caf\xe9", Want: "café"},
+		"windows1252": {Header: "Content-Type: text/html; charset=windows-1252", Body: "\x93quoted\x94 \x80", Want: "“quoted” €"},
+		"qp": {Header: "Content-Type: text/plain; charset=iso-8859-1\r\nContent-Transfer-Encoding: quoted-printable",
+			Body: "caf=E9 =\r\nsoft", Want: "café soft"},
+		"base64": {Header: "Content-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: BASE64",
+			Body: "Y2Fmw6k=\r\n", Want: "café"},
+		"unknown": {Header: "Content-Type: text/plain; charset=x-unknown", WantError: true},
+		"image":   {Header: "Content-Type: image/png", WantError: true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			msg, err := ReadMessage(strings.NewReader(test.Header + "\r\n\r\n" + test.Body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r, err := msg.TextBody()
+			if test.WantError {
+				if err == nil {
+					t.Errorf("expected error, didn't get one")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.Want {
+				t.Errorf("want %q, got %q", test.Want, got)
+			}
+		})
+	}
+}
+
+func TestRegisterCharset(t *testing.T) {
+	RegisterCharset("X-Upper", func(r io.Reader) (io.Reader, error) {
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(bytes.ToUpper(b)), nil
+	})
+	t.Cleanup(func() {
+		delete(CharsetReaders, "x-upper")
+	})
+	msg, err := ReadMessage(strings.NewReader("Content-Type: text/plain; charset=x-upper\r\n\r\nshout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := msg.TextBody()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(r)
+	if string(got) != "SHOUT" {
+		t.Errorf("unexpected body %q", got)
+	}
+	if _, err := (&Message{Header: Header{Headers: []KV{{Key: "Content-Type", Value: "image/png"}}}}).TextBody(); !errors.Is(err, ErrNotText) {
+		t.Errorf("expected ErrNotText, got %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+// Command hdrfmt reformats the header of an email message, read from the
+// files named on the command line or from stdin. Fields are unfolded,
+// optionally normalized and sorted, and written again with consistent
+// folding and encoding. The body is copied unchanged.
+//
+//	hdrfmt [-w] [-l] [-sort] [-normalize=false] [-wrap n] [-8bit] [file ...]
+//
+// By default the result is written to stdout. With -w files are
+// rewritten in place, and with -l the names of files that would change
+// are listed. It exits 0 on success, 1 if -l listed any files, and 2 if a
+// message couldn't be read or written.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/wttw/orderedheaders"
+)
+
+const (
+	exitOK      = 0
+	exitChanged = 1
+	exitFailed  = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+type formatter struct {
+	pipeline *orderedheaders.Pipeline
+	options  orderedheaders.Options
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("hdrfmt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	write := flags.Bool("w", false, "write the result to the file rather than stdout")
+	list := flags.Bool("l", false, "list files whose formatting would change")
+	sortFields := flags.Bool("sort", false, "sort fields into the conventional order")
+	normalize := flags.Bool("normalize", true, "repair irregular whitespace in values")
+	wrap := flags.Int("wrap", 0, "fold lines at this column, rather than 78")
+	noEscape := flags.Bool("8bit", false, "don't encode non-ascii text")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: hdrfmt [-w] [-l] [-sort] [-normalize=false] [-wrap n] [-8bit] [file ...]\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitFailed
+	}
+	if (*write || *list) && flags.NArg() == 0 {
+		fmt.Fprintf(stderr, "hdrfmt: -w and -l need files\n")
+		return exitFailed
+	}
+
+	f := formatter{
+		pipeline: orderedheaders.NewPipeline(),
+		options: orderedheaders.Options{
+			RenderBCC:   true,
+			RenderBlank: true,
+			NoEscape:    *noEscape,
+			WrapColumn:  *wrap,
+		},
+	}
+	if *normalize {
+		f.pipeline.Add("normalize", orderedheaders.NormalizeTransform())
+	}
+	if *sortFields {
+		f.pipeline.Add("sort", orderedheaders.SortTransform(orderedheaders.CanonicalOrder))
+	}
+
+	if flags.NArg() == 0 {
+		src, err := ioutil.ReadAll(stdin)
+		if err == nil {
+			var out []byte
+			out, err = f.format(src)
+			if err == nil {
+				_, err = stdout.Write(out)
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "<stdin>: %v\n", err)
+			return exitFailed
+		}
+		return exitOK
+	}
+
+	ret := exitOK
+	for _, name := range flags.Args() {
+		changed, err := f.file(name, *write, *list, stdout)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", name, err)
+			ret = exitFailed
+			continue
+		}
+		if changed && *list && ret == exitOK {
+			ret = exitChanged
+		}
+	}
+	return ret
+}
+
+// file formats the named file, reporting whether its formatting changed
+func (f formatter) file(name string, write, list bool, stdout io.Writer) (bool, error) {
+	src, err := ioutil.ReadFile(name)
+	if err != nil {
+		return false, err
+	}
+	out, err := f.format(src)
+	if err != nil {
+		return false, err
+	}
+	changed := !bytes.Equal(src, out)
+	if list && changed {
+		fmt.Fprintln(stdout, name)
+	}
+	if write {
+		if changed {
+			return true, replaceFile(name, out)
+		}
+		return false, nil
+	}
+	if !list {
+		_, err = stdout.Write(out)
+	}
+	return changed, err
+}
+
+// format reformats the header of the message in src
+func (f formatter) format(src []byte) ([]byte, error) {
+	m, err := orderedheaders.ReadMessage(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	if err := f.pipeline.Apply(&m.Header); err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := m.Rewrite(&out, f.options); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// replaceFile atomically replaces the contents of the named file, keeping
+// its permissions
+func replaceFile(name string, data []byte) error {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(name), "."+filepath.Base(name)+".")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(fi.Mode().Perm()); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const messy = "Subject:   a   messy\r\n" +
+	"   subject\r\n" +
+	"X-Mailer: Mailer 1.0\r\n" +
+	"From: alice@example.com\r\n" +
+	"Date: Mon, 1 Jan 2024 00:00:00 +0000\r\n" +
+	"\r\n" +
+	"body  text\r\n"
+
+const tidy = "Subject: a messy subject\r\n" +
+	"X-Mailer: Mailer 1.0\r\n" +
+	"From: <alice@example.com>\r\n" +
+	"Date: Mon, 1 Jan 2024 00:00:00 +0000\r\n" +
+	"\r\n" +
+	"body  text\r\n"
+
+func TestRun(t *testing.T) {
+	long := "Subject: " + strings.TrimSpace(strings.Repeat("word ", 20)) + "\n\nbody\n"
+	tests := map[string]struct {
+		Args   []string
+		Stdin  string
+		Want   int
+		Stdout string
+	}{
+		"format": {Stdin: messy, Want: exitOK, Stdout: tidy},
+		"sort": {Args: []string{"-sort"}, Stdin: messy, Want: exitOK, Stdout: "Date: Mon, 1 Jan 2024 00:00:00 +0000\r\n" +
+			"From: <alice@example.com>\r\n" +
+			"Subject: a messy subject\r\n" +
+			"X-Mailer: Mailer 1.0\r\n" +
+			"\r\n" +
+			"body  text\r\n"},
+		"no normalize": {Args: []string{"-normalize=false"}, Stdin: "Subject:  a   b\r\n\r\n", Want: exitOK, Stdout: "Subject: a   b\r\n\r\n"},
+		"encode":       {Stdin: "Subject: café\r\n\r\n", Want: exitOK, Stdout: "Subject: =?utf-8?q?caf=C3=A9?=\r\n\r\n"},
+		"8bit":         {Args: []string{"-8bit"}, Stdin: "Subject: café\r\n\r\n", Want: exitOK, Stdout: "Subject: café\r\n\r\n"},
+		"wrap":         {Args: []string{"-wrap", "40"}, Stdin: long, Want: exitOK, Stdout: "Subject: word word word word word word\n word word word word word word word word\n word word word word word word\n\nbody\n"},
+		"unwritable":   {Stdin: "Subject: ok\r\nX-Long: " + strings.Repeat("x", 1000) + "\r\n\r\n", Want: exitFailed},
+		"needs files":  {Args: []string{"-w"}, Want: exitFailed},
+		"usage":        {Args: []string{"-nope"}, Want: exitFailed},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			got := run(test.Args, strings.NewReader(test.Stdin), &stdout, &stderr)
+			if got != test.Want {
+				t.Errorf("want exit %d, got %d: %s", test.Want, got, stderr.String())
+			}
+			if stdout.String() != test.Stdout {
+				t.Errorf("want output\n%q\ngot\n%q", test.Stdout, stdout.String())
+			}
+		})
+	}
+}
+
+func TestRunFiles(t *testing.T) {
+	dir := t.TempDir()
+	dirty := filepath.Join(dir, "dirty.eml")
+	clean := filepath.Join(dir, "clean.eml")
+	if err := os.WriteFile(dirty, []byte(messy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(clean, []byte(tidy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if got := run([]string{"-l", dirty, clean}, nil, &stdout, &stderr); got != exitChanged {
+		t.Errorf("-l: want exit %d, got %d: %s", exitChanged, got, stderr.String())
+	}
+	if stdout.String() != dirty+"\n" {
+		t.Errorf("-l listed %q", stdout.String())
+	}
+
+	stdout.Reset()
+	if got := run([]string{"-w", dirty, clean}, nil, &stdout, &stderr); got != exitOK {
+		t.Errorf("-w: want exit %d, got %d: %s", exitOK, got, stderr.String())
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("-w wrote %q to stdout", stdout.String())
+	}
+	b, err := os.ReadFile(dirty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != tidy {
+		t.Errorf("-w wrote %q", b)
+	}
+	fi, err := os.Stat(dirty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0o600 {
+		t.Errorf("-w changed the mode to %v", fi.Mode())
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
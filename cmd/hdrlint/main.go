@@ -0,0 +1,122 @@
+// Command hdrlint checks the header of an email message, read from the
+// files named on the command line or from stdin, printing each issue
+// found by Lint with the file name and, for issues with a single field,
+// the line it starts on.
+//
+//	hdrlint [-strict] [-q] [file ...]
+//
+// It exits 0 if there were no errors, 1 if there were, and 2 if a
+// message couldn't be read. With -strict warnings count as errors.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"strings"
+
+	"github.com/wttw/orderedheaders"
+)
+
+const (
+	exitOK     = 0
+	exitIssues = 1
+	exitFailed = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("hdrlint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	strict := flags.Bool("strict", false, "treat warnings as errors")
+	quiet := flags.Bool("q", false, "don't print warnings")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: hdrlint [-strict] [-q] [file ...]\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitFailed
+	}
+
+	ret := exitOK
+	check := func(name string, r io.Reader) {
+		issues, err := lint(r)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", name, err)
+			ret = exitFailed
+			return
+		}
+		for _, issue := range issues {
+			failed := issue.Severity == orderedheaders.SeverityError || *strict
+			if !failed && *quiet {
+				continue
+			}
+			if issue.line > 0 {
+				fmt.Fprintf(stdout, "%s:%d: %s\n", name, issue.line, issue.LintIssue.Error())
+			} else {
+				fmt.Fprintf(stdout, "%s: %s\n", name, issue.LintIssue.Error())
+			}
+			if failed && ret == exitOK {
+				ret = exitIssues
+			}
+		}
+	}
+
+	if flags.NArg() == 0 {
+		check("<stdin>", stdin)
+		return ret
+	}
+	for _, name := range flags.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintf(stderr, "%v\n", err)
+			ret = exitFailed
+			continue
+		}
+		check(name, f)
+		_ = f.Close()
+	}
+	return ret
+}
+
+// issue is a LintIssue with the line it was found on, 0 for issues with
+// the header as a whole
+type issue struct {
+	orderedheaders.LintIssue
+	line int
+}
+
+// lint reads a header from r, keeping the raw bytes so that line lengths
+// are checked as written and issues can be given line numbers
+func lint(r io.Reader) ([]issue, error) {
+	tp := textproto.NewReader(bufio.NewReader(r))
+	h, err := orderedheaders.ReadHeaderWithOptions(tp, orderedheaders.ReadOptions{KeepRaw: true})
+	if err == io.EOF && len(h.Headers) > 0 {
+		// a header with nothing after it
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]int, len(h.Headers))
+	line := 1
+	for i, kv := range h.Headers {
+		lines[i] = line
+		line += strings.Count(kv.Raw, "\n")
+	}
+	var ret []issue
+	for _, li := range h.Lint() {
+		is := issue{LintIssue: li}
+		if li.Field >= 0 {
+			is.line = lines[li.Field]
+		}
+		ret = append(ret, is)
+	}
+	return ret, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const clean = "From: alice@example.com\r\n" +
+	"Date: Mon, 1 Jan 2024 00:00:00 +0000\r\n" +
+	"Subject: hello\r\n" +
+	"\r\n" +
+	"body\r\n"
+
+const broken = "From: alice@example.com\r\n" +
+	"Subject: a folded\r\n" +
+	" subject\r\n" +
+	"Message-Id: nope\r\n" +
+	"X-Note: café\r\n" +
+	"\r\n"
+
+func TestRun(t *testing.T) {
+	tests := map[string]struct {
+		Args   []string
+		Stdin  string
+		Want   int
+		Stdout string
+	}{
+		"clean": {Stdin: clean, Want: exitOK},
+		"broken": {Stdin: broken, Want: exitIssues, Stdout: "<stdin>:4: error: Message-Id: 'nope' is not a valid Message-ID [syntax]\n" +
+			"<stdin>: error: Date: required header is missing [required]\n" +
+			"<stdin>:5: warning: X-Note: contains unencoded non-ascii characters [8bit]\n"},
+		"warning":    {Stdin: strings.Replace(clean, "hello", "café", 1), Want: exitOK, Stdout: "<stdin>:3: warning: Subject: contains unencoded non-ascii characters [8bit]\n"},
+		"strict":     {Args: []string{"-strict"}, Stdin: strings.Replace(clean, "hello", "café", 1), Want: exitIssues, Stdout: "<stdin>:3: warning: Subject: contains unencoded non-ascii characters [8bit]\n"},
+		"quiet":      {Args: []string{"-q"}, Stdin: strings.Replace(clean, "hello", "café", 1), Want: exitOK},
+		"headeronly": {Stdin: strings.TrimSuffix(clean, "\r\nbody\r\n"), Want: exitOK},
+		"empty":      {Stdin: "", Want: exitFailed},
+		"usage":      {Args: []string{"-nope"}, Want: exitFailed},
+		"missing":    {Args: []string{filepath.Join(t.TempDir(), "missing.eml")}, Want: exitFailed},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			got := run(test.Args, strings.NewReader(test.Stdin), &stdout, &stderr)
+			if got != test.Want {
+				t.Errorf("want exit %d, got %d: %s", test.Want, got, stderr.String())
+			}
+			if stdout.String() != test.Stdout {
+				t.Errorf("want output\n%s\ngot\n%s", test.Stdout, stdout.String())
+			}
+		})
+	}
+}
+
+func TestRunFiles(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.eml")
+	bad := filepath.Join(dir, "bad.eml")
+	if err := os.WriteFile(good, []byte(clean), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(bad, []byte(broken), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	if got := run([]string{"-q", good, bad}, nil, &stdout, &stderr); got != exitIssues {
+		t.Errorf("want exit %d, got %d", exitIssues, got)
+	}
+	if !strings.HasPrefix(stdout.String(), bad+":4: error: Message-Id:") {
+		t.Errorf("unexpected output %q", stdout.String())
+	}
+}
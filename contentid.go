@@ -0,0 +1,88 @@
+package orderedheaders
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// https://tools.wordtothewise.com/rfc2045#section-7
+// https://tools.wordtothewise.com/rfc2392
+
+// ContentID returns the Content-ID of a body part, without the angle
+// brackets. Comments are ignored.
+func (h *Header) ContentID() (string, error) {
+	if !h.Has(HdrContentID) {
+		return "", mail.ErrHeaderNotPresent
+	}
+	value := h.Get(HdrContentID)
+	ids := parseMessageIDs(value)
+	if len(ids) != 1 {
+		return "", fmt.Errorf("'%s' is not a valid Content-ID", value)
+	}
+	if err := validMessageId(ids[0]); err != nil {
+		return "", fmt.Errorf("'%s' is not a valid Content-ID: %w", value, err)
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(ids[0], "<"), ">"), nil
+}
+
+// SetContentID sets the Content-ID, replacing any existing ones. Angle
+// brackets are added if needed.
+func (h *Header) SetContentID(id string) error {
+	id = strings.TrimSpace(id)
+	if !strings.HasPrefix(id, "<") {
+		id = "<" + id + ">"
+	}
+	if err := validMessageId(id); err != nil {
+		return fmt.Errorf("'%s' is not a valid Content-ID: %w", id, err)
+	}
+	h.replaceAll(HdrContentID, id)
+	return nil
+}
+
+// ContentIDURL returns the cid: URL that refers to the part with the
+// given Content-ID, with or without angle brackets
+func ContentIDURL(id string) string {
+	return "cid:" + url.PathEscape(strings.Trim(strings.TrimSpace(id), "<>"))
+}
+
+// cidURLRe matches cid: URLs in HTML, in attribute values or CSS url()
+var cidURLRe = regexp.MustCompile(`(?i)\bcid:[^\s"'<>()\\]+`)
+
+// ResolveContentIDs finds the cid: URLs in an HTML body and maps each,
+// as it appears in the HTML, to the part of the message with that
+// Content-ID, or to nil if there is no such part. Parts are found by
+// walking the message, and if more than one has the same Content-ID the
+// first is used.
+func (m *Message) ResolveContentIDs(html []byte) (map[string]*Message, error) {
+	parts := map[string]*Message{}
+	err := m.Walk(func(part *Message, depth int) error {
+		id, err := part.Header.ContentID()
+		if err != nil {
+			return nil
+		}
+		if _, ok := parts[id]; !ok {
+			parts[id] = part
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	resolved := map[string]*Message{}
+	for _, ref := range cidURLRe.FindAll(html, -1) {
+		u := string(ref)
+		if _, ok := resolved[u]; ok {
+			continue
+		}
+		id, err := url.PathUnescape(u[len("cid:"):])
+		if err != nil {
+			resolved[u] = nil
+			continue
+		}
+		resolved[u] = parts[id]
+	}
+	return resolved, nil
+}
@@ -0,0 +1,113 @@
+package orderedheaders
+
+import (
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+func TestContentID(t *testing.T) {
+	tests := map[string]struct {
+		Value   string
+		Want    string
+		WantErr bool
+	}{
+		"plain":   {Value: "<logo@example.com>", Want: "logo@example.com"},
+		"comment": {Value: " (image) <logo.1@example.com> ", Want: "logo.1@example.com"},
+		"bare":    {Value: "logo@example.com", WantErr: true},
+		"invalid": {Value: "<a@b@example.com>", WantErr: true},
+		"two":     {Value: "<a@example.com> <b@example.com>", WantErr: true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := Header{Headers: []KV{{Key: HdrContentID, Value: test.Value}}}
+			got, err := h.ContentID()
+			if (err != nil) != test.WantErr {
+				t.Fatalf("got error %v, want error %v", err, test.WantErr)
+			}
+			if got != test.Want {
+				t.Errorf("want '%s', got '%s'", test.Want, got)
+			}
+		})
+	}
+
+	var h Header
+	if _, err := h.ContentID(); err != mail.ErrHeaderNotPresent {
+		t.Errorf("missing Content-ID: got %v", err)
+	}
+}
+
+func TestSetContentID(t *testing.T) {
+	var h Header
+	if err := h.SetContentID("a@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.SetContentID(" <b@example.com> "); err != nil {
+		t.Fatal(err)
+	}
+	if got := h.ToMap()[HdrContentID]; len(got) != 1 || got[0] != "<b@example.com>" {
+		t.Errorf("got %q", got)
+	}
+	if err := h.SetContentID("no at sign"); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestContentIDURL(t *testing.T) {
+	tests := map[string]string{
+		"logo@example.com":      "cid:logo@example.com",
+		"<logo@example.com>":    "cid:logo@example.com",
+		"a%b/c@example.com":     "cid:a%25b%2Fc@example.com",
+		"part1.06090408@x.test": "cid:part1.06090408@x.test",
+	}
+	for in, want := range tests {
+		if got := ContentIDURL(in); got != want {
+			t.Errorf("%s: want %s, got %s", in, want, got)
+		}
+	}
+}
+
+func TestResolveContentIDs(t *testing.T) {
+	b := NewBuilder().From(&mail.Address{Address: "alice@example.com"})
+	logo, err := b.AttachInline("logo@example.com", "image/png", strings.NewReader("PNG"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	odd, err := b.AttachInline("a/b@example.com", "image/gif", strings.NewReader("GIF89a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := `<img src="` + logo + `"><div style="background: url(` + odd + `)"></div>` +
+		`<img src='CID:logo@example.com'><img src="cid:missing@example.com">`
+	msg, err := b.HTML(html).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolved, err := msg.ResolveContentIDs([]byte(html))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"cid:logo@example.com":    "image/png",
+		"cid:a%2Fb@example.com":   "image/gif",
+		"CID:logo@example.com":    "image/png",
+		"cid:missing@example.com": "",
+	}
+	if len(resolved) != len(want) {
+		t.Errorf("got %d URLs, want %d: %v", len(resolved), len(want), resolved)
+	}
+	for u, ct := range want {
+		part, ok := resolved[u]
+		if !ok {
+			t.Errorf("%s not found", u)
+			continue
+		}
+		got := ""
+		if part != nil {
+			got = part.Header.Get(HdrContentType)
+		}
+		if got != ct {
+			t.Errorf("%s: want part %q, got %q", u, ct, got)
+		}
+	}
+}
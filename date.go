@@ -0,0 +1,272 @@
+package orderedheaders
+
+import (
+	"fmt"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// https://tools.wordtothewise.com/rfc5322#section-3.3
+
+// FormatDate formats a time as an RFC 5322 date-time, with a numeric
+// zone and optionally a leading day of the week. Offsets that aren't a
+// whole number of minutes, which RFC 5322 can't represent, are rendered
+// in UTC.
+func FormatDate(t time.Time, dayOfWeek bool) string {
+	if _, offset := t.Zone(); offset%60 != 0 {
+		t = t.UTC()
+	}
+	if dayOfWeek {
+		return t.Format("Mon, 2 Jan 2006 15:04:05 -0700")
+	}
+	return t.Format("2 Jan 2006 15:04:05 -0700")
+}
+
+// SetDate sets the Date header to t, including the day of the week,
+// replacing any existing ones
+func (h *Header) SetDate(t time.Time) {
+	h.replaceAll(HdrDate, FormatDate(t, true))
+}
+
+// Touch sets the Date header to the current time
+func (h *Header) Touch() {
+	h.SetDate(time.Now())
+}
+
+// DateOptions configures how the Date header is parsed
+type DateOptions struct {
+	// Lenient recovers common malformed dates, using ParseDateLenient
+	Lenient bool
+}
+
+// DateWithOptions parses the Date header field. When parsing leniently
+// it also returns a description of each fix needed to parse the date.
+func (h *Header) DateWithOptions(o DateOptions) (time.Time, []string, error) {
+	hdr := h.Get(HdrDate)
+	if hdr == "" {
+		return time.Time{}, nil, mail.ErrHeaderNotPresent
+	}
+	if o.Lenient {
+		return ParseDateLenient(hdr)
+	}
+	t, err := mail.ParseDate(hdr)
+	return t, nil, err
+}
+
+// dateZones maps zone names seen in real mail to their offset in minutes
+var dateZones = map[string]int{
+	"UT": 0, "UTC": 0, "GMT": 0, "Z": 0, "WET": 0,
+	"EST": -5 * 60, "EDT": -4 * 60,
+	"CST": -6 * 60, "CDT": -5 * 60,
+	"MST": -7 * 60, "MDT": -6 * 60,
+	"PST": -8 * 60, "PDT": -7 * 60,
+	"AKST": -9 * 60, "AKDT": -8 * 60,
+	"HST": -10 * 60,
+	"BST": 60, "CET": 60, "MET": 60, "WEST": 60,
+	"CEST": 2 * 60, "MEST": 2 * 60, "EET": 2 * 60,
+	"EEST": 3 * 60, "MSK": 3 * 60,
+	"IST": 5*60 + 30,
+	"HKT": 8 * 60, "SGT": 8 * 60, "AWST": 8 * 60,
+	"JST": 9 * 60, "KST": 9 * 60,
+	"AEST": 10 * 60, "AEDT": 11 * 60,
+	"NZST": 12 * 60, "NZDT": 13 * 60,
+}
+
+var dateMonths = map[string]time.Month{
+	"jan": time.January, "feb": time.February, "mar": time.March,
+	"apr": time.April, "may": time.May, "jun": time.June,
+	"jul": time.July, "aug": time.August, "sep": time.September,
+	"oct": time.October, "nov": time.November, "dec": time.December,
+}
+
+var dateDays = map[string]struct{}{
+	"mon": {}, "tue": {}, "wed": {}, "thu": {}, "fri": {}, "sat": {}, "sun": {},
+}
+
+// ParseDateLenient parses a date as mail.ParseDate does, but also
+// recovers forms seen in real mail: zone names other than the obsolete
+// ones RFC 5322 allows, missing zones, zones with colons, unterminated
+// comments, full month names and fields in asctime order. It returns the
+// time and a description of each fix that was needed, which is empty for
+// any date that's valid RFC 5322, including its obsolete syntax such as
+// two digit years and zones like EST.
+// https://tools.wordtothewise.com/rfc5322#section-4.3
+func ParseDateLenient(s string) (time.Time, []string, error) {
+	var fixes []string
+	fail := func() (time.Time, []string, error) {
+		return time.Time{}, nil, fmt.Errorf("'%s' is not a valid date", s)
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if s[i] == '(' {
+			_, next, err := readComment(s, i)
+			if err != nil {
+				next = len(s)
+				fixes = append(fixes, "removed unterminated comment")
+			}
+			b.WriteByte(' ')
+			i = next
+			continue
+		}
+		b.WriteByte(s[i])
+		i++
+	}
+	tokens := strings.FieldsFunc(b.String(), func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '\r' || r == '\n' || r == ','
+	})
+
+	day, year := -1, -1
+	var month time.Month
+	var clock string
+	zone, zoneSet := 0, false
+	var yearToken string
+	for _, tok := range tokens {
+		lower := strings.ToLower(tok)
+		switch {
+		case strings.Contains(tok, ":") && clock == "" && tok[0] >= '0' && tok[0] <= '9':
+			clock = tok
+		case tok[0] >= '0' && tok[0] <= '9':
+			n, err := strconv.Atoi(tok)
+			if err != nil {
+				return fail()
+			}
+			if day < 0 && len(tok) <= 2 && n >= 1 && n <= 31 {
+				day = n
+			} else if year < 0 {
+				year = n
+				yearToken = tok
+			} else {
+				return fail()
+			}
+		case tok[0] == '+' || tok[0] == '-':
+			offset, ok := parseNumericZone(tok)
+			if !ok || zoneSet {
+				return fail()
+			}
+			if strings.Contains(tok, ":") {
+				fixes = append(fixes, "removed colon from zone")
+			}
+			zone, zoneSet = offset, true
+		default:
+			if len(lower) >= 3 {
+				if m, ok := dateMonths[lower[:3]]; ok && month == 0 && strings.HasPrefix(strings.ToLower(m.String()), lower) {
+					if len(lower) > 3 {
+						fixes = append(fixes, "abbreviated month name")
+					}
+					month = m
+					continue
+				}
+				if _, ok := dateDays[lower[:3]]; ok {
+					continue
+				}
+			}
+			offset, ok := parseZoneName(tok)
+			if !ok || zoneSet {
+				return fail()
+			}
+			if !isObsZone(tok) {
+				fixes = append(fixes, "converted zone name "+tok)
+			}
+			zone, zoneSet = offset, true
+		}
+	}
+	if day < 0 || month == 0 || year < 0 || clock == "" {
+		return fail()
+	}
+	// obsolete two and three digit years, and times without seconds,
+	// are valid
+	switch len(yearToken) {
+	case 2:
+		if year < 50 {
+			year += 2000
+		} else {
+			year += 1900
+		}
+	case 3:
+		year += 1900
+	}
+	parts := strings.Split(clock, ":")
+	if len(parts) == 2 {
+		parts = append(parts, "00")
+	}
+	if len(parts) != 3 {
+		return fail()
+	}
+	var hms [3]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || len(p) > 2 {
+			return fail()
+		}
+		hms[i] = n
+	}
+	if hms[0] > 23 || hms[1] > 59 || hms[2] > 60 {
+		return fail()
+	}
+	if !zoneSet {
+		fixes = append(fixes, "assumed UTC for missing zone")
+	}
+	t := time.Date(year, month, day, hms[0], hms[1], hms[2], 0, time.FixedZone("", zone*60))
+	if t.Day() != day {
+		return fail()
+	}
+	return t, fixes, nil
+}
+
+// parseNumericZone parses +hhmm, +hh:mm, +hh or +h, returning minutes
+func parseNumericZone(s string) (int, bool) {
+	sign := 1
+	if s[0] == '-' {
+		sign = -1
+	}
+	digits := strings.Replace(s[1:], ":", "", 1)
+	if len(digits) == 1 {
+		digits = "0" + digits
+	}
+	if len(digits) == 2 {
+		digits += "00"
+	}
+	if len(digits) != 4 {
+		return 0, false
+	}
+	hh, err1 := strconv.Atoi(digits[:2])
+	mm, err2 := strconv.Atoi(digits[2:])
+	if err1 != nil || err2 != nil || mm > 59 {
+		return 0, false
+	}
+	return sign * (hh*60 + mm), true
+}
+
+// isObsZone checks whether s is one of the obsolete zone names that RFC
+// 5322 allows, the US zones and military zones
+func isObsZone(s string) bool {
+	switch upper := strings.ToUpper(s); upper {
+	case "UT", "GMT", "EST", "EDT", "CST", "CDT", "MST", "MDT", "PST", "PDT":
+		return true
+	default:
+		return len(upper) == 1 && upper[0] >= 'A' && upper[0] <= 'Z' && upper != "J"
+	}
+}
+
+// parseZoneName parses zone names, including forms like GMT+2, and
+// military zones, which are treated as UTC per RFC 5322 section 4.3
+func parseZoneName(s string) (int, bool) {
+	upper := strings.ToUpper(s)
+	if offset, ok := dateZones[upper]; ok {
+		return offset, true
+	}
+	for _, prefix := range []string{"GMT", "UTC", "UT"} {
+		if strings.HasPrefix(upper, prefix) && len(upper) > len(prefix) {
+			if offset, ok := parseNumericZone(upper[len(prefix):]); ok {
+				return offset, true
+			}
+		}
+	}
+	if len(upper) == 1 && upper[0] >= 'A' && upper[0] <= 'Z' && upper != "J" {
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,125 @@
+package orderedheaders
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFormatDate(t *testing.T) {
+	tests := map[string]struct {
+		In        time.Time
+		DayOfWeek bool
+		Want      string
+	}{
+		"utc":     {time.Date(2023, 5, 2, 9, 4, 5, 999, time.UTC), true, "Tue, 2 May 2023 09:04:05 +0000"},
+		"zone":    {time.Date(2023, 12, 25, 23, 0, 0, 0, time.FixedZone("PST", -8*3600)), true, "Mon, 25 Dec 2023 23:00:00 -0800"},
+		"halfhr":  {time.Date(2023, 12, 25, 23, 0, 0, 0, time.FixedZone("IST", 5*3600+1800)), false, "25 Dec 2023 23:00:00 +0530"},
+		"seconds": {time.Date(1900, 1, 1, 0, 0, 0, 0, time.FixedZone("LMT", -75)), true, "Mon, 1 Jan 1900 00:01:15 +0000"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := FormatDate(test.In, test.DayOfWeek)
+			if got != test.Want {
+				t.Errorf("want '%s', got '%s'", test.Want, got)
+			}
+			if err := validDate(got); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestTouch(t *testing.T) {
+	h := &Header{}
+	h.Add("Date", "yesterday")
+	h.Add("Date", "the day before")
+	h.Touch()
+	d, err := h.Date()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(d) > time.Minute {
+		t.Errorf("Touch set an old date %v", d)
+	}
+	if len(h.Headers) != 1 {
+		t.Errorf("Touch left %d Date headers", len(h.Headers))
+	}
+}
+
+func TestParseDateLenient(t *testing.T) {
+	tests := map[string]struct {
+		In        string
+		Want      time.Time
+		WantFixes []string
+		WantError bool
+	}{
+		"valid": {In: "Mon, 22 May 2023 10:00:00 +0100",
+			Want: time.Date(2023, 5, 22, 9, 0, 0, 0, time.UTC)},
+		"twodigit": {In: "Fri, 31 Dec 99 23:59:59 -0000",
+			Want: time.Date(1999, 12, 31, 23, 59, 59, 0, time.UTC)},
+		"threedigit": {In: "Fri, 31 Dec 103 23:59:59 -0000",
+			Want: time.Date(2003, 12, 31, 23, 59, 59, 0, time.UTC)},
+		"noseconds": {In: "22 May 2023 10:00 +0000",
+			Want: time.Date(2023, 5, 22, 10, 0, 0, 0, time.UTC)},
+		"obszone": {In: "Mon, 22 May 2023 10:00:00 EST",
+			Want: time.Date(2023, 5, 22, 15, 0, 0, 0, time.UTC)},
+		"military": {In: "Mon, 22 May 2023 10:00:00 z",
+			Want: time.Date(2023, 5, 22, 10, 0, 0, 0, time.UTC)},
+		"zonename": {In: "Mon, 22 May 2023 10:00:00 CEST",
+			Want: time.Date(2023, 5, 22, 8, 0, 0, 0, time.UTC), WantFixes: []string{"converted zone name CEST"}},
+		"gmtoffset": {In: "Mon, 22 May 2023 10:00:00 GMT+2",
+			Want: time.Date(2023, 5, 22, 8, 0, 0, 0, time.UTC), WantFixes: []string{"converted zone name GMT+2"}},
+		"colon": {In: "Mon, 22 May 2023 10:00:00 +05:30",
+			Want: time.Date(2023, 5, 22, 4, 30, 0, 0, time.UTC), WantFixes: []string{"removed colon from zone"}},
+		"comment": {In: "Mon, 22 (a comment) May 2023 10:00:00 -0700 (PDT)",
+			Want: time.Date(2023, 5, 22, 17, 0, 0, 0, time.UTC)},
+		"unterminated": {In: "Mon, 22 May 2023 10:00:00 +0000 (UTC",
+			Want: time.Date(2023, 5, 22, 10, 0, 0, 0, time.UTC), WantFixes: []string{"removed unterminated comment"}},
+		"utc": {In: "Mon, 22 May 2023 10:00:00 UTC",
+			Want: time.Date(2023, 5, 22, 10, 0, 0, 0, time.UTC), WantFixes: []string{"converted zone name UTC"}},
+		"asctime": {In: "Mon May 22 10:00:00 2023",
+			Want: time.Date(2023, 5, 22, 10, 0, 0, 0, time.UTC), WantFixes: []string{"assumed UTC for missing zone"}},
+		"fullmonth": {In: "22 September 2023 10:00:00 +0000",
+			Want: time.Date(2023, 9, 22, 10, 0, 0, 0, time.UTC), WantFixes: []string{"abbreviated month name"}},
+		"garbage":  {In: "next Tuesday", WantError: true},
+		"baddate":  {In: "31 Feb 2023 10:00:00 +0000", WantError: true},
+		"badclock": {In: "1 Feb 2023 25:00:00 +0000", WantError: true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, fixes, err := ParseDateLenient(test.In)
+			if test.WantError {
+				if err == nil {
+					t.Errorf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(test.Want) {
+				t.Errorf("want %v, got %v", test.Want, got)
+			}
+			if diff := cmp.Diff(test.WantFixes, fixes); diff != "" {
+				t.Errorf("fixes mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDateWithOptions(t *testing.T) {
+	h := &Header{}
+	h.Add("Date", "22 May 2023 10:00 -04:00 (Eastern)")
+	if _, err := h.Date(); err == nil {
+		t.Errorf("expected strict parsing to fail")
+	}
+	got, fixes, err := h.DateWithOptions(DateOptions{Lenient: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(time.Date(2023, 5, 22, 14, 0, 0, 0, time.UTC)) || len(fixes) != 1 {
+		t.Errorf("unexpected result %v, %v", got, fixes)
+	}
+}
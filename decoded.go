@@ -0,0 +1,116 @@
+package orderedheaders
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/mail"
+	"sort"
+	"strings"
+)
+
+// Decoded returns a copy of the header in the form people read rather
+// than the form sent on the wire, for search indexes and user interfaces.
+// RFC 2047 encoded words in unstructured and unknown fields are decoded
+// to UTF-8, addresses are rendered with their decoded display names, and
+// the parameters of Content-Type and Content-Disposition are decoded
+// from RFC 2231 or RFC 2047 encoding. Encoded words may use any charset
+// in CharsetReaders. Fields that don't parse, or that use a charset that
+// isn't there, are copied unchanged, and fields that change lose their
+// Raw bytes.
+func (h *Header) Decoded() *Header {
+	h.CanonicalizeKeys()
+	dec := wordDecoder()
+	ret := &Header{Headers: make([]KV, len(h.Headers)), Truncated: h.Truncated}
+	for i, kv := range h.Headers {
+		ret.Headers[i] = kv
+		value, err := decodeField(dec, kv.Key, kv.Value)
+		if err == nil && value != kv.Value {
+			ret.Headers[i] = KV{Key: kv.Key, Value: value}
+		}
+	}
+	return ret
+}
+
+// wordDecoder returns a decoder for RFC 2047 encoded words that supports
+// the charsets in CharsetReaders
+func wordDecoder() *mime.WordDecoder {
+	return &mime.WordDecoder{CharsetReader: func(charset string, input io.Reader) (io.Reader, error) {
+		convert, ok := CharsetReaders[strings.ToLower(charset)]
+		if !ok {
+			return nil, fmt.Errorf("unsupported charset %s", charset)
+		}
+		return convert(input)
+	}}
+}
+
+// decodeField returns the decoded form of a field value
+func decodeField(dec *mime.WordDecoder, key, value string) (string, error) {
+	switch key {
+	case HdrContentType, HdrContentDisposition:
+		return decodeParams(dec, value)
+	}
+	syntax, ok := HeaderSyntax[key]
+	if !ok {
+		return dec.DecodeHeader(value)
+	}
+	switch syntax.Type {
+	case HeaderTypeUnstructured, HeaderTypePhraseList:
+		return dec.DecodeHeader(value)
+	case HeaderTypeMailbox, HeaderTypeMailboxList:
+		return decodeAddresses(dec, value)
+	}
+	return value, nil
+}
+
+// decodeAddresses renders an address list with decoded display names.
+// Lists with groups, or that don't parse, just have any encoded words
+// decoded.
+func decodeAddresses(dec *mime.WordDecoder, value string) (string, error) {
+	if _, ok := splitAddressList(value); !ok {
+		return dec.DecodeHeader(value)
+	}
+	parser := mail.AddressParser{WordDecoder: dec}
+	addrs, err := parser.ParseList(value)
+	if err != nil || len(addrs) == 0 {
+		return dec.DecodeHeader(value)
+	}
+	rendered := make([]string, len(addrs))
+	for i, a := range addrs {
+		if a.Name == "" {
+			rendered[i] = a.Address
+			continue
+		}
+		rendered[i] = QuoteString(a.Name) + " <" + a.Address + ">"
+	}
+	return strings.Join(rendered, ", "), nil
+}
+
+// decodeParams renders a MIME header value with its parameters decoded
+// and in a stable order. mime.ParseMediaType undoes RFC 2231 encoding,
+// and RFC 2047 encoded words, which some clients use for filenames
+// though they aren't allowed there, are decoded too.
+func decodeParams(dec *mime.WordDecoder, value string) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(value)
+	if err != nil {
+		return value, nil
+	}
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString(mediaType)
+	for _, k := range keys {
+		v, err := dec.DecodeHeader(params[k])
+		if err != nil {
+			return "", err
+		}
+		if !isToken(v) {
+			v = quoteString(v)
+		}
+		b.WriteString("; " + k + "=" + v)
+	}
+	return b.String(), nil
+}
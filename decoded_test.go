@@ -0,0 +1,49 @@
+package orderedheaders
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDecoded(t *testing.T) {
+	h := &Header{Headers: []KV{
+		{Key: "Subject", Value: "=?utf-8?q?caf=C3=A9?= =?iso-8859-1?q?cr=E8me?=", Raw: "Subject: ...\r\n"},
+		{Key: "From", Value: "=?utf-8?b?w4lsb8Ovc2U=?= <eloise@example.com>"},
+		{Key: "To", Value: "bob@example.com, =?windows-1252?q?Z=F6e_Smith?= <zoe@example.com>"},
+		{Key: "Cc", Value: "team: =?utf-8?q?J=C3=B6rg?= <j@example.com>;"},
+		{Key: "Content-Type", Value: "text/plain; format=flowed; charset=\"us-ascii\""},
+		{Key: "Content-Disposition", Value: "attachment; filename*=utf-8''r%C3%A9sum%C3%A9.pdf"},
+		{Key: "X-Mailer", Value: "=?utf-8?q?M=C3=A4iler?="},
+		{Key: "Message-Id", Value: "<=?utf-8?q?a?=@example.com>", Raw: "Message-Id: ...\r\n"},
+		{Key: "Date", Value: "Mon, 1 Jan 2024 00:00:00 +0000"},
+	}}
+	got := h.Decoded()
+	want := []KV{
+		{Key: "Subject", Value: "cafécrème"},
+		{Key: "From", Value: `"Éloïse" <eloise@example.com>`},
+		{Key: "To", Value: `bob@example.com, "Zöe Smith" <zoe@example.com>`},
+		{Key: "Cc", Value: "team: Jörg <j@example.com>;"},
+		{Key: "Content-Type", Value: "text/plain; charset=us-ascii; format=flowed"},
+		{Key: "Content-Disposition", Value: `attachment; filename="résumé.pdf"`},
+		{Key: "X-Mailer", Value: "Mäiler"},
+		{Key: "Message-Id", Value: "<=?utf-8?q?a?=@example.com>", Raw: "Message-Id: ...\r\n"},
+		{Key: "Date", Value: "Mon, 1 Jan 2024 00:00:00 +0000"},
+	}
+	if diff := cmp.Diff(want, got.Headers); diff != "" {
+		t.Errorf("Decoded mismatch (-want +got):\n%s", diff)
+	}
+	if h.Headers[0].Value != "=?utf-8?q?caf=C3=A9?= =?iso-8859-1?q?cr=E8me?=" {
+		t.Errorf("Decoded changed the original")
+	}
+
+	unknown := []KV{
+		{Key: "Subject", Value: "=?koi8-r?q?=F0=D2=C9=D7=C5=D4?="},
+		{Key: "X-Mailer", Value: "=?utf-8?q?M=C3=A4iler?="},
+	}
+	got = (&Header{Headers: unknown}).Decoded()
+	want = []KV{unknown[0], {Key: "X-Mailer", Value: "Mäiler"}}
+	if diff := cmp.Diff(want, got.Headers); diff != "" {
+		t.Errorf("Decoded with unknown charset mismatch (-want +got):\n%s", diff)
+	}
+}
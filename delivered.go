@@ -0,0 +1,43 @@
+package orderedheaders
+
+import "strings"
+
+// Delivered-To is added by Postfix and other MTAs on final delivery, and
+// X-Original-To records the recipient before any aliasing
+// http://www.postfix.org/local.8.html
+
+const (
+	HdrDeliveredTo = "Delivered-To"
+	HdrXOriginalTo = "X-Original-To"
+)
+
+// HasDeliveryLoop checks whether any Delivered-To or X-Original-To field
+// names one of localAddresses, meaning the message has already been
+// delivered here once and is looping. Addresses are compared without
+// regard to case, as Postfix does, and may be bare or in angle brackets.
+func (h *Header) HasDeliveryLoop(localAddresses []string) bool {
+	h.CanonicalizeKeys()
+	local := make(map[string]struct{}, len(localAddresses))
+	for _, a := range localAddresses {
+		local[deliveryAddress(a)] = struct{}{}
+	}
+	for _, kv := range h.Headers {
+		if kv.Key != HdrDeliveredTo && kv.Key != HdrXOriginalTo {
+			continue
+		}
+		if _, ok := local[deliveryAddress(kv.Value)]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// deliveryAddress normalizes an address for comparison, falling back to
+// the trimmed value if it doesn't parse
+func deliveryAddress(s string) string {
+	addr, isNull, err := parseReturnPath(s)
+	if err != nil || isNull {
+		addr = strings.TrimSpace(s)
+	}
+	return strings.ToLower(addr)
+}
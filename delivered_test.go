@@ -0,0 +1,37 @@
+package orderedheaders
+
+import "testing"
+
+func TestHasDeliveryLoop(t *testing.T) {
+	local := []string{"alice@example.com", "<Lists@Example.com>"}
+	tests := map[string]struct {
+		Headers []KV
+		Want    bool
+	}{
+		"none":         {Headers: []KV{{Key: "To", Value: "alice@example.com"}}},
+		"other":        {Headers: []KV{{Key: "Delivered-To", Value: "bob@example.com"}}},
+		"delivered":    {Headers: []KV{{Key: "Delivered-To", Value: "bob@example.com"}, {Key: "Delivered-To", Value: "alice@example.com"}}, Want: true},
+		"original":     {Headers: []KV{{Key: "X-Original-To", Value: "lists@example.com"}}, Want: true},
+		"case":         {Headers: []KV{{Key: "Delivered-To", Value: "ALICE@EXAMPLE.COM"}}, Want: true},
+		"brackets":     {Headers: []KV{{Key: "Delivered-To", Value: " <alice@example.com> (local)"}}, Want: true},
+		"garbage":      {Headers: []KV{{Key: "Delivered-To", Value: "not an address"}}},
+		"subaddress":   {Headers: []KV{{Key: "Delivered-To", Value: "alice+tag@example.com"}}},
+		"wrong header": {Headers: []KV{{Key: "Resent-To", Value: "alice@example.com"}}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := &Header{Headers: test.Headers}
+			if got := h.HasDeliveryLoop(local); got != test.Want {
+				t.Errorf("want %v, got %v", test.Want, got)
+			}
+		})
+	}
+
+	h := &Header{Headers: []KV{{Key: "delivered-to", Value: "alice@example.com"}}, RawKeys: true}
+	if !h.HasDeliveryLoop(local) {
+		t.Errorf("loop not found with raw keys")
+	}
+	if h.HasDeliveryLoop(nil) {
+		t.Errorf("loop found with no local addresses")
+	}
+}
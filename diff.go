@@ -0,0 +1,149 @@
+package orderedheaders
+
+import (
+	"net/textproto"
+)
+
+//go:generate enumer -json -trimprefix=Change -transform=kebab -type ChangeType
+
+// ChangeType describes how a header field differs between two headers
+type ChangeType int
+
+const (
+	// ChangeAdded is a field only in the new header
+	ChangeAdded ChangeType = iota
+	// ChangeRemoved is a field only in the old header
+	ChangeRemoved
+	// ChangeModified is a field whose value has changed
+	ChangeModified
+	// ChangeMoved is a field with the same value in a different
+	// position relative to the fields around it
+	ChangeMoved
+)
+
+// Change is a difference between two headers. Indices are positions in
+// Header.Headers, and are -1 for the header a field isn't in. A list of
+// changes can be encoded as JSON, and applied with ApplyPatch.
+type Change struct {
+	Type     ChangeType `json:"type"`
+	Key      string     `json:"key"`
+	OldIndex int        `json:"oldIndex"`
+	NewIndex int        `json:"newIndex"`
+	OldValue string     `json:"oldValue,omitempty"`
+	NewValue string     `json:"newValue,omitempty"`
+}
+
+// DiffOptions configures DiffWithOptions
+type DiffOptions struct {
+	// IgnoreOrder compares the headers as unordered collections of
+	// fields, so no ChangeMoved is reported
+	IgnoreOrder bool
+	// Ignore lists header fields that are expected to differ, such as
+	// Received or Date, and are left out of the comparison
+	Ignore []string
+}
+
+// Diff compares two headers, returning the changes that turn a into b
+func Diff(a, b Header) []Change {
+	return DiffWithOptions(a, b, DiffOptions{})
+}
+
+// DiffWithOptions compares two headers, returning the changes that turn
+// a into b. Fields are matched by key and value, keeping as many as
+// possible in their original order. An unmatched field in a is paired
+// with the first unmatched field in b with the same value, as a move,
+// or failing that the same key, as a modification. Changes to fields of
+// a come first, in the order of a, followed by additions in the order of
+// b.
+func DiffWithOptions(a, b Header, o DiffOptions) []Change {
+	a.CanonicalizeKeys()
+	b.CanonicalizeKeys()
+	ignore := map[string]struct{}{}
+	for _, key := range o.Ignore {
+		ignore[textproto.CanonicalMIMEHeaderKey(key)] = struct{}{}
+	}
+	filter := func(h Header) ([]KV, []int) {
+		var kvs []KV
+		var indices []int
+		for i, kv := range h.Headers {
+			if _, ok := ignore[kv.Key]; !ok {
+				kvs = append(kvs, kv)
+				indices = append(indices, i)
+			}
+		}
+		return kvs, indices
+	}
+	fa, ia := filter(a)
+	fb, ib := filter(b)
+
+	// pairedA and pairedB record matched fields, by filtered index
+	pairedA := map[int]int{}
+	pairedB := map[int]bool{}
+	pair := func(i, j int) {
+		pairedA[i] = j
+		pairedB[j] = true
+	}
+	var changes []Change
+	newChange := func(t ChangeType, i, j int) Change {
+		c := Change{Type: t, OldIndex: -1, NewIndex: -1}
+		if i >= 0 {
+			c.Key, c.OldIndex, c.OldValue = fa[i].Key, ia[i], fa[i].Value
+		}
+		if j >= 0 {
+			c.Key, c.NewIndex, c.NewValue = fb[j].Key, ib[j], fb[j].Value
+		}
+		return c
+	}
+
+	unchanged := map[int]bool{}
+	if !o.IgnoreOrder {
+		for _, m := range matchFields(fa, fb, sameField) {
+			pair(m[0], m[1])
+			unchanged[m[0]] = true
+		}
+	}
+	findB := func(i int, sameValue bool) int {
+		for j := range fb {
+			if !pairedB[j] && fb[j].Key == fa[i].Key && (!sameValue || fb[j].Value == fa[i].Value) {
+				return j
+			}
+		}
+		return -1
+	}
+	for i := range fa {
+		if _, ok := pairedA[i]; ok {
+			continue
+		}
+		if j := findB(i, true); j >= 0 {
+			pair(i, j)
+			unchanged[i] = o.IgnoreOrder
+		}
+	}
+	for i := range fa {
+		if _, ok := pairedA[i]; ok {
+			continue
+		}
+		if j := findB(i, false); j >= 0 {
+			pair(i, j)
+		}
+	}
+
+	for i := range fa {
+		j, ok := pairedA[i]
+		switch {
+		case !ok:
+			changes = append(changes, newChange(ChangeRemoved, i, -1))
+		case unchanged[i]:
+		case fa[i].Value == fb[j].Value:
+			changes = append(changes, newChange(ChangeMoved, i, j))
+		default:
+			changes = append(changes, newChange(ChangeModified, i, j))
+		}
+	}
+	for j := range fb {
+		if !pairedB[j] {
+			changes = append(changes, newChange(ChangeAdded, -1, j))
+		}
+	}
+	return changes
+}
@@ -0,0 +1,69 @@
+package orderedheaders
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiff(t *testing.T) {
+	old := Header{Headers: []KV{
+		{Key: "Received", Value: "from a"},
+		{Key: "Date", Value: "Mon, 22 May 2023 10:00:00 +0000"},
+		{Key: "From", Value: "a@example.com"},
+		{Key: "To", Value: "b@example.com"},
+		{Key: "Subject", Value: "hello"},
+		{Key: "X-Old", Value: "gone"},
+	}}
+	tests := map[string]struct {
+		New  []KV
+		Opts DiffOptions
+		Want []Change
+	}{
+		"same": {New: old.Headers},
+		"added": {
+			New: append([]KV{{Key: "Received", Value: "from b"}}, old.Headers...),
+			Want: []Change{
+				{Type: ChangeAdded, Key: "Received", OldIndex: -1, NewIndex: 0, NewValue: "from b"},
+			},
+		},
+		"removed": {
+			New:  old.Headers[:5],
+			Want: []Change{{Type: ChangeRemoved, Key: "X-Old", OldIndex: 5, NewIndex: -1, OldValue: "gone"}},
+		},
+		"modified": {
+			New: []KV{old.Headers[0], old.Headers[1], old.Headers[2], old.Headers[3], {Key: "Subject", Value: "[list] hello"}, old.Headers[5]},
+			Want: []Change{
+				{Type: ChangeModified, Key: "Subject", OldIndex: 4, NewIndex: 4, OldValue: "hello", NewValue: "[list] hello"},
+			},
+		},
+		"moved": {
+			New: []KV{old.Headers[0], old.Headers[1], old.Headers[2], old.Headers[3], old.Headers[5], old.Headers[4]},
+			Want: []Change{
+				{Type: ChangeMoved, Key: "Subject", OldIndex: 4, NewIndex: 5, OldValue: "hello", NewValue: "hello"},
+			},
+		},
+		"ignoreorder": {
+			New:  []KV{old.Headers[5], old.Headers[4], old.Headers[3], old.Headers[2], old.Headers[1], old.Headers[0]},
+			Opts: DiffOptions{IgnoreOrder: true},
+		},
+		"ignore": {
+			New: []KV{
+				{Key: "Received", Value: "from b"},
+				old.Headers[0],
+				{Key: "Date", Value: "Tue, 23 May 2023 10:00:00 +0000"},
+				old.Headers[2], old.Headers[3], old.Headers[4],
+			},
+			Opts: DiffOptions{Ignore: []string{"received", "date"}},
+			Want: []Change{{Type: ChangeRemoved, Key: "X-Old", OldIndex: 5, NewIndex: -1, OldValue: "gone"}},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := DiffWithOptions(old, Header{Headers: test.New}, test.Opts)
+			if diff := cmp.Diff(test.Want, got); diff != "" {
+				t.Errorf("changes mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package orderedheaders
+
+import (
+	"bytes"
+)
+
+// https://tools.wordtothewise.com/rfc2046#section-5.1.5
+
+// Digest adds a multipart/digest part containing the messages, such as
+// a mailing list digest, after the text and HTML bodies. The default
+// Content-Type within a digest is message/rfc822, so each message is
+// included with no part header of its own. Messages are rendered with
+// Rewrite, so those read with KeepRaw are included unchanged, and their
+// bodies are consumed.
+func (b *Builder) Digest(msgs ...*Message) *Builder {
+	parts := make([]builderPart, len(msgs))
+	for i, m := range msgs {
+		var buf bytes.Buffer
+		if err := m.Rewrite(&buf, Options{}); err != nil {
+			b.setErr(err)
+			return b
+		}
+		parts[i] = builderPart{body: buf.Bytes()}
+	}
+	digest, err := multipartPart("digest", nil, parts)
+	if err != nil {
+		b.setErr(err)
+		return b
+	}
+	b.parts = append(b.parts, digest)
+	return b
+}
@@ -0,0 +1,79 @@
+package orderedheaders
+
+import (
+	"io"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDigest(t *testing.T) {
+	first, err := ReadMessageWithOptions(strings.NewReader("Subject:  first\r\nFrom: a@example.com\r\n\r\none\r\n"),
+		MessageOptions{ReadOptions: ReadOptions{KeepRaw: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := NewBuilder().From(&mail.Address{Address: "b@example.com"}).Subject("second").Text("two").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := NewBuilder().
+		From(&mail.Address{Address: "list@example.com"}).
+		Subject("digest").
+		Text("Today's messages").
+		Digest(first, second).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	err = msg.Walk(func(part *Message, depth int) error {
+		mediaType, _ := part.mediaType()
+		got = append(got, strings.Repeat(" ", depth)+mediaType+" "+part.Header.Get(HdrSubject))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"multipart/mixed digest",
+		" text/plain ",
+		" multipart/digest ",
+		"  message/rfc822 ",
+		"   text/plain first",
+		"  message/rfc822 ",
+		"   text/plain second",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("structure mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDigestRaw(t *testing.T) {
+	in := "Subject:  first\r\nX-Odd:value\r\n\r\none\r\n"
+	first, err := ReadMessageWithOptions(strings.NewReader(in), MessageOptions{ReadOptions: ReadOptions{KeepRaw: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := NewBuilder().From(&mail.Address{Address: "list@example.com"}).Digest(first).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts, err := msg.Parts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 1 || parts[0].Header.Has(HdrContentType) {
+		t.Fatalf("expected one part with no Content-Type")
+	}
+	body, err := io.ReadAll(parts[0].Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != in {
+		t.Errorf("want %q, got %q", in, body)
+	}
+}
@@ -0,0 +1,413 @@
+package orderedheaders
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// https://tools.wordtothewise.com/rfc6376#section-3.2
+// https://tools.wordtothewise.com/rfc6376#section-3.5
+
+const HdrDKIMSignature = "Dkim-Signature"
+
+// A Tag is a single tag=value pair from a DKIM-style tag-list
+type Tag struct {
+	Name  string
+	Value string
+}
+
+// Canonicalization is a DKIM canonicalization algorithm
+type Canonicalization string
+
+const (
+	CanonicalizationSimple  Canonicalization = "simple"
+	CanonicalizationRelaxed Canonicalization = "relaxed"
+)
+
+// DKIMSignature is a parsed DKIM-Signature header
+type DKIMSignature struct {
+	// Version is the v= tag, always 1
+	Version int
+	// Algorithm is the a= tag, e.g. rsa-sha256
+	Algorithm string
+	// Domain is the signing domain, d=
+	Domain string
+	// Selector is the s= tag
+	Selector string
+	// Headers is the list of signed header fields from h=, in order
+	Headers []string
+	// BodyHash is the base64 encoded body hash, bh=, with whitespace removed
+	BodyHash string
+	// Signature is the base64 encoded signature, b=, with whitespace removed
+	Signature string
+	// HeaderCanonicalization and BodyCanonicalization come from c=,
+	// defaulting to simple
+	HeaderCanonicalization Canonicalization
+	BodyCanonicalization   Canonicalization
+	// Timestamp is the signature timestamp, t=, or the zero time if absent
+	Timestamp time.Time
+	// Expiration is the signature expiration, x=, or the zero time if absent
+	Expiration time.Time
+	// Length is the body length count, l=, or -1 if absent
+	Length int64
+	// Tags is every tag in the signature, in the order they appear
+	Tags []Tag
+}
+
+// Tag returns the value of the named tag, and whether it was present
+func (sig DKIMSignature) Tag(name string) (string, bool) {
+	return lookupTag(sig.Tags, name)
+}
+
+// DKIMSignatures parses every DKIM-Signature header, in order. Headers
+// that fail to parse are skipped, and the first error seen is returned
+// along with the signatures that did parse.
+func (h *Header) DKIMSignatures() ([]DKIMSignature, error) {
+	h.CanonicalizeKeys()
+	var sigs []DKIMSignature
+	var firstErr error
+	for _, kv := range h.Headers {
+		if kv.Key != HdrDKIMSignature {
+			continue
+		}
+		sig, err := ParseDKIMSignature(kv.Value)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		sigs = append(sigs, sig)
+	}
+	return sigs, firstErr
+}
+
+// ParseDKIMSignature parses the value of a DKIM-Signature header
+func ParseDKIMSignature(value string) (DKIMSignature, error) {
+	sig, err := parseSignature(value, []string{"v", "a", "b", "bh", "d", "h", "s"})
+	if err != nil {
+		return DKIMSignature{}, fmt.Errorf("invalid DKIM-Signature: %w", err)
+	}
+	return sig, nil
+}
+
+// parseSignature parses the tags common to DKIM-Signature and
+// ARC-Message-Signature headers
+func parseSignature(value string, required []string) (DKIMSignature, error) {
+	tags, err := parseTagList(value)
+	if err != nil {
+		return DKIMSignature{}, err
+	}
+	sig := DKIMSignature{
+		HeaderCanonicalization: CanonicalizationSimple,
+		BodyCanonicalization:   CanonicalizationSimple,
+		Length:                 -1,
+		Tags:                   tags,
+	}
+	for _, name := range required {
+		if _, ok := lookupTag(tags, name); !ok {
+			return DKIMSignature{}, fmt.Errorf("missing required tag %s=", name)
+		}
+	}
+	for _, tag := range tags {
+		switch tag.Name {
+		case "v":
+			if tag.Value != "1" {
+				return DKIMSignature{}, fmt.Errorf("unsupported version '%s'", tag.Value)
+			}
+			sig.Version = 1
+		case "a":
+			sig.Algorithm = strings.ToLower(tag.Value)
+		case "d":
+			sig.Domain = tag.Value
+		case "s":
+			sig.Selector = tag.Value
+		case "h":
+			sig.Headers = splitColonList(tag.Value)
+			if len(sig.Headers) == 0 {
+				return DKIMSignature{}, errors.New("empty h= tag")
+			}
+		case "bh":
+			sig.BodyHash = stripFWS(tag.Value)
+		case "b":
+			sig.Signature = stripFWS(tag.Value)
+		case "c":
+			sig.HeaderCanonicalization, sig.BodyCanonicalization, err = parseCanonicalization(tag.Value)
+			if err != nil {
+				return DKIMSignature{}, err
+			}
+		case "t":
+			sig.Timestamp, err = parseUnixTag(tag)
+			if err != nil {
+				return DKIMSignature{}, err
+			}
+		case "x":
+			sig.Expiration, err = parseUnixTag(tag)
+			if err != nil {
+				return DKIMSignature{}, err
+			}
+		case "l":
+			sig.Length, err = strconv.ParseInt(tag.Value, 10, 64)
+			if err != nil || sig.Length < 0 {
+				return DKIMSignature{}, fmt.Errorf("'%s' is not a valid body length", tag.Value)
+			}
+		}
+	}
+	return sig, nil
+}
+
+func parseCanonicalization(s string) (Canonicalization, Canonicalization, error) {
+	hdr := s
+	body := string(CanonicalizationSimple)
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		hdr = s[:i]
+		body = s[i+1:]
+	}
+	hc := Canonicalization(strings.ToLower(hdr))
+	bc := Canonicalization(strings.ToLower(body))
+	for _, c := range []Canonicalization{hc, bc} {
+		if c != CanonicalizationSimple && c != CanonicalizationRelaxed {
+			return "", "", fmt.Errorf("'%s' is not a valid canonicalization", s)
+		}
+	}
+	return hc, bc, nil
+}
+
+func parseUnixTag(tag Tag) (time.Time, error) {
+	secs, err := strconv.ParseInt(tag.Value, 10, 64)
+	if err != nil || secs < 0 {
+		return time.Time{}, fmt.Errorf("'%s' is not a valid %s= timestamp", tag.Value, tag.Name)
+	}
+	return time.Unix(secs, 0).UTC(), nil
+}
+
+// parseTagList parses an RFC 6376 tag-list, as used by DKIM, ARC and
+// similar headers, preserving the order of the tags
+func parseTagList(s string) ([]Tag, error) {
+	var tags []Tag
+	seen := map[string]struct{}{}
+	for _, spec := range strings.Split(s, ";") {
+		if strings.TrimSpace(spec) == "" {
+			// trailing ";" is permitted
+			continue
+		}
+		eq := strings.IndexByte(spec, '=')
+		if eq < 0 {
+			return nil, fmt.Errorf("'%s' is not a valid tag-spec", strings.TrimSpace(spec))
+		}
+		name := strings.TrimSpace(spec[:eq])
+		if !validTagName(name) {
+			return nil, fmt.Errorf("'%s' is not a valid tag name", name)
+		}
+		if _, ok := seen[name]; ok {
+			return nil, fmt.Errorf("duplicate tag %s=", name)
+		}
+		seen[name] = struct{}{}
+		tags = append(tags, Tag{
+			Name:  name,
+			Value: strings.TrimSpace(spec[eq+1:]),
+		})
+	}
+	return tags, nil
+}
+
+// validTagName checks tag-name = ALPHA *ALNUMPUNC
+func validTagName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case i > 0 && (c >= '0' && c <= '9' || c == '_'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func lookupTag(tags []Tag, name string) (string, bool) {
+	for _, t := range tags {
+		if t.Name == name {
+			return t.Value, true
+		}
+	}
+	return "", false
+}
+
+// splitColonList splits a colon separated list, such as a DKIM h= tag,
+// trimming whitespace and dropping empty entries
+func splitColonList(s string) []string {
+	var ret []string
+	for _, v := range strings.Split(s, ":") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+// stripFWS removes all whitespace from a value, such as base64 data
+func stripFWS(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '\r', '\n':
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// CanonicalizedHeaders returns the named header fields canonicalized
+// for DKIM signing or verification, as described in RFC 6376 section 3.4.
+//
+// Each name in fields selects the last instance of that header field not
+// already selected, working from the bottom of the header upwards, and
+// names with no remaining instance contribute nothing. Simple
+// canonicalization requires the raw bytes of each selected field, so the
+// header must have been read with ReadOptions.KeepRaw.
+func (h *Header) CanonicalizedHeaders(fields []string, canon Canonicalization) ([]byte, error) {
+	h.CanonicalizeKeys()
+	if canon != CanonicalizationSimple && canon != CanonicalizationRelaxed {
+		return nil, fmt.Errorf("'%s' is not a valid canonicalization", canon)
+	}
+	var buff bytes.Buffer
+	used := map[int]struct{}{}
+	for _, field := range fields {
+		kv, ok := h.selectUnused(field, used)
+		if !ok {
+			continue
+		}
+		switch canon {
+		case CanonicalizationSimple:
+			if kv.Raw == "" {
+				return nil, fmt.Errorf("%s: simple canonicalization requires raw header bytes", kv.Key)
+			}
+			buff.Write(crlf([]byte(kv.Raw)))
+		case CanonicalizationRelaxed:
+			buff.WriteString(relaxedHeader(kv))
+		}
+	}
+	return buff.Bytes(), nil
+}
+
+// selectUnused finds the last instance of a header that is not in used,
+// and marks it as used
+func (h *Header) selectUnused(key string, used map[int]struct{}) (KV, bool) {
+	key = textproto.CanonicalMIMEHeaderKey(key)
+	for i := len(h.Headers) - 1; i >= 0; i-- {
+		if h.Headers[i].Key != key {
+			continue
+		}
+		if _, ok := used[i]; ok {
+			continue
+		}
+		used[i] = struct{}{}
+		return h.Headers[i], true
+	}
+	return KV{}, false
+}
+
+// relaxedHeader applies DKIM relaxed header canonicalization to a
+// single header field, including the trailing CRLF
+func relaxedHeader(kv KV) string {
+	name := kv.Key
+	value := kv.Value
+	if kv.Raw != "" {
+		if i := strings.IndexByte(kv.Raw, ':'); i >= 0 {
+			name = kv.Raw[:i]
+			value = kv.Raw[i+1:]
+		}
+	}
+	name = strings.ToLower(strings.TrimRight(name, " \t"))
+	value = strings.NewReplacer("\r", "", "\n", "").Replace(value)
+	value = strings.Join(strings.FieldsFunc(value, func(r rune) bool {
+		return r == ' ' || r == '\t'
+	}), " ")
+	return name + ":" + value + "\r\n"
+}
+
+// crlf converts bare LF line endings to CRLF, as email on disk often
+// has local line endings rather than the CRLF used on the wire
+func crlf(b []byte) []byte {
+	if !bytes.Contains(b, []byte{'\n'}) {
+		return append(b[:len(b):len(b)], '\r', '\n')
+	}
+	var ret []byte
+	for i, c := range b {
+		if c == '\n' && (i == 0 || b[i-1] != '\r') {
+			ret = append(ret, '\r')
+		}
+		ret = append(ret, c)
+	}
+	return ret
+}
+
+// SigningPolicy describes which header fields a DKIM signature covers
+type SigningPolicy struct {
+	// Fields are signed once for every instance present in the header
+	Fields []string
+	// Oversign fields are signed one more time than they occur, so that
+	// further instances cannot be added without breaking the signature.
+	// Fields listed here needn't also be listed in Fields.
+	Oversign []string
+}
+
+// DefaultSigningPolicy signs the fields recommended by RFC 6376 section
+// 5.4.1, oversigning From, Subject and To.
+var DefaultSigningPolicy = SigningPolicy{
+	Fields: []string{
+		HdrReplyTo, HdrDate, HdrCc, HdrMessageId, HdrInReplyTo, HdrReferences,
+		HdrResentDate, HdrResentFrom, HdrResentSender, HdrResentTo, HdrResentCc,
+		HdrMimeVersion, HdrContentType, HdrContentTransferEncoding,
+		"List-Id", "List-Help", "List-Unsubscribe", "List-Subscribe",
+		"List-Post", "List-Owner", "List-Archive",
+	},
+	Oversign: []string{HdrFrom, HdrSubject, HdrTo},
+}
+
+// SignedHeaders applies a signing policy to the header. It returns the
+// list of field names for a DKIM h= tag, and the header fields that list
+// selects in the order they are hashed. Oversigned fields that are absent
+// appear in the list but select no header field.
+func (h *Header) SignedHeaders(p SigningPolicy) ([]string, []KV) {
+	h.CanonicalizeKeys()
+	counts := map[string]int{}
+	for _, kv := range h.Headers {
+		counts[kv.Key]++
+	}
+	var names []string
+	seen := map[string]struct{}{}
+	add := func(field string, extra int) {
+		key := textproto.CanonicalMIMEHeaderKey(field)
+		if _, ok := seen[key]; ok {
+			return
+		}
+		seen[key] = struct{}{}
+		for i := 0; i < counts[key]+extra; i++ {
+			names = append(names, strings.ToLower(field))
+		}
+	}
+	for _, field := range p.Oversign {
+		add(field, 1)
+	}
+	for _, field := range p.Fields {
+		add(field, 0)
+	}
+	var kvs []KV
+	used := map[int]struct{}{}
+	for _, name := range names {
+		if kv, ok := h.selectUnused(name, used); ok {
+			kvs = append(kvs, kv)
+		}
+	}
+	return names, kvs
+}
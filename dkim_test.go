@@ -0,0 +1,124 @@
+package orderedheaders
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseDKIMSignature(t *testing.T) {
+	in := "v=1; a=rsa-sha256; c=relaxed/simple; d=example.com;\r\n" +
+		" s=sel1; t=1117574938; x=1118006938; l=200;\r\n" +
+		" h=from:to:subject:date; bh=MTIzNDU2Nzg5MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTI=;\r\n" +
+		" b=dzdVyOfAKCdLXdJOc9G2q8LoXSlEniSbav+yuU4zGeeruD00lszZ\r\n" +
+		"\tVoG4ZHRNiYzR"
+	got, err := ParseDKIMSignature(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DKIMSignature{
+		Version:                1,
+		Algorithm:              "rsa-sha256",
+		Domain:                 "example.com",
+		Selector:               "sel1",
+		Headers:                []string{"from", "to", "subject", "date"},
+		BodyHash:               "MTIzNDU2Nzg5MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTI=",
+		Signature:              "dzdVyOfAKCdLXdJOc9G2q8LoXSlEniSbav+yuU4zGeeruD00lszZVoG4ZHRNiYzR",
+		HeaderCanonicalization: CanonicalizationRelaxed,
+		BodyCanonicalization:   CanonicalizationSimple,
+		Timestamp:              time.Unix(1117574938, 0).UTC(),
+		Expiration:             time.Unix(1118006938, 0).UTC(),
+		Length:                 200,
+	}
+	wantTags := []string{"v", "a", "c", "d", "s", "t", "x", "l", "h", "bh", "b"}
+	var gotTags []string
+	for _, tag := range got.Tags {
+		gotTags = append(gotTags, tag.Name)
+	}
+	if diff := cmp.Diff(wantTags, gotTags); diff != "" {
+		t.Errorf("tag order mismatch (-want +got):\n%s", diff)
+	}
+	got.Tags = nil
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("DKIMSignature mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseDKIMSignature_Errors(t *testing.T) {
+	tests := map[string]string{
+		"missing":   "v=1; a=rsa-sha256; d=example.com; s=sel; h=from; bh=abc=",
+		"version":   "v=2; a=rsa-sha256; d=example.com; s=sel; h=from; bh=abc=; b=def=",
+		"duplicate": "v=1; a=rsa-sha256; d=example.com; d=example.org; s=sel; h=from; bh=abc=; b=def=",
+		"canon":     "v=1; a=rsa-sha256; c=loose; d=example.com; s=sel; h=from; bh=abc=; b=def=",
+		"tagspec":   "v=1; a=rsa-sha256; d=example.com; s=sel; h=from; bh=abc=; b=def=; junk",
+		"length":    "v=1; a=rsa-sha256; d=example.com; s=sel; h=from; bh=abc=; b=def=; l=-4",
+	}
+	for name, in := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseDKIMSignature(in)
+			if err == nil {
+				t.Errorf("expected error, didn't get one")
+			}
+		})
+	}
+}
+
+func TestCanonicalizedHeaders(t *testing.T) {
+	// https://tools.wordtothewise.com/rfc6376#section-3.4.5
+	in := "A: X\r\nB : Y\t\r\n\tZ  \r\nA: second\r\n\r\n"
+	hdr, err := ReadHeaderWithOptions(reader(in), ReadOptions{KeepRaw: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]struct {
+		Fields []string
+		Canon  Canonicalization
+		Want   string
+	}{
+		"relaxed": {[]string{"a", "b"}, CanonicalizationRelaxed, "a:second\r\nb:Y Z\r\n"},
+		"simple":  {[]string{"a", "b"}, CanonicalizationSimple, "A: second\r\nB : Y\t\r\n\tZ  \r\n"},
+		"bottomup": {[]string{"a", "a", "a", "b"}, CanonicalizationRelaxed,
+			"a:second\r\na:X\r\nb:Y Z\r\n"},
+		"missing": {[]string{"from", "b"}, CanonicalizationRelaxed, "b:Y Z\r\n"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := hdr.CanonicalizedHeaders(test.Fields, test.Canon)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.Want, string(got)); diff != "" {
+				t.Errorf("CanonicalizedHeaders mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	hdr.Add("C", "added")
+	if _, err := hdr.CanonicalizedHeaders([]string{"c"}, CanonicalizationSimple); err == nil {
+		t.Errorf("expected error for simple canonicalization without raw bytes")
+	}
+}
+
+func TestSignedHeaders(t *testing.T) {
+	hdr := Header{}
+	hdr.Add("From", "a@example.com")
+	hdr.Add("Received", "from nowhere")
+	hdr.Add("Subject", "one")
+	hdr.Add("Date", "Mon, 22 May 2023 10:00:00 +0000")
+	hdr.Add("Subject", "two")
+	names, kvs := hdr.SignedHeaders(DefaultSigningPolicy)
+	wantNames := []string{"from", "from", "subject", "subject", "subject", "to", "date"}
+	if diff := cmp.Diff(wantNames, names); diff != "" {
+		t.Errorf("names mismatch (-want +got):\n%s", diff)
+	}
+	wantKVs := []KV{
+		{Key: "From", Value: "a@example.com"},
+		{Key: "Subject", Value: "two"},
+		{Key: "Subject", Value: "one"},
+		{Key: "Date", Value: "Mon, 22 May 2023 10:00:00 +0000"},
+	}
+	if diff := cmp.Diff(wantKVs, kvs); diff != "" {
+		t.Errorf("values mismatch (-want +got):\n%s", diff)
+	}
+}
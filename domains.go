@@ -0,0 +1,87 @@
+package orderedheaders
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+)
+
+// addressHeaders are the fields holding mailbox lists, for RewriteDomains
+var addressHeaders = map[string]struct{}{
+	HdrFrom:                      {},
+	HdrSender:                    {},
+	HdrReplyTo:                   {},
+	HdrTo:                        {},
+	HdrCc:                        {},
+	HdrBcc:                       {},
+	HdrResentFrom:                {},
+	HdrResentSender:              {},
+	HdrResentTo:                  {},
+	HdrResentCc:                  {},
+	HdrResentBcc:                 {},
+	HdrDispositionNotificationTo: {},
+}
+
+// RewriteDomains replaces the domains of addresses in the address fields
+// and Return-Path, such as during a domain migration. mapping maps old
+// domains to new, and is matched case insensitively against the whole
+// domain, so subdomains need their own entries. Fields with a changed
+// address are re-rendered, and others are left as they were. It returns
+// the number of fields changed. If a field can't be parsed nothing is
+// changed.
+func (h *Header) RewriteDomains(mapping map[string]string) (int, error) {
+	h.CanonicalizeKeys()
+	domains := make(map[string]string, len(mapping))
+	for from, to := range mapping {
+		domains[strings.ToLower(from)] = to
+	}
+	rewrite := func(addr string) (string, bool) {
+		at := strings.LastIndexByte(addr, '@')
+		if at < 0 {
+			return addr, false
+		}
+		to, ok := domains[strings.ToLower(addr[at+1:])]
+		if !ok {
+			return addr, false
+		}
+		return addr[:at+1] + to, true
+	}
+
+	updated := map[int]string{}
+	for i, kv := range h.Headers {
+		if kv.Key == HdrReturnPath {
+			addr, isNull, err := parseReturnPath(kv.Value)
+			if err != nil {
+				return 0, fmt.Errorf("%s: %w", kv.Key, err)
+			}
+			if isNull {
+				continue
+			}
+			if addr, ok := rewrite(addr); ok {
+				updated[i] = (&mail.Address{Address: addr}).String()
+			}
+			continue
+		}
+		if _, ok := addressHeaders[kv.Key]; !ok || StripComments(kv.Value) == "" {
+			continue
+		}
+		addrs, err := mail.ParseAddressList(kv.Value)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", kv.Key, err)
+		}
+		changed := false
+		for _, a := range addrs {
+			var ok bool
+			if a.Address, ok = rewrite(a.Address); ok {
+				changed = true
+			}
+		}
+		if changed {
+			updated[i] = formatAddressList(addrs)
+		}
+	}
+	for i, value := range updated {
+		h.Headers[i] = KV{Key: h.Headers[i].Key, Value: value}
+	}
+	return len(updated), nil
+}
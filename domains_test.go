@@ -0,0 +1,53 @@
+package orderedheaders
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRewriteDomains(t *testing.T) {
+	h := Header{Headers: []KV{
+		{Key: "Return-Path", Value: "<bounce@Old.example>"},
+		{Key: "From", Value: `"Smith, Alice" <alice@old.example>`, Raw: "From: ...\r\n"},
+		{Key: "To", Value: "bob@other.example, carol@OLD.EXAMPLE"},
+		{Key: "Cc", Value: "dave@other.example", Raw: "Cc: dave@other.example\r\n"},
+		{Key: "Resent-To", Value: "erin@sub.old.example"},
+		{Key: "Bcc", Value: ""},
+		{Key: "Cc", Value: "(undisclosed)"},
+		{Key: "Subject", Value: "mail from alice@old.example"},
+	}}
+	n, err := h.RewriteDomains(map[string]string{"old.example": "new.example"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []KV{
+		{Key: "Return-Path", Value: "<bounce@new.example>"},
+		{Key: "From", Value: `"Smith, Alice" <alice@new.example>`},
+		{Key: "To", Value: "<bob@other.example>, <carol@new.example>"},
+		{Key: "Cc", Value: "dave@other.example", Raw: "Cc: dave@other.example\r\n"},
+		{Key: "Resent-To", Value: "erin@sub.old.example"},
+		{Key: "Bcc", Value: ""},
+		{Key: "Cc", Value: "(undisclosed)"},
+		{Key: "Subject", Value: "mail from alice@old.example"},
+	}
+	if diff := cmp.Diff(want, h.Headers); diff != "" {
+		t.Errorf("header mismatch (-want +got):\n%s", diff)
+	}
+	if n != 3 {
+		t.Errorf("expected 3 fields changed, got %d", n)
+	}
+}
+
+func TestRewriteDomainsInvalid(t *testing.T) {
+	h := Header{Headers: []KV{
+		{Key: "From", Value: "alice@old.example"},
+		{Key: "To", Value: "not an address"},
+	}}
+	if _, err := h.RewriteDomains(map[string]string{"old.example": "new.example"}); err == nil {
+		t.Errorf("expected error")
+	}
+	if h.Get("From") != "alice@old.example" {
+		t.Errorf("header changed despite error")
+	}
+}
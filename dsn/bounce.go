@@ -0,0 +1,98 @@
+package dsn
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/wttw/orderedheaders"
+)
+
+// https://tools.wordtothewise.com/rfc3464#section-4
+// https://tools.wordtothewise.com/rfc3834#section-5
+
+// DefaultBounceSubject is the subject of a bounce if none is given
+const DefaultBounceSubject = "Undelivered Mail Returned to Sender"
+
+// BounceOptions configures Bounce
+type BounceOptions struct {
+	// From is the author of the bounce, such as the postmaster or
+	// MAILER-DAEMON of the reporting MTA, and is required
+	From *mail.Address
+	// To overrides the recipient of the bounce, which is otherwise the
+	// Return-Path of the original
+	To *mail.Address
+	// Subject defaults to DefaultBounceSubject
+	Subject string
+	// ReportingMTA is the name of the MTA generating the bounce, and is
+	// required
+	ReportingMTA string
+	// Recipients are the recipients delivery failed for. Action
+	// defaults to failed and Status to 5.0.0.
+	Recipients []Recipient
+	// ArrivalDate is when the original arrived, or the zero time
+	ArrivalDate time.Time
+}
+
+// Bounce creates a non-delivery report for a message that couldn't be
+// delivered, explaining why with reason. The bounce has a null
+// Return-Path, so it can't itself be bounced, is marked Auto-Submitted:
+// auto-replied, and references the original's Message-Id if it's valid.
+// The original header is returned as text/rfc822-headers. A message with
+// a null Return-Path is never bounced.
+func Bounce(orig *orderedheaders.Message, reason string, opts BounceOptions) (*orderedheaders.Message, error) {
+	if opts.From == nil {
+		return nil, errors.New("a bounce requires a From address")
+	}
+	if len(opts.Recipients) == 0 {
+		return nil, errors.New("a bounce requires at least one recipient")
+	}
+	to := opts.To
+	if to == nil {
+		rp, isNull, err := orig.Header.ReturnPath()
+		if err != nil {
+			return nil, fmt.Errorf("no address to bounce to: %w", err)
+		}
+		if isNull {
+			return nil, errors.New("message has a null Return-Path, so can't be bounced")
+		}
+		to = &mail.Address{Address: rp}
+	}
+	subject := opts.Subject
+	if subject == "" {
+		subject = DefaultBounceSubject
+	}
+
+	b := orderedheaders.NewBuilder().
+		From(opts.From).
+		To(to).
+		Subject(subject).
+		Header(orderedheaders.HdrReturnPath, "<>").
+		Header(orderedheaders.HdrAutoSubmitted, "auto-replied")
+	if id := strings.TrimSpace(orig.Header.Get(orderedheaders.HdrMessageId)); orderedheaders.ValidateMessageID(id) == nil {
+		b.Header(orderedheaders.HdrInReplyTo, id).
+			Header(orderedheaders.HdrReferences, id)
+	}
+
+	r := &Report{
+		PerMessage: PerMessage{
+			ReportingMTA: opts.ReportingMTA,
+			ArrivalDate:  opts.ArrivalDate,
+		},
+		Text:        reason,
+		Original:    orig,
+		HeadersOnly: true,
+	}
+	for _, rcpt := range opts.Recipients {
+		if rcpt.Action == "" {
+			rcpt.Action = ActionFailed
+		}
+		if rcpt.Status == "" {
+			rcpt.Status = "5.0.0"
+		}
+		r.Recipients = append(r.Recipients, rcpt)
+	}
+	return r.Build(b)
+}
@@ -0,0 +1,124 @@
+package dsn
+
+import (
+	"io"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/wttw/orderedheaders"
+)
+
+const undeliverable = "Return-Path: <alice@example.com>\r\n" +
+	"From: alice@example.com\r\n" +
+	"To: bob@example.net\r\n" +
+	"Subject: hello\r\n" +
+	"Message-Id: <1@example.com>\r\n" +
+	"\r\n" +
+	"body\r\n"
+
+func TestBounce(t *testing.T) {
+	orig, err := orderedheaders.ReadMessage(strings.NewReader(undeliverable))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := Bounce(orig, "The mailbox is full", BounceOptions{
+		From:         &mail.Address{Name: "Mail Delivery System", Address: "mailer-daemon@mx.example.com"},
+		ReportingMTA: "mx.example.com",
+		Recipients:   []Recipient{{FinalRecipient: "bob@example.net", Status: "5.2.2"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &msg.Header
+	if _, isNull, err := h.ReturnPath(); err != nil || !isNull {
+		t.Errorf("expected null Return-Path, got %q", h.Get(orderedheaders.HdrReturnPath))
+	}
+	for key, want := range map[string]string{
+		orderedheaders.HdrTo:            "<alice@example.com>",
+		orderedheaders.HdrSubject:       DefaultBounceSubject,
+		orderedheaders.HdrAutoSubmitted: "auto-replied",
+		orderedheaders.HdrInReplyTo:     "<1@example.com>",
+		orderedheaders.HdrReferences:    "<1@example.com>",
+	} {
+		if got := h.Get(key); got != want {
+			t.Errorf("%s: want %q, got %q", key, want, got)
+		}
+	}
+	if ok, _ := h.ShouldAutoRespond(); ok {
+		t.Errorf("expected bounce to suppress auto responses")
+	}
+
+	r, err := Parse(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Recipient{{FinalRecipient: "bob@example.net", Action: ActionFailed, Status: "5.2.2"}}
+	if diff := cmp.Diff(want, r.Recipients, cmpopts.IgnoreFields(Recipient{}, "Fields")); diff != "" {
+		t.Errorf("recipients mismatch (-want +got):\n%s", diff)
+	}
+	if r.Text != "The mailbox is full" {
+		t.Errorf("unexpected text %q", r.Text)
+	}
+	if !r.HeadersOnly || r.Original.Header.Get(orderedheaders.HdrMessageId) != "<1@example.com>" {
+		t.Errorf("expected original header as text/rfc822-headers")
+	}
+	if body, _ := io.ReadAll(r.Original.Body); len(body) != 0 {
+		t.Errorf("original body included: %q", body)
+	}
+}
+
+func TestBounceMalformed(t *testing.T) {
+	const in = "Return-Path: <alice@example.com>\r\n" +
+		"From: broken <<s@example.com>\r\n" +
+		"Message-Id: not-a-msgid\r\n" +
+		"\r\n"
+	orig, err := orderedheaders.ReadMessageWithOptions(strings.NewReader(in), orderedheaders.MessageOptions{KeepRawHeader: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := Bounce(orig, "failed", BounceOptions{
+		From:         &mail.Address{Address: "mailer-daemon@mx.example.com"},
+		ReportingMTA: "mx.example.com",
+		Recipients:   []Recipient{{FinalRecipient: "bob@example.net"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Header.Has(orderedheaders.HdrInReplyTo) || msg.Header.Has(orderedheaders.HdrReferences) {
+		t.Errorf("invalid Message-Id referenced")
+	}
+	r, err := Parse(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Original.Header.Get(orderedheaders.HdrFrom); got != "broken <<s@example.com>" {
+		t.Errorf("original From not preserved, got %q", got)
+	}
+}
+
+func TestBounceErrors(t *testing.T) {
+	opts := BounceOptions{
+		From:         &mail.Address{Address: "mailer-daemon@mx.example.com"},
+		ReportingMTA: "mx.example.com",
+		Recipients:   []Recipient{{FinalRecipient: "bob@example.net"}},
+	}
+	tests := map[string]string{
+		"null":    "Return-Path: <>\r\nFrom: a@example.com\r\n\r\n",
+		"missing": "From: a@example.com\r\n\r\n",
+		"invalid": "Return-Path: <a@\r\nFrom: a@example.com\r\n\r\n",
+	}
+	for name, in := range tests {
+		t.Run(name, func(t *testing.T) {
+			orig, err := orderedheaders.ReadMessage(strings.NewReader(in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Bounce(orig, "failed", opts); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,357 @@
+// Package dsn builds and parses delivery status notifications, the
+// bounces and delay warnings described in RFC 3464, with each group of
+// delivery status fields held as an ordered header.
+package dsn
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/wttw/orderedheaders"
+)
+
+// https://tools.wordtothewise.com/rfc3464#section-2
+
+const (
+	HdrOriginalEnvelopeID = "Original-Envelope-Id"
+	HdrReportingMTA       = "Reporting-Mta"
+	HdrDSNGateway         = "Dsn-Gateway"
+	HdrReceivedFromMTA    = "Received-From-Mta"
+	HdrArrivalDate        = "Arrival-Date"
+	HdrOriginalRecipient  = "Original-Recipient"
+	HdrFinalRecipient     = "Final-Recipient"
+	HdrAction             = "Action"
+	HdrStatus             = "Status"
+	HdrRemoteMTA          = "Remote-Mta"
+	HdrDiagnosticCode     = "Diagnostic-Code"
+	HdrLastAttemptDate    = "Last-Attempt-Date"
+	HdrFinalLogID         = "Final-Log-Id"
+	HdrWillRetryUntil     = "Will-Retry-Until"
+)
+
+// Actions
+const (
+	ActionFailed    = "failed"
+	ActionDelayed   = "delayed"
+	ActionDelivered = "delivered"
+	ActionRelayed   = "relayed"
+	ActionExpanded  = "expanded"
+)
+
+// ReportType is the report-type of a multipart/report containing a DSN
+const ReportType = "delivery-status"
+
+// ErrNotDSN is returned by Parse for a message that isn't a DSN
+var ErrNotDSN = errors.New("message is not a delivery status notification")
+
+// PerMessage holds the fields describing the message as a whole
+type PerMessage struct {
+	// ReportingMTA is the name of the MTA generating the DSN, without
+	// its "dns;" type
+	ReportingMTA string
+	// EnvelopeID is the ENVID given when the message was submitted
+	EnvelopeID string
+	// ReceivedFromMTA is the MTA the message was received from, without
+	// its type
+	ReceivedFromMTA string
+	// ArrivalDate is when the message arrived at the reporting MTA, or
+	// the zero time
+	ArrivalDate time.Time
+	// Fields holds every per-message field, in order, including
+	// extensions. When building it's used for fields not set above.
+	Fields orderedheaders.Header
+}
+
+// Recipient holds the fields describing delivery to one recipient
+type Recipient struct {
+	// OriginalRecipient is the ORCPT address, without its type
+	OriginalRecipient string
+	// FinalRecipient is the recipient address, without its type
+	FinalRecipient string
+	// Action is one of the Action constants
+	Action string
+	// Status is the enhanced status code, e.g. 5.1.1
+	Status string
+	// RemoteMTA is the MTA that reported the status, without its type
+	RemoteMTA string
+	// DiagnosticCode is the diagnostic, including its type, e.g.
+	// "smtp; 550 5.1.1 unknown user"
+	DiagnosticCode string
+	// LastAttemptDate is the time of the last delivery attempt, or the
+	// zero time
+	LastAttemptDate time.Time
+	// WillRetryUntil is, for delayed messages, when attempts will stop,
+	// or the zero time
+	WillRetryUntil time.Time
+	// Fields holds every per-recipient field, in order, including
+	// extensions. When building it's used for fields not set above.
+	Fields orderedheaders.Header
+}
+
+// Report is a delivery status notification
+type Report struct {
+	PerMessage
+	Recipients []Recipient
+	// Text is the human readable explanation
+	Text string
+	// Original is the message the DSN is about, or just its header
+	Original *orderedheaders.Message
+	// HeadersOnly returns only the header of Original, as
+	// text/rfc822-headers, when building. The header is copied exactly
+	// as it was read if Original has a RawHeaderBlock.
+	HeadersOnly bool
+}
+
+// Parse parses a multipart/report DSN. Only the delivery status part is
+// required; the human readable text and returned message are included
+// if present.
+func Parse(m *orderedheaders.Message) (*Report, error) {
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get(orderedheaders.HdrContentType))
+	if err != nil || mediaType != "multipart/report" || !strings.EqualFold(params["report-type"], ReportType) {
+		return nil, ErrNotDSN
+	}
+	parts, err := m.Parts()
+	if err != nil {
+		return nil, err
+	}
+	r := &Report{}
+	found := false
+	for i, part := range parts {
+		mediaType, _, _ := mime.ParseMediaType(part.Header.Get(orderedheaders.HdrContentType))
+		switch {
+		case i == 0 && strings.HasPrefix(mediaType, "text/"):
+			body, err := part.TextBody()
+			if err != nil {
+				return nil, err
+			}
+			text, err := io.ReadAll(body)
+			if err != nil {
+				return nil, err
+			}
+			r.Text = string(text)
+		case mediaType == "message/delivery-status", mediaType == "message/global-delivery-status":
+			if err := r.parseStatus(part.Body); err != nil {
+				return nil, err
+			}
+			found = true
+		case part.IsEmbedded():
+			if r.Original, err = part.Embedded(); err != nil {
+				return nil, err
+			}
+		case mediaType == "text/rfc822-headers", mediaType == "message/global-headers":
+			r.Original, err = orderedheaders.ReadMessageWithOptions(part.Body, orderedheaders.MessageOptions{KeepRawHeader: true})
+			if err != nil {
+				return nil, err
+			}
+			r.HeadersOnly = true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: no delivery status part", ErrNotDSN)
+	}
+	return r, nil
+}
+
+// ParseStatus parses the body of a message/delivery-status part, a
+// per-message group of fields followed by a group for each recipient,
+// separated by blank lines.
+func ParseStatus(r io.Reader) (*Report, error) {
+	report := &Report{}
+	if err := report.parseStatus(r); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (r *Report) parseStatus(body io.Reader) error {
+	tp := textproto.NewReader(bufio.NewReader(body))
+	var groups []orderedheaders.Header
+	for {
+		h, err := orderedheaders.ReadHeader(tp)
+		if len(h.Headers) > 0 {
+			groups = append(groups, h)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if len(groups) == 0 {
+		return errors.New("empty delivery status")
+	}
+	r.PerMessage = parsePerMessage(groups[0])
+	for _, g := range groups[1:] {
+		r.Recipients = append(r.Recipients, parseRecipient(g))
+	}
+	return nil
+}
+
+func parsePerMessage(h orderedheaders.Header) PerMessage {
+	p := PerMessage{
+		ReportingMTA:    untyped(h.Get(HdrReportingMTA)),
+		EnvelopeID:      strings.TrimSpace(h.Get(HdrOriginalEnvelopeID)),
+		ReceivedFromMTA: untyped(h.Get(HdrReceivedFromMTA)),
+		Fields:          h,
+	}
+	p.ArrivalDate, _ = mail.ParseDate(h.Get(HdrArrivalDate))
+	return p
+}
+
+func parseRecipient(h orderedheaders.Header) Recipient {
+	r := Recipient{
+		OriginalRecipient: untyped(h.Get(HdrOriginalRecipient)),
+		FinalRecipient:    untyped(h.Get(HdrFinalRecipient)),
+		Action:            strings.ToLower(strings.TrimSpace(h.Get(HdrAction))),
+		Status:            statusCode(h.Get(HdrStatus)),
+		RemoteMTA:         untyped(h.Get(HdrRemoteMTA)),
+		DiagnosticCode:    strings.TrimSpace(h.Get(HdrDiagnosticCode)),
+		Fields:            h,
+	}
+	r.LastAttemptDate, _ = mail.ParseDate(h.Get(HdrLastAttemptDate))
+	r.WillRetryUntil, _ = mail.ParseDate(h.Get(HdrWillRetryUntil))
+	return r
+}
+
+// untyped removes the type from a "type; value" field, such as
+// "rfc822; user@example.com"
+func untyped(s string) string {
+	if semi := strings.IndexByte(s, ';'); semi >= 0 {
+		s = s[semi+1:]
+	}
+	return strings.TrimSpace(s)
+}
+
+// statusCode returns the status code from a Status field, dropping any
+// trailing comment
+func statusCode(s string) string {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// Build creates a DSN message from the report, using b for the header of
+// the DSN itself, such as From, To and Subject.
+func (r *Report) Build(b *orderedheaders.Builder) (*orderedheaders.Message, error) {
+	if len(r.Recipients) == 0 {
+		return nil, errors.New("a DSN requires at least one recipient")
+	}
+	if r.ReportingMTA == "" {
+		return nil, errors.New("a DSN requires a Reporting-MTA")
+	}
+	var status bytes.Buffer
+	groups := []orderedheaders.Header{r.PerMessage.header()}
+	for _, rcpt := range r.Recipients {
+		if rcpt.FinalRecipient == "" || rcpt.Action == "" || rcpt.Status == "" {
+			return nil, errors.New("each DSN recipient requires Final-Recipient, Action and Status")
+		}
+		groups = append(groups, rcpt.header())
+	}
+	for i, g := range groups {
+		if i > 0 {
+			status.WriteString("\r\n")
+		}
+		if err := g.WriteTo(&status, orderedheaders.Options{}); err != nil {
+			return nil, err
+		}
+	}
+	var statusHeader orderedheaders.Header
+	statusHeader.Add(orderedheaders.HdrContentType, "message/delivery-status")
+	b.Report(ReportType).Text(r.Text).Part(statusHeader, status.Bytes())
+
+	if r.Original != nil {
+		var original bytes.Buffer
+		var originalHeader orderedheaders.Header
+		if r.HeadersOnly {
+			originalHeader.Add(orderedheaders.HdrContentType, "text/rfc822-headers")
+			if raw := r.Original.RawHeaderBlock(); raw != nil {
+				original.Write(raw)
+			} else if err := r.Original.Header.WriteTo(&original, orderedheaders.Options{}); err != nil {
+				return nil, err
+			}
+		} else {
+			originalHeader.Add(orderedheaders.HdrContentType, "message/rfc822")
+			if err := r.Original.Rewrite(&original, orderedheaders.Options{}); err != nil {
+				return nil, err
+			}
+		}
+		b.Part(originalHeader, original.Bytes())
+	}
+	return b.Build()
+}
+
+// group accumulates typed fields into a header, remembering which keys
+// have been set so that finish can add the remaining extension fields
+type group struct {
+	h    orderedheaders.Header
+	keys map[string]struct{}
+}
+
+func (g *group) add(key, value string) {
+	if g.keys == nil {
+		g.keys = map[string]struct{}{}
+	}
+	g.keys[key] = struct{}{}
+	if value != "" {
+		g.h.Add(key, value)
+	}
+}
+
+func (g *group) addDate(key string, t time.Time) {
+	value := ""
+	if !t.IsZero() {
+		value = orderedheaders.FormatDate(t, true)
+	}
+	g.add(key, value)
+}
+
+func (g *group) finish(extra orderedheaders.Header) orderedheaders.Header {
+	extra.CanonicalizeKeys()
+	for _, kv := range extra.Headers {
+		if _, ok := g.keys[kv.Key]; !ok {
+			g.h.Add(kv.Key, kv.Value)
+		}
+	}
+	return g.h
+}
+
+// typed adds a type to a value, unless it's empty
+func typed(t, value string) string {
+	if value == "" {
+		return ""
+	}
+	return t + "; " + value
+}
+
+func (p PerMessage) header() orderedheaders.Header {
+	var g group
+	g.add(HdrOriginalEnvelopeID, p.EnvelopeID)
+	g.add(HdrReportingMTA, typed("dns", p.ReportingMTA))
+	g.add(HdrReceivedFromMTA, typed("dns", p.ReceivedFromMTA))
+	g.addDate(HdrArrivalDate, p.ArrivalDate)
+	return g.finish(p.Fields)
+}
+
+func (r Recipient) header() orderedheaders.Header {
+	var g group
+	g.add(HdrOriginalRecipient, typed("rfc822", r.OriginalRecipient))
+	g.add(HdrFinalRecipient, typed("rfc822", r.FinalRecipient))
+	g.add(HdrAction, r.Action)
+	g.add(HdrStatus, r.Status)
+	g.add(HdrRemoteMTA, typed("dns", r.RemoteMTA))
+	g.add(HdrDiagnosticCode, r.DiagnosticCode)
+	g.addDate(HdrLastAttemptDate, r.LastAttemptDate)
+	g.addDate(HdrWillRetryUntil, r.WillRetryUntil)
+	return g.finish(r.Fields)
+}
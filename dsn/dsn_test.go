@@ -0,0 +1,186 @@
+package dsn
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/wttw/orderedheaders"
+)
+
+const bounce = "From: MAILER-DAEMON@mx.example.com\r\n" +
+	"To: alice@example.com\r\n" +
+	"Subject: Undelivered Mail Returned to Sender\r\n" +
+	"Content-Type: multipart/report; report-type=delivery-status;\r\n" +
+	"\tboundary=\"abc\"\r\n" +
+	"\r\n" +
+	"--abc\r\n" +
+	"Content-Type: text/plain; charset=us-ascii\r\n" +
+	"\r\n" +
+	"Your message could not be delivered.\r\n" +
+	"--abc\r\n" +
+	"Content-Type: message/delivery-status\r\n" +
+	"\r\n" +
+	"Reporting-MTA: dns; mx.example.com\r\n" +
+	"X-Postfix-Queue-ID: 4ABC\r\n" +
+	"Arrival-Date: Mon, 22 May 2023 10:00:00 +0000\r\n" +
+	"\r\n" +
+	"Final-Recipient: rfc822; bob@example.net\r\n" +
+	"Original-Recipient: rfc822;Bob@example.net\r\n" +
+	"Action: failed\r\n" +
+	"Status: 5.1.1 (unknown user)\r\n" +
+	"Remote-MTA: dns; mx.example.net\r\n" +
+	"Diagnostic-Code: smtp; 550 5.1.1 <bob@example.net>: Recipient address\r\n" +
+	"    rejected: User unknown\r\n" +
+	"\r\n" +
+	"Final-Recipient: rfc822; carol@example.net\r\n" +
+	"Action: delayed\r\n" +
+	"Status: 4.4.1\r\n" +
+	"Will-Retry-Until: Tue, 23 May 2023 10:00:00 +0000\r\n" +
+	"\r\n" +
+	"--abc\r\n" +
+	"Content-Type: text/rfc822-headers\r\n" +
+	"\r\n" +
+	"From: alice@example.com\r\n" +
+	"Subject: hello\r\n" +
+	"\r\n" +
+	"--abc--\r\n"
+
+func TestParse(t *testing.T) {
+	msg, err := orderedheaders.ReadMessage(strings.NewReader(bounce))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := Parse(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Text != "Your message could not be delivered." {
+		t.Errorf("unexpected text %q", r.Text)
+	}
+	want := PerMessage{
+		ReportingMTA: "mx.example.com",
+		ArrivalDate:  time.Date(2023, 5, 22, 10, 0, 0, 0, time.UTC),
+	}
+	if diff := cmp.Diff(want, r.PerMessage, cmpopts.IgnoreFields(PerMessage{}, "Fields")); diff != "" {
+		t.Errorf("per-message mismatch (-want +got):\n%s", diff)
+	}
+	if got := r.Fields.Get("X-Postfix-Queue-Id"); got != "4ABC" {
+		t.Errorf("extension field missing, got '%s'", got)
+	}
+	wantRcpts := []Recipient{
+		{
+			FinalRecipient:    "bob@example.net",
+			OriginalRecipient: "Bob@example.net",
+			Action:            ActionFailed,
+			Status:            "5.1.1",
+			RemoteMTA:         "mx.example.net",
+			DiagnosticCode:    "smtp; 550 5.1.1 <bob@example.net>: Recipient address rejected: User unknown",
+		},
+		{
+			FinalRecipient: "carol@example.net",
+			Action:         ActionDelayed,
+			Status:         "4.4.1",
+			WillRetryUntil: time.Date(2023, 5, 23, 10, 0, 0, 0, time.UTC),
+		},
+	}
+	if diff := cmp.Diff(wantRcpts, r.Recipients, cmpopts.IgnoreFields(Recipient{}, "Fields")); diff != "" {
+		t.Errorf("recipients mismatch (-want +got):\n%s", diff)
+	}
+	if !r.HeadersOnly || r.Original == nil || r.Original.Header.Get("Subject") != "hello" {
+		t.Errorf("original headers not parsed: %+v", r.Original)
+	}
+}
+
+func TestParseNotDSN(t *testing.T) {
+	for _, in := range []string{
+		"Content-Type: text/plain\r\n\r\nhello",
+		"Content-Type: multipart/report; report-type=feedback-report; boundary=b\r\n\r\n--b\r\n\r\nx\r\n--b--\r\n",
+		"Content-Type: multipart/report; report-type=delivery-status; boundary=b\r\n\r\n--b\r\n\r\nx\r\n--b--\r\n",
+	} {
+		msg, err := orderedheaders.ReadMessage(strings.NewReader(in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Parse(msg); !errors.Is(err, ErrNotDSN) {
+			t.Errorf("expected ErrNotDSN, got %v", err)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	original, err := orderedheaders.ReadMessageWithOptions(strings.NewReader(
+		"From:  alice@example.com\r\nSubject: hello\r\n\r\nbody\r\n"),
+		orderedheaders.MessageOptions{ReadOptions: orderedheaders.ReadOptions{KeepRaw: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var extra orderedheaders.Header
+	extra.Add("X-Queue-Id", "Q1")
+	report := &Report{
+		PerMessage: PerMessage{
+			ReportingMTA: "mx.example.com",
+			ArrivalDate:  time.Date(2023, 5, 22, 10, 0, 0, 0, time.UTC),
+			Fields:       extra,
+		},
+		Recipients: []Recipient{{
+			FinalRecipient: "bob@example.net",
+			Action:         ActionFailed,
+			Status:         "5.1.1",
+			DiagnosticCode: "smtp; 550 5.1.1 unknown user",
+		}},
+		Text:     "Delivery failed",
+		Original: original,
+	}
+	b := orderedheaders.NewBuilder().
+		From(&mail.Address{Address: "mailer-daemon@mx.example.com"}).
+		To(&mail.Address{Address: "alice@example.com"}).
+		Subject("Undeliverable")
+	msg, err := report.Build(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := Parse(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(report.Recipients, parsed.Recipients, cmpopts.IgnoreFields(Recipient{}, "Fields")); diff != "" {
+		t.Errorf("recipients mismatch (-want +got):\n%s", diff)
+	}
+	if parsed.ReportingMTA != "mx.example.com" || parsed.Fields.Get("X-Queue-Id") != "Q1" {
+		t.Errorf("unexpected per-message fields %+v", parsed.PerMessage)
+	}
+	if parsed.Text != "Delivery failed" {
+		t.Errorf("unexpected text %q", parsed.Text)
+	}
+	if parsed.HeadersOnly || parsed.Original == nil {
+		t.Fatalf("expected embedded original")
+	}
+	if raw := parsed.Original.Header.Headers[0].Raw; raw != "From:  alice@example.com\r\n" {
+		t.Errorf("original header not preserved, got %q", raw)
+	}
+
+	report.HeadersOnly = true
+	msg, err = report.Build(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err = Parse(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parsed.HeadersOnly || parsed.Original == nil {
+		t.Fatalf("expected original header")
+	}
+	if raw := string(parsed.Original.RawHeaderBlock()); raw != "From:  alice@example.com\r\nSubject: hello\r\n\r\n" {
+		t.Errorf("original header not preserved, got %q", raw)
+	}
+
+	if _, err := (&Report{PerMessage: PerMessage{ReportingMTA: "x"}}).Build(orderedheaders.NewBuilder()); err == nil {
+		t.Errorf("expected error without recipients")
+	}
+}
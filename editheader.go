@@ -0,0 +1,106 @@
+package orderedheaders
+
+import (
+	"errors"
+	"fmt"
+	"net/textproto"
+	"strings"
+)
+
+// https://tools.wordtothewise.com/rfc5293#section-4
+// https://tools.wordtothewise.com/rfc5293#section-5
+
+// ErrProtectedHeader is returned by DeleteHeader for fields that Sieve
+// scripts must not remove
+var ErrProtectedHeader = errors.New("header field can't be deleted")
+
+// protectedHeaders can't be deleted by DeleteHeader, so that trace and
+// loop detection information survives filtering
+var protectedHeaders = map[string]struct{}{
+	HdrReceived:      {},
+	HdrAutoSubmitted: {},
+}
+
+// AddHeader adds a header field as the Sieve editheader "addheader"
+// action does, at the start of the header, or at the end if last is
+// set. Any field name is accepted, not just those known to Set, but it
+// must be valid, and the value can't contain CR or LF.
+func (h *Header) AddHeader(key, value string, last bool) error {
+	h.CanonicalizeKeys()
+	if !validFieldName(key) {
+		return fmt.Errorf("'%s' is not a valid header field name", key)
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("value for %s contains a line break", key)
+	}
+	kv := KV{Key: textproto.CanonicalMIMEHeaderKey(key), Value: value}
+	if last {
+		h.Headers = append(h.Headers, kv)
+		return nil
+	}
+	h.insert(0, kv)
+	return nil
+}
+
+// DeleteHeader removes header fields as the Sieve editheader
+// "deleteheader" action does, and returns how many were removed. Fields
+// named key are removed if matcher is nil or returns true for their
+// value. If index is greater than zero only the index'th field named
+// key is considered, counting from 1 at the start of the header, or
+// from the end if last is set. Received and Auto-Submitted fields can't
+// be deleted.
+func (h *Header) DeleteHeader(key string, matcher func(value string) bool, index int, last bool) (int, error) {
+	h.CanonicalizeKeys()
+	key = textproto.CanonicalMIMEHeaderKey(key)
+	if _, ok := protectedHeaders[key]; ok {
+		return 0, fmt.Errorf("%s: %w", key, ErrProtectedHeader)
+	}
+	var positions []int
+	for i, kv := range h.Headers {
+		if kv.Key == key {
+			positions = append(positions, i)
+		}
+	}
+	if index > 0 {
+		if index > len(positions) {
+			return 0, nil
+		}
+		if last {
+			positions = positions[len(positions)-index : len(positions)-index+1]
+		} else {
+			positions = positions[index-1 : index]
+		}
+	}
+	remove := map[int]struct{}{}
+	for _, i := range positions {
+		if matcher == nil || matcher(h.Headers[i].Value) {
+			remove[i] = struct{}{}
+		}
+	}
+	if len(remove) == 0 {
+		return 0, nil
+	}
+	filtered := h.Headers[:0]
+	for i, kv := range h.Headers {
+		if _, ok := remove[i]; !ok {
+			filtered = append(filtered, kv)
+		}
+	}
+	h.Headers = filtered
+	return len(remove), nil
+}
+
+// validFieldName checks a header field name is one or more printable
+// ASCII characters other than colon
+// https://tools.wordtothewise.com/rfc5322#section-3.6.8
+func validFieldName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < 33 || s[i] > 126 || s[i] == ':' {
+			return false
+		}
+	}
+	return true
+}
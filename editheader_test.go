@@ -0,0 +1,91 @@
+package orderedheaders
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func editHeaderInput() Header {
+	return Header{Headers: []KV{
+		{Key: "Received", Value: "from a"},
+		{Key: "X-Spam", Value: "one"},
+		{Key: "Subject", Value: "hello"},
+		{Key: "X-Spam", Value: "two"},
+		{Key: "X-Spam", Value: "three"},
+	}}
+}
+
+func TestAddHeader(t *testing.T) {
+	h := editHeaderInput()
+	if err := h.AddHeader("x-first", "1", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.AddHeader("X-Last", "2", true); err != nil {
+		t.Fatal(err)
+	}
+	want := append([]KV{{Key: "X-First", Value: "1"}}, editHeaderInput().Headers...)
+	want = append(want, KV{Key: "X-Last", Value: "2"})
+	if diff := cmp.Diff(want, h.Headers); diff != "" {
+		t.Errorf("header mismatch (-want +got):\n%s", diff)
+	}
+	for _, bad := range [][2]string{{"X Bad", "v"}, {"X:Bad", "v"}, {"", "v"}, {"X-Ok", "a\r\nb"}} {
+		if err := h.AddHeader(bad[0], bad[1], false); err == nil {
+			t.Errorf("expected error adding %q: %q", bad[0], bad[1])
+		}
+	}
+}
+
+func TestDeleteHeader(t *testing.T) {
+	contains := func(s string) func(string) bool {
+		return func(v string) bool { return strings.Contains(v, s) }
+	}
+	tests := map[string]struct {
+		Key     string
+		Matcher func(string) bool
+		Index   int
+		Last    bool
+		Want    []string
+	}{
+		"all":        {Key: "x-spam", Want: []string{"from a", "hello"}},
+		"match":      {Key: "X-Spam", Matcher: contains("t"), Want: []string{"from a", "one", "hello"}},
+		"index":      {Key: "X-Spam", Index: 2, Want: []string{"from a", "one", "hello", "three"}},
+		"indexlast":  {Key: "X-Spam", Index: 1, Last: true, Want: []string{"from a", "one", "hello", "two"}},
+		"indexmatch": {Key: "X-Spam", Index: 1, Matcher: contains("t"), Want: []string{"from a", "one", "hello", "two", "three"}},
+		"outofrange": {Key: "X-Spam", Index: 4, Want: []string{"from a", "one", "hello", "two", "three"}},
+		"missing":    {Key: "X-Other", Want: []string{"from a", "one", "hello", "two", "three"}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := editHeaderInput()
+			n, err := h.DeleteHeader(test.Key, test.Matcher, test.Index, test.Last)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, kv := range h.Headers {
+				got = append(got, kv.Value)
+			}
+			if diff := cmp.Diff(test.Want, got); diff != "" {
+				t.Errorf("header mismatch (-want +got):\n%s", diff)
+			}
+			if n != 5-len(test.Want) {
+				t.Errorf("reported %d deleted, expected %d", n, 5-len(test.Want))
+			}
+		})
+	}
+}
+
+func TestDeleteProtectedHeader(t *testing.T) {
+	h := editHeaderInput()
+	for _, key := range []string{"Received", "auto-submitted"} {
+		if _, err := h.DeleteHeader(key, nil, 0, false); !errors.Is(err, ErrProtectedHeader) {
+			t.Errorf("%s: expected ErrProtectedHeader, got %v", key, err)
+		}
+	}
+	if len(h.Headers) != 5 {
+		t.Errorf("protected header deleted")
+	}
+}
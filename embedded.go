@@ -0,0 +1,59 @@
+package orderedheaders
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime/quotedprintable"
+	"strings"
+)
+
+// https://tools.wordtothewise.com/rfc2046#section-5.2.1
+// https://tools.wordtothewise.com/rfc6532#section-3.7
+
+// ErrNotEmbedded is returned by Embedded for a message that isn't a
+// message/rfc822 or message/global part
+var ErrNotEmbedded = errors.New("message does not embed another message")
+
+// IsEmbedded reports whether the message is a message/rfc822 or
+// message/global part, such as a forwarded message or the original
+// message returned in a bounce
+func (m *Message) IsEmbedded() bool {
+	mediaType, _ := m.mediaType()
+	return mediaType == "message/rfc822" || mediaType == "message/global"
+}
+
+// Embedded parses the message embedded in a message/rfc822 or
+// message/global part. The fields of its header are in order, with their
+// raw bytes retained. The part body is read, and replaced with an in
+// memory copy so it can be read again.
+func (m *Message) Embedded() (*Message, error) {
+	if !m.IsEmbedded() {
+		return nil, ErrNotEmbedded
+	}
+	body, err := io.ReadAll(m.Body)
+	if err != nil {
+		return nil, err
+	}
+	m.Body = bytes.NewReader(body)
+	content, err := io.ReadAll(decodeTransferEncoding(m.Header.Get(HdrContentTransferEncoding), bytes.NewReader(body)))
+	if err != nil {
+		return nil, err
+	}
+	return readPart(content)
+}
+
+// decodeTransferEncoding returns a reader that undoes a
+// Content-Transfer-Encoding. Identity encodings, and ones that aren't
+// recognized, are returned unchanged.
+// https://tools.wordtothewise.com/rfc2045#section-6
+func decodeTransferEncoding(cte string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(cte)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+	return r
+}
@@ -0,0 +1,134 @@
+package orderedheaders
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const bounce = "From: MAILER-DAEMON@example.com\r\n" +
+	"Content-Type: multipart/report; report-type=delivery-status; boundary=b\r\n" +
+	"\r\n" +
+	"--b\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"\r\n" +
+	"Delivery failed\r\n" +
+	"--b\r\n" +
+	"Content-Type: message/delivery-status\r\n" +
+	"\r\n" +
+	"Status: 5.1.1\r\n" +
+	"--b\r\n" +
+	"Content-Type: message/rfc822\r\n" +
+	"\r\n" +
+	"Subject: original\r\n" +
+	"From: a@example.com\r\n" +
+	"Message-Id: <1@example.com>\r\n" +
+	"\r\n" +
+	"original body\r\n" +
+	"--b--\r\n"
+
+func TestEmbedded(t *testing.T) {
+	msg, err := ReadMessage(strings.NewReader(bounce))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := msg.Embedded(); !errors.Is(err, ErrNotEmbedded) {
+		t.Errorf("expected ErrNotEmbedded, got %v", err)
+	}
+	parts, err := msg.Parts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parts[1].IsEmbedded() || !parts[2].IsEmbedded() {
+		t.Errorf("unexpected IsEmbedded results")
+	}
+	inner, err := parts[2].Embedded()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []KV{
+		{Key: "Subject", Value: "original", Raw: "Subject: original\r\n"},
+		{Key: "From", Value: "a@example.com", Raw: "From: a@example.com\r\n"},
+		{Key: "Message-Id", Value: "<1@example.com>", Raw: "Message-Id: <1@example.com>\r\n"},
+	}
+	if diff := cmp.Diff(want, inner.Header.Headers); diff != "" {
+		t.Errorf("embedded header mismatch (-want +got):\n%s", diff)
+	}
+	body, _ := io.ReadAll(inner.Body)
+	if string(body) != "original body" {
+		t.Errorf("unexpected embedded body %q", body)
+	}
+	// the part can be parsed again
+	if again, err := parts[2].Embedded(); err != nil || again.Header.Get("Subject") != "original" {
+		t.Errorf("second Embedded() failed: %v", err)
+	}
+}
+
+func TestEmbeddedGlobal(t *testing.T) {
+	inner := "Subject: caf\xc3\xa9\r\n\r\nbody\r\n"
+	msg, err := ReadMessage(strings.NewReader("Content-Type: message/global\r\n" +
+		"Content-Transfer-Encoding: base64\r\n\r\n" +
+		base64.StdEncoding.EncodeToString([]byte(inner)) + "\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	embedded, err := msg.Embedded()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := embedded.Header.Get("Subject"); got != "café" {
+		t.Errorf("unexpected Subject '%s'", got)
+	}
+}
+
+func TestWalkEmbedded(t *testing.T) {
+	digest := "Content-Type: multipart/digest; boundary=d\r\n" +
+		"\r\n" +
+		"--d\r\n" +
+		"\r\n" +
+		"Subject: one\r\n\r\nfirst\r\n" +
+		"--d\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"not a message\r\n" +
+		"--d--\r\n"
+	for name, in := range map[string]string{"bounce": bounce, "digest": digest} {
+		t.Run(name, func(t *testing.T) {
+			msg, err := ReadMessage(strings.NewReader(in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			err = msg.Walk(func(part *Message, depth int) error {
+				mediaType, _ := part.mediaType()
+				got = append(got, strings.Repeat(" ", depth)+mediaType+" "+part.Header.Get("Subject"))
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := map[string][]string{
+				"bounce": {
+					"multipart/report ",
+					" text/plain ",
+					" message/delivery-status ",
+					" message/rfc822 ",
+					"  text/plain original",
+				},
+				"digest": {
+					"multipart/digest ",
+					" message/rfc822 ",
+					"  text/plain one",
+					" text/plain ",
+				},
+			}[name]
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("Walk mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
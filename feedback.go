@@ -0,0 +1,112 @@
+package orderedheaders
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/textproto"
+	"strings"
+)
+
+// https://support.google.com/a/answer/6254652
+
+const HdrFeedbackID = "Feedback-Id"
+
+// maxFeedbackIdentifiers is the number of optional identifiers that may
+// precede the sender identifier in a Feedback-ID
+const maxFeedbackIdentifiers = 3
+
+// maxFeedbackIDLength limits the total length of a Feedback-ID value
+const maxFeedbackIDLength = 255
+
+// HeaderValidators maps header names, such as campaign tracking headers,
+// to a function that checks their value. Set accepts headers listed here
+// as well as those in HeaderSyntax, and applies both checks to headers
+// in both. Keys must be canonical; RegisterValidator takes care of that.
+var HeaderValidators = map[string]func(value string) error{
+	HdrFeedbackID:   ValidateFeedbackID,
+	HdrBIMISelector: ValidateBIMISelector,
+	HdrMimeVersion:  ValidateMIMEVersion,
+}
+
+// RegisterValidator adds a validator for a non-standard header, so that
+// Set will accept it
+func RegisterValidator(key string, validate func(value string) error) {
+	HeaderValidators[textproto.CanonicalMIMEHeaderKey(key)] = validate
+}
+
+// FeedbackID is a Google Feedback-ID header, a colon separated list of
+// up to three optional identifiers followed by a sender identifier
+type FeedbackID struct {
+	// Identifiers are the optional identifiers, e.g. campaign, customer
+	// and mail type, in the order they appear
+	Identifiers []string
+	// SenderID identifies the sender, and must be consistent across
+	// all their mail
+	SenderID string
+}
+
+// String renders the Feedback-ID value
+func (f FeedbackID) String() string {
+	return strings.Join(append(append([]string(nil), f.Identifiers...), f.SenderID), ":")
+}
+
+// ParseFeedbackID parses and validates a Feedback-ID value
+func ParseFeedbackID(value string) (FeedbackID, error) {
+	value = strings.TrimSpace(value)
+	if len(value) > maxFeedbackIDLength {
+		return FeedbackID{}, fmt.Errorf("Feedback-ID is longer than %d characters", maxFeedbackIDLength)
+	}
+	fields := strings.Split(value, ":")
+	if len(fields) > maxFeedbackIdentifiers+1 {
+		return FeedbackID{}, fmt.Errorf("'%s' has more than %d fields", value, maxFeedbackIdentifiers+1)
+	}
+	for _, f := range fields {
+		if err := validFeedbackField(f); err != nil {
+			return FeedbackID{}, err
+		}
+	}
+	f := FeedbackID{SenderID: fields[len(fields)-1]}
+	if len(fields) > 1 {
+		f.Identifiers = fields[:len(fields)-1]
+	}
+	return f, nil
+}
+
+// ValidateFeedbackID checks the syntax of a Feedback-ID value
+func ValidateFeedbackID(value string) error {
+	_, err := ParseFeedbackID(value)
+	return err
+}
+
+// validFeedbackField checks a single Feedback-ID field is non-empty
+// and contains only letters, digits, '.', '-' and '_'
+func validFeedbackField(s string) error {
+	if s == "" {
+		return errors.New("Feedback-ID fields cannot be empty")
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '.', c == '-', c == '_':
+		default:
+			return fmt.Errorf("'%s' is not a valid Feedback-ID field", s)
+		}
+	}
+	return nil
+}
+
+// FeedbackID parses the Feedback-ID header
+func (h *Header) FeedbackID() (FeedbackID, error) {
+	value := h.Get(HdrFeedbackID)
+	if value == "" {
+		return FeedbackID{}, mail.ErrHeaderNotPresent
+	}
+	return ParseFeedbackID(value)
+}
+
+// SetFeedbackID validates and sets the Feedback-ID header
+func (h *Header) SetFeedbackID(f FeedbackID) error {
+	return h.Set(HdrFeedbackID, f.String())
+}
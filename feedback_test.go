@@ -0,0 +1,74 @@
+package orderedheaders
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseFeedbackID(t *testing.T) {
+	tests := map[string]struct {
+		In        string
+		WantError bool
+		Want      FeedbackID
+	}{
+		"full":    {In: "campaign42:customer7:newsletter:acme", Want: FeedbackID{Identifiers: []string{"campaign42", "customer7", "newsletter"}, SenderID: "acme"}},
+		"sender":  {In: "acme", Want: FeedbackID{SenderID: "acme"}},
+		"toomany": {In: "a:b:c:d:e", WantError: true},
+		"empty":   {In: "a::acme", WantError: true},
+		"chars":   {In: "a b:acme", WantError: true},
+		"toolong": {In: strings.Repeat("x", 256), WantError: true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseFeedbackID(test.In)
+			if test.WantError {
+				if err == nil {
+					t.Errorf("expected error, didn't get one")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.Want, got); diff != "" {
+				t.Errorf("FeedbackID mismatch (-want +got):\n%s", diff)
+			}
+			if got.String() != test.In {
+				t.Errorf("want '%s', got '%s'", test.In, got.String())
+			}
+		})
+	}
+}
+
+func TestSetRegisteredHeader(t *testing.T) {
+	h := &Header{}
+	if err := h.SetFeedbackID(FeedbackID{Identifiers: []string{"c1"}, SenderID: "acme"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Set("feedback-id", "bad value"); err == nil {
+		t.Errorf("expected error for invalid Feedback-ID")
+	}
+	if err := h.Set("X-Campaign", "spring"); err == nil {
+		t.Errorf("expected error for unregistered header")
+	}
+	RegisterValidator("x-campaign", func(value string) error {
+		if strings.ContainsAny(value, " \t") {
+			return errors.New("campaign names cannot contain whitespace")
+		}
+		return nil
+	})
+	defer delete(HeaderValidators, "X-Campaign")
+	if err := h.Set("X-Campaign", "spring sale"); err == nil {
+		t.Errorf("expected error for invalid X-Campaign")
+	}
+	if err := h.Set("X-Campaign", "spring"); err != nil {
+		t.Fatal(err)
+	}
+	want := []KV{{Key: "Feedback-Id", Value: "c1:acme"}, {Key: "X-Campaign", Value: "spring"}}
+	if diff := cmp.Diff(want, h.Headers); diff != "" {
+		t.Errorf("Set mismatch (-want +got):\n%s", diff)
+	}
+}
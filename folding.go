@@ -0,0 +1,228 @@
+package orderedheaders
+
+import (
+	"io"
+	"net/textproto"
+	"strings"
+)
+
+// TagListHeaders are the opaque headers that are folded as lists of
+// tag=value pairs separated by semicolons, such as DKIM-Signature, rather
+// than at any whitespace. They're folded after a semicolon where possible,
+// and a tag that doesn't fit on a line of its own is folded at whitespace
+// within it or, if it's the b= signature, anywhere in the base64 value.
+// Keys must be canonical; RegisterTagList takes care of that.
+//
+// Folding inserts whitespace into the b= value, which a signature ignores
+// in its own field but not in other signed fields, so a field that another
+// signature covers should be written as it was read, with Rewrite.
+// https://tools.wordtothewise.com/rfc6376#section-3.2
+// https://tools.wordtothewise.com/rfc6376#section-3.5
+var TagListHeaders = map[string]struct{}{
+	HdrDKIMSignature:            {},
+	HdrARCSeal:                  {},
+	HdrARCMessageSignature:      {},
+	HdrARCAuthenticationResults: {},
+	HdrAuthenticationResults:    {},
+}
+
+// RegisterTagList adds a header to TagListHeaders, so that it's folded
+// as a tag=value list
+func RegisterTagList(key string) {
+	TagListHeaders[textproto.CanonicalMIMEHeaderKey(key)] = struct{}{}
+}
+
+// isTagList checks whether a field is folded as a tag=value list
+func isTagList(headerType HeaderType, key string) bool {
+	if headerType != HeaderTypeOpaque {
+		return false
+	}
+	_, ok := TagListHeaders[key]
+	return ok
+}
+
+// fieldFoldable checks whether a field can be written with no line
+// longer than maxLineLength, as writeHeader folds it
+func fieldFoldable(headerType HeaderType, key, value string) bool {
+	column := len(key) + 2
+	if !isTagList(headerType, key) {
+		return foldable(value, column, headerType != HeaderTypeUnstructured)
+	}
+	tokens, starts := listTokens(value, ';')
+	for _, chunk := range listChunks(tokens, starts) {
+		if start, _ := splitRange(chunk); start >= 0 {
+			continue
+		}
+		for _, tok := range chunk {
+			if column+len(tok) > maxLineLength {
+				return false
+			}
+			column = 0
+		}
+	}
+	return true
+}
+
+// listTokens splits a list of items separated by sep into the pieces it
+// can be folded between, each but the first starting with the whitespace
+// that it would fold at. Whitespace within quoted strings isn't folded
+// at. starts reports whether each piece starts a new item, following a
+// separator.
+func listTokens(value string, sep byte) (tokens []string, starts []bool) {
+	start, depth := 0, 0
+	inString, separated, startsItem := false, false, false
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if (c == ' ' || c == '\t') && !inString {
+			if i > start && !isWSP(value[i-1]) {
+				tokens = append(tokens, value[start:i])
+				starts = append(starts, startsItem)
+				start, startsItem = i, separated
+			}
+			continue
+		}
+		topLevel := !inString && depth == 0
+		switch {
+		case c == '\\' && (inString || depth > 0):
+			i++
+		case inString:
+			inString = c != '"'
+		case c == '"' && depth == 0:
+			inString = true
+		case c == '(':
+			depth++
+		case c == ')' && depth > 0:
+			depth--
+		}
+		separated = c == sep && topLevel
+	}
+	return append(tokens, value[start:]), append(starts, startsItem)
+}
+
+// listChunks groups the tokens of each item together
+func listChunks(tokens []string, starts []bool) [][]string {
+	var chunks [][]string
+	for i := 0; i < len(tokens); {
+		end := i + 1
+		for end < len(tokens) && !starts[end] {
+			end++
+		}
+		chunks = append(chunks, tokens[i:end])
+		i = end
+	}
+	return chunks
+}
+
+// splitRange returns the offsets of the b= signature value in a
+// tag=value list item, which can be folded anywhere, or -1, -1 if the
+// item can only be folded at whitespace. Other values are covered by the
+// signature, so can't have whitespace inserted.
+func splitRange(chunk []string) (int, int) {
+	item := strings.Join(chunk, "")
+	for i := 0; i < len(item); i++ {
+		if i > 0 && item[i-1] != ';' {
+			continue
+		}
+		start := i
+		for start < len(item) && isWSP(item[start]) {
+			start++
+		}
+		if !strings.HasPrefix(item[start:], "b=") {
+			continue
+		}
+		start += len("b=")
+		end := strings.IndexByte(item[start:], ';')
+		if end < 0 {
+			return start, len(item)
+		}
+		return start, start + end
+	}
+	return -1, -1
+}
+
+// writeList writes a list that's too long for one line, folding between
+// items where possible, and only within an item if it doesn't fit on a
+// line of its own. Items of tag=value lists are folded as described in
+// TagListHeaders.
+func writeList(w io.Writer, value string, tagList bool, column, wrap int) error {
+	sep := byte(',')
+	if tagList {
+		sep = ';'
+	}
+	tokens, starts := listTokens(value, sep)
+	for i, chunk := range listChunks(tokens, starts) {
+		length := 0
+		for _, tok := range chunk {
+			length += len(tok)
+		}
+		fitted := column+length <= wrap
+		if !fitted && i > 0 {
+			if _, err := io.WriteString(w, "\r\n"); err != nil {
+				return err
+			}
+			column = 0
+			fitted = length <= wrap
+		}
+		if !fitted && tagList {
+			if start, end := splitRange(chunk); start >= 0 {
+				var err error
+				column, err = writeSplit(w, strings.Join(chunk, ""), start, end, column, wrap)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		for j, tok := range chunk {
+			if !fitted && j > 0 && column+len(tok) > wrap {
+				if _, err := io.WriteString(w, "\r\n"); err != nil {
+					return err
+				}
+				column = 0
+			}
+			if _, err := io.WriteString(w, tok); err != nil {
+				return err
+			}
+			column += len(tok)
+		}
+	}
+	_, err := io.WriteString(w, "\r\n")
+	return err
+}
+
+// writeSplit writes item, folding it anywhere between start and end to
+// fill each line, and returns the column it finishes at
+func writeSplit(w io.Writer, item string, start, end, column, wrap int) (int, error) {
+	if _, err := io.WriteString(w, item[:start]); err != nil {
+		return 0, err
+	}
+	column += start
+	rest := item[start:end]
+	for len(rest) > 0 {
+		n := wrap - column
+		if n <= 0 && column <= 1 {
+			// always make progress, however small wrap is
+			n = 1
+		}
+		if n >= len(rest) {
+			break
+		}
+		if n > 0 {
+			if _, err := io.WriteString(w, rest[:n]); err != nil {
+				return 0, err
+			}
+			rest = rest[n:]
+		}
+		fold := "\r\n "
+		if isWSP(rest[0]) {
+			fold = "\r\n"
+		}
+		if _, err := io.WriteString(w, fold); err != nil {
+			return 0, err
+		}
+		column = len(fold) - 2
+	}
+	rest += item[end:]
+	_, err := io.WriteString(w, rest)
+	return column + len(rest), err
+}
@@ -0,0 +1,103 @@
+package orderedheaders
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTagListFolding(t *testing.T) {
+	sig := strings.Repeat("AbCdEfGh", 43)
+	dkim := "v=1; a=rsa-sha256; c=relaxed/relaxed; d=example.com; s=selector1; t=1684749600; " +
+		"h=from:to:subject:date:message-id; bh=47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=; b=" + sig
+	tests := map[string]struct {
+		Key   string
+		Value string
+		Wrap  int
+		Want  string
+	}{
+		"short": {
+			Key:   HdrDKIMSignature,
+			Value: "v=1; d=example.com; b=abc",
+			Want:  "Dkim-Signature: v=1; d=example.com; b=abc\r\n",
+		},
+		"dkim": {
+			Key:   HdrDKIMSignature,
+			Value: dkim,
+			Want: "Dkim-Signature: v=1; a=rsa-sha256; c=relaxed/relaxed; d=example.com;\r\n" +
+				" s=selector1; t=1684749600; h=from:to:subject:date:message-id;\r\n" +
+				" bh=47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=;\r\n" +
+				" b=" + sig[:75] + "\r\n " + sig[75:152] + "\r\n " + sig[152:229] + "\r\n " + sig[229:306] + "\r\n " + sig[306:] + "\r\n",
+		},
+		"no space": {
+			Key:   HdrARCSeal,
+			Value: "i=1;a=rsa-sha256;cv=none;d=example.com;s=arc;b=" + sig[:100],
+			Want:  "Arc-Seal: i=1;a=rsa-sha256;cv=none;d=example.com;s=arc;b=" + sig[:21] + "\r\n " + sig[21:98] + "\r\n " + sig[98:100] + "\r\n",
+		},
+		"tag after b": {
+			Key:   HdrDKIMSignature,
+			Value: "v=1;b=" + sig[:100] + ";d=example.com",
+			Want:  "Dkim-Signature: v=1;b=" + sig[:56] + "\r\n " + sig[56:100] + ";d=example.com\r\n",
+		},
+		"authentication results": {
+			Key:   HdrAuthenticationResults,
+			Value: "mx.example.com; dkim=pass (2048-bit key) header.d=example.com header.s=selector1 header.b=AbCdEfGh; spf=pass smtp.mailfrom=example.com",
+			Want: "Authentication-Results: mx.example.com;\r\n" +
+				" dkim=pass (2048-bit key) header.d=example.com header.s=selector1\r\n" +
+				" header.b=AbCdEfGh; spf=pass smtp.mailfrom=example.com\r\n",
+		},
+		"tiny wrap": {
+			Key:   HdrDKIMSignature,
+			Value: "v=1; b=" + sig[:4],
+			Wrap:  1,
+			Want:  "Dkim-Signature: v=1;\r\n b=\r\n " + sig[:1] + "\r\n " + sig[1:2] + "\r\n " + sig[2:3] + "\r\n " + sig[3:4] + "\r\n",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeHeader(&buf, HeaderTypeOpaque, test.Key, test.Value, Options{WrapColumn: test.Wrap}); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != test.Want {
+				t.Errorf("want\n%s\ngot\n%s", test.Want, buf.String())
+			}
+		})
+	}
+}
+
+func TestRegisterTagList(t *testing.T) {
+	RegisterTagList("x-signature")
+	defer delete(TagListHeaders, "X-Signature")
+	value := "d=example.com; b=" + strings.Repeat("x", 100)
+	var buf bytes.Buffer
+	if err := writeHeader(&buf, HeaderTypeOpaque, "X-Signature", value, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	want := "X-Signature: d=example.com;\r\n b=" + strings.Repeat("x", 75) + "\r\n " + strings.Repeat("x", 25) + "\r\n"
+	if buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+}
+
+func TestFieldFoldable(t *testing.T) {
+	long := strings.Repeat("x", 1200)
+	tests := map[string]struct {
+		Type  HeaderType
+		Key   string
+		Value string
+		Want  bool
+	}{
+		"long signature": {Type: HeaderTypeOpaque, Key: HdrDKIMSignature, Value: "v=1; b=" + long, Want: true},
+		"long tag":       {Type: HeaderTypeOpaque, Key: HdrDKIMSignature, Value: "v=1; h=" + long + "; b=abc", Want: false},
+		"opaque":         {Type: HeaderTypeOpaque, Key: "X-Signature", Value: "v=1; b=" + long, Want: false},
+		"short":          {Type: HeaderTypeOpaque, Key: HdrDKIMSignature, Value: "v=1; b=abc", Want: true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := fieldFoldable(test.Type, test.Key, test.Value); got != test.Want {
+				t.Errorf("want %v, got %v", test.Want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package orderedheaders
+
+import (
+	"bytes"
+	"errors"
+	"mime"
+	"net/mail"
+	"strings"
+)
+
+// https://tools.wordtothewise.com/rfc2046#section-5.2.1
+
+// ForwardOptions configures BuildForward
+type ForwardOptions struct {
+	// From is the author of the forwarded message, and is required
+	From *mail.Address
+	To   []*mail.Address
+	Cc   []*mail.Address
+	// Comment is text to put before the summary of the original
+	Comment string
+	// Headers lists the fields of the original that are summarised in
+	// the text body. If it's nil DefaultForwardHeaders is used.
+	Headers []string
+}
+
+// DefaultForwardHeaders are the fields of a forwarded message that are
+// summarised in the text body by default
+var DefaultForwardHeaders = []string{HdrFrom, HdrDate, HdrSubject, HdrTo, HdrCc}
+
+// forwardSeparator introduces the summary of the original message
+const forwardSeparator = "---------- Forwarded message ----------"
+
+// BuildForward creates a message forwarding orig as a message/rfc822
+// attachment. The subject gets a single "Fwd: " prefix, and the text
+// body is the comment followed by a summary of the original's headers.
+// The original is rendered with Rewrite, so if it was read with KeepRaw
+// its header is attached byte for byte. Its body is consumed.
+func BuildForward(orig *Message, opts ForwardOptions) (*Message, error) {
+	if opts.From == nil {
+		return nil, errors.New("a forwarded message requires a From address")
+	}
+	headers := opts.Headers
+	if headers == nil {
+		headers = DefaultForwardHeaders
+	}
+
+	dec := new(mime.WordDecoder)
+	decode := func(key string) string {
+		v := orig.Header.Get(key)
+		if decoded, err := dec.DecodeHeader(v); err == nil {
+			v = decoded
+		}
+		return strings.Join(strings.Fields(v), " ")
+	}
+
+	var text strings.Builder
+	if opts.Comment != "" {
+		text.WriteString(strings.TrimRight(opts.Comment, "\r\n"))
+		text.WriteString("\n\n")
+	}
+	text.WriteString(forwardSeparator + "\n")
+	for _, key := range headers {
+		if v := decode(key); v != "" {
+			text.WriteString(key + ": " + v + "\n")
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := orig.Rewrite(&buf, Options{}); err != nil {
+		return nil, err
+	}
+	var part Header
+	part.Add(HdrContentType, "message/rfc822")
+	part.Add(HdrContentDisposition, "inline")
+	if is7bit(buf.String()) {
+		part.Add(HdrContentTransferEncoding, "7bit")
+	} else {
+		part.Add(HdrContentTransferEncoding, "8bit")
+	}
+
+	return NewBuilder().
+		From(opts.From).
+		To(opts.To...).
+		Cc(opts.Cc...).
+		Subject("Fwd: "+stripForwardPrefix(decode(HdrSubject))).
+		Text(text.String()).
+		Part(part, buf.Bytes()).
+		Build()
+}
+
+// stripForwardPrefix removes any number of leading "Fwd:" or "Fw:"
+// prefixes
+func stripForwardPrefix(s string) string {
+	s = strings.TrimSpace(s)
+	for {
+		lower := strings.ToLower(s)
+		switch {
+		case strings.HasPrefix(lower, "fwd:"):
+			s = strings.TrimSpace(s[4:])
+		case strings.HasPrefix(lower, "fw:"):
+			s = strings.TrimSpace(s[3:])
+		default:
+			return s
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package orderedheaders
+
+import (
+	"io"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const forwardOriginal = "From: Bob <bob@example.com>\r\n" +
+	"To: alice@example.com\r\n" +
+	"Date: Mon, 22 May 2023 10:00:00 +0000\r\n" +
+	"Subject: =?utf-8?q?caf=C3=A9?=\r\n" +
+	"X-Folded: one\r\n" +
+	"  two\r\n" +
+	"\r\n" +
+	"original body\r\n"
+
+func TestBuildForward(t *testing.T) {
+	orig, err := ReadMessageWithOptions(strings.NewReader(forwardOriginal), MessageOptions{ReadOptions: ReadOptions{KeepRaw: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := BuildForward(orig, ForwardOptions{
+		From:    &mail.Address{Address: "alice@example.com"},
+		To:      []*mail.Address{{Address: "carol@example.com"}},
+		Comment: "FYI",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := msg.Header.Get(HdrSubject); got != "Fwd: café" {
+		t.Errorf("unexpected subject '%s'", got)
+	}
+	parts, err := msg.Parts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 2 {
+		t.Fatalf("expected 2 parts, got %d", len(parts))
+	}
+	text, err := io.ReadAll(parts[0].Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantText := "FYI\r\n\r\n" +
+		"---------- Forwarded message ----------\r\n" +
+		"From: Bob <bob@example.com>\r\n" +
+		"Date: Mon, 22 May 2023 10:00:00 +0000\r\n" +
+		"Subject: café\r\n" +
+		"To: alice@example.com\r\n"
+	body, err := io.ReadAll(decodeTransferEncoding(parts[0].Header.Get(HdrContentTransferEncoding), strings.NewReader(string(text))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(wantText, string(body)); diff != "" {
+		t.Errorf("text mismatch (-want +got):\n%s", diff)
+	}
+	if !parts[1].IsEmbedded() {
+		t.Fatalf("second part isn't message/rfc822")
+	}
+	attached, err := io.ReadAll(parts[1].Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(attached) != forwardOriginal {
+		t.Errorf("original not attached byte for byte:\n%q", attached)
+	}
+}
+
+func TestBuildForwardOptions(t *testing.T) {
+	tests := map[string]struct {
+		Subject string
+		Headers []string
+		Want    string
+	}{
+		"prefixed": {Subject: "Fwd: FW: hello", Want: "Fwd: hello"},
+		"headers":  {Subject: "hello", Headers: []string{HdrSubject}, Want: "Fwd: hello"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			orig := &Message{Body: strings.NewReader("body")}
+			orig.Header.Add(HdrSubject, test.Subject)
+			orig.Header.Add(HdrFrom, "bob@example.com")
+			msg, err := BuildForward(orig, ForwardOptions{From: &mail.Address{Address: "alice@example.com"}, Headers: test.Headers})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := msg.Header.Get(HdrSubject); got != test.Want {
+				t.Errorf("want '%s', got '%s'", test.Want, got)
+			}
+		})
+	}
+	if _, err := BuildForward(&Message{}, ForwardOptions{}); err == nil {
+		t.Errorf("expected error without From")
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"mime"
 	"net/mail"
 	"net/textproto"
-	"regexp"
 	"strings"
 )
 
@@ -42,11 +41,17 @@ const (
 	HdrResentTo                = "Resent-To"
 	HdrResentCc                = "Resent-Cc"
 	HdrResentBcc               = "Resent-Bcc"
+	HdrResentMessageId         = "Resent-Message-Id"
 	HdrMimeVersion             = "Mime-Version"
 	HdrContentType             = "Content-Type"
-	HdrContentID               = "Content-ID"
+	HdrContentID               = "Content-Id"
 	HdrContentTransferEncoding = "Content-Transfer-Encoding"
 	HdrContentDescription      = "Content-Description"
+	HdrContentDisposition      = "Content-Disposition"
+	// HdrAuthenticationResults is added by a receiving MTA to record
+	// the results of authentication checks such as SPF, DKIM and DMARC
+	// https://tools.wordtothewise.com/rfc8601#section-2.2
+	HdrAuthenticationResults = "Authentication-Results"
 )
 
 const utf8 = "utf-8"
@@ -100,11 +105,29 @@ var HeaderSyntax = map[string]Syntax{
 	HdrResentTo:                {Type: HeaderTypeMailboxList},
 	HdrResentCc:                {Type: HeaderTypeMailboxList},
 	HdrResentBcc:               {Type: HeaderTypeMailboxList},
+	HdrResentMessageId:         {Type: HeaderTypeMessageID},
 	HdrMimeVersion:             {Unique: true, Type: HeaderTypeOpaque},
 	HdrContentType:             {Unique: true, Type: HeaderTypeOpaque},
 	HdrContentID:               {Unique: true, Type: HeaderTypeMessageID},
 	HdrContentTransferEncoding: {Unique: true, Type: HeaderTypeOpaque},
 	HdrContentDescription:      {Unique: true, Type: HeaderTypeUnstructured},
+
+	// https://tools.wordtothewise.com/rfc2183#section-2
+	HdrContentDisposition: {Unique: true, Type: HeaderTypeOpaque},
+
+	// https://tools.wordtothewise.com/rfc3834#section-5
+	HdrAutoSubmitted: {Unique: true, Type: HeaderTypeOpaque},
+
+	// https://tools.wordtothewise.com/rfc8098#section-2
+	HdrDispositionNotificationTo:      {Unique: true, Type: HeaderTypeMailboxList},
+	HdrDispositionNotificationOptions: {Unique: true, Type: HeaderTypeOpaque},
+
+	// https://learn.microsoft.com/en-us/openspecs/exchange_server_protocols/ms-oxomsg/
+	HdrThreadIndex: {Unique: true, Type: HeaderTypeOpaque},
+	HdrThreadTopic: {Unique: true, Type: HeaderTypeUnstructured},
+
+	// https://datatracker.ietf.org/doc/html/draft-brand-indicators-for-message-identification#section-5.1
+	HdrBIMISelector: {Unique: true, Type: HeaderTypeOpaque},
 }
 
 // Options configures how a set of headers will be rendered.
@@ -115,63 +138,190 @@ type Options struct {
 	RenderBlank bool
 	// NoEscape disables encoding of non-ASCI content in a header
 	NoEscape bool
+	// NoEscapeFor disables encoding of non-ASCII content in just the
+	// fields with these keys, in any case, as NoEscape does for all of them
+	NoEscapeFor []string
+	// BareAddresses renders mailboxes with no display name as a bare
+	// addr-spec, bob@example.com, rather than in angle brackets
+	BareAddresses bool
+	// OnHeaderWritten, if set, is called for each field as it's written,
+	// with its key, the number of bytes written and whether it was
+	// folded. Fields that aren't written, such as Bcc, blank fields or
+	// repeats of unique fields, are reported with zero bytes.
+	OnHeaderWritten func(key string, bytes int, folded bool)
+	// OnRepair, if set, is called whenever a value is changed as it's
+	// written, other than by folding, such as when whitespace is
+	// trimmed, non-ASCII text is encoded or an address is requoted. It's
+	// given the key, the value before and after the change and a short
+	// description of the change. A change may be reported in several
+	// steps. A structured field whose value can't be parsed is written
+	// unchanged, and reported with after the same as before.
+	OnRepair func(key, before, after, reason string)
+	// WrapColumn is the line length that long fields are folded to fit
+	// where possible, defaulting to 78. It can't be more than 998.
+	WrapColumn int
 }
 
-// Set sets a standard header, replacing any existing one. It only accepts
-// standard email headers, not extensions.
+// noEscape checks whether non-ASCII content in the field with key should
+// be left unencoded
+func (o Options) noEscape(key string) bool {
+	if o.NoEscape {
+		return true
+	}
+	for _, k := range o.NoEscapeFor {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultWrapColumn is the recommended maximum line length
+// https://tools.wordtothewise.com/rfc5322#section-2.1.1
+const defaultWrapColumn = 78
+
+// wrapColumn returns the column to fold at
+func (o Options) wrapColumn() int {
+	switch {
+	case o.WrapColumn <= 0:
+		return defaultWrapColumn
+	case o.WrapColumn > maxLineLength:
+		return maxLineLength
+	}
+	return o.WrapColumn
+}
+
+// SetOptions configures SetWithOptions
+type SetOptions struct {
+	// AllowUnknown accepts headers that are neither in HeaderSyntax nor
+	// in HeaderValidators, as long as the name is a valid field name and
+	// the value is valid as an opaque header. They're treated as unique.
+	AllowUnknown bool
+	// SMTPUTF8 allows UTF-8 in Received, which is valid in messages sent
+	// with the SMTPUTF8 extension, where trace fields can have UTF-8
+	// addresses and comments
+	// https://tools.wordtothewise.com/rfc6532#section-3.7
+	SMTPUTF8 bool
+	// Addresses adds checks of the addresses in mailbox headers
+	Addresses AddressPolicy
+}
+
+// Set sets a standard header. It only accepts standard email headers, and
+// extensions registered in HeaderSyntax, by RegisterSyntax, or in
+// HeaderValidators. Headers in both are checked against their syntax and
+// the validator.
+//
+// A header that may only appear once, such as Subject, replaces any
+// existing one. Headers that may appear more than once, those whose
+// Syntax isn't Unique, are added alongside any existing ones, since
+// there's no telling which one a caller means. Trace headers, Received
+// and Return-Path, and Resent-* fields are prepended as an MTA would, and
+// others such as Comments are appended. Setting one of them to an empty
+// value removes them all, and RemoveAll clears them first if replacing is
+// what's wanted. AddResentBlock adds a whole resent block at once, in
+// order.
 func (h *Header) Set(key, value string) error {
+	return h.SetWithOptions(key, value, SetOptions{})
+}
+
+// SetWithOptions sets a header, as Set, configured by o.
+func (h *Header) SetWithOptions(key, value string, o SetOptions) error {
 	canonKey := textproto.CanonicalMIMEHeaderKey(key)
 	syntax, ok := HeaderSyntax[canonKey]
-	if !ok {
-		return fmt.Errorf("%s is not a standard email header", canonKey)
+	validate, hasValidator := HeaderValidators[canonKey]
+	if !ok && !hasValidator {
+		if !o.AllowUnknown {
+			return fmt.Errorf("%s is not a standard email header", canonKey)
+		}
+		if !validFieldName(canonKey) {
+			return fmt.Errorf("'%s' is not a valid header field name", key)
+		}
+		syntax, ok = Syntax{Unique: true, Type: HeaderTypeOpaque}, true
 	}
 	if value != "" {
-		err := checkHeader(syntax.Type, value)
-		if err != nil {
-			return fmt.Errorf("invalid value for %s: %w", key, err)
+		if ok {
+			check := checkHeader
+			if o.SMTPUTF8 {
+				check = checkHeaderUTF8
+			}
+			err := check(syntax.Type, value)
+			if err != nil {
+				return fmt.Errorf("invalid value for %s: %w", key, err)
+			}
+			err = o.Addresses.check(syntax.Type, value)
+			if err != nil {
+				return fmt.Errorf("invalid value for %s: %w", key, err)
+			}
 		}
-	}
-	for i, v := range h.Headers {
-		if v.Key == canonKey {
-			h.Headers[i] = KV{
-				Key:   canonKey,
-				Value: value,
+		if hasValidator {
+			if err := validate(value); err != nil {
+				return fmt.Errorf("invalid value for %s: %w", key, err)
 			}
-			return nil
 		}
 	}
-	h.Headers = append(h.Headers, KV{
-		Key:   canonKey,
-		Value: value,
-	})
+	if ok && !syntax.Unique {
+		h.CanonicalizeKeys()
+		switch {
+		case value == "":
+			h.RemoveAll(canonKey)
+		case syntax.Type == HeaderTypeReceived || syntax.Type == HeaderTypeReturnPath || isResent(canonKey):
+			h.insert(0, KV{Key: canonKey, Value: value})
+		default:
+			h.Headers = append(h.Headers, KV{Key: canonKey, Value: value})
+		}
+		return nil
+	}
+	h.replace(canonKey, value)
 	return nil
 }
 
 func (h *Header) WriteTo(w io.Writer, o Options) error {
+	h.CanonicalizeKeys()
 	seen := map[string]struct{}{}
 	for _, h := range h.Headers {
 		if !o.RenderBlank && h.Value == "" {
+			o.suppressed(h.Key)
 			continue
 		}
 		if h.Key == "Bcc" && !o.RenderBCC {
+			o.suppressed(h.Key)
 			continue
 		}
 		syn, ok := HeaderSyntax[h.Key]
-		if ok {
-			if syn.Unique {
-				_, ok = seen[h.Key]
-				if ok {
-					continue
+		if ok && syn.Unique {
+			if _, dup := seen[h.Key]; dup {
+				o.suppressed(h.Key)
+				continue
+			}
+			seen[h.Key] = struct{}{}
+		}
+		if handler, isStructured := StructuredHeaders[h.Key]; isStructured {
+			value, parseErr := reserialize(handler, h.Value)
+			if parseErr != nil {
+				// write it as it is rather than fail the whole header
+				value = h.Value
+			}
+			err := o.writeField(w, HeaderTypeOpaque, h.Key, value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", h.Key, err)
+			}
+			if o.OnRepair != nil {
+				if parseErr != nil {
+					o.OnRepair(h.Key, h.Value, value, "written unchanged, "+parseErr.Error())
+				} else if value != h.Value {
+					o.OnRepair(h.Key, h.Value, value, "reserialized")
 				}
-				seen[h.Key] = struct{}{}
 			}
-			err := writeHeader(w, syn.Type, h.Key, h.Value, o)
+			continue
+		}
+		if ok {
+			err := o.writeField(w, syn.Type, h.Key, h.Value)
 			if err != nil {
 				return fmt.Errorf("%s: %w", h.Key, err)
 			}
 			continue
 		}
-		err := writeHeader(w, HeaderTypeOpaque, h.Key, h.Value, o)
+		err := o.writeField(w, HeaderTypeOpaque, h.Key, h.Value)
 		if err != nil {
 			return fmt.Errorf("%s: %w", h.Key, err)
 		}
@@ -179,6 +329,39 @@ func (h *Header) WriteTo(w io.Writer, o Options) error {
 	return nil
 }
 
+// writeField writes a field with writeHeader, reporting it to
+// OnHeaderWritten if that's set
+func (o Options) writeField(w io.Writer, headerType HeaderType, key, value string) error {
+	if o.OnHeaderWritten == nil {
+		return writeHeader(w, headerType, key, value, o)
+	}
+	var buf bytes.Buffer
+	if err := writeHeader(&buf, headerType, key, value, o); err != nil {
+		return err
+	}
+	return o.written(w, key, buf.Bytes())
+}
+
+// written writes a rendered or raw field to w, and reports it to
+// OnHeaderWritten if that's set
+func (o Options) written(w io.Writer, key string, field []byte) error {
+	n, err := w.Write(field)
+	if o.OnHeaderWritten != nil {
+		// a field is folded if it has a line break before the final one
+		i := bytes.IndexByte(field, '\n')
+		folded := i >= 0 && i < len(field)-1
+		o.OnHeaderWritten(key, n, folded)
+	}
+	return err
+}
+
+// suppressed reports a field that wasn't written to OnHeaderWritten
+func (o Options) suppressed(key string) {
+	if o.OnHeaderWritten != nil {
+		o.OnHeaderWritten(key, 0, false)
+	}
+}
+
 func (h *Header) Bytes(o Options) ([]byte, error) {
 	var buff bytes.Buffer
 	err := h.WriteTo(&buff, o)
@@ -191,25 +374,18 @@ func (h *Header) Bytes(o Options) ([]byte, error) {
 func checkHeader(headerType HeaderType, value string) error {
 	value = strings.TrimSpace(value)
 	switch headerType {
-	case HeaderTypeUnstructured, HeaderTypePhraseList:
+	case HeaderTypeUnstructured:
 		return nil
+	case HeaderTypePhraseList:
+		return checkPhraseList(value)
 	case HeaderTypeOpaque, HeaderTypeReceived:
 		if isAscii(value) {
 			return nil
 		}
 		return errors.New("cannot contain non-ascii characters")
 	case HeaderTypeReturnPath:
-		if value == "<>" {
-			return nil
-		}
-		addr, err := mail.ParseAddress(value)
-		if err != nil {
-			return fmt.Errorf("'%s' is not a valid return path: %w", value, err)
-		}
-		if addr.Name != "" {
-			return fmt.Errorf("'%s' is not a valid return path: cannot ahve display name", value)
-		}
-		return nil
+		_, _, err := parseReturnPath(value)
+		return err
 	case HeaderTypeDate:
 		return validDate(value)
 	case HeaderTypeMailbox:
@@ -233,9 +409,27 @@ func checkHeader(headerType HeaderType, value string) error {
 	}
 }
 
+// checkHeaderUTF8 checks a value as checkHeader does, but allowing UTF-8
+// in Received as RFC 6532 does
+func checkHeaderUTF8(headerType HeaderType, value string) error {
+	if headerType == HeaderTypeReceived {
+		if strings.ToValidUTF8(value, "") != value {
+			return errors.New("is not valid UTF-8")
+		}
+		return nil
+	}
+	return checkHeader(headerType, value)
+}
+
 // isAscii checks whether all characters in a string are low ASCII
 func isAscii(s string) bool {
-	for i := 0; i < len(s); i++ {
+	i := 0
+	for ; i+8 <= len(s); i += 8 {
+		if loadWord(s[i:])&highBits != 0 {
+			return false
+		}
+	}
+	for ; i < len(s); i++ {
 		if s[i] > 127 {
 			return false
 		}
@@ -243,7 +437,32 @@ func isAscii(s string) bool {
 	return true
 }
 
-const atext = "[a-zA-Z0-9!#$%&'*+-/=?^_`{|}~]"
+const (
+	lowBits  = 0x0101010101010101
+	highBits = 0x8080808080808080
+)
+
+// loadWord returns the first 8 bytes of s as a word, which the compiler
+// turns into a single load
+func loadWord(s string) uint64 {
+	_ = s[7]
+	return uint64(s[0]) | uint64(s[1])<<8 | uint64(s[2])<<16 | uint64(s[3])<<24 |
+		uint64(s[4])<<32 | uint64(s[5])<<40 | uint64(s[6])<<48 | uint64(s[7])<<56
+}
+
+// plainWord checks whether none of the 8 bytes of s starting at i are
+// whitespace, control characters or a double quote, which are the bytes
+// that matter when folding
+func plainWord(s string, i int) bool {
+	w := loadWord(s[i:])
+	// a byte below 0x21 or equal to '"' gives a high bit after the
+	// subtraction that wasn't set in the byte itself; bytes with the high
+	// bit set are non-ASCII and never special
+	below := (w - lowBits*0x21) &^ w & highBits
+	q := w ^ (lowBits * '"')
+	quote := (q - lowBits) &^ q & highBits
+	return below|quote == 0
+}
 
 func validDate(s string) error {
 	_, err := mail.ParseDate(s)
@@ -253,64 +472,192 @@ func validDate(s string) error {
 	return fmt.Errorf("'%s' is not a valid date: %w", s, err)
 }
 
-var messageIdRe = regexp.MustCompile(`^\s*<` + atext + `+(?:\.` + atext + `+)*@` + atext + `+(?:\.` + atext + `+)>\s*`)
-
+// validMessageId checks that s is a single msg-id, optionally surrounded
+// by whitespace
+// https://tools.wordtothewise.com/rfc5322#section-3.6.4
 func validMessageId(s string) error {
-	if messageIdRe.MatchString(s) {
-		return nil
+	end := scanMessageId(s, skipWhitespace(s, 0))
+	if end < 0 || skipWhitespace(s, end) != len(s) {
+		return fmt.Errorf("'%s' is not a valid Message-ID", s)
+	}
+	return nil
+}
+
+// scanMessageId returns the end of the msg-id starting at s[i], or -1 if
+// there isn't one
+func scanMessageId(s string, i int) int {
+	if i >= len(s) || s[i] != '<' {
+		return -1
+	}
+	at := scanDotAtomText(s, i+1)
+	if at < 0 || at >= len(s) || s[at] != '@' {
+		return -1
+	}
+	var end int
+	if at+1 < len(s) && s[at+1] == '[' {
+		end = scanNoFoldLiteral(s, at+1)
+	} else {
+		end = scanDotAtomText(s, at+1)
+	}
+	if end < 0 || end >= len(s) || s[end] != '>' {
+		return -1
+	}
+	return end + 1
+}
+
+// skipWhitespace returns the index of the first character at or after i
+// that isn't whitespace
+func skipWhitespace(s string, i int) int {
+	for i < len(s) && (isWSP(s[i]) || s[i] == '\f') {
+		i++
 	}
-	return fmt.Errorf("'%s' is not a valid Message-ID", s)
+	return i
+}
+
+// scanDotAtomText returns the end of the dot-atom-text starting at s[i],
+// or -1 if there isn't one
+func scanDotAtomText(s string, i int) int {
+	for {
+		start := i
+		for i < len(s) && isAtextChar(s[i]) {
+			i++
+		}
+		if i == start {
+			return -1
+		}
+		if i == len(s) || s[i] != '.' {
+			return i
+		}
+		i++
+	}
+}
+
+// scanNoFoldLiteral returns the end of the no-fold-literal starting at
+// s[i], or -1 if there isn't one
+func scanNoFoldLiteral(s string, i int) int {
+	if i >= len(s) || s[i] != '[' {
+		return -1
+	}
+	for i++; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == ']':
+			return i + 1
+		case c < 33 || c > 126 || c == '[' || c == '\\':
+			return -1
+		}
+	}
+	return -1
 }
 
 func validMessageIdList(s string) error {
-	ids := strings.Split(s, ",")
-	for _, id := range ids {
-		err := validMessageId(id)
-		if err != nil {
-			return err
+	i, n := 0, 0
+	for {
+		// ids are separated by whitespace, and commas are tolerated
+		for i < len(s) && (s[i] == ',' || isWSP(s[i]) || s[i] == '\f') {
+			i++
+		}
+		if i == len(s) {
+			if n == 0 {
+				return fmt.Errorf("'%s' is not a valid Message-ID", s)
+			}
+			return nil
 		}
+		end := scanMessageId(s, i)
+		if end < 0 {
+			return fmt.Errorf("'%s' is not a valid Message-ID", strings.TrimSpace(s[i:]))
+		}
+		i = end
+		n++
 	}
-	return nil
 }
 
 func writeHeader(w io.Writer, headerType HeaderType, key, value string, o Options) error {
-	value = strings.TrimSpace(value)
-	column := len(key) + 2
-	if _, err := io.WriteString(w, key); err != nil {
-		return err
+	if !validFieldName(key) {
+		return fmt.Errorf("'%s' is not a valid header field name", key)
 	}
-	if _, err := io.WriteString(w, ": "); err != nil {
-		return err
+	// changes to the value are reported to OnRepair once it's known the
+	// field can be written
+	var repairs [][3]string
+	repair := func(after, reason string) {
+		if o.OnRepair != nil && after != value {
+			repairs = append(repairs, [3]string{value, after, reason})
+		}
+		value = after
 	}
+	repair(unfoldLineBreaks(value), "removed line breaks")
+	repair(strings.TrimSpace(value), "trimmed whitespace")
+	o.NoEscape = o.noEscape(key)
+	column := len(key) + 2
 	switch headerType {
-	case HeaderTypeUnstructured, HeaderTypePhraseList:
+	case HeaderTypeUnstructured:
+		if !isAscii(value) && !o.NoEscape {
+			repair(mime.QEncoding.Encode(utf8, value), "encoded non-ascii text")
+		}
+	case HeaderTypePhraseList:
 		if !isAscii(value) && !o.NoEscape {
-			value = mime.QEncoding.Encode(utf8, value)
+			// each phrase is encoded separately, so the commas between
+			// them aren't hidden in an encoded-word
+			phrases := parsePhraseList(value)
+			for i, p := range phrases {
+				phrases[i] = formatPhrase(p)
+			}
+			repair(strings.Join(phrases, ", "), "encoded non-ascii text")
 		}
 	case HeaderTypeOpaque, HeaderTypeReceived, HeaderTypeReturnPath, HeaderTypeDate, HeaderTypeMessageID, HeaderTypeMessageIDList:
 	// do nothing
 	case HeaderTypeMailbox:
+		if value == "" {
+			break
+		}
+		if hasComments(value) {
+			repair(formatCommentedAddresses(value, o), "reformatted address")
+			break
+		}
 		// TODO(steve): implement non-escaped version
 		addr, err := mail.ParseAddress(value)
 		if err != nil {
 			return err
 		}
-		value = addr.String()
+		repair(formatMailbox(addr, o), "reformatted address")
 	case HeaderTypeMailboxList:
+		if value == "" {
+			break
+		}
+		if hasComments(value) {
+			repair(formatCommentedAddresses(value, o), "reformatted addresses")
+			break
+		}
 		// TODO(steve): implement non-escaped version
 		addrs, err := mail.ParseAddressList(value)
 		if err != nil {
 			return err
 		}
+		if len(addrs) == 0 {
+			// only empty groups, such as undisclosed-recipients:;
+			break
+		}
 		addresses := make([]string, len(addrs))
 		for i, v := range addrs {
-			addresses[i] = v.String()
+			addresses[i] = formatMailbox(v, o)
 		}
-		value = strings.Join(addresses, ", ")
+		repair(strings.Join(addresses, ", "), "reformatted addresses")
 	default:
 		return fmt.Errorf("internal error, invalid header type: %v", headerType)
 	}
-	if len(value)+column < 78 {
+	if !fieldFoldable(headerType, key, value) {
+		return fmt.Errorf("a word is too long to fit in a %d character line", maxLineLength)
+	}
+	for _, r := range repairs {
+		o.OnRepair(key, r[0], r[1], r[2])
+	}
+	if _, err := io.WriteString(w, key); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, ": "); err != nil {
+		return err
+	}
+	wrap := o.wrapColumn()
+	if len(value)+column < wrap {
 		// simple case
 		_, err := io.WriteString(w, value)
 		if err != nil {
@@ -322,52 +669,29 @@ func writeHeader(w io.Writer, headerType HeaderType, key, value string, o Option
 		}
 		return nil
 	}
+	if tagList := isTagList(headerType, key); tagList || headerType == HeaderTypePhraseList {
+		return writeList(w, value, tagList, column, wrap)
+	}
+	quotes := headerType != HeaderTypeUnstructured
 	inString := false
 	tokenStart := 0
 	val := []byte(value)
 	for i := 0; i < len(val); i++ {
+		if i+8 <= len(value) && plainWord(value, i) {
+			i += 7
+			continue
+		}
 		v := val[i]
-		if v == '"' {
+		if v == '"' && quotes {
 			inString = !inString
 			continue
 		}
 		if inString {
-			if v == '\r' || v == '\n' {
-				return fmt.Errorf("CR or LF found in quoted string at offset %d", i)
-			}
 			continue
 		}
-		if v == '\r' || v == '\n' {
+		if v == ' ' || v == '\t' {
 			tok := val[tokenStart:i]
-			tokenStart = i
-			for ; i < len(val) && (val[i] == '\r' || val[i] == '\n'); i++ {
-			}
-			if len(tok) > 0 {
-				_, err := w.Write(tok)
-				column += len(tok)
-				if err != nil {
-					return err
-				}
-
-				if i >= len(val) {
-					break
-				}
-				switch val[i] {
-				case ' ', '\t':
-					_, err = w.Write([]byte{'\r', '\n', val[i]})
-					i++
-				default:
-					_, err = w.Write([]byte{'\r', '\n', '\t'})
-				}
-				if err != nil {
-					return err
-				}
-				column = 1
-			}
-		}
-		if v == ' ' || v == '\t' || v == '\v' || v == '\f' {
-			tok := val[tokenStart:i]
-			if column+len(tok) > 78 && tokenStart != 0 {
+			if column+len(tok) > wrap && tokenStart != 0 {
 				_, err := w.Write([]byte{'\r', '\n'})
 				if err != nil {
 					return err
@@ -384,7 +708,7 @@ func writeHeader(w io.Writer, headerType HeaderType, key, value string, o Option
 	}
 	if tokenStart < len(val) {
 		tok := val[tokenStart:]
-		if column+len(tok) > 78 && tokenStart != 0 {
+		if column+len(tok) > wrap && tokenStart != 0 {
 			_, err := w.Write([]byte{'\r', '\n'})
 			if err != nil {
 				return err
@@ -405,3 +729,163 @@ func writeHeader(w io.Writer, headerType HeaderType, key, value string, o Option
 	}
 	return nil
 }
+
+// formatMailbox renders an address, without angle brackets if it has no
+// display name and o.BareAddresses is set
+func formatMailbox(addr *mail.Address, o Options) string {
+	s := addr.String()
+	if o.BareAddresses && addr.Name == "" {
+		return strings.TrimSuffix(strings.TrimPrefix(s, "<"), ">")
+	}
+	return s
+}
+
+// hasComments checks whether a structured value has any comments
+func hasComments(value string) bool {
+	return strings.IndexByte(value, '(') >= 0 && len(ExtractComments(value)) > 0
+}
+
+// formatCommentedAddresses renders an address list that has comments,
+// which mail.ParseAddress would drop or mistake for a display name. Each
+// comment follows the mailbox it was found in. A value this can't handle,
+// such as one with groups or that doesn't parse, is passed through as it
+// is rather than losing the comments.
+func formatCommentedAddresses(value string, o Options) string {
+	elements, ok := splitAddressList(value)
+	if !ok {
+		return value
+	}
+	var rendered []string
+	for _, e := range elements {
+		if strings.TrimSpace(e) == "" {
+			continue
+		}
+		addr, err := mail.ParseAddress(StripComments(e))
+		if err != nil {
+			return value
+		}
+		mailbox := formatMailbox(addr, o)
+		for _, c := range ExtractComments(e) {
+			mailbox += " " + formatComment(c, o)
+		}
+		rendered = append(rendered, mailbox)
+	}
+	if len(rendered) == 0 {
+		return value
+	}
+	return strings.Join(rendered, ", ")
+}
+
+// splitAddressList splits an address list at the commas between
+// mailboxes. It fails if the list has groups, or is unbalanced.
+func splitAddressList(value string) ([]string, bool) {
+	var ret []string
+	start := 0
+	angle := false
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '"':
+			_, next, err := readQuotedString(value, i)
+			if err != nil {
+				return nil, false
+			}
+			i = next - 1
+		case '(':
+			_, next, err := readComment(value, i)
+			if err != nil {
+				return nil, false
+			}
+			i = next - 1
+		case '<':
+			angle = true
+		case '>':
+			angle = false
+		case ':', ';':
+			if !angle {
+				return nil, false
+			}
+		case ',':
+			if !angle {
+				ret = append(ret, value[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if angle {
+		return nil, false
+	}
+	return append(ret, value[start:]), true
+}
+
+// formatComment renders the text of a comment in parentheses, escaping
+// any parentheses or backslashes within it, and encoding it if it isn't
+// ASCII
+// https://tools.wordtothewise.com/rfc2047#section-5
+func formatComment(c string, o Options) string {
+	if !isAscii(c) && !o.NoEscape {
+		return "(" + mime.QEncoding.Encode(utf8, c) + ")"
+	}
+	var b strings.Builder
+	b.WriteByte('(')
+	for i := 0; i < len(c); i++ {
+		if c[i] == '(' || c[i] == ')' || c[i] == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c[i])
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// unfoldLineBreaks removes any CR or LF characters from a value, so that
+// it can't end the field early. A fold is unfolded, and any other line
+// break replaced by a space.
+func unfoldLineBreaks(value string) string {
+	if strings.IndexAny(value, "\r\n") < 0 {
+		return value
+	}
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c != '\r' && c != '\n' {
+			b.WriteByte(c)
+			continue
+		}
+		for i+1 < len(value) && (value[i+1] == '\r' || value[i+1] == '\n') {
+			i++
+		}
+		if i+1 < len(value) && isWSP(value[i+1]) {
+			continue
+		}
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
+
+// foldable checks whether value can be folded so that no line is longer
+// than maxLineLength, starting at column, and not folding within quoted
+// strings if quotes is set
+func foldable(value string, column int, quotes bool) bool {
+	if len(value)+column <= maxLineLength {
+		return true
+	}
+	start := 0
+	inString := false
+	for i := 0; i <= len(value); i++ {
+		if i < len(value) {
+			c := value[i]
+			if c == '"' && quotes {
+				inString = !inString
+			}
+			if inString || (c != ' ' && c != '\t') {
+				continue
+			}
+		}
+		if column+i-start > maxLineLength {
+			return false
+		}
+		// later words start a line after a folding space
+		start, column = i+1, 1
+	}
+	return true
+}
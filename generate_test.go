@@ -2,6 +2,9 @@ package orderedheaders
 
 import (
 	"github.com/google/go-cmp/cmp"
+	"fmt"
+	"io"
+	"strings"
 	"testing"
 )
 
@@ -13,29 +16,29 @@ func TestSet_Singles(t *testing.T) {
 	}{
 		"single": {
 			[]KV{
-				{"subject", "foo"},
+				{Key: "subject", Value: "foo"},
 			}, false, "Subject: foo\r\n",
 		},
 		"simple": {
 			[]KV{
-				{"from", `Steve <steve@blighty.com>`},
-				{"to", "bob@example.com"},
-				{"Subject", "bar"},
+				{Key: "from", Value: `Steve <steve@blighty.com>`},
+				{Key: "to", Value: "bob@example.com"},
+				{Key: "Subject", Value: "bar"},
 			}, false, "From: \"Steve\" <steve@blighty.com>\r\nTo: <bob@example.com>\r\nSubject: bar\r\n",
 		},
 		"wrap": {
 			[]KV{
-				{"subject", "abcdefghi 123456798 abcdefghi 123456798 abcdefghi 123456798 abcdefghi 123456798 abcdefghi 123456798 "},
+				{Key: "subject", Value: "abcdefghi 123456798 abcdefghi 123456798 abcdefghi 123456798 abcdefghi 123456798 abcdefghi 123456798 "},
 			}, false, "Subject: abcdefghi 123456798 abcdefghi 123456798 abcdefghi 123456798 abcdefghi\r\n 123456798 abcdefghi 123456798\r\n",
 		},
 		"long": {
 			[]KV{
-				{"subject", "abcdefghi123456798abcdefghi123456798abcdefghi123456798abcdefghi123456798abcdefghi 123456798 "},
+				{Key: "subject", Value: "abcdefghi123456798abcdefghi123456798abcdefghi123456798abcdefghi123456798abcdefghi 123456798 "},
 			}, false, "Subject: abcdefghi123456798abcdefghi123456798abcdefghi123456798abcdefghi123456798abcdefghi\r\n 123456798\r\n",
 		},
 		"i18n": {
 			[]KV{
-				{"subject", "Síneadh Fada"},
+				{Key: "subject", Value: "Síneadh Fada"},
 			}, false, "Subject: =?utf-8?q?S=C3=ADneadh_Fada?=\r\n",
 		},
 	}
@@ -65,3 +68,430 @@ func TestSet_Singles(t *testing.T) {
 		})
 	}
 }
+
+func TestSetWithOptions(t *testing.T) {
+	tests := map[string]struct {
+		Key, Value   string
+		AllowUnknown bool
+		WantError    bool
+	}{
+		"standard":          {"Subject", "hello", false, false},
+		"standard invalid":  {"Date", "yesterday", true, true},
+		"unknown":           {"X-Campaign", "spring", false, true},
+		"allowed":           {"X-Campaign", "spring", true, false},
+		"allowed blank":     {"X-Campaign", "", true, false},
+		"allowed non-ascii": {"X-Campaign", "printemps été", true, true},
+		"bad name":          {"X Campaign", "spring", true, true},
+		"registered":        {"Feedback-Id", "a b", true, true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := &Header{}
+			err := h.SetWithOptions(test.Key, test.Value, SetOptions{AllowUnknown: test.AllowUnknown})
+			if test.WantError {
+				if err == nil {
+					t.Errorf("expected error, didn't get one")
+				}
+				if h.Has(test.Key) {
+					t.Errorf("header set despite error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := h.Get(test.Key); got != test.Value {
+				t.Errorf("want '%s', got '%s'", test.Value, got)
+			}
+		})
+	}
+}
+
+func TestSetRepeatable(t *testing.T) {
+	h := &Header{Headers: []KV{
+		{Key: "Received", Value: "from a.example by b.example; Mon, 1 Jan 2024 00:00:00 +0000"},
+		{Key: "Subject", Value: "old"},
+		{Key: "Comments", Value: "first"},
+	}}
+	if err := h.Set(HdrReceived, "from b.example by c.example; Mon, 1 Jan 2024 00:00:01 +0000"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Set(HdrComments, "second"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Set(HdrSubject, "new"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Set(HdrResentTo, "carol@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	want := []KV{
+		{Key: "Resent-To", Value: "carol@example.com"},
+		{Key: "Received", Value: "from b.example by c.example; Mon, 1 Jan 2024 00:00:01 +0000"},
+		{Key: "Received", Value: "from a.example by b.example; Mon, 1 Jan 2024 00:00:00 +0000"},
+		{Key: "Subject", Value: "new"},
+		{Key: "Comments", Value: "first"},
+		{Key: "Comments", Value: "second"},
+	}
+	if diff := cmp.Diff(want, h.Headers); diff != "" {
+		t.Errorf("header mismatch (-want +got):\n%s", diff)
+	}
+	if err := h.Set(HdrComments, ""); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want[:4], h.Headers); diff != "" {
+		t.Errorf("header mismatch after clearing Comments (-want +got):\n%s", diff)
+	}
+}
+
+func TestWriteHeaderUnsafe(t *testing.T) {
+	long := strings.Repeat("x", 1000)
+	tests := map[string]struct {
+		Key, Value string
+		WantError  bool
+		Want       string
+	}{
+		"injection":               {"Subject", "a\r\nBcc: injected@example.com", false, "Subject: a Bcc: injected@example.com\r\n"},
+		"blank line":              {"X-Custom", "a\r\n\r\nbody", false, "X-Custom: a body\r\n"},
+		"bare cr":                 {"Subject", "bare\rcarriage return", false, "Subject: bare carriage return\r\n"},
+		"fold":                    {"Subject", "folded\r\n value", false, "Subject: folded value\r\n"},
+		"long word":               {"X-Custom", long, true, ""},
+		"long word later":         {"X-Custom", "short " + long, true, ""},
+		"long quoted":             {"To", `"` + strings.Repeat("a ", 500) + `" <a@example.com>`, true, ""},
+		"long unstructured quote": {"Subject", `"` + strings.Repeat("a ", 500) + `"`, false, ""},
+		"vertical tab":            {"X-Custom", strings.Repeat("abcdefgh\v", 10), false, "X-Custom: " + strings.Repeat("abcdefgh\v", 10)[:89] + "\r\n"},
+		"bad key":                 {"Bad Key", "value", true, ""},
+		"empty group":             {"To", "undisclosed-recipients:;", false, "To: undisclosed-recipients:;\r\n"},
+		"blank mailbox":           {"To", "", false, "To: \r\n"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := &Header{Headers: []KV{{Key: test.Key, Value: test.Value}}}
+			got, err := h.Bytes(Options{RenderBlank: true})
+			if test.WantError {
+				if err == nil {
+					t.Errorf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.Want != "" {
+				if diff := cmp.Diff(test.Want, string(got)); diff != "" {
+					t.Errorf("mismatch (-want +got):\n%s", diff)
+				}
+			}
+			for _, line := range strings.Split(strings.TrimSuffix(string(got), "\r\n"), "\r\n") {
+				if len(line) > maxLineLength || strings.ContainsAny(line, "\r\n") {
+					t.Errorf("bad line %q", line)
+				}
+			}
+		})
+	}
+}
+
+func TestBareAddresses(t *testing.T) {
+	h := &Header{Headers: []KV{
+		{Key: "From", Value: "alice@example.com"},
+		{Key: "To", Value: `Bob <bob@example.com>, carol@example.com, "d e"@example.com`},
+		{Key: "Sender", Value: "<list@example.com>"},
+		{Key: "Return-Path", Value: "<bounce@example.com>"},
+	}}
+	tests := map[string]struct {
+		Options Options
+		Want    string
+	}{
+		"brackets": {Options{}, "From: <alice@example.com>\r\n" +
+			"To: \"Bob\" <bob@example.com>, <carol@example.com>, <\"d e\"@example.com>\r\n" +
+			"Sender: <list@example.com>\r\n" +
+			"Return-Path: <bounce@example.com>\r\n"},
+		"bare": {Options{BareAddresses: true}, "From: alice@example.com\r\n" +
+			"To: \"Bob\" <bob@example.com>, carol@example.com, \"d e\"@example.com\r\n" +
+			"Sender: list@example.com\r\n" +
+			"Return-Path: <bounce@example.com>\r\n"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := h.Bytes(test.Options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.Want, string(got)); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAddressComments(t *testing.T) {
+	tests := map[string]struct {
+		Key, Value string
+		Options    Options
+		Want       string
+	}{
+		"trailing": {"From", "john@example.com (John Doe)", Options{}, "From: <john@example.com> (John Doe)\r\n"},
+		"bare":     {"From", "john@example.com (John Doe)", Options{BareAddresses: true}, "From: john@example.com (John Doe)\r\n"},
+		"named":    {"Sender", "Alice (work) <alice@example.com>", Options{}, "Sender: \"Alice\" <alice@example.com> (work)\r\n"},
+		"list":     {"To", "a@example.com (first), Bob <b@example.com>, c@example.com (third (nested))", Options{}, "To: <a@example.com> (first), \"Bob\" <b@example.com>, <c@example.com> (third\r\n \\(nested\\))\r\n"},
+		"escaped":  {"To", `a@example.com (a \) b)`, Options{}, `To: <a@example.com> (a \) b)` + "\r\n"},
+		"comma":    {"To", `a@example.com (x, y), b@example.com`, Options{}, "To: <a@example.com> (x, y), <b@example.com>\r\n"},
+		"quoted":   {"To", `"Smith (Alice)" <a@example.com>`, Options{}, "To: \"Smith (Alice)\" <a@example.com>\r\n"},
+		"encoded":  {"To", "a@example.com (café)", Options{}, "To: <a@example.com> (=?utf-8?q?caf=C3=A9?=)\r\n"},
+		"group":    {"To", "team: a@example.com (x);", Options{}, "To: team: a@example.com (x);\r\n"},
+		"unparsed": {"To", "a@ (x)", Options{}, "To: a@ (x)\r\n"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := &Header{Headers: []KV{{Key: test.Key, Value: test.Value}}}
+			got, err := h.Bytes(test.Options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.Want, string(got)); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNoEscapeFor(t *testing.T) {
+	h := &Header{Headers: []KV{
+		{Key: "Subject", Value: "café"},
+		{Key: "X-Vendor", Value: "crème"},
+		{Key: "Comments", Value: "brûlée"},
+	}}
+	tests := map[string]struct {
+		Options Options
+		Want    string
+	}{
+		"none": {Options{}, "Subject: =?utf-8?q?caf=C3=A9?=\r\nX-Vendor: crème\r\nComments: =?utf-8?q?br=C3=BBl=C3=A9e?=\r\n"},
+		"one":  {Options{NoEscapeFor: []string{"comments"}}, "Subject: =?utf-8?q?caf=C3=A9?=\r\nX-Vendor: crème\r\nComments: brûlée\r\n"},
+		"all":  {Options{NoEscape: true, NoEscapeFor: []string{"comments"}}, "Subject: café\r\nX-Vendor: crème\r\nComments: brûlée\r\n"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := h.Bytes(test.Options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.Want, string(got)); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestOnHeaderWritten(t *testing.T) {
+	type call struct {
+		Key    string
+		Bytes  int
+		Folded bool
+	}
+	var got []call
+	o := Options{OnHeaderWritten: func(key string, bytes int, folded bool) {
+		got = append(got, call{key, bytes, folded})
+	}}
+	long := strings.TrimSpace(strings.Repeat("word ", 20))
+	h := &Header{Headers: []KV{
+		{Key: "Subject", Value: "hello"},
+		{Key: "Bcc", Value: "secret@example.com"},
+		{Key: "Comments", Value: long},
+		{Key: "X-Empty", Value: ""},
+		{Key: "Subject", Value: "again"},
+	}}
+	b, err := h.Bytes(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []call{
+		{"Subject", len("Subject: hello\r\n"), false},
+		{"Bcc", 0, false},
+		{"Comments", len(b) - len("Subject: hello\r\n"), true},
+		{"X-Empty", 0, false},
+		{"Subject", 0, false},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("callback mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestReceivedUTF8(t *testing.T) {
+	value := "from mail.example.jp (mail.example.jp [192.0.2.1]) by mx.example.com with UTF8SMTPS id 4XyZ for <用户@例子.广告> (envelope from <δοκιμή@παράδειγμα.δοκιμή>); Mon, 1 Jan 2024 00:00:00 +0000"
+	h := &Header{}
+	if err := h.Set(HdrReceived, value); err == nil {
+		t.Errorf("expected error for UTF-8 without SMTPUTF8")
+	}
+	if err := h.SetWithOptions(HdrReceived, value, SetOptions{SMTPUTF8: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.SetWithOptions(HdrReceived, "from a\xff by b", SetOptions{SMTPUTF8: true}); err == nil {
+		t.Errorf("expected error for invalid UTF-8")
+	}
+	b, err := h.Bytes(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(b), "\r\n"), "\r\n")
+	if len(lines) < 2 {
+		t.Errorf("long Received wasn't folded: %q", b)
+	}
+	for _, line := range lines {
+		if len(line) > defaultWrapColumn || strings.ToValidUTF8(line, "") != line {
+			t.Errorf("bad line %q", line)
+		}
+	}
+	if got := strings.Join(lines, ""); got != "Received: "+value {
+		t.Errorf("unfolded value changed: %q", got)
+	}
+}
+
+func TestOnRepair(t *testing.T) {
+	var got []string
+	o := Options{OnRepair: func(key, before, after, reason string) {
+		got = append(got, fmt.Sprintf("%s: %q -> %q (%s)", key, before, after, reason))
+	}}
+	h := &Header{Headers: []KV{
+		{Key: "Subject", Value: "  café\r\n"},
+		{Key: "To", Value: "Bob Smith <bob@example.com>"},
+		{Key: "X-Clean", Value: "unchanged"},
+		{Key: "Date", Value: "Mon, 1 Jan 2024 00:00:00 +0000"},
+	}}
+	if _, err := h.Bytes(o); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`Subject: "  café\r\n" -> "  café " (removed line breaks)`,
+		`Subject: "  café " -> "café" (trimmed whitespace)`,
+		`Subject: "café" -> "=?utf-8?q?caf=C3=A9?=" (encoded non-ascii text)`,
+		`To: "Bob Smith <bob@example.com>" -> "\"Bob Smith\" <bob@example.com>" (reformatted addresses)`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("repairs mismatch (-want +got):\n%s", diff)
+	}
+
+	got = nil
+	bad := &Header{Headers: []KV{{Key: "X-Long", Value: " " + strings.Repeat("x", 1000)}}}
+	if _, err := bad.Bytes(o); err == nil {
+		t.Errorf("expected error")
+	}
+	if len(got) != 0 {
+		t.Errorf("repairs reported for a field that wasn't written: %q", got)
+	}
+}
+
+func TestWrapColumn(t *testing.T) {
+	value := strings.TrimSpace(strings.Repeat("word ", 40))
+	tests := map[string]struct {
+		Wrap    int
+		Longest int
+		Lines   int
+	}{
+		"default":  {0, 78, 3},
+		"narrow":   {30, 30, 7},
+		"wide":     {500, 500, 1},
+		"too wide": {5000, maxLineLength, 1},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := &Header{Headers: []KV{{Key: "Subject", Value: value}}}
+			got, err := h.Bytes(Options{WrapColumn: test.Wrap})
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Split(strings.TrimSuffix(string(got), "\r\n"), "\r\n")
+			for _, line := range lines {
+				if len(line) > test.Longest {
+					t.Errorf("%d character line %q", len(line), line)
+				}
+			}
+			if len(lines) != test.Lines {
+				t.Errorf("want %d lines, got %d:\n%s", test.Lines, len(lines), got)
+			}
+		})
+	}
+}
+
+func TestIsAscii(t *testing.T) {
+	base := strings.Repeat("a", 20)
+	if !isAscii(base) || !isAscii("") {
+		t.Errorf("isAscii rejected ASCII")
+	}
+	for i := 0; i < len(base); i++ {
+		for _, c := range []byte{0x80, 0xff} {
+			s := base[:i] + string([]byte{c}) + base[i+1:]
+			if isAscii(s) {
+				t.Errorf("isAscii accepted %q", s)
+			}
+		}
+	}
+}
+
+func TestPlainWord(t *testing.T) {
+	for c := 0; c < 256; c++ {
+		want := c > ' ' && c != '"'
+		for i := 0; i < 8; i++ {
+			b := []byte("abcdefgh")
+			b[i] = byte(c)
+			if got := plainWord(string(b), 0); got != want {
+				t.Errorf("plainWord(%q) = %v, want %v", b, got, want)
+			}
+		}
+	}
+}
+
+var benchmarkDKIM = "v=1; a=rsa-sha256; c=relaxed/relaxed; d=example.com; s=selector1; h=from:to:subject:date:message-id; bh=" +
+	strings.Repeat("AbCdEfGhIjKlMnOpQrStUvWxYz0123456789+/", 4) + "; b=" + strings.Repeat("ZyXwVuTsRqPoNmLkJiHgFeDcBa9876543210+/", 10)
+
+func BenchmarkIsAscii(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if !isAscii(benchmarkDKIM) {
+			b.Fatal("not ascii")
+		}
+	}
+}
+
+func BenchmarkWriteHeaderFolding(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := writeHeader(io.Discard, HeaderTypeOpaque, HdrDKIMSignature, benchmarkDKIM, Options{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWriteTo(b *testing.B) {
+	ascii := Header{Headers: []KV{
+		{Key: "From", Value: `"Alice Example" <alice@example.com>`},
+		{Key: "To", Value: "bob@example.com, carol@example.com, dave@example.com"},
+		{Key: "Subject", Value: "A subject line that's long enough that it will need to be folded when it's written out"},
+		{Key: "Message-Id", Value: "<1234567890.abcdef@example.com>"},
+		{Key: "Dkim-Signature", Value: benchmarkDKIM},
+	}}
+	encoded := Header{Headers: []KV{
+		{Key: "From", Value: `"Síneadh Fada" <alice@example.com>`},
+		{Key: "To", Value: "Jürgen <bob@example.com>, Zoë <carol@example.com>"},
+		{Key: "Subject", Value: "Ré: naïve café déjà vu, with enough accented words that it's folded too"},
+		{Key: "Message-Id", Value: "<1234567890.abcdef@example.com>"},
+	}}
+	tests := []struct {
+		name string
+		h    Header
+		o    Options
+	}{
+		{"ascii", ascii, Options{}},
+		{"encoded", encoded, Options{}},
+		{"noescape", encoded, Options{NoEscape: true}},
+	}
+	for _, tt := range tests {
+		b.Run(tt.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := tt.h.WriteTo(io.Discard, tt.o); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
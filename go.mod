@@ -2,4 +2,4 @@ module github.com/wttw/orderedheaders
 
 go 1.16
 
-require github.com/google/go-cmp v0.5.9 // indirect
+require github.com/google/go-cmp v0.5.9
@@ -0,0 +1,11 @@
+module github.com/wttw/orderedheaders/goenmime
+
+go 1.16
+
+require (
+	github.com/google/go-cmp v0.5.9
+	github.com/jhillyerd/enmime v0.10.1
+	github.com/wttw/orderedheaders v0.0.0-00010101000000-000000000000
+)
+
+replace github.com/wttw/orderedheaders => ../
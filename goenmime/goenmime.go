@@ -0,0 +1,55 @@
+// Package goenmime converts between orderedheaders and the envelope and
+// part types of github.com/jhillyerd/enmime.
+//
+// enmime keeps headers in a textproto.MIMEHeader, so field order is lost
+// going into it; fields coming out of it are sorted by key, as enmime
+// itself writes them. It's a separate module so that orderedheaders
+// itself doesn't depend on enmime.
+package goenmime
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/jhillyerd/enmime"
+	"github.com/wttw/orderedheaders"
+)
+
+// HeaderFromPart returns the header of an enmime part, sorted by key,
+// keeping the order of multiple values for the same key
+func HeaderFromPart(p *enmime.Part) orderedheaders.Header {
+	keys := make([]string, 0, len(p.Header))
+	for k := range p.Header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var h orderedheaders.Header
+	for _, k := range keys {
+		for _, v := range p.Header[k] {
+			h.Add(k, v)
+		}
+	}
+	return h
+}
+
+// ToEnvelope parses m with enmime. The message is rendered with Rewrite,
+// which consumes m.Body. Bcc and empty fields are rendered too, so they
+// reach the envelope.
+func ToEnvelope(m *orderedheaders.Message) (*enmime.Envelope, error) {
+	var buf bytes.Buffer
+	if err := m.Rewrite(&buf, orderedheaders.Options{RenderBCC: true, RenderBlank: true}); err != nil {
+		return nil, err
+	}
+	return enmime.ReadEnvelope(&buf)
+}
+
+// FromEnvelope converts an enmime envelope to a Message. The envelope's
+// root part is encoded, as enmime would send it, and read back, so the
+// whole message is held in memory.
+func FromEnvelope(env *enmime.Envelope) (*orderedheaders.Message, error) {
+	var buf bytes.Buffer
+	if err := env.Root.Encode(&buf); err != nil {
+		return nil, err
+	}
+	return orderedheaders.ReadMessage(&buf)
+}
@@ -0,0 +1,62 @@
+package goenmime
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wttw/orderedheaders"
+)
+
+const testMessage = "Subject: hello\r\n" +
+	"From: alice@example.com\r\n" +
+	"To: bob@example.com\r\n" +
+	"Bcc: carol@example.com\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"caf=C3=A9\r\n"
+
+func TestEnvelope(t *testing.T) {
+	m, err := orderedheaders.ReadMessage(strings.NewReader(testMessage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := ToEnvelope(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.Text != "café\r\n" {
+		t.Errorf("unexpected text %q", env.Text)
+	}
+	if got := env.GetHeader("Subject"); got != "hello" {
+		t.Errorf("unexpected subject %q", got)
+	}
+	if got := env.GetHeader("Bcc"); got != "<carol@example.com>" {
+		t.Errorf("unexpected bcc %q", got)
+	}
+
+	h := HeaderFromPart(env.Root)
+	var keys []string
+	for _, kv := range h.Headers {
+		keys = append(keys, kv.Key)
+	}
+	want := []string{"Bcc", "Content-Transfer-Encoding", "Content-Type", "From", "Subject", "To"}
+	if diff := cmp.Diff(want, keys); diff != "" {
+		t.Errorf("key mismatch (-want +got):\n%s", diff)
+	}
+
+	back, err := FromEnvelope(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{orderedheaders.HdrFrom: "alice@example.com", orderedheaders.HdrTo: "bob@example.com"} {
+		addrs, err := back.Header.AddressList(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(addrs) != 1 || addrs[0].Address != want {
+			t.Errorf("unexpected %s %v", key, addrs)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// Package gomessage converts between orderedheaders and the header and
+// entity types of github.com/emersion/go-message, which also keeps
+// header fields in order, so the two can be used side by side.
+//
+// It's a separate module so that orderedheaders itself doesn't depend on
+// go-message.
+package gomessage
+
+import (
+	"bytes"
+
+	"github.com/emersion/go-message"
+	"github.com/emersion/go-message/textproto"
+	"github.com/wttw/orderedheaders"
+)
+
+// ToHeader converts h to a go-message header, with the fields in the
+// same order. Fields that still have their Raw bytes keep them, so they
+// are written unchanged.
+func ToHeader(h orderedheaders.Header) message.Header {
+	var th textproto.Header
+	// go-message adds each field at the top of the header
+	for i := len(h.Headers) - 1; i >= 0; i-- {
+		kv := h.Headers[i]
+		if kv.Raw != "" {
+			th.AddRaw([]byte(kv.Raw))
+		} else {
+			th.Add(kv.Key, kv.Value)
+		}
+	}
+	return message.Header{Header: th}
+}
+
+// FromHeader converts a go-message header to a Header, with the fields
+// in the same order
+func FromHeader(h message.Header) orderedheaders.Header {
+	return FromHeaderWithOptions(h, orderedheaders.ReadOptions{})
+}
+
+// FromHeaderWithOptions converts a go-message header to a Header. With
+// KeepRaw, each KV's Raw is set to the field as go-message would write
+// it, which is the original bytes for a header that was read.
+func FromHeaderWithOptions(h message.Header, o orderedheaders.ReadOptions) orderedheaders.Header {
+	var result orderedheaders.Header
+	fields := h.Fields()
+	for fields.Next() {
+		kv := orderedheaders.KV{Key: fields.Key(), Value: fields.Value()}
+		if o.KeepRaw {
+			if raw, err := fields.Raw(); err == nil {
+				kv.Raw = string(raw)
+			}
+		}
+		result.Headers = append(result.Headers, kv)
+	}
+	return result
+}
+
+// ToEntity converts m to a go-message entity. As with message.New, the
+// entity's body is decoded from the Content-Transfer-Encoding and, for
+// text parts, the charset; an unknown charset gives both an entity and
+// an error that message.IsUnknownCharset recognizes.
+func ToEntity(m *orderedheaders.Message) (*message.Entity, error) {
+	return message.New(ToHeader(m.Header), m.Body)
+}
+
+// FromEntity converts a go-message entity to a Message. The entity is
+// written out, re-encoding its body, and read back, so the whole message
+// is held in memory and e.Body is consumed.
+func FromEntity(e *message.Entity) (*orderedheaders.Message, error) {
+	var buf bytes.Buffer
+	if err := e.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return orderedheaders.ReadMessage(&buf)
+}
@@ -0,0 +1,94 @@
+package gomessage
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"mime"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-message"
+	"github.com/google/go-cmp/cmp"
+	"github.com/wttw/orderedheaders"
+)
+
+const testMessage = "Received: from a by b\r\n" +
+	"From: alice@example.com\r\n" +
+	"Subject: hello\r\n" +
+	"Received: from c by d\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"caf=C3=A9\r\n"
+
+func TestHeaderRoundTrip(t *testing.T) {
+	h, err := orderedheaders.ReadHeaderWithOptions(textproto.NewReader(bufio.NewReader(strings.NewReader(testMessage))), orderedheaders.ReadOptions{KeepRaw: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mh := ToHeader(h)
+	fields := mh.Fields()
+	var keys []string
+	for fields.Next() {
+		keys = append(keys, fields.Key())
+	}
+	want := []string{"Received", "From", "Subject", "Received", "Content-Type", "Content-Transfer-Encoding"}
+	if diff := cmp.Diff(want, keys); diff != "" {
+		t.Errorf("go-message key mismatch (-want +got):\n%s", diff)
+	}
+
+	back := FromHeaderWithOptions(mh, orderedheaders.ReadOptions{KeepRaw: true})
+	if diff := cmp.Diff(h, back); diff != "" {
+		t.Errorf("round trip mismatch (-want +got):\n%s", diff)
+	}
+	if FromHeader(mh).Headers[0].Raw != "" {
+		t.Errorf("FromHeader kept raw bytes")
+	}
+}
+
+func TestEntity(t *testing.T) {
+	m, err := orderedheaders.ReadMessage(strings.NewReader(testMessage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := ToEntity(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(e.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "café\r\n" {
+		t.Errorf("unexpected decoded body %q", body)
+	}
+
+	var h message.Header
+	h.SetContentType("text/plain", map[string]string{"charset": "utf-8"})
+	h.Set("Content-Transfer-Encoding", "base64")
+	h.SetText("Subject", "café")
+	e, err = message.New(h, strings.NewReader("aGVsbG8=\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err = FromEntity(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject != "café" {
+		t.Errorf("unexpected subject %q", subject)
+	}
+	raw, err := io.ReadAll(m.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(raw, []byte("aGVsbG8=")) {
+		t.Errorf("unexpected raw body %q", raw)
+	}
+}
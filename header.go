@@ -3,25 +3,68 @@ package orderedheaders
 import (
 	"net/mail"
 	"net/textproto"
-	"regexp"
 	"strings"
 	"time"
+	"unicode"
 )
 
 // A KV represents a single mime header
 type KV struct {
 	Key   string
 	Value string
+	// Raw is the exact bytes of the header field as read, including the
+	// field name, any folding and the line ending. It is only populated
+	// when reading with ReadOptions.KeepRaw.
+	Raw string
 }
 
 // A Header represents a MIME-style header consisting
 // of a list of key, value pairs
 type Header struct {
 	Headers []KV
+	// RawKeys is set if the keys in Headers may not have been
+	// canonicalized, because the header was read with
+	// ReadOptions.LazyKeys
+	RawKeys bool
+	// Truncated is set if fields, or their raw bytes, were dropped or
+	// shortened because the header was larger than ReadOptions.MaxFields
+	// or ReadOptions.MaxFieldBytes allow
+	Truncated bool
+}
+
+// CanonicalizeKeys canonicalizes any keys left as they were read by
+// ReadOptions.LazyKeys. Methods that look fields up by key, such as Get,
+// Has, Set and WriteTo, call it first, but code that uses Headers
+// directly after reading with LazyKeys needs to call it itself.
+func (h *Header) CanonicalizeKeys() {
+	if !h.RawKeys {
+		return
+	}
+	for i, kv := range h.Headers {
+		h.Headers[i].Key = textproto.CanonicalMIMEHeaderKey(kv.Key)
+	}
+	h.RawKeys = false
+}
+
+// NewHeader returns an empty header with room for capacity fields
+func NewHeader(capacity int) *Header {
+	return &Header{Headers: make([]KV, 0, capacity)}
+}
+
+// Grow makes room for at least n more fields, so they can be added
+// without reallocating
+func (h *Header) Grow(n int) {
+	if n <= cap(h.Headers)-len(h.Headers) {
+		return
+	}
+	kvs := make([]KV, len(h.Headers), len(h.Headers)+n)
+	copy(kvs, h.Headers)
+	h.Headers = kvs
 }
 
 // ToMap converts a Header to a textproto.MIMEHeader
 func (h *Header) ToMap() textproto.MIMEHeader {
+	h.CanonicalizeKeys()
 	m := make(textproto.MIMEHeader)
 	for _, h := range h.Headers {
 		m.Add(h.Key, h.Value)
@@ -40,6 +83,7 @@ func (h *Header) Add(key, value string) {
 // to canonicalize the provided key.
 // If there are no values associated with the key, Get returns "".
 func (h *Header) Get(key string) string {
+	h.CanonicalizeKeys()
 	key = textproto.CanonicalMIMEHeaderKey(key)
 	for _, h := range h.Headers {
 		if key == h.Key {
@@ -49,6 +93,18 @@ func (h *Header) Get(key string) string {
 	return ""
 }
 
+// Has reports whether there is at least one header with the given key.
+func (h *Header) Has(key string) bool {
+	h.CanonicalizeKeys()
+	key = textproto.CanonicalMIMEHeaderKey(key)
+	for _, h := range h.Headers {
+		if key == h.Key {
+			return true
+		}
+	}
+	return false
+}
+
 // AddressList parses the named header field as a list of addresses.
 func (h *Header) AddressList(key string) ([]*mail.Address, error) {
 	hdr := h.Get(key)
@@ -60,24 +116,74 @@ func (h *Header) AddressList(key string) ([]*mail.Address, error) {
 
 // Date parses the Date header field.
 func (h *Header) Date() (time.Time, error) {
-	hdr := h.Get("Date")
-	if hdr == "" {
-		return time.Time{}, mail.ErrHeaderNotPresent
+	t, _, err := h.DateWithOptions(DateOptions{})
+	return t, err
+}
+
+// isCollapsibleSpace reports whether Normalize collapses r
+func isCollapsibleSpace(r rune) bool {
+	switch r {
+	case ' ', '\t', '\n', '\f', '\r':
+		return true
 	}
-	return mail.ParseDate(hdr)
+	return r > unicode.MaxASCII && unicode.Is(unicode.Zs, r)
 }
 
-var whitespaceRe = regexp.MustCompile(`[\s\p{Zs}]+`)
+// collapseWhitespace replaces each run of ASCII whitespace or Unicode
+// space separators in s with a single space, returning s itself if there
+// are none to replace
+func collapseWhitespace(s string) string {
+	var b strings.Builder
+	changed := false
+	// written is the end of the part of s copied to b, and run the start
+	// of the current run of whitespace, or -1
+	written, run := 0, -1
+	endRun := func(end int) {
+		if s[run:end] != " " {
+			if !changed {
+				b.Grow(len(s))
+				changed = true
+			}
+			b.WriteString(s[written:run])
+			b.WriteByte(' ')
+			written = end
+		}
+		run = -1
+	}
+	for i, r := range s {
+		switch {
+		case isCollapsibleSpace(r):
+			if run < 0 {
+				run = i
+			}
+		case run >= 0:
+			endRun(i)
+		}
+	}
+	if run >= 0 {
+		endRun(len(s))
+	}
+	if !changed {
+		return s
+	}
+	b.WriteString(s[written:])
+	return b.String()
+}
 
 // Normalize replaces all whitespace in a header with a single space.
 func (h *Header) Normalize() {
 	for i, kv := range h.Headers {
-		h.Headers[i].Value = strings.TrimSpace(whitespaceRe.ReplaceAllLiteralString(kv.Value, " "))
+		value := strings.TrimSpace(collapseWhitespace(kv.Value))
+		if value != kv.Value {
+			h.Headers[i].Value = value
+			h.Headers[i].Raw = ""
+		}
 	}
 }
 
 // RemoveAll removes all headers with this (canonicalized) name
 func (h *Header) RemoveAll(key string) {
+	h.CanonicalizeKeys()
 	key = textproto.CanonicalMIMEHeaderKey(key)
 	filtered := h.Headers[:0]
 	for _, kv := range h.Headers {
@@ -87,3 +193,54 @@ func (h *Header) RemoveAll(key string) {
 	}
 	h.Headers = filtered
 }
+
+// replace sets the first instance of a header to value, or appends it if
+// there isn't one, without any validation of the key or value
+func (h *Header) replace(key, value string) {
+	h.CanonicalizeKeys()
+	for i, v := range h.Headers {
+		if v.Key == key {
+			h.Headers[i] = KV{
+				Key:   key,
+				Value: value,
+			}
+			return
+		}
+	}
+	h.Headers = append(h.Headers, KV{
+		Key:   key,
+		Value: value,
+	})
+}
+
+// replaceAll sets the first instance of a header to value and removes
+// any others, or appends it if there isn't one. An empty value removes
+// the header entirely.
+func (h *Header) replaceAll(key, value string) {
+	h.CanonicalizeKeys()
+	if value == "" {
+		h.RemoveAll(key)
+		return
+	}
+	found := false
+	filtered := h.Headers[:0]
+	for _, kv := range h.Headers {
+		if kv.Key == key {
+			if found {
+				continue
+			}
+			found = true
+			kv = KV{Key: key, Value: value}
+		}
+		filtered = append(filtered, kv)
+	}
+	h.Headers = filtered
+	if !found {
+		h.Headers = append(h.Headers, KV{Key: key, Value: value})
+	}
+}
+
+// insert inserts header fields before index i
+func (h *Header) insert(i int, kvs ...KV) {
+	h.Headers = append(h.Headers[:i], append(kvs, h.Headers[i:]...)...)
+}
@@ -1,11 +1,15 @@
 package orderedheaders
 
-import "testing"
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
 
 func TestHeaderNormalize(t *testing.T) {
 	in := Header{
 		Headers: []KV{
-			{"FOO", "  one\ttwo   three\n  four\t five\t\nsix\r\nseven\n"},
+			{Key: "FOO", Value: "  one\ttwo   three\n  four\t five\t\nsix\r\nseven\n"},
 		},
 	}
 	in.Normalize()
@@ -15,3 +19,104 @@ func TestHeaderNormalize(t *testing.T) {
 		t.Errorf("want: '%s', got: '%s'", want, got)
 	}
 }
+
+// whitespaceRe is what Normalize used to collapse whitespace with, kept
+// to check collapseWhitespace against
+var whitespaceRe = regexp.MustCompile(`[\s\p{Zs}]+`)
+
+func TestCollapseWhitespace(t *testing.T) {
+	inputs := []string{
+		"",
+		" ",
+		"plain",
+		"one two",
+		"one  two",
+		" lead and trail ",
+		"tab\there",
+		"crlf\r\n folded",
+		"nbsp\u00a0here",
+		"ideographic\u3000 space",
+		"form\ffeed",
+		"vertical\vtab",
+		"line\u2028separator",
+		"invalid \xff\xfe utf-8",
+		"   ",
+		"a \u00a0",
+	}
+	for _, in := range inputs {
+		want := whitespaceRe.ReplaceAllLiteralString(in, " ")
+		if got := collapseWhitespace(in); got != want {
+			t.Errorf("collapseWhitespace(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+var benchmarkValue = strings.Repeat("from mail.example.com (mail.example.com [192.0.2.1])\r\n\tby mx.example.net  with ESMTPS; ", 4)
+
+func BenchmarkCollapseWhitespace(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		collapseWhitespace(benchmarkValue)
+	}
+}
+
+func BenchmarkCollapseWhitespaceRegexp(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		whitespaceRe.ReplaceAllLiteralString(benchmarkValue, " ")
+	}
+}
+
+func TestHeaderGrow(t *testing.T) {
+	h := NewHeader(2)
+	if len(h.Headers) != 0 || cap(h.Headers) != 2 {
+		t.Fatalf("NewHeader(2) has len %d cap %d", len(h.Headers), cap(h.Headers))
+	}
+	h.Add("Subject", "hello")
+	h.Grow(1)
+	if cap(h.Headers) != 2 {
+		t.Errorf("Grow(1) reallocated with room to spare")
+	}
+	h.Grow(10)
+	if cap(h.Headers) < 11 || h.Get(HdrSubject) != "hello" {
+		t.Errorf("Grow(10) gave cap %d, fields %v", cap(h.Headers), h.Headers)
+	}
+}
+
+func TestReadHeaderFieldsHint(t *testing.T) {
+	input := strings.Repeat("Received: from a by b\r\n", 100) + "\r\n"
+	h, err := ReadHeaderWithOptions(reader(input), ReadOptions{FieldsHint: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Headers) != 100 || cap(h.Headers) < 200 {
+		t.Errorf("read %d fields with cap %d", len(h.Headers), cap(h.Headers))
+	}
+}
+
+func BenchmarkAddressList(b *testing.B) {
+	h := Header{Headers: []KV{
+		{Key: "To", Value: `"Alice Example" <alice@example.com>, bob@example.com (Bob), =?utf-8?q?Zo=C3=AB?= <zoe@example.com>, Team: carol@example.com, dave@example.com;`},
+	}}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := h.AddressList(HdrTo); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkNormalize(b *testing.B) {
+	fields := []KV{
+		{Key: "Received", Value: benchmarkValue},
+		{Key: "Subject", Value: "  a subject\r\n\twith   folding  "},
+		{Key: "From", Value: "alice@example.com"},
+		{Key: "To", Value: "bob@example.com"},
+	}
+	h := Header{Headers: make([]KV, len(fields))}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		copy(h.Headers, fields)
+		h.Normalize()
+	}
+}
@@ -0,0 +1,48 @@
+//go:build go1.18
+// +build go1.18
+
+package headerfuzz
+
+import (
+	"testing"
+)
+
+func FuzzReadHeader(f *testing.F) {
+	for _, b := range Corpus() {
+		f.Add(b)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if err := CheckReadHeader(data); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func FuzzRoundTrip(f *testing.F) {
+	for _, b := range Corpus() {
+		f.Add(b)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if err := CheckRoundTrip(data); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func FuzzWriteHeaderFolding(f *testing.F) {
+	for _, b := range Corpus() {
+		h, err := orderedheadersRead(b)
+		if err != nil {
+			continue
+		}
+		for _, kv := range h.Headers {
+			f.Add(kv.Key, kv.Value)
+		}
+	}
+	f.Add("Subject", "a\r\nBcc: injected@example.com")
+	f.Fuzz(func(t *testing.T, key, value string) {
+		if err := CheckWriteHeaderFolding(key, value); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
@@ -0,0 +1,198 @@
+// Package headerfuzz checks properties of reading and writing headers
+// that should hold for any input, for use by fuzz tests. The tests in
+// this package run them with go test -fuzz, and other fuzzers can call
+// them directly, seeded with Corpus.
+package headerfuzz
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"net/textproto"
+	"reflect"
+	"sort"
+
+	"github.com/wttw/orderedheaders"
+)
+
+// maxLine is the longest line allowed, excluding the CRLF
+// https://tools.wordtothewise.com/rfc5322#section-2.1.1
+const maxLine = 998
+
+//go:embed corpus
+var corpus embed.FS
+
+// Corpus returns the seed inputs, real world headers with unusual
+// syntax, in a stable order.
+func Corpus() [][]byte {
+	entries, err := corpus.ReadDir("corpus")
+	if err != nil {
+		panic(err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	ret := make([][]byte, 0, len(names))
+	for _, name := range names {
+		b, err := corpus.ReadFile("corpus/" + name)
+		if err != nil {
+			panic(err)
+		}
+		ret = append(ret, b)
+	}
+	return ret
+}
+
+func reader(data []byte) *textproto.Reader {
+	return textproto.NewReader(bufio.NewReader(bytes.NewReader(data)))
+}
+
+// fields returns the keys and values of h, without raw bytes
+func fields(h orderedheaders.Header) []orderedheaders.KV {
+	ret := make([]orderedheaders.KV, len(h.Headers))
+	for i, kv := range h.Headers {
+		ret[i] = orderedheaders.KV{Key: kv.Key, Value: kv.Value}
+	}
+	return ret
+}
+
+// CheckReadHeader reads data as a header in each of the ways the package
+// can, checking that none of them panic and that they all agree, and
+// that raw bytes kept by ReadOptions.KeepRaw are exactly those read.
+func CheckReadHeader(data []byte) error {
+	h, err := orderedheaders.ReadHeader(reader(data))
+
+	r := reader(data)
+	raw, rawErr := orderedheaders.ReadHeaderWithOptions(r, orderedheaders.ReadOptions{KeepRaw: true})
+	if (err == nil) != (rawErr == nil) {
+		return fmt.Errorf("ReadHeader returned %v, but with KeepRaw %v", err, rawErr)
+	}
+	if !reflect.DeepEqual(fields(h), fields(raw)) {
+		return fmt.Errorf("ReadHeader read %q, but with KeepRaw %q", fields(h), fields(raw))
+	}
+	if rawErr == nil {
+		var b []byte
+		for _, kv := range raw.Headers {
+			b = append(b, kv.Raw...)
+		}
+		if !bytes.HasPrefix(data, b) {
+			return fmt.Errorf("raw bytes %q aren't the start of the input", b)
+		}
+	}
+
+	var into orderedheaders.Header
+	intoErr := orderedheaders.ReadHeaderInto(reader(data), &into)
+	if (err == nil) != (intoErr == nil) {
+		return fmt.Errorf("ReadHeader returned %v, but ReadHeaderInto %v", err, intoErr)
+	}
+	if !reflect.DeepEqual(fields(h), fields(into)) {
+		return fmt.Errorf("ReadHeader read %q, but ReadHeaderInto %q", fields(h), fields(into))
+	}
+
+	_, _ = orderedheaders.ReadHeaderWithOptions(reader(data), orderedheaders.ReadOptions{MaxFields: 2, MaxFieldBytes: 16})
+	return nil
+}
+
+// writeOptions renders every field, so that nothing is dropped between
+// one pass and the next
+var writeOptions = orderedheaders.Options{RenderBCC: true, RenderBlank: true}
+
+// CheckRoundTrip checks that data read as a message is reproduced
+// exactly by Rewrite. It then reads data as a header, writes it and reads
+// it again, checking that what's written can be read and that a second
+// write makes no further changes. Input that can't be read, or a header
+// that WriteTo refuses to write, isn't an error.
+func CheckRoundTrip(data []byte) error {
+	var diff *orderedheaders.RoundTripError
+	if err := orderedheaders.VerifyRoundTrip(data); errors.As(err, &diff) {
+		return err
+	}
+	h, err := orderedheaders.ReadHeader(reader(data))
+	if err != nil {
+		return nil
+	}
+	first, err := h.Bytes(writeOptions)
+	if err != nil {
+		return nil
+	}
+	if err := checkWritten(first); err != nil {
+		return err
+	}
+	h2, err := orderedheaders.ReadHeader(reader(append(first, "\r\n"...)))
+	if err != nil {
+		return fmt.Errorf("can't read %q: %w", first, err)
+	}
+	second, err := h2.Bytes(writeOptions)
+	if err != nil {
+		return fmt.Errorf("can't write %q: %w", fields(h2), err)
+	}
+	h3, err := orderedheaders.ReadHeader(reader(append(second, "\r\n"...)))
+	if err != nil {
+		return fmt.Errorf("can't read %q: %w", second, err)
+	}
+	if !reflect.DeepEqual(fields(h2), fields(h3)) {
+		return fmt.Errorf("%q became %q when written again", fields(h2), fields(h3))
+	}
+	return nil
+}
+
+// CheckWriteHeaderFolding writes a single field, checking that the
+// output is one field, with CRLF line endings and no line longer than
+// 998 bytes. A key that isn't a valid field name, or a value that
+// WriteTo refuses to write, isn't an error.
+func CheckWriteHeaderFolding(key, value string) error {
+	if key == "" {
+		return nil
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 33 || key[i] > 126 || key[i] == ':' {
+			return nil
+		}
+	}
+	h := orderedheaders.Header{}
+	h.Add(key, value)
+	b, err := h.Bytes(writeOptions)
+	if err != nil {
+		return nil
+	}
+	if err := checkWritten(b); err != nil {
+		return err
+	}
+	lines := bytes.Split(bytes.TrimSuffix(b, []byte("\r\n")), []byte("\r\n"))
+	for i, line := range lines {
+		if i > 0 && (len(line) == 0 || (line[0] != ' ' && line[0] != '\t')) {
+			return fmt.Errorf("%q starts a new field", line)
+		}
+	}
+	if len(b) > 0 && !bytes.HasPrefix(b, []byte(textproto.CanonicalMIMEHeaderKey(key)+":")) {
+		return fmt.Errorf("%q doesn't start with the key", b)
+	}
+	return nil
+}
+
+// checkWritten checks that written header fields have CRLF line endings
+// and no line longer than 998 bytes
+func checkWritten(b []byte) error {
+	if len(b) == 0 {
+		return nil
+	}
+	if !bytes.HasSuffix(b, []byte("\r\n")) {
+		return fmt.Errorf("%q doesn't end with CRLF", b)
+	}
+	for _, line := range bytes.Split(bytes.TrimSuffix(b, []byte("\r\n")), []byte("\r\n")) {
+		if i := bytes.IndexAny(line, "\r\n"); i >= 0 {
+			return fmt.Errorf("bare CR or LF in %q", line)
+		}
+		if len(line) == 0 {
+			return fmt.Errorf("blank line in %q", b)
+		}
+		if len(line) > maxLine {
+			return fmt.Errorf("%d byte line %.40q...", len(line), line)
+		}
+	}
+	return nil
+}
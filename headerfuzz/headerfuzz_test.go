@@ -0,0 +1,52 @@
+package headerfuzz
+
+import (
+	"testing"
+
+	"github.com/wttw/orderedheaders"
+)
+
+// orderedheadersRead reads a corpus entry as a header
+func orderedheadersRead(b []byte) (orderedheaders.Header, error) {
+	return orderedheaders.ReadHeader(reader(b))
+}
+
+func TestCorpus(t *testing.T) {
+	inputs := Corpus()
+	if len(inputs) == 0 {
+		t.Fatal("empty corpus")
+	}
+	for i, b := range inputs {
+		if err := CheckReadHeader(b); err != nil {
+			t.Errorf("%d: CheckReadHeader: %v", i, err)
+		}
+		if err := CheckRoundTrip(b); err != nil {
+			t.Errorf("%d: CheckRoundTrip: %v", i, err)
+		}
+		h, err := orderedheadersRead(b)
+		if err != nil {
+			continue
+		}
+		for _, kv := range h.Headers {
+			if err := CheckWriteHeaderFolding(kv.Key, kv.Value); err != nil {
+				t.Errorf("%d: CheckWriteHeaderFolding: %v", i, err)
+			}
+		}
+	}
+}
+
+func TestCheckWriteHeaderFolding(t *testing.T) {
+	tests := []struct {
+		key, value string
+	}{
+		{"Subject", "a\r\nBcc: injected@example.com"},
+		{"X-Custom", "a\nb"},
+		{"X-Custom", "a\r\n\r\nbody"},
+		{"Subject", "a long value that needs folding, with an injected line\r\nBcc: injected@example.com and more words to make it longer"},
+	}
+	for _, tt := range tests {
+		if err := CheckWriteHeaderFolding(tt.key, tt.value); err != nil {
+			t.Errorf("%s: %q: %v", tt.key, tt.value, err)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Package headertest provides helpers for testing code that uses
+// orderedheaders: go-cmp options for comparing headers, a round trip
+// assertion, and generators of random valid headers.
+package headertest
+
+import (
+	"bufio"
+	"bytes"
+	"math/rand"
+	"net/textproto"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/wttw/orderedheaders"
+)
+
+// VolatileKeys are the fields that usually differ each time a message is
+// generated or delivered, and that IgnoreVolatile ignores
+var VolatileKeys = []string{
+	orderedheaders.HdrDate,
+	orderedheaders.HdrMessageId,
+	orderedheaders.HdrReceived,
+	orderedheaders.HdrResentDate,
+	orderedheaders.HdrResentMessageId,
+	orderedheaders.HdrReturnPath,
+}
+
+// IgnoreOrder compares the fields of headers without regard to their
+// order, though fields with the same key and value are still counted.
+func IgnoreOrder() cmp.Option {
+	return cmpopts.SortSlices(func(a, b orderedheaders.KV) bool {
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		return a.Value < b.Value
+	})
+}
+
+// IgnoreKeys ignores fields with any of the keys, in any case
+func IgnoreKeys(keys ...string) cmp.Option {
+	ignore := map[string]struct{}{}
+	for _, k := range keys {
+		ignore[textproto.CanonicalMIMEHeaderKey(k)] = struct{}{}
+	}
+	return cmpopts.IgnoreSliceElements(func(kv orderedheaders.KV) bool {
+		_, ok := ignore[textproto.CanonicalMIMEHeaderKey(kv.Key)]
+		return ok
+	})
+}
+
+// IgnoreVolatile ignores the fields in VolatileKeys
+func IgnoreVolatile() cmp.Option {
+	return IgnoreKeys(VolatileKeys...)
+}
+
+// IgnoreRaw ignores the raw bytes of fields, comparing only keys and
+// values
+func IgnoreRaw() cmp.Option {
+	return cmpopts.IgnoreFields(orderedheaders.KV{}, "Raw")
+}
+
+// Equivalent combines IgnoreOrder, IgnoreVolatile and IgnoreRaw, and
+// treats a nil list of fields as equal to an empty one, for checking
+// that two headers say the same thing.
+func Equivalent() cmp.Options {
+	return cmp.Options{IgnoreOrder(), IgnoreVolatile(), IgnoreRaw(), cmpopts.EquateEmpty()}
+}
+
+// writeOptions renders every field, so that nothing is dropped in a round
+// trip
+var writeOptions = orderedheaders.Options{RenderBCC: true, RenderBlank: true}
+
+// AssertRoundTrip checks that h can be written and read back unchanged,
+// reporting any difference through t. Raw bytes are ignored, as are any
+// differences opts allow. It returns whether the check passed.
+func AssertRoundTrip(t testing.TB, h orderedheaders.Header, opts ...cmp.Option) bool {
+	t.Helper()
+	b, err := h.Bytes(writeOptions)
+	if err != nil {
+		t.Errorf("writing header: %v", err)
+		return false
+	}
+	r := textproto.NewReader(bufio.NewReader(bytes.NewReader(append(b, "\r\n"...))))
+	got, err := orderedheaders.ReadHeader(r)
+	if err != nil {
+		t.Errorf("reading header back: %v\n%s", err, b)
+		return false
+	}
+	opts = append([]cmp.Option{IgnoreRaw(), cmpopts.EquateEmpty()}, opts...)
+	if diff := cmp.Diff(h, got, opts...); diff != "" {
+		t.Errorf("header changed in round trip (-want +got):\n%s", diff)
+		return false
+	}
+	return true
+}
+
+// RandomHeader returns a random header of up to n fields, as
+// orderedheaders.Header.Generate does, that survives AssertRoundTrip
+func RandomHeader(r *rand.Rand, n int) orderedheaders.Header {
+	return orderedheaders.Header{}.Generate(r, n).Interface().(orderedheaders.Header)
+}
@@ -0,0 +1,103 @@
+package headertest
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wttw/orderedheaders"
+)
+
+func TestOptions(t *testing.T) {
+	a := orderedheaders.Header{Headers: []orderedheaders.KV{
+		{Key: "From", Value: "alice@example.com", Raw: "From: alice@example.com\r\n"},
+		{Key: "To", Value: "bob@example.com"},
+		{Key: "Date", Value: "Mon, 1 Jan 2024 00:00:00 +0000"},
+		{Key: "Message-Id", Value: "<1@example.com>"},
+	}}
+	b := orderedheaders.Header{Headers: []orderedheaders.KV{
+		{Key: "To", Value: "bob@example.com"},
+		{Key: "Received", Value: "from a.example by b.example; Mon, 1 Jan 2024 00:00:01 +0000"},
+		{Key: "From", Value: "alice@example.com"},
+		{Key: "Message-Id", Value: "<2@example.com>"},
+	}}
+	tests := []struct {
+		name  string
+		opts  []cmp.Option
+		equal bool
+	}{
+		{"none", nil, false},
+		{"order", []cmp.Option{IgnoreOrder(), IgnoreRaw()}, false},
+		{"volatile", []cmp.Option{IgnoreVolatile(), IgnoreRaw()}, false},
+		{"order and volatile", []cmp.Option{IgnoreOrder(), IgnoreVolatile()}, false},
+		{"equivalent", []cmp.Option{Equivalent()}, true},
+		{"keys", []cmp.Option{IgnoreOrder(), IgnoreRaw(), IgnoreKeys("date", "MESSAGE-ID", "received")}, true},
+	}
+	for _, tt := range tests {
+		if got := cmp.Equal(a, b, tt.opts...); got != tt.equal {
+			t.Errorf("%s: cmp.Equal = %v, want %v", tt.name, got, tt.equal)
+		}
+	}
+
+	dup := orderedheaders.Header{Headers: []orderedheaders.KV{{Key: "To", Value: "bob@example.com"}, {Key: "To", Value: "bob@example.com"}}}
+	single := orderedheaders.Header{Headers: []orderedheaders.KV{{Key: "To", Value: "bob@example.com"}}}
+	if cmp.Equal(dup, single, Equivalent()) {
+		t.Errorf("Equivalent ignored a repeated field")
+	}
+}
+
+// recorder is a testing.TB that records failures rather than reporting
+// them
+type recorder struct {
+	testing.TB
+	failed string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failed = fmt.Sprintf(format, args...)
+}
+
+func TestAssertRoundTrip(t *testing.T) {
+	for seed := int64(0); seed < 200; seed++ {
+		h := RandomHeader(rand.New(rand.NewSource(seed)), 1+int(seed%30))
+		AssertRoundTrip(t, h)
+	}
+
+	tests := []struct {
+		name string
+		h    orderedheaders.Header
+		opts []cmp.Option
+		ok   bool
+	}{
+		{"empty", orderedheaders.Header{}, nil, true},
+		{"changed", orderedheaders.Header{Headers: []orderedheaders.KV{{Key: "To", Value: "bob@example.com"}}}, nil, false},
+		{"allowed", orderedheaders.Header{Headers: []orderedheaders.KV{{Key: "To", Value: "bob@example.com"}}}, []cmp.Option{IgnoreKeys("To")}, true},
+		{"unwritable", orderedheaders.Header{Headers: []orderedheaders.KV{{Key: "Bad Key", Value: "value"}}}, nil, false},
+	}
+	for _, tt := range tests {
+		r := &recorder{TB: t}
+		if got := AssertRoundTrip(r, tt.h, tt.opts...); got != tt.ok || (r.failed == "") != tt.ok {
+			t.Errorf("%s: AssertRoundTrip = %v, reported %q", tt.name, got, r.failed)
+		}
+	}
+}
+
+func TestRandomHeader(t *testing.T) {
+	a := RandomHeader(rand.New(rand.NewSource(1)), 50)
+	b := RandomHeader(rand.New(rand.NewSource(1)), 50)
+	if !cmp.Equal(a, b) {
+		t.Errorf("the same seed gave different headers")
+	}
+	seen := map[string]int{}
+	for _, kv := range a.Headers {
+		seen[kv.Key]++
+	}
+	for key, n := range seen {
+		if s, ok := orderedheaders.HeaderSyntax[key]; ok && s.Unique && n > 1 {
+			t.Errorf("%s appears %d times", key, n)
+		}
+	}
+}
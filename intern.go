@@ -0,0 +1,105 @@
+package orderedheaders
+
+import (
+	"net/textproto"
+	"strings"
+)
+
+// commonKeys are header field names seen often enough in mail to be
+// worth sharing, in addition to those in HeaderSyntax
+var commonKeys = []string{
+	HdrDKIMSignature,
+	HdrARCSeal,
+	HdrARCMessageSignature,
+	HdrARCAuthenticationResults,
+	HdrAuthenticationResults,
+	HdrReceivedSPF,
+	"Delivered-To",
+	"X-Original-To",
+	"Envelope-To",
+	"X-Mailer",
+	"User-Agent",
+	"Organization",
+	HdrThreadIndex,
+	HdrThreadTopic,
+	"Accept-Language",
+	"Content-Language",
+	HdrPrecedence,
+	HdrXPriority,
+	HdrImportance,
+	"Feedback-Id",
+	HdrListID,
+	HdrListUnsubscribe,
+	"List-Unsubscribe-Post",
+	"X-Spam-Status",
+	"X-Spam-Score",
+	"X-Ms-Exchange-Organization-Scl",
+}
+
+// internedKeys maps the lower case form of each common key to the
+// canonical string that every header shares
+var internedKeys = map[string]string{}
+
+func init() {
+	for key := range HeaderSyntax {
+		internedKeys[strings.ToLower(key)] = key
+	}
+	for _, key := range commonKeys {
+		internedKeys[strings.ToLower(key)] = key
+	}
+}
+
+// maxInternedKey is the length of the longest key that's looked up
+const maxInternedKey = 64
+
+// lookupKey returns the shared canonical string for a common key, in
+// any case, without allocating
+func lookupKey(key []byte) (string, bool) {
+	if len(key) > maxInternedKey {
+		return "", false
+	}
+	var lower [maxInternedKey]byte
+	for i, c := range key {
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		lower[i] = c
+	}
+	s, ok := internedKeys[string(lower[:len(key)])]
+	return s, ok
+}
+
+// KeyCache shares canonical key strings between the headers read with
+// it, for keys that aren't common enough to be shared by every reader,
+// such as those an application or a particular sender uses. It isn't
+// safe for concurrent use, so each reader needs its own.
+type KeyCache struct {
+	keys map[string]string
+	max  int
+}
+
+// NewKeyCache returns a KeyCache that holds up to max keys, after which
+// new keys are no longer added
+func NewKeyCache(max int) *KeyCache {
+	return &KeyCache{keys: map[string]string{}, max: max}
+}
+
+// canonicalKey returns the canonical form of key, as
+// textproto.CanonicalMIMEHeaderKey does, sharing the string with other
+// headers where it can
+func (c *KeyCache) canonicalKey(key []byte) string {
+	if s, ok := lookupKey(key); ok {
+		return s
+	}
+	if c == nil {
+		return textproto.CanonicalMIMEHeaderKey(string(key))
+	}
+	if s, ok := c.keys[string(key)]; ok {
+		return s
+	}
+	s := textproto.CanonicalMIMEHeaderKey(string(key))
+	if len(c.keys) < c.max {
+		c.keys[string(key)] = s
+	}
+	return s
+}
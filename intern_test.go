@@ -0,0 +1,104 @@
+package orderedheaders
+
+import (
+	"bufio"
+	"net/textproto"
+	"reflect"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+// sameString reports whether a and b share storage
+func sameString(a, b string) bool {
+	return (*reflect.StringHeader)(unsafe.Pointer(&a)).Data == (*reflect.StringHeader)(unsafe.Pointer(&b)).Data
+}
+
+func TestLookupKey(t *testing.T) {
+	for lower, key := range internedKeys {
+		for _, variant := range []string{key, lower, strings.ToUpper(key)} {
+			got, ok := lookupKey([]byte(variant))
+			if !ok || got != textproto.CanonicalMIMEHeaderKey(variant) {
+				t.Errorf("lookupKey(%q) = %q, %v", variant, got, ok)
+			}
+		}
+	}
+	for _, key := range []string{"X-Unusual", "audio mode", strings.Repeat("x", 100)} {
+		if got, ok := lookupKey([]byte(key)); ok {
+			t.Errorf("lookupKey(%q) = %q, expected no match", key, got)
+		}
+	}
+}
+
+func TestInternedKeys(t *testing.T) {
+	const input = "DKIM-Signature: v=1\r\nMIME-Version: 1.0\r\nX-Unusual: a\r\n\r\n"
+	cache := NewKeyCache(10)
+	var first, second Header
+	for _, h := range []*Header{&first, &second} {
+		var err error
+		*h, err = ReadHeaderWithOptions(reader(input), ReadOptions{Keys: cache})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{HdrDKIMSignature, HdrMimeVersion, "X-Unusual"}
+	for i, key := range want {
+		if first.Headers[i].Key != key || !sameString(first.Headers[i].Key, second.Headers[i].Key) {
+			t.Errorf("key %d: %q and %q not shared", i, first.Headers[i].Key, second.Headers[i].Key)
+		}
+	}
+
+	full := NewKeyCache(0)
+	a, _ := ReadHeaderWithOptions(reader(input), ReadOptions{Keys: full})
+	b, _ := ReadHeaderWithOptions(reader(input), ReadOptions{Keys: full})
+	if sameString(a.Headers[2].Key, b.Headers[2].Key) {
+		t.Errorf("full cache shared a key")
+	}
+}
+
+func TestReadHeaderIntoInternedAllocs(t *testing.T) {
+	const input = "DKIM-Signature: v=1\r\nMIME-Version: 1.0\r\nCONTENT-TYPE: text/plain\r\n\r\n"
+	sr := strings.NewReader(input)
+	br := bufio.NewReader(sr)
+	tp := textproto.NewReader(br)
+	h := Header{Headers: make([]KV, 0, 8)}
+	allocs := testing.AllocsPerRun(100, func() {
+		sr.Reset(input)
+		br.Reset(sr)
+		if err := ReadHeaderInto(tp, &h); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs > 1 {
+		t.Errorf("ReadHeaderInto made %v allocations, want 1", allocs)
+	}
+}
+
+var benchmarkHeader = strings.Repeat("Received: from a.example by b.example; Mon, 1 Jan 2024 00:00:00 +0000\r\n", 4) +
+	"DKIM-Signature: v=1; a=rsa-sha256; d=example.com; s=sel;\r\n b=abc\r\n" +
+	"MIME-Version: 1.0\r\nFrom: alice@example.com\r\nTo: bob@example.com\r\n" +
+	"Subject: hello\r\nMessage-ID: <1@example.com>\r\nContent-Type: text/plain\r\n\r\n"
+
+func BenchmarkReadHeader(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := ReadHeader(reader(benchmarkHeader)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReadHeaderInto(b *testing.B) {
+	b.ReportAllocs()
+	sr := strings.NewReader(benchmarkHeader)
+	br := bufio.NewReader(sr)
+	tp := textproto.NewReader(br)
+	var h Header
+	for i := 0; i < b.N; i++ {
+		sr.Reset(benchmarkHeader)
+		br.Reset(sr)
+		if err := ReadHeaderInto(tp, &h); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
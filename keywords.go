@@ -0,0 +1,168 @@
+package orderedheaders
+
+import (
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// https://tools.wordtothewise.com/rfc5322#section-3.6.5
+
+// Keywords returns the phrases from every Keywords header, in order,
+// with quoting removed and encoded-words decoded.
+func (h *Header) Keywords() []string {
+	h.CanonicalizeKeys()
+	var ret []string
+	for _, kv := range h.Headers {
+		if kv.Key != HdrKeywords {
+			continue
+		}
+		ret = append(ret, parsePhraseList(kv.Value)...)
+	}
+	return ret
+}
+
+// AddKeyword adds a phrase to the Keywords, if it is not already present.
+// Multiple Keywords headers are combined into one.
+func (h *Header) AddKeyword(keyword string) {
+	keyword = strings.Join(strings.Fields(keyword), " ")
+	if keyword == "" {
+		return
+	}
+	keywords := h.Keywords()
+	for _, k := range keywords {
+		if strings.EqualFold(k, keyword) {
+			return
+		}
+	}
+	h.setKeywords(append(keywords, keyword))
+}
+
+// RemoveKeyword removes a phrase from the Keywords, comparing case
+// insensitively, and reports whether it was present. Multiple Keywords
+// headers are combined into one.
+func (h *Header) RemoveKeyword(keyword string) bool {
+	keyword = strings.Join(strings.Fields(keyword), " ")
+	keywords := h.Keywords()
+	filtered := keywords[:0]
+	for _, k := range keywords {
+		if !strings.EqualFold(k, keyword) {
+			filtered = append(filtered, k)
+		}
+	}
+	if len(filtered) == len(keywords) {
+		return false
+	}
+	h.setKeywords(filtered)
+	return true
+}
+
+func (h *Header) setKeywords(keywords []string) {
+	phrases := make([]string, len(keywords))
+	for i, k := range keywords {
+		phrases[i] = formatPhrase(k)
+	}
+	h.replaceAll(HdrKeywords, strings.Join(phrases, ", "))
+}
+
+// formatPhrase renders s as an RFC 5322 phrase, quoting or encoding it
+// as needed
+func formatPhrase(s string) string {
+	if !isAscii(s) {
+		return mime.QEncoding.Encode(utf8, s)
+	}
+	return QuoteString(s)
+}
+
+// parsePhraseList splits a comma separated list of phrases, removing
+// comments and quoting, collapsing whitespace and decoding encoded-words.
+// Empty list elements are dropped.
+func parsePhraseList(s string) []string {
+	var ret []string
+	var b strings.Builder
+	flush := func() {
+		phrase := strings.Join(strings.Fields(b.String()), " ")
+		b.Reset()
+		if phrase == "" {
+			return
+		}
+		dec := new(mime.WordDecoder)
+		if decoded, err := dec.DecodeHeader(phrase); err == nil {
+			phrase = decoded
+		}
+		ret = append(ret, phrase)
+	}
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case ',':
+			flush()
+			i++
+		case '"':
+			quoted, next, err := readQuotedString(s, i)
+			if err != nil {
+				quoted = s[i+1:]
+			}
+			b.WriteString(quoted)
+			i = next
+		case '(':
+			_, next, err := readComment(s, i)
+			if err != nil {
+				next = len(s)
+			}
+			b.WriteByte(' ')
+			i = next
+		default:
+			b.WriteByte(s[i])
+			i++
+		}
+	}
+	flush()
+	return ret
+}
+
+// checkPhraseList checks a value is a comma separated list of phrases,
+// each one or more words, which are atoms or quoted strings, with
+// optional comments. Specials other than the dots of an obsolete phrase
+// such as "Mr. Smith" must be quoted. Non-ascii text is allowed, as
+// RFC 6532 allows and encoding repairs.
+// https://tools.wordtothewise.com/rfc5322#section-3.2.5
+// https://tools.wordtothewise.com/rfc5322#section-4.1
+func checkPhraseList(value string) error {
+	words := 0
+	for i := 0; ; {
+		var err error
+		i, err = skipCFWS(value, i)
+		if err != nil {
+			return fmt.Errorf("'%s' is not a valid list of phrases: %w", value, err)
+		}
+		if i >= len(value) || value[i] == ',' {
+			if words == 0 {
+				return fmt.Errorf("'%s' is not a valid list of phrases: empty phrase", value)
+			}
+			if i >= len(value) {
+				return nil
+			}
+			words = 0
+			i++
+			continue
+		}
+		c := value[i]
+		switch {
+		case c == '"':
+			_, i, err = readQuotedString(value, i)
+			if err != nil {
+				return fmt.Errorf("'%s' is not a valid list of phrases: %w", value, err)
+			}
+		case c == '.' && words > 0:
+			i++
+			continue
+		case isAtextChar(c) || c > 127:
+			for i < len(value) && (isAtextChar(value[i]) || value[i] > 127) {
+				i++
+			}
+		default:
+			return fmt.Errorf("'%s' is not a valid list of phrases: '%c' must be quoted", value, c)
+		}
+		words++
+	}
+}
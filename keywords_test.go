@@ -0,0 +1,120 @@
+package orderedheaders
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestKeywords(t *testing.T) {
+	h := &Header{}
+	h.Add("Subject", "hello")
+	h.Add("Keywords", `budget, "Q3, 2023" (quarter), =?utf-8?q?caf=C3=A9?=`)
+	h.Add("Date", "Mon, 22 May 2023 10:00:00 +0000")
+	h.Add("Keywords", "  planning\r\n  meeting ,,")
+	want := []string{"budget", "Q3, 2023", "café", "planning meeting"}
+	if diff := cmp.Diff(want, h.Keywords()); diff != "" {
+		t.Errorf("Keywords mismatch (-want +got):\n%s", diff)
+	}
+
+	h.AddKeyword("BUDGET")
+	h.AddKeyword("Bob's list")
+	if !h.RemoveKeyword("planning meeting") {
+		t.Errorf("expected planning meeting to be removed")
+	}
+	if h.RemoveKeyword("absent") {
+		t.Errorf("didn't expect absent to be removed")
+	}
+	wantHeaders := []KV{
+		{Key: "Subject", Value: "hello"},
+		{Key: "Keywords", Value: `budget, "Q3, 2023", =?utf-8?q?caf=C3=A9?=, Bob's list`},
+		{Key: "Date", Value: "Mon, 22 May 2023 10:00:00 +0000"},
+	}
+	if diff := cmp.Diff(wantHeaders, h.Headers); diff != "" {
+		t.Errorf("headers mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCheckPhraseList(t *testing.T) {
+	tests := map[string]string{
+		"budget":                           "",
+		"budget, planning meeting":         "",
+		`"Q3, 2023" (quarter), Bob's list`: "",
+		"=?utf-8?q?caf=C3=A9?=, tea":       "",
+		"Mr. Smith, J. R. R. Tolkien":      "",
+		"café, thé":                        "",
+		"(all comment)":                    "empty phrase",
+		"a, , b":                           "empty phrase",
+		"a,":                               "empty phrase",
+		".net":                             "'.' must be quoted",
+		"a <b>":                            "'<' must be quoted",
+		"a: b":                             "':' must be quoted",
+		"a@b":                              "'@' must be quoted",
+		`"unterminated`:                    "unterminated quoted-string",
+		"a (unterminated":                  "unterminated comment",
+		`a "b, c" (d, e) f, g`:             "",
+		`"Q3\" 2023"`:                      "",
+	}
+	for in, want := range tests {
+		err := checkPhraseList(in)
+		switch {
+		case want == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", in, err)
+		case want != "" && (err == nil || !strings.Contains(err.Error(), want)):
+			t.Errorf("%s: want error containing '%s', got %v", in, want, err)
+		}
+	}
+
+	var h Header
+	if err := h.Set(HdrKeywords, "a; b"); err == nil {
+		t.Error("Set accepted an invalid phrase list")
+	}
+}
+
+func TestWritePhraseList(t *testing.T) {
+	tests := map[string]struct {
+		Value string
+		Want  string
+	}{
+		"short": {
+			Value: "budget, planning",
+			Want:  "Keywords: budget, planning\r\n",
+		},
+		"phrase boundaries": {
+			Value: "quarterly budget review, project planning meeting, lunch on friday, status update",
+			Want:  "Keywords: quarterly budget review, project planning meeting, lunch on friday,\r\n status update\r\n",
+		},
+		"whole phrase moves": {
+			Value: `"the quick, brown fox" jumps, over the lazy dog and keeps running onwards quickly`,
+			Want:  "Keywords: \"the quick, brown fox\" jumps,\r\n over the lazy dog and keeps running onwards quickly\r\n",
+		},
+		"long phrase": {
+			Value: "alpha, " + strings.Repeat("word ", 20) + "end",
+			Want: "Keywords: alpha,\r\n" +
+				" word word word word word word word word word word word word word word word\r\n" +
+				" word word word word word end\r\n",
+		},
+		"non-ascii": {
+			Value: "café, tea",
+			Want:  "Keywords: =?utf-8?q?caf=C3=A9?=, tea\r\n",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeHeader(&buf, HeaderTypePhraseList, HdrKeywords, test.Value, Options{}); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != test.Want {
+				t.Errorf("want %q, got %q", test.Want, buf.String())
+			}
+			for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+				if len(line) > 78 {
+					t.Errorf("line is %d characters: %q", len(line), line)
+				}
+			}
+		})
+	}
+}
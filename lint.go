@@ -0,0 +1,350 @@
+package orderedheaders
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//go:generate enumer -json -trimprefix=Severity -transform=kebab -type Severity
+
+// Severity is how serious a LintIssue is
+type Severity int
+
+const (
+	// SeverityError is a violation of the standards that may cause the
+	// message to be rejected or misread
+	SeverityError Severity = iota
+	// SeverityWarning is legal, but likely to cause problems
+	SeverityWarning
+)
+
+// LintIssue is a problem found by Lint
+type LintIssue struct {
+	// Field is the index into Headers of the field with the problem,
+	// or -1 if it's a problem with the header as a whole
+	Field    int
+	Key      string
+	Rule     string
+	Severity Severity
+	Message  string
+}
+
+// Error describes the issue, so that it can be returned as an error
+func (i LintIssue) Error() string {
+	if i.Key == "" {
+		return fmt.Sprintf("%s: %s [%s]", i.Severity, i.Message, i.Rule)
+	}
+	return fmt.Sprintf("%s: %s: %s [%s]", i.Severity, i.Key, i.Message, i.Rule)
+}
+
+// DefaultMaxHeaderBytes is the largest header, in bytes, that Lint
+// accepts. Many MTAs reject messages with larger headers.
+const DefaultMaxHeaderBytes = 32 * 1024
+
+// LintOptions configures LintWithOptions
+type LintOptions struct {
+	// MaxHeaderBytes is the largest header, in bytes, that's accepted,
+	// defaulting to DefaultMaxHeaderBytes. Negative means no limit.
+	MaxHeaderBytes int
+}
+
+// LintRule is a named check run by Lint
+type LintRule struct {
+	Name  string
+	Check func(h *Header) []LintIssue
+}
+
+// LintRules are the checks Lint runs, in order. Applications can add
+// their own.
+var LintRules = []LintRule{
+	{"field-name", lintFieldNames},
+	{"syntax", lintSyntax},
+	{"required", lintRequired},
+	{"unique", lintUnique},
+	{"line-length", lintLineLength},
+	{"8bit", lint8bit},
+	{"arc", lintARC},
+	{"order", lintOrder},
+}
+
+// Lint runs LintRules against the header, returning every issue found,
+// and checks its size is no more than DefaultMaxHeaderBytes. Line
+// lengths and size are checked against the raw bytes if they were kept.
+func (h *Header) Lint() []LintIssue {
+	return h.LintWithOptions(LintOptions{})
+}
+
+// LintWithOptions lints the header, as Lint, configured by o.
+func (h *Header) LintWithOptions(o LintOptions) []LintIssue {
+	h.CanonicalizeKeys()
+	var issues []LintIssue
+	for _, r := range LintRules {
+		for _, issue := range r.Check(h) {
+			if issue.Rule == "" {
+				issue.Rule = r.Name
+			}
+			issues = append(issues, issue)
+		}
+	}
+	max := o.MaxHeaderBytes
+	if max == 0 {
+		max = DefaultMaxHeaderBytes
+	}
+	return append(issues, lintSize(h, max)...)
+}
+
+// Validate returns the first error severity issue found by Lint, as a
+// LintIssue, or nil if there are none
+func (h *Header) Validate() error {
+	return h.ValidateWithOptions(LintOptions{})
+}
+
+// ValidateWithOptions validates the header, as Validate, configured by o.
+func (h *Header) ValidateWithOptions(o LintOptions) error {
+	for _, issue := range h.LintWithOptions(o) {
+		if issue.Severity == SeverityError {
+			return issue
+		}
+	}
+	return nil
+}
+
+func lintFieldNames(h *Header) []LintIssue {
+	var issues []LintIssue
+	for i, kv := range h.Headers {
+		if !validFieldName(kv.Key) {
+			issues = append(issues, LintIssue{Field: i, Key: kv.Key, Message: fmt.Sprintf("'%s' is not a valid header field name", kv.Key)})
+		}
+	}
+	return issues
+}
+
+// https://tools.wordtothewise.com/rfc5322#section-3.6
+
+func lintSyntax(h *Header) []LintIssue {
+	var issues []LintIssue
+	for i, kv := range h.Headers {
+		if kv.Value == "" {
+			continue
+		}
+		if syntax, ok := HeaderSyntax[kv.Key]; ok {
+			// UTF-8 in Received is only a warning, from lint8bit
+			if err := checkHeaderUTF8(syntax.Type, kv.Value); err != nil {
+				issues = append(issues, LintIssue{Field: i, Key: kv.Key, Message: err.Error()})
+				continue
+			}
+		}
+		if validate, ok := HeaderValidators[kv.Key]; ok {
+			if err := validate(kv.Value); err != nil {
+				issues = append(issues, LintIssue{Field: i, Key: kv.Key, Message: err.Error()})
+			}
+		}
+	}
+	return issues
+}
+
+func lintRequired(h *Header) []LintIssue {
+	var required []string
+	for key, syntax := range HeaderSyntax {
+		if syntax.Required {
+			required = append(required, key)
+		}
+	}
+	sort.Strings(required)
+	var issues []LintIssue
+	for _, key := range required {
+		if !h.Has(key) {
+			issues = append(issues, LintIssue{Field: -1, Key: key, Message: "required header is missing"})
+		}
+	}
+	return issues
+}
+
+func lintUnique(h *Header) []LintIssue {
+	var issues []LintIssue
+	seen := map[string]struct{}{}
+	for i, kv := range h.Headers {
+		if !HeaderSyntax[kv.Key].Unique {
+			continue
+		}
+		if _, ok := seen[kv.Key]; ok {
+			issues = append(issues, LintIssue{Field: i, Key: kv.Key, Message: "may only appear once"})
+			continue
+		}
+		seen[kv.Key] = struct{}{}
+	}
+	return issues
+}
+
+// https://tools.wordtothewise.com/rfc5322#section-2.1.1
+
+func lintLineLength(h *Header) []LintIssue {
+	var issues []LintIssue
+	for i, kv := range h.Headers {
+		if kv.Raw != "" {
+			for _, line := range strings.Split(kv.Raw, "\n") {
+				if len(strings.TrimSuffix(line, "\r")) > maxLineLength {
+					issues = append(issues, LintIssue{Field: i, Key: kv.Key, Message: fmt.Sprintf("line is longer than %d characters", maxLineLength)})
+					break
+				}
+			}
+			continue
+		}
+		headerType := HeaderTypeOpaque
+		if syntax, ok := HeaderSyntax[kv.Key]; ok {
+			headerType = syntax.Type
+		}
+		if !fieldFoldable(headerType, kv.Key, kv.Value) {
+			issues = append(issues, LintIssue{Field: i, Key: kv.Key, Message: fmt.Sprintf("can't be folded into %d character lines", maxLineLength)})
+		}
+	}
+	return issues
+}
+
+// lint8bit warns about raw UTF-8 in fields that syntax checks allow it
+// in, including Received, as it's only valid with SMTPUTF8
+// https://tools.wordtothewise.com/rfc6532#section-3
+func lint8bit(h *Header) []LintIssue {
+	var issues []LintIssue
+	for i, kv := range h.Headers {
+		if isAscii(kv.Value) {
+			continue
+		}
+		if syntax, ok := HeaderSyntax[kv.Key]; ok {
+			switch syntax.Type {
+			case HeaderTypeUnstructured, HeaderTypePhraseList, HeaderTypeReceived:
+			default:
+				continue
+			}
+		}
+		issues = append(issues, LintIssue{Field: i, Key: kv.Key, Severity: SeverityWarning, Message: "contains unencoded non-ascii characters"})
+	}
+	return issues
+}
+
+// lintSize checks the header isn't larger than max bytes, measuring it
+// as read if every field has its raw bytes, or as WriteTo would render
+// it otherwise
+func lintSize(h *Header, max int) []LintIssue {
+	if max < 0 {
+		return nil
+	}
+	size := 0
+	for _, kv := range h.Headers {
+		if kv.Raw == "" {
+			size = -1
+			break
+		}
+		size += len(kv.Raw)
+	}
+	if size < 0 {
+		var err error
+		size, err = h.WireSize(Options{})
+		if err != nil {
+			// fields that can't be written are reported by other rules
+			return nil
+		}
+	}
+	if size > max {
+		return []LintIssue{{Field: -1, Rule: "size", Message: fmt.Sprintf("header is %d bytes, more than the %d allowed", size, max)}}
+	}
+	return nil
+}
+
+func lintARC(h *Header) []LintIssue {
+	if _, err := h.ARCSets(); err != nil {
+		return []LintIssue{{Field: -1, Message: err.Error()}}
+	}
+	return nil
+}
+
+// rfc5322Fields are the originator, destination, identification and
+// informational fields, which the message author writes
+var rfc5322Fields = map[string]struct{}{
+	HdrDate:       {},
+	HdrFrom:       {},
+	HdrSender:     {},
+	HdrReplyTo:    {},
+	HdrTo:         {},
+	HdrCc:         {},
+	HdrBcc:        {},
+	HdrMessageId:  {},
+	HdrInReplyTo:  {},
+	HdrReferences: {},
+	HdrSubject:    {},
+	HdrComments:   {},
+	HdrKeywords:   {},
+}
+
+// lintOrder warns about fields in an order that software handling the
+// message wouldn't have written, which suggests they've been added or
+// moved afterwards. Trace and resent fields are added at the top, above
+// the fields the author wrote, each resent block is written at once, and
+// the Content-* fields follow the RFC 5322 fields. MIME-Version isn't
+// checked, as some widely used mail clients write it first. Other fields
+// are ignored, as they're added alongside trace fields as often as by the
+// author.
+// https://tools.wordtothewise.com/rfc5322#section-3.6
+func lintOrder(h *Header) []LintIssue {
+	var issues []LintIssue
+	warn := func(i int, format string, args ...interface{}) {
+		issues = append(issues, LintIssue{Field: i, Key: h.Headers[i].Key, Severity: SeverityWarning, Message: fmt.Sprintf(format, args...)})
+	}
+	author, mime := -1, -1
+	for i, kv := range h.Headers {
+		_, authored := rfc5322Fields[kv.Key]
+		switch {
+		case kv.Key == HdrReturnPath || kv.Key == HdrReceived:
+			if author >= 0 {
+				warn(i, "trace field is below %s", h.Headers[author].Key)
+			}
+		case isResent(kv.Key):
+			if author >= 0 {
+				warn(i, "resent field is below %s", h.Headers[author].Key)
+			}
+		case authored:
+			if author < 0 {
+				author = i
+			}
+			if mime >= 0 {
+				warn(i, "is below MIME field %s", h.Headers[mime].Key)
+			}
+		case strings.HasPrefix(kv.Key, "Content-"):
+			if mime < 0 {
+				mime = i
+			}
+		}
+	}
+
+	// each run of resent fields should be a whole block, so a run without
+	// the required fields is part of a block that's been split up
+	// https://tools.wordtothewise.com/rfc5322#section-3.6.6
+	start := -1
+	var seen map[string]struct{}
+	endRun := func() {
+		if start < 0 {
+			return
+		}
+		for _, key := range []string{HdrResentDate, HdrResentFrom} {
+			if _, ok := seen[key]; !ok {
+				warn(start, "resent block has no %s, so may have been split", key)
+			}
+		}
+		start = -1
+	}
+	for i, kv := range h.Headers {
+		if !isResent(kv.Key) {
+			endRun()
+			continue
+		}
+		if _, ok := seen[kv.Key]; start < 0 || ok {
+			endRun()
+			start = i
+			seen = map[string]struct{}{}
+		}
+		seen[kv.Key] = struct{}{}
+	}
+	endRun()
+	return issues
+}
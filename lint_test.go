@@ -0,0 +1,186 @@
+package orderedheaders
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLint(t *testing.T) {
+	valid := []KV{
+		{Key: "From", Value: "alice@example.com"},
+		{Key: "Date", Value: "Mon, 1 Jan 2024 00:00:00 +0000"},
+	}
+	long := strings.Repeat("x", 1000)
+	tests := map[string]struct {
+		Headers []KV
+		Want    []LintIssue
+	}{
+		"valid": {Headers: valid},
+		"missing": {Headers: valid[:1], Want: []LintIssue{
+			{Field: -1, Key: "Date", Rule: "required", Message: "required header is missing"},
+		}},
+		"field name": {Headers: append([]KV{{Key: "Bad Key", Value: "x"}}, valid...), Want: []LintIssue{
+			{Field: 0, Key: "Bad Key", Rule: "field-name", Message: "'Bad Key' is not a valid header field name"},
+		}},
+		"syntax": {Headers: append([]KV{{Key: "Message-Id", Value: "nope"}}, valid...), Want: []LintIssue{
+			{Field: 0, Key: "Message-Id", Rule: "syntax", Message: checkHeader(HeaderTypeMessageID, "nope").Error()},
+		}},
+		"validator": {Headers: append([]KV{{Key: "Feedback-Id", Value: "a b"}}, valid...), Want: []LintIssue{
+			{Field: 0, Key: "Feedback-Id", Rule: "syntax", Message: ValidateFeedbackID("a b").Error()},
+		}},
+		"unique": {Headers: append(valid, KV{Key: "From", Value: "bob@example.com"}), Want: []LintIssue{
+			{Field: 2, Key: "From", Rule: "unique", Message: "may only appear once"},
+		}},
+		"unfoldable": {Headers: append(valid, KV{Key: "X-Long", Value: long}), Want: []LintIssue{
+			{Field: 2, Key: "X-Long", Rule: "line-length", Message: "can't be folded into 998 character lines"},
+		}},
+		"raw line": {Headers: append(valid, KV{Key: "X-Long", Value: "a b", Raw: "X-Long: a\r\n " + long + "\r\n"}), Want: []LintIssue{
+			{Field: 2, Key: "X-Long", Rule: "line-length", Message: "line is longer than 998 characters"},
+		}},
+		"8bit": {Headers: append(valid, KV{Key: "Subject", Value: "café"}, KV{Key: "X-Note", Value: "café"}), Want: []LintIssue{
+			{Field: 2, Key: "Subject", Rule: "8bit", Severity: SeverityWarning, Message: "contains unencoded non-ascii characters"},
+			{Field: 3, Key: "X-Note", Rule: "8bit", Severity: SeverityWarning, Message: "contains unencoded non-ascii characters"},
+		}},
+		"received": {Headers: append([]KV{{Key: "Received", Value: "by mx.example.com for <用户@例子.广告>"}, {Key: "Received", Value: "by \xff"}}, valid...), Want: []LintIssue{
+			{Field: 1, Key: "Received", Rule: "syntax", Message: "is not valid UTF-8"},
+			{Field: 0, Key: "Received", Rule: "8bit", Severity: SeverityWarning, Message: "contains unencoded non-ascii characters"},
+			{Field: 1, Key: "Received", Rule: "8bit", Severity: SeverityWarning, Message: "contains unencoded non-ascii characters"},
+		}},
+		"arc": {Headers: append(valid, KV{Key: "Arc-Seal", Value: "i=0"}), Want: []LintIssue{
+			{Field: -1, Rule: "arc", Message: parseARCSealError("i=0")},
+		}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := Header{Headers: test.Headers}
+			if diff := cmp.Diff(test.Want, h.Lint()); diff != "" {
+				t.Errorf("Lint mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func parseARCSealError(value string) string {
+	_, err := ParseARCSeal(value)
+	return err.Error()
+}
+
+func TestValidate(t *testing.T) {
+	h := Header{Headers: []KV{
+		{Key: "From", Value: "alice@example.com"},
+		{Key: "Date", Value: "Mon, 1 Jan 2024 00:00:00 +0000"},
+		{Key: "X-Note", Value: "café"},
+	}}
+	if err := h.Validate(); err != nil {
+		t.Errorf("warnings shouldn't fail validation: %v", err)
+	}
+	h.Add("Date", "Tue, 2 Jan 2024 00:00:00 +0000")
+	err := h.Validate()
+	var issue LintIssue
+	if !errors.As(err, &issue) {
+		t.Fatalf("want a LintIssue, got %v", err)
+	}
+	if issue.Rule != "unique" || issue.Field != 3 {
+		t.Errorf("got %+v", issue)
+	}
+	if want := "error: Date: may only appear once [unique]"; err.Error() != want {
+		t.Errorf("want '%s', got '%s'", want, err.Error())
+	}
+}
+
+func TestLintRules(t *testing.T) {
+	LintRules = append(LintRules, LintRule{"no-x-mailer", func(h *Header) []LintIssue {
+		if h.Has("X-Mailer") {
+			return []LintIssue{{Field: -1, Severity: SeverityWarning, Message: "discloses the mail client"}}
+		}
+		return nil
+	}})
+	defer func() { LintRules = LintRules[:len(LintRules)-1] }()
+	h := Header{Headers: []KV{{Key: "X-Mailer", Value: "mutt"}}}
+	issues := h.Lint()
+	if len(issues) == 0 || issues[len(issues)-1].Rule != "no-x-mailer" {
+		t.Errorf("custom rule didn't run: %+v", issues)
+	}
+}
+
+func TestLintSize(t *testing.T) {
+	h := &Header{Headers: []KV{
+		{Key: "From", Value: "alice@example.com"},
+		{Key: "Date", Value: "Mon, 1 Jan 2024 00:00:00 +0000"},
+	}}
+	for i := 0; i < 400; i++ {
+		h.Add("Received", "from a.example.com by b.example.com with ESMTPS id abc123; Mon, 1 Jan 2024 00:00:00 +0000")
+	}
+	size, err := h.WireSize(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = h.Validate()
+	var issue LintIssue
+	if !errors.As(err, &issue) || issue.Rule != "size" {
+		t.Fatalf("want a size issue, got %v", err)
+	}
+	if want := fmt.Sprintf("header is %d bytes, more than the %d allowed", size, DefaultMaxHeaderBytes); issue.Message != want {
+		t.Errorf("want '%s', got '%s'", want, issue.Message)
+	}
+
+	if err := h.ValidateWithOptions(LintOptions{MaxHeaderBytes: -1}); err != nil {
+		t.Errorf("no limit, but got %v", err)
+	}
+
+	raw := &Header{Headers: []KV{
+		{Key: "From", Value: "alice@example.com", Raw: "From: alice@example.com\r\n"},
+		{Key: "Date", Value: "Mon, 1 Jan 2024 00:00:00 +0000", Raw: "Date: Mon, 1 Jan 2024 00:00:00 +0000\r\n"},
+		{Key: "Subject", Value: "hi", Raw: "Subject:\r\n" + strings.Repeat(" ", 60) + "hi\r\n"},
+	}}
+	if err := raw.ValidateWithOptions(LintOptions{MaxHeaderBytes: 100}); err == nil {
+		t.Errorf("raw size wasn't measured")
+	}
+}
+
+func TestLintOrder(t *testing.T) {
+	kvs := func(keys ...string) []KV {
+		h := make([]KV, len(keys))
+		for i, k := range keys {
+			h[i] = KV{Key: k}
+		}
+		return h
+	}
+	warn := func(i int, key, msg string) LintIssue {
+		return LintIssue{Field: i, Key: key, Severity: SeverityWarning, Message: msg}
+	}
+	tests := map[string]struct {
+		Headers []KV
+		Want    []LintIssue
+	}{
+		"conventional": {Headers: kvs("Return-Path", "Received", "Dkim-Signature", "Received", "Resent-Date", "Resent-From", "Received",
+			"Mime-Version", "Date", "From", "Subject", "X-Mailer", "Content-Type")},
+		"two resent blocks": {Headers: kvs("Resent-Date", "Resent-From", "Received", "Resent-From", "Resent-Date", "Resent-To", "From")},
+		"trace below": {Headers: kvs("Received", "From", "Received"), Want: []LintIssue{
+			warn(2, "Received", "trace field is below From"),
+		}},
+		"resent below": {Headers: kvs("Subject", "Resent-Date", "Resent-From"), Want: []LintIssue{
+			warn(1, "Resent-Date", "resent field is below Subject"),
+			warn(2, "Resent-From", "resent field is below Subject"),
+		}},
+		"mime above": {Headers: kvs("Content-Type", "Mime-Version", "From"), Want: []LintIssue{
+			warn(2, "From", "is below MIME field Content-Type"),
+		}},
+		"split block": {Headers: kvs("Resent-Date", "X-Inserted", "Resent-From", "From"), Want: []LintIssue{
+			warn(0, "Resent-Date", "resent block has no Resent-From, so may have been split"),
+			warn(2, "Resent-From", "resent block has no Resent-Date, so may have been split"),
+		}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := Header{Headers: test.Headers}
+			if diff := cmp.Diff(test.Want, lintOrder(&h)); diff != "" {
+				t.Errorf("lintOrder mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
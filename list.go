@@ -0,0 +1,429 @@
+package orderedheaders
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/mail"
+	"net/url"
+	"strings"
+)
+
+// https://tools.wordtothewise.com/rfc2369
+// https://tools.wordtothewise.com/rfc2919
+
+const (
+	HdrListID          = "List-Id"
+	HdrListUnsubscribe = "List-Unsubscribe"
+	HdrListSubscribe   = "List-Subscribe"
+	HdrListPost        = "List-Post"
+	HdrListHelp        = "List-Help"
+	HdrListArchive     = "List-Archive"
+	HdrListOwner       = "List-Owner"
+)
+
+// ListHeaders describes the headers a mailing list adds to a message
+type ListHeaders struct {
+	// ID is the list identifier, e.g. "announce.example.com"
+	ID string
+	// Description is an optional human readable name for the list
+	Description string
+	// The remaining fields are lists of URIs, most preferred first
+	Unsubscribe []string
+	Subscribe   []string
+	Post        []string
+	Help        []string
+	Archive     []string
+	Owner       []string
+	// NoPost renders List-Post as "NO", for announcement-only lists
+	NoPost bool
+}
+
+// SetListHeaders sets the List-* headers described by l, replacing any
+// existing ones. Headers with no value in l are removed.
+func (h *Header) SetListHeaders(l ListHeaders) error {
+	values := map[string]string{}
+	if l.ID != "" {
+		id, err := formatListID(l.Description, l.ID)
+		if err != nil {
+			return err
+		}
+		values[HdrListID] = id
+	} else if l.Description != "" {
+		return errors.New("List-Id description given without an identifier")
+	}
+	uris := []struct {
+		key  string
+		uris []string
+	}{
+		{HdrListHelp, l.Help},
+		{HdrListUnsubscribe, l.Unsubscribe},
+		{HdrListSubscribe, l.Subscribe},
+		{HdrListPost, l.Post},
+		{HdrListOwner, l.Owner},
+		{HdrListArchive, l.Archive},
+	}
+	for _, u := range uris {
+		if len(u.uris) == 0 {
+			continue
+		}
+		value, err := formatURIList(u.uris)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %w", u.key, err)
+		}
+		values[u.key] = value
+	}
+	if l.NoPost {
+		if len(l.Post) != 0 {
+			return errors.New("List-Post cannot have both URIs and NO")
+		}
+		values[HdrListPost] = "NO"
+	}
+	for _, key := range []string{HdrListID, HdrListHelp, HdrListUnsubscribe, HdrListSubscribe, HdrListPost, HdrListOwner, HdrListArchive} {
+		h.replaceAll(key, values[key])
+	}
+	return nil
+}
+
+// ListID parses the List-Id header into its optional description and
+// the list identifier, such as "announce.example.com". Comments are
+// ignored and an encoded description is decoded.
+func (h *Header) ListID() (description string, id string, err error) {
+	if !h.Has(HdrListID) {
+		return "", "", mail.ErrHeaderNotPresent
+	}
+	return parseListID(h.Get(HdrListID))
+}
+
+// SetListID sets the List-Id header, replacing any existing ones. The
+// description is optional, and is quoted or encoded as needed.
+func (h *Header) SetListID(description, id string) error {
+	value, err := formatListID(description, id)
+	if err != nil {
+		return err
+	}
+	h.replaceAll(HdrListID, value)
+	return nil
+}
+
+// parseListID parses list-id-header = [phrase] CFWS "<" list-id ">"
+// https://tools.wordtothewise.com/rfc2919#section-3
+func parseListID(value string) (string, string, error) {
+	// runs of atoms are decoded together, so that the whitespace between
+	// adjacent encoded words is dropped
+	var words, atoms []string
+	dec := new(mime.WordDecoder)
+	flush := func() {
+		if len(atoms) == 0 {
+			return
+		}
+		run := strings.Join(atoms, " ")
+		if decoded, err := dec.DecodeHeader(run); err == nil {
+			run = decoded
+		}
+		words = append(words, run)
+		atoms = nil
+	}
+	i := 0
+	for {
+		var err error
+		i, err = skipCFWS(value, i)
+		if err != nil {
+			return "", "", fmt.Errorf("'%s' is not a valid List-Id: %w", value, err)
+		}
+		if i >= len(value) {
+			return "", "", fmt.Errorf("'%s' is not a valid List-Id: missing <list-id>", value)
+		}
+		switch c := value[i]; {
+		case c == '"':
+			word, next, err := readQuotedString(value, i)
+			if err != nil {
+				return "", "", fmt.Errorf("'%s' is not a valid List-Id: %w", value, err)
+			}
+			flush()
+			words = append(words, word)
+			i = next
+		case c == '<':
+			flush()
+			end := strings.IndexByte(value[i:], '>')
+			if end < 0 {
+				return "", "", fmt.Errorf("'%s' is not a valid List-Id: unterminated <list-id>", value)
+			}
+			id := value[i+1 : i+end]
+			if err := validListID(id); err != nil {
+				return "", "", err
+			}
+			rest, err := skipCFWS(value, i+end+1)
+			if err != nil || rest != len(value) {
+				return "", "", fmt.Errorf("'%s' is not a valid List-Id: unexpected text after <list-id>", value)
+			}
+			return strings.Join(words, " "), id, nil
+		case isAtextChar(c) || c == '.':
+			start := i
+			for i < len(value) && (isAtextChar(value[i]) || value[i] == '.') {
+				i++
+			}
+			atoms = append(atoms, value[start:i])
+		default:
+			return "", "", fmt.Errorf("'%s' is not a valid List-Id: unexpected '%c'", value, c)
+		}
+	}
+}
+
+// formatListID renders a List-Id value, quoting or encoding the
+// description as needed
+func formatListID(description, id string) (string, error) {
+	if err := validListID(id); err != nil {
+		return "", err
+	}
+	if description == "" {
+		return "<" + id + ">", nil
+	}
+	if !isAscii(description) {
+		return mime.QEncoding.Encode(utf8, description) + " <" + id + ">", nil
+	}
+	return QuoteString(description) + " <" + id + ">", nil
+}
+
+// validListID checks list-id = list-label "." list-id-namespace, which
+// is a dot-atom-text containing at least one dot
+func validListID(id string) error {
+	if !strings.Contains(id, ".") {
+		return fmt.Errorf("'%s' is not a valid list identifier", id)
+	}
+	for _, label := range strings.Split(id, ".") {
+		if label == "" || !isAtext(label) {
+			return fmt.Errorf("'%s' is not a valid list identifier", id)
+		}
+	}
+	return nil
+}
+
+// formatURIList renders a comma separated list of angle-bracketed URIs
+func formatURIList(uris []string) (string, error) {
+	formatted := make([]string, len(uris))
+	for i, u := range uris {
+		u = strings.TrimSpace(u)
+		u = strings.TrimSuffix(strings.TrimPrefix(u, "<"), ">")
+		if err := validListURI(u); err != nil {
+			return "", err
+		}
+		formatted[i] = "<" + u + ">"
+	}
+	return strings.Join(formatted, ", "), nil
+}
+
+func validListURI(s string) error {
+	if strings.ContainsAny(s, " \t\r\n<>\"") || !isAscii(s) {
+		return fmt.Errorf("'%s' is not a valid URI", s)
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return fmt.Errorf("'%s' is not a valid URI: %w", s, err)
+	}
+	if u.Scheme == "" {
+		return fmt.Errorf("'%s' is not an absolute URI", s)
+	}
+	return nil
+}
+
+// https://tools.wordtothewise.com/rfc8058
+
+const HdrListUnsubscribePost = "List-Unsubscribe-Post"
+
+const oneClick = "List-Unsubscribe=One-Click"
+
+// SetOneClickUnsubscribe sets List-Unsubscribe to the given https URL
+// and, optionally, a mailto address, along with the RFC 8058
+// List-Unsubscribe-Post header enabling one-click unsubscription.
+func (h *Header) SetOneClickUnsubscribe(httpsURL string, mailto string) error {
+	u, err := url.Parse(httpsURL)
+	if err != nil || u.Scheme != "https" {
+		return fmt.Errorf("'%s' is not an https URL", httpsURL)
+	}
+	uris := []string{httpsURL}
+	if mailto != "" {
+		if !strings.HasPrefix(strings.ToLower(mailto), "mailto:") {
+			mailto = "mailto:" + mailto
+		}
+		uris = append(uris, mailto)
+	}
+	value, err := formatURIList(uris)
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %w", HdrListUnsubscribe, err)
+	}
+	h.replaceAll(HdrListUnsubscribe, value)
+	h.replaceAll(HdrListUnsubscribePost, oneClick)
+	return nil
+}
+
+// CheckOneClickUnsubscribe checks the header meets the one-click
+// unsubscribe requirements that large mailbox providers place on bulk
+// senders: a single List-Unsubscribe with an https URI, a
+// List-Unsubscribe-Post of exactly "List-Unsubscribe=One-Click", and a
+// DKIM signature covering both.
+func (h *Header) CheckOneClickUnsubscribe() error {
+	h.CanonicalizeKeys()
+	var unsub, post []string
+	for _, kv := range h.Headers {
+		switch kv.Key {
+		case HdrListUnsubscribe:
+			unsub = append(unsub, kv.Value)
+		case HdrListUnsubscribePost:
+			post = append(post, kv.Value)
+		}
+	}
+	switch {
+	case len(unsub) == 0:
+		return fmt.Errorf("missing %s header", HdrListUnsubscribe)
+	case len(unsub) > 1:
+		return fmt.Errorf("multiple %s headers", HdrListUnsubscribe)
+	case len(post) == 0:
+		return fmt.Errorf("missing %s header", HdrListUnsubscribePost)
+	case len(post) > 1:
+		return fmt.Errorf("multiple %s headers", HdrListUnsubscribePost)
+	case strings.TrimSpace(post[0]) != oneClick:
+		return fmt.Errorf("%s must be '%s', not '%s'", HdrListUnsubscribePost, oneClick, post[0])
+	}
+	uris, err := parseURIList(unsub[0])
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", HdrListUnsubscribe, err)
+	}
+	https := false
+	for _, u := range uris {
+		if strings.HasPrefix(strings.ToLower(u), "https:") {
+			https = true
+		}
+	}
+	if !https {
+		return fmt.Errorf("%s has no https URI", HdrListUnsubscribe)
+	}
+	sigs, _ := h.DKIMSignatures()
+	for _, sig := range sigs {
+		signed := map[string]bool{}
+		for _, field := range sig.Headers {
+			signed[strings.ToLower(field)] = true
+		}
+		if signed["list-unsubscribe"] && signed["list-unsubscribe-post"] {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s and %s are not covered by a DKIM signature", HdrListUnsubscribe, HdrListUnsubscribePost)
+}
+
+// ParseListUnsubscribe extracts the targets of a List-Unsubscribe value:
+// the http and https URLs, and the addresses of any mailto URIs. Targets
+// are returned in the order given, which is the sender's preference.
+// URIs with other schemes are ignored. Addresses given in a mailto's
+// "to" parameter are included, but other parameters, such as subject,
+// are not; use the URL itself if those matter.
+// https://tools.wordtothewise.com/rfc2369#section-3.2
+// https://tools.wordtothewise.com/rfc6068#section-2
+func ParseListUnsubscribe(value string) ([]url.URL, []mail.Address, error) {
+	uris, err := parseURIList(value)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid %s: %w", HdrListUnsubscribe, err)
+	}
+	var urls []url.URL
+	var addrs []mail.Address
+	for _, s := range uris {
+		u, err := url.Parse(s)
+		if err != nil {
+			return nil, nil, fmt.Errorf("'%s' is not a valid URI: %w", s, err)
+		}
+		switch strings.ToLower(u.Scheme) {
+		case "http", "https":
+			if u.Host == "" {
+				return nil, nil, fmt.Errorf("'%s' is not a valid URL", s)
+			}
+			urls = append(urls, *u)
+		case "mailto":
+			to, err := mailtoAddresses(u)
+			if err != nil {
+				return nil, nil, fmt.Errorf("'%s' is not a valid mailto URI: %w", s, err)
+			}
+			addrs = append(addrs, to...)
+		}
+	}
+	return urls, addrs, nil
+}
+
+// mailtoAddresses returns the addresses in a mailto URI, from its path and
+// any "to" parameters
+func mailtoAddresses(u *url.URL) ([]mail.Address, error) {
+	path := u.Opaque
+	if path == "" {
+		path = u.Path
+	}
+	to, err := url.PathUnescape(path)
+	if err != nil {
+		return nil, err
+	}
+	lists := []string{to}
+	for _, param := range strings.Split(u.RawQuery, "&") {
+		eq := strings.IndexByte(param, '=')
+		if eq < 0 || !strings.EqualFold(param[:eq], "to") {
+			continue
+		}
+		v, err := url.QueryUnescape(param[eq+1:])
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, v)
+	}
+	var ret []mail.Address
+	for _, list := range lists {
+		for _, a := range strings.Split(list, ",") {
+			a = strings.TrimSpace(a)
+			if a == "" {
+				continue
+			}
+			addr, err := mail.ParseAddress(a)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, *addr)
+		}
+	}
+	if len(ret) == 0 {
+		return nil, errors.New("no address")
+	}
+	return ret, nil
+}
+
+// parseURIList extracts the URIs from a comma separated list of
+// angle-bracketed URIs, as used by the List-* headers, ignoring comments
+// and whitespace
+func parseURIList(s string) ([]string, error) {
+	var ret []string
+	i := 0
+	for {
+		var err error
+		i, err = skipCFWS(s, i)
+		if err != nil {
+			return nil, err
+		}
+		if i >= len(s) {
+			return ret, nil
+		}
+		if s[i] != '<' {
+			return nil, fmt.Errorf("expected '<' at offset %d", i)
+		}
+		end := strings.IndexByte(s[i:], '>')
+		if end < 0 {
+			return nil, errors.New("unterminated URI")
+		}
+		// whitespace within a URI is ignored
+		ret = append(ret, stripFWS(s[i+1:i+end]))
+		i, err = skipCFWS(s, i+end+1)
+		if err != nil {
+			return nil, err
+		}
+		if i < len(s) {
+			if s[i] != ',' {
+				return nil, fmt.Errorf("expected ',' at offset %d", i)
+			}
+			i++
+		}
+	}
+}
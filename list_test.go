@@ -0,0 +1,207 @@
+package orderedheaders
+
+import (
+	"errors"
+	"net/mail"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSetListHeaders(t *testing.T) {
+	tests := map[string]struct {
+		List      ListHeaders
+		WantError bool
+		Want      string
+	}{
+		"full": {
+			List: ListHeaders{
+				ID:          "announce.example.com",
+				Description: "Example Announcements",
+				Unsubscribe: []string{"https://example.com/unsub?id=123", "mailto:leave@example.com"},
+				Help:        []string{"<https://example.com/help>"},
+				Archive:     []string{"https://example.com/archive/"},
+				NoPost:      true,
+			},
+			Want: "List-Id: Example Announcements <announce.example.com>\r\n" +
+				"List-Help: <https://example.com/help>\r\n" +
+				"List-Unsubscribe: <https://example.com/unsub?id=123>,\r\n <mailto:leave@example.com>\r\n" +
+				"List-Post: NO\r\n" +
+				"List-Archive: <https://example.com/archive/>\r\n",
+		},
+		"quoted": {
+			List: ListHeaders{ID: "a.example.com", Description: "Bob's list, mostly"},
+			Want: "List-Id: \"Bob's list, mostly\" <a.example.com>\r\n",
+		},
+		"badid":    {List: ListHeaders{ID: "nodots"}, WantError: true},
+		"baduri":   {List: ListHeaders{ID: "a.example.com", Post: []string{"not a uri"}}, WantError: true},
+		"relative": {List: ListHeaders{ID: "a.example.com", Post: []string{"/post"}}, WantError: true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := &Header{}
+			h.Add("List-Id", "<old.example.com>")
+			h.Add("List-Id", "<older.example.com>")
+			h.Add("List-Subscribe", "<mailto:old@example.com>")
+			err := h.SetListHeaders(test.List)
+			if test.WantError {
+				if err == nil {
+					t.Errorf("expected error, didn't get one")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := h.Bytes(Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.Want, string(got)); diff != "" {
+				t.Errorf("SetListHeaders mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestOneClickUnsubscribe(t *testing.T) {
+	h := &Header{}
+	h.Add("From", "news@example.com")
+	if err := h.SetOneClickUnsubscribe("http://example.com/unsub", ""); err == nil {
+		t.Errorf("expected error for http URL")
+	}
+	if err := h.SetOneClickUnsubscribe("https://example.com/unsub?u=1", "leave@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if got := h.Get("List-Unsubscribe"); got != "<https://example.com/unsub?u=1>, <mailto:leave@example.com>" {
+		t.Errorf("unexpected List-Unsubscribe '%s'", got)
+	}
+	if err := h.CheckOneClickUnsubscribe(); err == nil {
+		t.Errorf("expected error for unsigned headers")
+	}
+	h.Add("DKIM-Signature", "v=1; a=rsa-sha256; d=example.com; s=sel; h=from:list-unsubscribe:List-Unsubscribe-Post; bh=aGFzaA==; b=c2ln")
+	if err := h.CheckOneClickUnsubscribe(); err != nil {
+		t.Error(err)
+	}
+	h.Add("List-Unsubscribe-Post", oneClick)
+	if err := h.CheckOneClickUnsubscribe(); err == nil {
+		t.Errorf("expected error for duplicate List-Unsubscribe-Post")
+	}
+	if err := h.SetOneClickUnsubscribe("https://example.com/unsub?u=1", "leave@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.CheckOneClickUnsubscribe(); err != nil {
+		t.Errorf("duplicate not replaced: %v", err)
+	}
+}
+
+func TestParseListUnsubscribe(t *testing.T) {
+	tests := map[string]struct {
+		In        string
+		WantError bool
+		URLs      []string
+		Addresses []string
+	}{
+		"one click": {In: "<https://example.com/unsub?u=1>, <mailto:leave@example.com>", URLs: []string{"https://example.com/unsub?u=1"}, Addresses: []string{"leave@example.com"}},
+		"folded":    {In: "<mailto:leave@example.com?subject=unsubscribe>,\r\n <http://example.com/\r\n unsub>", URLs: []string{"http://example.com/unsub"}, Addresses: []string{"leave@example.com"}},
+		"comments":  {In: "(preferred) <https://example.com/u> (fallback), <mailto:a@example.com>", URLs: []string{"https://example.com/u"}, Addresses: []string{"a@example.com"}},
+		"escaped":   {In: "<mailto:list%2Bleave@example.com,b@example.com?To=c@example.com&subject=x>", Addresses: []string{"list+leave@example.com", "b@example.com", "c@example.com"}},
+		"other":     {In: "<ftp://example.com/unsub>, <https://example.com/u>", URLs: []string{"https://example.com/u"}},
+		"empty":     {In: ""},
+		"no angle":  {In: "https://example.com/u", WantError: true},
+		"bad mail":  {In: "<mailto:nobody>", WantError: true},
+		"no host":   {In: "<https:/unsub>", WantError: true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			urls, addrs, err := ParseListUnsubscribe(test.In)
+			if test.WantError {
+				if err == nil {
+					t.Errorf("expected error, got %v %v", urls, addrs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var gotURLs, gotAddrs []string
+			for _, u := range urls {
+				gotURLs = append(gotURLs, u.String())
+			}
+			for _, a := range addrs {
+				gotAddrs = append(gotAddrs, a.Address)
+			}
+			if diff := cmp.Diff(test.URLs, gotURLs); diff != "" {
+				t.Errorf("URL mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(test.Addresses, gotAddrs); diff != "" {
+				t.Errorf("address mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestListID(t *testing.T) {
+	tests := map[string]struct {
+		In          string
+		WantError   bool
+		Description string
+		ID          string
+	}{
+		"bare":     {In: "<announce.example.com>", ID: "announce.example.com"},
+		"phrase":   {In: "Example Announcements <announce.example.com>", Description: "Example Announcements", ID: "announce.example.com"},
+		"quoted":   {In: `"Example, Inc. news" <news.example.com>`, Description: "Example, Inc. news", ID: "news.example.com"},
+		"encoded":  {In: "=?utf-8?q?Caf=C3=A9?= =?utf-8?q?_list?= <cafe.example.com>", Description: "Café list", ID: "cafe.example.com"},
+		"comments": {In: "(the list) Lista <lista.example.com> (id)", Description: "Lista", ID: "lista.example.com"},
+		"folded":   {In: "Example\r\n <announce.example.com>", Description: "Example", ID: "announce.example.com"},
+		"obsolete": {In: "Example Inc. <announce.example.com>", Description: "Example Inc.", ID: "announce.example.com"},
+		"no dot":   {In: "<announce>", WantError: true},
+		"missing":  {In: "Example", WantError: true},
+		"trailing": {In: "<announce.example.com> extra", WantError: true},
+		"open":     {In: "<announce.example.com", WantError: true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := &Header{}
+			h.Add("List-Id", test.In)
+			description, id, err := h.ListID()
+			if test.WantError {
+				if err == nil {
+					t.Errorf("expected error, got '%s' '%s'", description, id)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if description != test.Description || id != test.ID {
+				t.Errorf("want '%s' '%s', got '%s' '%s'", test.Description, test.ID, description, id)
+			}
+
+			round := &Header{}
+			if err := round.SetListID(description, id); err != nil {
+				t.Fatal(err)
+			}
+			d2, id2, err := round.ListID()
+			if err != nil || d2 != description || id2 != id {
+				t.Errorf("round trip of '%s' gave '%s' '%s' %v", round.Get("List-Id"), d2, id2, err)
+			}
+		})
+	}
+
+	h := &Header{}
+	if _, _, err := h.ListID(); !errors.Is(err, mail.ErrHeaderNotPresent) {
+		t.Errorf("expected ErrHeaderNotPresent, got %v", err)
+	}
+	h.Add("List-Id", "<old.example.com>")
+	h.Add("List-Id", "<older.example.com>")
+	if err := h.SetListID("", "new.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Headers) != 1 || h.Get("List-Id") != "<new.example.com>" {
+		t.Errorf("SetListID didn't replace existing fields: %v", h.Headers)
+	}
+	if err := h.SetListID("", "nodot"); err == nil {
+		t.Errorf("expected error for invalid identifier")
+	}
+}
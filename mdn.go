@@ -0,0 +1,160 @@
+package orderedheaders
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/mail"
+	"strings"
+)
+
+// https://tools.wordtothewise.com/rfc8098#section-2
+
+const (
+	HdrDispositionNotificationTo      = "Disposition-Notification-To"
+	HdrDispositionNotificationOptions = "Disposition-Notification-Options"
+	HdrAutoSubmitted                  = "Auto-Submitted"
+)
+
+// DispositionOption is a single parameter from a
+// Disposition-Notification-Options header
+type DispositionOption struct {
+	Name string
+	// Required is true for importance "required", false for "optional"
+	Required bool
+	Values   []string
+}
+
+// DispositionNotificationTo returns the addresses an MDN has been
+// requested to be sent to
+func (h *Header) DispositionNotificationTo() ([]*mail.Address, error) {
+	return h.AddressList(HdrDispositionNotificationTo)
+}
+
+// SetDispositionNotificationTo requests that MDNs be sent to the given
+// addresses. With no addresses it removes the request.
+func (h *Header) SetDispositionNotificationTo(addrs ...*mail.Address) error {
+	if len(addrs) == 0 {
+		h.RemoveAll(HdrDispositionNotificationTo)
+		return nil
+	}
+	return h.Set(HdrDispositionNotificationTo, formatAddressList(addrs))
+}
+
+// DispositionNotificationOptions parses the
+// Disposition-Notification-Options header
+func (h *Header) DispositionNotificationOptions() ([]DispositionOption, error) {
+	value := h.Get(HdrDispositionNotificationOptions)
+	if value == "" {
+		return nil, mail.ErrHeaderNotPresent
+	}
+	return ParseDispositionNotificationOptions(value)
+}
+
+// SetDispositionNotificationOptions sets the
+// Disposition-Notification-Options header
+func (h *Header) SetDispositionNotificationOptions(opts []DispositionOption) error {
+	params := make([]string, len(opts))
+	for i, o := range opts {
+		if !isToken(o.Name) {
+			return fmt.Errorf("'%s' is not a valid disposition option name", o.Name)
+		}
+		if len(o.Values) == 0 {
+			return fmt.Errorf("disposition option %s has no values", o.Name)
+		}
+		importance := "optional"
+		if o.Required {
+			importance = "required"
+		}
+		values := []string{importance}
+		for _, v := range o.Values {
+			if !isToken(v) {
+				v = quoteString(v)
+			}
+			values = append(values, v)
+		}
+		params[i] = o.Name + "=" + strings.Join(values, ",")
+	}
+	return h.Set(HdrDispositionNotificationOptions, strings.Join(params, "; "))
+}
+
+// ParseDispositionNotificationOptions parses the value of a
+// Disposition-Notification-Options header
+func ParseDispositionNotificationOptions(value string) ([]DispositionOption, error) {
+	var opts []DispositionOption
+	for _, param := range strings.Split(value, ";") {
+		param = strings.TrimSpace(param)
+		if param == "" {
+			continue
+		}
+		eq := strings.IndexByte(param, '=')
+		if eq < 0 {
+			return nil, fmt.Errorf("'%s' is not a valid disposition option", param)
+		}
+		opt := DispositionOption{Name: strings.ToLower(strings.TrimSpace(param[:eq]))}
+		values := strings.Split(param[eq+1:], ",")
+		switch strings.ToLower(strings.TrimSpace(values[0])) {
+		case "required":
+			opt.Required = true
+		case "optional":
+		default:
+			return nil, fmt.Errorf("'%s' is not a valid disposition option importance", strings.TrimSpace(values[0]))
+		}
+		for _, v := range values[1:] {
+			v = strings.TrimSpace(v)
+			if strings.HasPrefix(v, `"`) {
+				unquoted, err := UnquoteString(v)
+				if err != nil {
+					return nil, err
+				}
+				v = unquoted
+			}
+			opt.Values = append(opt.Values, v)
+		}
+		if len(opt.Values) == 0 {
+			return nil, fmt.Errorf("disposition option %s has no values", opt.Name)
+		}
+		opts = append(opts, opt)
+	}
+	return opts, nil
+}
+
+// SuppressMDN reports whether a message disposition notification should
+// not be sent automatically in response to this message, and why. An MDN
+// is suppressed if none was requested, if the request is malformed or
+// names more than one address, if it doesn't match the Return-Path, or if
+// the message is itself a report or automatically submitted.
+func (h *Header) SuppressMDN() (bool, string) {
+	addrs, err := h.DispositionNotificationTo()
+	if errors.Is(err, mail.ErrHeaderNotPresent) {
+		return true, "no MDN requested"
+	}
+	if err != nil {
+		return true, "invalid Disposition-Notification-To"
+	}
+	if len(addrs) != 1 {
+		return true, "Disposition-Notification-To has more than one address"
+	}
+	if h.autoSubmitted() != "" {
+		return true, "message is auto-submitted"
+	}
+	if _, params, err := mime.ParseMediaType(h.Get(HdrContentType)); err == nil {
+		if params["report-type"] != "" {
+			return true, "message is a report"
+		}
+	}
+	rp, isNull, err := h.ReturnPath()
+	if errors.Is(err, mail.ErrHeaderNotPresent) {
+		return true, "no Return-Path"
+	}
+	if err != nil {
+		return true, "invalid Return-Path"
+	}
+	if isNull {
+		return true, "null Return-Path"
+	}
+	if !strings.EqualFold(rp, addrs[0].Address) {
+		return true, "Disposition-Notification-To does not match Return-Path"
+	}
+	return false, ""
+}
@@ -0,0 +1,70 @@
+package orderedheaders
+
+import (
+	"net/mail"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDispositionNotification(t *testing.T) {
+	h := &Header{}
+	if err := h.Set(HdrDispositionNotificationTo, "not an address"); err == nil {
+		t.Errorf("expected error for invalid Disposition-Notification-To")
+	}
+	if err := h.SetDispositionNotificationTo(&mail.Address{Name: "Bob", Address: "bob@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	opts := []DispositionOption{
+		{Name: "signed-receipt-protocol", Required: false, Values: []string{"pkcs7-signature"}},
+		{Name: "signed-receipt-micalg", Required: true, Values: []string{"sha1", "md5"}},
+	}
+	if err := h.SetDispositionNotificationOptions(opts); err != nil {
+		t.Fatal(err)
+	}
+	want := "Disposition-Notification-To: \"Bob\" <bob@example.com>\r\n" +
+		"Disposition-Notification-Options: signed-receipt-protocol=optional,pkcs7-signature;\r\n" +
+		" signed-receipt-micalg=required,sha1,md5\r\n"
+	got, err := h.Bytes(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("rendered mismatch (-want +got):\n%s", diff)
+	}
+	gotOpts, err := h.DispositionNotificationOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(opts, gotOpts); diff != "" {
+		t.Errorf("DispositionNotificationOptions mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSuppressMDN(t *testing.T) {
+	tests := map[string]struct {
+		Headers  []KV
+		Suppress bool
+	}{
+		"none":     {[]KV{{Key: "Return-Path", Value: "<bob@example.com>"}}, true},
+		"matching": {[]KV{{Key: "Return-Path", Value: "<bob@example.com>"}, {Key: "Disposition-Notification-To", Value: "Bob <BOB@example.com>"}}, false},
+		"mismatch": {[]KV{{Key: "Return-Path", Value: "<alice@example.com>"}, {Key: "Disposition-Notification-To", Value: "bob@example.com"}}, true},
+		"null":     {[]KV{{Key: "Return-Path", Value: "<>"}, {Key: "Disposition-Notification-To", Value: "bob@example.com"}}, true},
+		"multiple": {[]KV{{Key: "Return-Path", Value: "<bob@example.com>"}, {Key: "Disposition-Notification-To", Value: "bob@example.com, carol@example.com"}}, true},
+		"auto": {[]KV{{Key: "Return-Path", Value: "<bob@example.com>"}, {Key: "Disposition-Notification-To", Value: "bob@example.com"},
+			{Key: "Auto-Submitted", Value: "auto-replied"}}, true},
+		"autono": {[]KV{{Key: "Return-Path", Value: "<bob@example.com>"}, {Key: "Disposition-Notification-To", Value: "bob@example.com"},
+			{Key: "Auto-Submitted", Value: "no (manual)"}}, false},
+		"report": {[]KV{{Key: "Return-Path", Value: "<bob@example.com>"}, {Key: "Disposition-Notification-To", Value: "bob@example.com"},
+			{Key: "Content-Type", Value: "multipart/report; report-type=disposition-notification; boundary=x"}}, true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := &Header{Headers: test.Headers}
+			suppress, reason := h.SuppressMDN()
+			if suppress != test.Suppress {
+				t.Errorf("want suppress %v, got %v (%s)", test.Suppress, suppress, reason)
+			}
+		})
+	}
+}
@@ -2,25 +2,198 @@ package orderedheaders
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"io"
+	"io/ioutil"
 	"net/textproto"
+	"os"
 )
 
 type Message struct {
 	Header Header
 	Body   io.Reader
+
+	// defaultType is the media type assumed if there's no Content-Type,
+	// which is message/rfc822 within a multipart/digest
+	defaultType string
+	// tempFile is the file a large buffered body was spilled to
+	tempFile *os.File
+	// rawHeader is the header section exactly as read, including the
+	// blank line that ends it
+	rawHeader []byte
+	// separator is the raw blank line between the header and body, empty
+	// if the input ended within the header and nil if the message wasn't
+	// read
+	separator []byte
+}
+
+// ErrBodyTooLarge is returned when a message body is larger than
+// MessageOptions.MaxBodyBytes
+var ErrBodyTooLarge = errors.New("message body too large")
+
+// MessageOptions configures how a message is read.
+type MessageOptions struct {
+	ReadOptions
+	// MaxBodyBytes limits the size of the body. Reading more than this
+	// returns ErrBodyTooLarge. Zero means no limit.
+	MaxBodyBytes int64
+	// BufferBody reads the whole body before returning, so that Body is an
+	// io.ReadSeeker that can be read more than once
+	BufferBody bool
+	// SpillBytes is the size above which a buffered body is written to a
+	// temporary file rather than kept in memory. Zero means never. The
+	// file is removed by Close.
+	SpillBytes int64
+	// TempDir is the directory for temporary files, defaulting to
+	// os.TempDir
+	TempDir string
+	// KeepRawHeader retains the header section exactly as it was read,
+	// for RawHeaderBlock. It's also retained if ReadOptions.KeepRaw is
+	// set.
+	KeepRawHeader bool
 }
 
 func ReadMessage(r io.Reader) (*Message, error) {
+	return ReadMessageWithOptions(r, MessageOptions{})
+}
+
+// ReadMessageWithOptions reads a message from r, as ReadMessage, configured
+// by o.
+func ReadMessageWithOptions(r io.Reader, o MessageOptions) (*Message, error) {
 	tp := textproto.NewReader(bufio.NewReader(r))
 
-	hdr, err := ReadHeader(tp)
-	if err != nil && err != io.EOF {
+	m, err := readMessageHeader(tp, o.ReadOptions, o.KeepRawHeader)
+	if err != nil {
 		return nil, err
 	}
+	if o.MaxBodyBytes > 0 {
+		m.Body = &maxBytesReader{r: m.Body, remaining: o.MaxBodyBytes}
+	}
+	if o.BufferBody {
+		if err := m.buffer(o); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
 
+// readMessageHeader reads the header of a message, retaining the raw
+// header section if keepRawHeader or o.KeepRaw is set, whether or not
+// KV.Raw is wanted
+func readMessageHeader(tp *textproto.Reader, o ReadOptions, keepRawHeader bool) (*Message, error) {
+	ro := o
+	ro.KeepRaw = o.KeepRaw || keepRawHeader
+	hdr, separator, err := readHeader(tp, ro)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if separator == nil {
+		// the header ended at EOF, with no blank line
+		separator = []byte{}
+	}
+	var raw []byte
+	for i, kv := range hdr.Headers {
+		raw = append(raw, kv.Raw...)
+		if !o.KeepRaw {
+			hdr.Headers[i].Raw = ""
+		}
+	}
+	rawHeader := append(raw, separator...)
+	if !ro.KeepRaw || hdr.Truncated {
+		// it wasn't wanted, or some of what was read has been discarded
+		rawHeader = nil
+	}
 	return &Message{
-		Header: hdr,
-		Body:   tp.R,
+		Header:    hdr,
+		Body:      tp.R,
+		rawHeader: rawHeader,
+		separator: separator,
 	}, nil
 }
+
+// RawHeaderBlock returns the header section of the message exactly as it
+// was read, including the blank line that ends it, for signing or
+// verification by external libraries. It's nil unless the message was
+// read with MessageOptions.KeepRawHeader or ReadOptions.KeepRaw, or is
+// a body part, and for a message that wasn't read, such as one created
+// by a Builder, or whose header was truncated by ReadOptions.MaxFields
+// or MaxFieldBytes.
+func (m *Message) RawHeaderBlock() []byte {
+	return m.rawHeader
+}
+
+// buffer reads the body into memory, or a temporary file if it's larger
+// than o.SpillBytes
+func (m *Message) buffer(o MessageOptions) error {
+	var buf bytes.Buffer
+	var err error
+	if o.SpillBytes > 0 {
+		_, err = io.CopyN(&buf, m.Body, o.SpillBytes+1)
+	} else {
+		_, err = io.Copy(&buf, m.Body)
+	}
+	if err == io.EOF || (err == nil && o.SpillBytes == 0) {
+		m.Body = bytes.NewReader(buf.Bytes())
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(o.TempDir, "orderedheaders-body-")
+	if err != nil {
+		return err
+	}
+	m.tempFile = f
+	if _, err := buf.WriteTo(f); err != nil {
+		_ = m.Close()
+		return err
+	}
+	if _, err := io.Copy(f, m.Body); err != nil {
+		_ = m.Close()
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		_ = m.Close()
+		return err
+	}
+	m.Body = f
+	return nil
+}
+
+// Close releases any temporary file used to buffer the body. It's safe to
+// call on any Message.
+func (m *Message) Close() error {
+	if m.tempFile == nil {
+		return nil
+	}
+	f := m.tempFile
+	m.tempFile = nil
+	err := f.Close()
+	if rmErr := os.Remove(f.Name()); err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+// maxBytesReader returns ErrBodyTooLarge once more than remaining bytes
+// have been read
+type maxBytesReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *maxBytesReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, ErrBodyTooLarge
+	}
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n + int(l.remaining), ErrBodyTooLarge
+	}
+	return n, err
+}
@@ -1,7 +1,9 @@
 package orderedheaders
 
 import (
+	"errors"
 	"io"
+	"os"
 	"strings"
 	"testing"
 )
@@ -40,3 +42,106 @@ func TestReadMessage(t *testing.T) {
 		})
 	}
 }
+
+func TestReadMessageWithOptions(t *testing.T) {
+	in := "Foo: bar\r\n\r\n" + strings.Repeat("x", 100)
+	tests := map[string]struct {
+		Opts      MessageOptions
+		WantSeek  bool
+		WantFile  bool
+		WantError bool
+	}{
+		"default":   {Opts: MessageOptions{}},
+		"limit":     {Opts: MessageOptions{MaxBodyBytes: 100}},
+		"toolarge":  {Opts: MessageOptions{MaxBodyBytes: 99}, WantError: true},
+		"buffer":    {Opts: MessageOptions{BufferBody: true}, WantSeek: true},
+		"nospill":   {Opts: MessageOptions{BufferBody: true, SpillBytes: 100}, WantSeek: true},
+		"spill":     {Opts: MessageOptions{BufferBody: true, SpillBytes: 99, TempDir: t.TempDir()}, WantSeek: true, WantFile: true},
+		"bufferbig": {Opts: MessageOptions{BufferBody: true, SpillBytes: 10, MaxBodyBytes: 50}, WantError: true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			msg, err := ReadMessageWithOptions(strings.NewReader(in), test.Opts)
+			if err == nil {
+				defer msg.Close()
+				_, err = io.ReadAll(msg.Body)
+			}
+			if test.WantError {
+				if !errors.Is(err, ErrBodyTooLarge) {
+					t.Errorf("expected ErrBodyTooLarge, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			seeker, ok := msg.Body.(io.ReadSeeker)
+			if ok != test.WantSeek {
+				t.Fatalf("want seekable %v, got %T", test.WantSeek, msg.Body)
+			}
+			_, isFile := msg.Body.(*os.File)
+			if isFile != test.WantFile {
+				t.Errorf("want file %v, got %T", test.WantFile, msg.Body)
+			}
+			if !ok {
+				return
+			}
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				t.Fatal(err)
+			}
+			body, err := io.ReadAll(seeker)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(body) != 100 {
+				t.Errorf("expected 100 byte body, got %d", len(body))
+			}
+			if isFile {
+				name := msg.Body.(*os.File).Name()
+				if err := msg.Close(); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := os.Stat(name); !os.IsNotExist(err) {
+					t.Errorf("expected temporary file to be removed")
+				}
+			}
+		})
+	}
+}
+
+func TestRawHeaderBlock(t *testing.T) {
+	tests := map[string]struct {
+		In   string
+		Want string
+	}{
+		"crlf":     {In: "Foo: bar\r\nBaz:  quux \r\n\r\nbody\r\n", Want: "Foo: bar\r\nBaz:  quux \r\n\r\n"},
+		"lf":       {In: "Foo: bar\nBaz: quux\n\nbody\n", Want: "Foo: bar\nBaz: quux\n\n"},
+		"folded":   {In: "Subject: one\r\n\ttwo\r\nFoo:bar\r\n\r\nbody", Want: "Subject: one\r\n\ttwo\r\nFoo:bar\r\n\r\n"},
+		"noblank":  {In: "Foo: bar\r\n", Want: "Foo: bar\r\n"},
+		"nobody":   {In: "Foo: bar\r\n\r\n", Want: "Foo: bar\r\n\r\n"},
+		"headless": {In: "\r\nbody", Want: "\r\n"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			msg, err := ReadMessage(strings.NewReader(test.In))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if raw := msg.RawHeaderBlock(); raw != nil {
+				t.Errorf("raw header kept without KeepRawHeader: %q", raw)
+			}
+			msg, err = ReadMessageWithOptions(strings.NewReader(test.In), MessageOptions{KeepRawHeader: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(msg.RawHeaderBlock()); got != test.Want {
+				t.Errorf("want %q, got %q", test.Want, got)
+			}
+			for _, kv := range msg.Header.Headers {
+				if kv.Raw != "" {
+					t.Errorf("Raw set without KeepRaw: %q", kv.Raw)
+				}
+			}
+		})
+	}
+}
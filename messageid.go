@@ -0,0 +1,65 @@
+package orderedheaders
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// https://tools.wordtothewise.com/rfc5322#section-3.6.4
+
+var messageIDEncoding = base32.NewEncoding("0123456789abcdefghijklmnopqrstuv").WithPadding(base32.NoPadding)
+
+// NewMessageID generates a new, globally unique, message ID for the given
+// domain, including angle brackets. If domain is empty the local hostname
+// is used. The left hand side combines the current time, random bits and
+// a hash of the hostname.
+func NewMessageID(domain string) string {
+	host, _ := os.Hostname()
+	if domain == "" {
+		domain = host
+	}
+	if domain == "" || validMessageIdDomain(domain) != nil {
+		domain = "localhost.invalid"
+	}
+	var random [10]byte
+	if _, err := rand.Read(random[:]); err != nil {
+		// crypto/rand can't fail on supported platforms
+		panic(fmt.Sprintf("reading random bytes: %v", err))
+	}
+	hostHash := fnv.New32a()
+	_, _ = hostHash.Write([]byte(host))
+	return "<" + strconv.FormatInt(time.Now().UnixNano(), 36) +
+		"." + messageIDEncoding.EncodeToString(random[:]) +
+		"." + messageIDEncoding.EncodeToString(hostHash.Sum(nil)) +
+		"@" + strings.ToLower(domain) + ">"
+}
+
+// EnsureMessageID adds a new Message-Id header if there isn't one
+func (h *Header) EnsureMessageID(domain string) error {
+	if strings.TrimSpace(h.Get(HdrMessageId)) != "" {
+		return nil
+	}
+	return h.Set(HdrMessageId, NewMessageID(domain))
+}
+
+// ValidateMessageID checks a value is a single msg-id, such as the value
+// of a Message-Id header
+func ValidateMessageID(value string) error {
+	return validMessageId(value)
+}
+
+// validMessageIdDomain checks a domain is suitable for the right hand
+// side of a message ID. A single label, such as localhost, is valid
+// syntax but unlikely to be unique.
+func validMessageIdDomain(domain string) error {
+	if !strings.Contains(domain, ".") {
+		return fmt.Errorf("'%s' is not a fully qualified domain", domain)
+	}
+	return validMessageId("<x@" + domain + ">")
+}
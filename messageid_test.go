@@ -0,0 +1,116 @@
+package orderedheaders
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestNewMessageID(t *testing.T) {
+	seen := map[string]struct{}{}
+	for i := 0; i < 1000; i++ {
+		id := NewMessageID("Example.COM")
+		if err := validMessageId(id); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasSuffix(id, "@example.com>") {
+			t.Fatalf("unexpected domain in %s", id)
+		}
+		if _, ok := seen[id]; ok {
+			t.Fatalf("duplicate message id %s", id)
+		}
+		seen[id] = struct{}{}
+	}
+	if id := NewMessageID("not a domain"); !strings.HasSuffix(id, "@localhost.invalid>") {
+		t.Errorf("unexpected fallback domain in %s", id)
+	}
+}
+
+func TestEnsureMessageID(t *testing.T) {
+	h := &Header{}
+	if err := h.EnsureMessageID("example.com"); err != nil {
+		t.Fatal(err)
+	}
+	id := h.Get("Message-Id")
+	if id == "" {
+		t.Fatal("expected a Message-Id")
+	}
+	if err := h.EnsureMessageID("example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if h.Get("Message-Id") != id || len(h.Headers) != 1 {
+		t.Errorf("EnsureMessageID replaced an existing Message-Id")
+	}
+}
+
+func TestValidMessageId(t *testing.T) {
+	tests := []struct {
+		id    string
+		valid bool
+	}{
+		{"<1@example.com>", true},
+		{"  <a.b+c@mail.example.com>\r\n", true},
+		{"<x@localhost>", true},
+		{"<x@[192.0.2.1]>", true},
+		{"<x@[bad\\literal]>", false},
+		{"<1@example.com> trailing", false},
+		{"<1@example.com><2@example.com>", false},
+		{"<a,b@example.com>", false},
+		{"<a..b@example.com>", false},
+		{"<.a@example.com>", false},
+		{"<a@example.com.>", false},
+		{"<@example.com>", false},
+		{"<a@>", false},
+		{"<a@example.com", false},
+		{"a@example.com", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if err := validMessageId(tt.id); (err == nil) != tt.valid {
+			t.Errorf("validMessageId(%q) = %v, want valid %v", tt.id, err, tt.valid)
+		}
+	}
+}
+
+func TestValidMessageIdList(t *testing.T) {
+	tests := []struct {
+		ids   string
+		valid bool
+	}{
+		{"<1@example.com>", true},
+		{"<1@example.com> <2@example.com>\r\n <3@example.com>", true},
+		{"<1@example.com>, <2@example.com>", true},
+		{"<1@example.com> garbage", false},
+		{"", false},
+		{"  ", false},
+	}
+	for _, tt := range tests {
+		if err := validMessageIdList(tt.ids); (err == nil) != tt.valid {
+			t.Errorf("validMessageIdList(%q) = %v, want valid %v", tt.ids, err, tt.valid)
+		}
+	}
+}
+
+// messageIdRe is the regular expression validMessageId used to use, kept
+// for comparison
+var messageIdRe = regexp.MustCompile("^\\s*<[a-zA-Z0-9!#$%&'*+-/=?^_`{|}~]+(?:\\.[a-zA-Z0-9!#$%&'*+-/=?^_`{|}~]+)*@[a-zA-Z0-9!#$%&'*+-/=?^_`{|}~]+(?:\\.[a-zA-Z0-9!#$%&'*+-/=?^_`{|}~]+)>\\s*")
+
+const benchmarkMessageId = "<CAF1a2b3c4d5e6f7g8h9i0jklmnopqrstuvwxyz.1234567890@mail.example.com>"
+
+func BenchmarkValidMessageId(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := validMessageId(benchmarkMessageId); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkValidMessageIdRegexp(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if !messageIdRe.MatchString(benchmarkMessageId) {
+			b.Fatal("no match")
+		}
+	}
+}
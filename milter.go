@@ -0,0 +1,150 @@
+package orderedheaders
+
+// MilterOpType is a milter header modification action
+type MilterOpType int
+
+const (
+	// MilterChangeHeader replaces the Index'th field named Key, counting
+	// from 1, with Value, or deletes it if Value is empty
+	MilterChangeHeader MilterOpType = iota
+	// MilterAddHeader appends a field to the header
+	MilterAddHeader
+	// MilterInsertHeader inserts a field before the field at Index,
+	// counting from 0
+	MilterInsertHeader
+)
+
+// MilterOp is a single header modification, as sent by a milter with
+// SMFIR_CHGHEADER, SMFIR_ADDHEADER or SMFIR_INSHEADER
+type MilterOp struct {
+	Type  MilterOpType
+	Index int
+	Key   string
+	Value string
+}
+
+// MilterOps returns the milter modifications that turn the orig header
+// into modified, to be sent in order. Fields are matched by key and
+// value, so reordered fields are deleted and reinserted, and a field
+// whose value has changed is replaced in place if that keeps it in the
+// same order relative to the fields around it.
+//
+// Changes and deletions are sent first, from the end of the header
+// backwards, so that each index is valid when it's applied, followed by
+// insertions from the start of the header forwards. Indices count only
+// the fields in orig, so if the MTA has prepended fields of its own,
+// such as Received, that the milter wasn't shown, MilterInsertHeader
+// indices need to be offset by the number of them.
+func MilterOps(orig, modified Header) []MilterOp {
+	orig.CanonicalizeKeys()
+	modified.CanonicalizeKeys()
+	a, b := orig.Headers, modified.Headers
+	matches := matchFields(a, b, sameField)
+
+	// changed maps positions in a to the positions in b that replace
+	// them, and inserted marks the positions in b that are new
+	changed := map[int]int{}
+	deleted := map[int]bool{}
+	inserted := map[int]bool{}
+	ai, bi := 0, 0
+	for _, m := range append(matches, [2]int{len(a), len(b)}) {
+		// pair fields with the same key in the gap before this match,
+		// keeping their relative order so that each change is applied
+		// in place, and delete and insert the rest
+		for i := ai; i < m[0]; i++ {
+			deleted[i] = true
+		}
+		for j := bi; j < m[1]; j++ {
+			inserted[j] = true
+		}
+		for _, p := range matchFields(a[ai:m[0]], b[bi:m[1]], sameKey) {
+			changed[ai+p[0]] = bi + p[1]
+			delete(deleted, ai+p[0])
+			delete(inserted, bi+p[1])
+		}
+		ai, bi = m[0]+1, m[1]+1
+	}
+
+	var ops []MilterOp
+	for i := len(a) - 1; i >= 0; i-- {
+		j, isChanged := changed[i]
+		if !isChanged && !deleted[i] {
+			continue
+		}
+		occurrence := 0
+		for k := 0; k <= i; k++ {
+			if a[k].Key == a[i].Key {
+				occurrence++
+			}
+		}
+		op := MilterOp{Type: MilterChangeHeader, Index: occurrence, Key: a[i].Key}
+		if isChanged {
+			op.Value = b[j].Value
+		}
+		ops = append(ops, op)
+	}
+
+	// after deletions the header holds just the fields of modified that
+	// aren't inserted, so inserting in order puts each at its final
+	// position
+	present := len(a) - len(deleted)
+	for j := range b {
+		if !inserted[j] {
+			continue
+		}
+		if j >= present {
+			ops = append(ops, MilterOp{Type: MilterAddHeader, Key: b[j].Key, Value: b[j].Value})
+		} else {
+			ops = append(ops, MilterOp{Type: MilterInsertHeader, Index: j, Key: b[j].Key, Value: b[j].Value})
+		}
+		present++
+	}
+	return ops
+}
+
+// sameField reports whether two fields have the same key and value
+func sameField(x, y KV) bool {
+	return x.Key == y.Key && x.Value == y.Value
+}
+
+// sameKey reports whether two fields have the same key
+func sameKey(x, y KV) bool {
+	return x.Key == y.Key
+}
+
+// matchFields returns the pairs of indices of a longest common
+// subsequence of fields in a and b that are the same, in order
+func matchFields(a, b []KV, same func(x, y KV) bool) [][2]int {
+	// lengths[i][j] is the length of the longest common subsequence of
+	// a[i:] and b[j:]
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case same(a[i], b[j]):
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			case lengths[i+1][j] >= lengths[i][j+1]:
+				lengths[i][j] = lengths[i+1][j]
+			default:
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+	var matches [][2]int
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case same(a[i], b[j]):
+			matches = append(matches, [2]int{i, j})
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return matches
+}
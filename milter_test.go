@@ -0,0 +1,133 @@
+package orderedheaders
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// applyMilterOps applies milter modifications the way an MTA would
+func applyMilterOps(t *testing.T, h Header, ops []MilterOp) Header {
+	kvs := append([]KV(nil), h.Headers...)
+	for _, op := range ops {
+		switch op.Type {
+		case MilterChangeHeader:
+			n := 0
+			found := false
+			for i, kv := range kvs {
+				if kv.Key != op.Key {
+					continue
+				}
+				n++
+				if n == op.Index {
+					if op.Value == "" {
+						kvs = append(kvs[:i], kvs[i+1:]...)
+					} else {
+						kvs[i].Value = op.Value
+					}
+					found = true
+					break
+				}
+			}
+			if !found {
+				t.Fatalf("no field %s #%d", op.Key, op.Index)
+			}
+		case MilterAddHeader:
+			kvs = append(kvs, KV{Key: op.Key, Value: op.Value})
+		case MilterInsertHeader:
+			if op.Index > len(kvs) {
+				t.Fatalf("insert index %d out of range", op.Index)
+			}
+			kvs = append(kvs[:op.Index], append([]KV{{Key: op.Key, Value: op.Value}}, kvs[op.Index:]...)...)
+		}
+	}
+	return Header{Headers: kvs}
+}
+
+func TestMilterOpsReorder(t *testing.T) {
+	orig := Header{Headers: []KV{
+		{Key: "A", Value: "a"},
+		{Key: "X", Value: "x1"},
+		{Key: "Y", Value: "y1"},
+		{Key: "B", Value: "b"},
+	}}
+	modified := Header{Headers: []KV{
+		{Key: "A", Value: "a"},
+		{Key: "Y", Value: "y2"},
+		{Key: "X", Value: "x2"},
+		{Key: "B", Value: "b"},
+	}}
+	got := applyMilterOps(t, orig, MilterOps(orig, modified))
+	if diff := cmp.Diff(modified.Headers, got.Headers); diff != "" {
+		t.Errorf("applied ops mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMilterOps(t *testing.T) {
+	orig := Header{Headers: []KV{
+		{Key: "Received", Value: "from a"},
+		{Key: "X-Spam", Value: "no"},
+		{Key: "Subject", Value: "hello"},
+		{Key: "X-Spam", Value: "maybe"},
+		{Key: "From", Value: "a@example.com"},
+	}}
+	tests := map[string]struct {
+		Modified []KV
+		Want     []MilterOp
+	}{
+		"same": {Modified: orig.Headers},
+		"change": {
+			Modified: []KV{orig.Headers[0], orig.Headers[1], orig.Headers[2], {Key: "X-Spam", Value: "yes"}, orig.Headers[4]},
+			Want:     []MilterOp{{Type: MilterChangeHeader, Index: 2, Key: "X-Spam", Value: "yes"}},
+		},
+		"delete": {
+			Modified: []KV{orig.Headers[0], orig.Headers[2], orig.Headers[4]},
+			Want: []MilterOp{
+				{Type: MilterChangeHeader, Index: 2, Key: "X-Spam"},
+				{Type: MilterChangeHeader, Index: 1, Key: "X-Spam"},
+			},
+		},
+		"prepend": {
+			Modified: append([]KV{{Key: "Authentication-Results", Value: "mx; none"}}, orig.Headers...),
+			Want:     []MilterOp{{Type: MilterInsertHeader, Index: 0, Key: "Authentication-Results", Value: "mx; none"}},
+		},
+		"append": {
+			Modified: append(append([]KV(nil), orig.Headers...), KV{Key: "X-Checked", Value: "yes"}),
+			Want:     []MilterOp{{Type: MilterAddHeader, Key: "X-Checked", Value: "yes"}},
+		},
+		"move": {
+			Modified: []KV{orig.Headers[4], orig.Headers[0], orig.Headers[1], orig.Headers[2], orig.Headers[3]},
+		},
+		"mixed": {
+			Modified: []KV{
+				{Key: "X-New", Value: "1"},
+				orig.Headers[0],
+				{Key: "Subject", Value: "[list] hello"},
+				{Key: "X-New", Value: "2"},
+				orig.Headers[3],
+				orig.Headers[4],
+				{Key: "X-New", Value: "3"},
+			},
+		},
+		"swap": {
+			Modified: []KV{orig.Headers[0], orig.Headers[2], {Key: "X-Spam", Value: "yes"}, orig.Headers[4]},
+		},
+		"empty": {Modified: nil},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			modified := Header{Headers: test.Modified}
+			ops := MilterOps(orig, modified)
+			if test.Want != nil {
+				if diff := cmp.Diff(test.Want, ops); diff != "" {
+					t.Errorf("ops mismatch (-want +got):\n%s", diff)
+				}
+			}
+			got := applyMilterOps(t, orig, ops)
+			if diff := cmp.Diff(modified.Headers, got.Headers, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("applied ops mismatch (-want +got):\n%s\nops: %+v", diff, ops)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package orderedheaders
+
+import (
+	"fmt"
+	"strings"
+)
+
+// https://tools.wordtothewise.com/rfc2045#section-4
+
+// mimeVersion is the only MIME version there has ever been
+const mimeVersion = "1.0"
+
+// EnsureMIME adds a MIME-Version header, immediately before the first
+// Content-* header, if there are any Content-* headers but no
+// MIME-Version. Without it recipients may ignore the MIME headers.
+func (h *Header) EnsureMIME() {
+	h.CanonicalizeKeys()
+	if h.Has(HdrMimeVersion) {
+		return
+	}
+	for i, kv := range h.Headers {
+		if strings.HasPrefix(kv.Key, "Content-") {
+			h.insert(i, KV{Key: HdrMimeVersion, Value: mimeVersion})
+			return
+		}
+	}
+}
+
+// ValidateMIMEVersion checks a MIME-Version value is 1.0. Comments and
+// whitespace are ignored, so "1.(produced by MetaSend Vx.x)0" is valid.
+func ValidateMIMEVersion(value string) error {
+	if stripFWS(StripComments(value)) != mimeVersion {
+		return fmt.Errorf("'%s' is not a valid MIME-Version, it must be %s", value, mimeVersion)
+	}
+	return nil
+}
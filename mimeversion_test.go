@@ -0,0 +1,62 @@
+package orderedheaders
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEnsureMIME(t *testing.T) {
+	tests := map[string]struct {
+		In   []KV
+		Want []KV
+	}{
+		"empty": {},
+		"no mime": {
+			In:   []KV{{Key: "From", Value: "a@example.com"}, {Key: "Subject", Value: "hi"}},
+			Want: []KV{{Key: "From", Value: "a@example.com"}, {Key: "Subject", Value: "hi"}},
+		},
+		"missing": {
+			In:   []KV{{Key: "From", Value: "a@example.com"}, {Key: "Content-Type", Value: "text/plain"}, {Key: "Content-Transfer-Encoding", Value: "7bit"}},
+			Want: []KV{{Key: "From", Value: "a@example.com"}, {Key: "Mime-Version", Value: "1.0"}, {Key: "Content-Type", Value: "text/plain"}, {Key: "Content-Transfer-Encoding", Value: "7bit"}},
+		},
+		"present": {
+			In:   []KV{{Key: "Content-Type", Value: "text/plain"}, {Key: "Mime-Version", Value: "1.0 (generated)"}},
+			Want: []KV{{Key: "Content-Type", Value: "text/plain"}, {Key: "Mime-Version", Value: "1.0 (generated)"}},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := Header{Headers: test.In}
+			h.EnsureMIME()
+			if diff := cmp.Diff(test.Want, h.Headers); diff != "" {
+				t.Errorf("EnsureMIME mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateMIMEVersion(t *testing.T) {
+	tests := map[string]bool{
+		"1.0":                            true,
+		" 1.0 ":                          true,
+		"1.0 (produced by example 2.1)":  true,
+		"1.(produced by MetaSend Vx.x)0": true,
+		"(comment (nested)) 1.0":         true,
+		"1.1":                            false,
+		"1":                              false,
+		"":                               false,
+		"1.0 extra":                      false,
+		"1.0 (unterminated":              false,
+	}
+	for in, valid := range tests {
+		if err := ValidateMIMEVersion(in); (err == nil) != valid {
+			t.Errorf("%q: got error %v, want valid %v", in, err, valid)
+		}
+	}
+
+	var h Header
+	if err := h.Set(HdrMimeVersion, "2.0"); err == nil {
+		t.Error("Set accepted MIME-Version 2.0")
+	}
+}
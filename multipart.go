@@ -0,0 +1,166 @@
+package orderedheaders
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/textproto"
+	"strings"
+)
+
+// https://tools.wordtothewise.com/rfc2046#section-5.1.1
+
+// ErrNotMultipart is returned by Parts for a message that isn't multipart
+var ErrNotMultipart = errors.New("message is not multipart")
+
+// IsMultipart reports whether the message has a multipart Content-Type
+// with a boundary
+func (m *Message) IsMultipart() bool {
+	_, err := m.boundary()
+	return err == nil
+}
+
+// mediaType returns the lower case media type and parameters of the
+// message, falling back to the default if Content-Type is missing or
+// can't be parsed
+// https://tools.wordtothewise.com/rfc2045#section-5.2
+func (m *Message) mediaType() (string, map[string]string) {
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get(HdrContentType))
+	if err == nil {
+		return mediaType, params
+	}
+	if m.defaultType != "" {
+		return m.defaultType, map[string]string{}
+	}
+	return "text/plain", map[string]string{"charset": "us-ascii"}
+}
+
+func (m *Message) boundary() (string, error) {
+	mediaType, params := m.mediaType()
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		return "", ErrNotMultipart
+	}
+	boundary := params["boundary"]
+	if boundary == "" {
+		return "", fmt.Errorf("%s has no boundary", mediaType)
+	}
+	return boundary, nil
+}
+
+// Parts splits the body of a multipart message into its parts, in order.
+// Each part is returned as a Message, with the fields of its header in
+// order and their raw bytes retained, and a body that can be split in
+// turn if the part is itself multipart. The message body is read, and
+// replaced with an in memory copy so it can be read again.
+func (m *Message) Parts() ([]*Message, error) {
+	boundary, err := m.boundary()
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(m.Body)
+	if err != nil {
+		return nil, err
+	}
+	m.Body = bytes.NewReader(body)
+
+	// https://tools.wordtothewise.com/rfc2046#section-5.1.5
+	var defaultType string
+	if mediaType, _ := m.mediaType(); mediaType == "multipart/digest" {
+		defaultType = "message/rfc822"
+	}
+	var parts []*Message
+	for _, content := range splitMultipart(body, boundary) {
+		part, err := readPart(content)
+		if err != nil {
+			return nil, err
+		}
+		part.defaultType = defaultType
+		parts = append(parts, part)
+	}
+	return parts, nil
+}
+
+// Walk calls fn for the message and then, depth first, for every part
+// of it, recursing into nested multiparts and embedded messages. Depth
+// is zero for the message itself. If fn returns an error the walk stops
+// and returns it.
+func (m *Message) Walk(fn func(part *Message, depth int) error) error {
+	return m.walk(fn, 0)
+}
+
+func (m *Message) walk(fn func(part *Message, depth int) error, depth int) error {
+	if err := fn(m, depth); err != nil {
+		return err
+	}
+	if m.IsEmbedded() {
+		inner, err := m.Embedded()
+		if err != nil {
+			return err
+		}
+		return inner.walk(fn, depth+1)
+	}
+	if !m.IsMultipart() {
+		return nil
+	}
+	parts, err := m.Parts()
+	if err != nil {
+		return err
+	}
+	for _, p := range parts {
+		if err := p.walk(fn, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readPart parses the header and body of a single body part
+func readPart(content []byte) (*Message, error) {
+	tp := textproto.NewReader(bufio.NewReader(bytes.NewReader(content)))
+	return readMessageHeader(tp, ReadOptions{KeepRaw: true}, true)
+}
+
+// splitMultipart returns the content of each body part between the
+// delimiter lines. The line ending before each delimiter belongs to the
+// delimiter. A missing close delimiter is tolerated.
+func splitMultipart(body []byte, boundary string) [][]byte {
+	delimiter := []byte("--" + boundary)
+	var parts [][]byte
+	start := -1
+	for pos := 0; pos < len(body); {
+		end := bytes.IndexByte(body[pos:], '\n')
+		next := len(body)
+		if end >= 0 {
+			next = pos + end + 1
+		}
+		line := bytes.TrimRight(body[pos:next], " \t\r\n")
+		if bytes.HasPrefix(line, delimiter) {
+			rest := line[len(delimiter):]
+			if len(rest) == 0 || bytes.Equal(rest, []byte("--")) {
+				if start >= 0 {
+					parts = append(parts, trimLineEnding(body[start:pos]))
+				}
+				if len(rest) != 0 {
+					return parts
+				}
+				start = next
+			}
+		}
+		pos = next
+	}
+	if start >= 0 {
+		parts = append(parts, body[start:])
+	}
+	return parts
+}
+
+// trimLineEnding removes a single trailing CRLF or LF
+func trimLineEnding(b []byte) []byte {
+	if bytes.HasSuffix(b, []byte("\r\n")) {
+		return b[:len(b)-2]
+	}
+	return bytes.TrimSuffix(b, []byte("\n"))
+}
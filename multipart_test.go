@@ -0,0 +1,162 @@
+package orderedheaders
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const nestedMultipart = "From: a@example.com\r\n" +
+	"Content-Type: multipart/mixed; boundary=\"outer\"\r\n" +
+	"\r\n" +
+	"This is the preamble\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=inner\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"X-Order: 1\r\n" +
+	"\r\n" +
+	"plain text\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/html\r\n" +
+	"\r\n" +
+	"<p>html</p>\r\n" +
+	"--inner--\r\n" +
+	"--outer \r\n" +
+	"Content-Type: application/octet-stream;\r\n" +
+	"\tname=data.bin\r\n" +
+	"\r\n" +
+	"binary\r\n\r\n" +
+	"--outer--\r\n" +
+	"epilogue\r\n"
+
+func TestParts(t *testing.T) {
+	msg, err := ReadMessage(strings.NewReader(nestedMultipart))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts, err := msg.Parts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 2 {
+		t.Fatalf("expected 2 parts, got %d", len(parts))
+	}
+	want := []KV{{
+		Key:   "Content-Type",
+		Value: "application/octet-stream; name=data.bin",
+		Raw:   "Content-Type: application/octet-stream;\r\n\tname=data.bin\r\n",
+	}}
+	if diff := cmp.Diff(want, parts[1].Header.Headers); diff != "" {
+		t.Errorf("header mismatch (-want +got):\n%s", diff)
+	}
+	body, _ := io.ReadAll(parts[1].Body)
+	if string(body) != "binary\r\n" {
+		t.Errorf("unexpected body %q", body)
+	}
+
+	// the body can be split again
+	again, err := msg.Parts()
+	if err != nil || len(again) != 2 {
+		t.Errorf("second Parts() = %d parts, %v", len(again), err)
+	}
+
+	inner, err := parts[0].Parts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inner) != 2 {
+		t.Fatalf("expected 2 inner parts, got %d", len(inner))
+	}
+	if got := inner[0].Header.Get("X-Order"); got != "1" {
+		t.Errorf("unexpected X-Order '%s'", got)
+	}
+	body, _ = io.ReadAll(inner[1].Body)
+	if string(body) != "<p>html</p>" {
+		t.Errorf("unexpected body %q", body)
+	}
+
+	if _, err := inner[0].Parts(); !errors.Is(err, ErrNotMultipart) {
+		t.Errorf("expected ErrNotMultipart, got %v", err)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	msg, err := ReadMessage(strings.NewReader(nestedMultipart))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	err = msg.Walk(func(part *Message, depth int) error {
+		got = append(got, strings.Repeat(" ", depth)+strings.SplitN(part.Header.Get("Content-Type"), ";", 2)[0])
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"multipart/mixed",
+		" multipart/alternative",
+		"  text/plain",
+		"  text/html",
+		" application/octet-stream",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Walk mismatch (-want +got):\n%s", diff)
+	}
+
+	stop := errors.New("stop")
+	count := 0
+	err = msg.Walk(func(part *Message, depth int) error {
+		count++
+		if count == 2 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || count != 2 {
+		t.Errorf("expected walk to stop after 2 parts, got %d, %v", count, err)
+	}
+}
+
+func TestSplitMultipart(t *testing.T) {
+	tests := map[string]struct {
+		In   string
+		Want []string
+	}{
+		"lf":        {In: "--b\nA: 1\n\none\n--b\n\ntwo\n--b--\n", Want: []string{"A: 1\n\none", "\ntwo"}},
+		"unclosed":  {In: "--b\n\none\n--b\n\ntwo\n", Want: []string{"\none", "\ntwo\n"}},
+		"lookalike": {In: "--b\n\n--bx\n--b--", Want: []string{"\n--bx"}},
+		"none":      {In: "no parts here\n"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var got []string
+			for _, p := range splitMultipart([]byte(test.In), "b") {
+				got = append(got, string(p))
+			}
+			if diff := cmp.Diff(test.Want, got); diff != "" {
+				t.Errorf("splitMultipart mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPartRawHeaderBlock(t *testing.T) {
+	msg, err := ReadMessage(strings.NewReader(nestedMultipart))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts, err := msg.Parts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Content-Type: multipart/alternative; boundary=inner\r\n\r\n"
+	if got := string(parts[0].RawHeaderBlock()); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,171 @@
+package orderedheaders
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strings"
+)
+
+// JSONVersion is the version of the JSON form of a header written by
+// MarshalJSON, and by HeaderEncoder when asked to include it. Readers
+// accept documents of this version or earlier, including those with no
+// version, which are version 1.
+const JSONVersion = 1
+
+// jsonHeader is the JSON form of a header
+type jsonHeader struct {
+	Version   int         `json:"version,omitempty"`
+	Headers   []jsonField `json:"headers"`
+	Truncated bool        `json:"truncated,omitempty"`
+}
+
+// jsonField is the JSON form of a field. Key is always canonical, and
+// RawKey is the field name as it was read, if that was different. Raw
+// is base64 encoded.
+type jsonField struct {
+	Key    string `json:"key"`
+	RawKey string `json:"rawKey,omitempty"`
+	Value  string `json:"value"`
+	Raw    []byte `json:"raw,omitempty"`
+}
+
+// toJSON returns the JSON form of h
+func (h Header) toJSON(version int) jsonHeader {
+	doc := jsonHeader{Version: version, Headers: make([]jsonField, len(h.Headers)), Truncated: h.Truncated}
+	for i, kv := range h.Headers {
+		key := textproto.CanonicalMIMEHeaderKey(kv.Key)
+		rawKey := kv.Key
+		var raw []byte
+		if kv.Raw != "" {
+			raw = []byte(kv.Raw)
+			if colon := strings.IndexByte(kv.Raw, ':'); colon >= 0 {
+				rawKey = strings.TrimRight(kv.Raw[:colon], " \t")
+			}
+		}
+		if rawKey == key {
+			rawKey = ""
+		}
+		doc.Headers[i] = jsonField{Key: key, RawKey: rawKey, Value: kv.Value, Raw: raw}
+	}
+	return doc
+}
+
+// fromJSON returns the header in doc
+func fromJSON(doc jsonHeader) (Header, error) {
+	if doc.Version > JSONVersion {
+		return Header{}, fmt.Errorf("unsupported header JSON version %d", doc.Version)
+	}
+	h := Header{Headers: make([]KV, len(doc.Headers)), Truncated: doc.Truncated}
+	for i, f := range doc.Headers {
+		h.Headers[i] = KV{Key: textproto.CanonicalMIMEHeaderKey(f.Key), Value: f.Value, Raw: string(f.Raw)}
+	}
+	return h, nil
+}
+
+// MarshalJSON writes the header in the form HeaderEncoder does, with
+// the version included, such as
+//
+//	{"version":1,"headers":[{"key":"From","value":"alice@example.com"}]}
+func (h Header) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.toJSON(JSONVersion))
+}
+
+// UnmarshalJSON reads a header written by MarshalJSON or HeaderEncoder.
+// Unknown fields are ignored, so that documents written by later
+// versions of this package can be read; use UnmarshalHeaderJSON to
+// reject them. Keys are canonicalized, and rawKey is ignored, as Raw has
+// the exact bytes.
+func (h *Header) UnmarshalJSON(data []byte) error {
+	ret, err := UnmarshalHeaderJSON(data, JSONOptions{})
+	if err != nil {
+		return err
+	}
+	*h = ret
+	return nil
+}
+
+// JSONOptions configures UnmarshalHeaderJSON
+type JSONOptions struct {
+	// Strict rejects documents with fields that aren't part of the JSON
+	// form of a header
+	Strict bool
+}
+
+// UnmarshalHeaderJSON reads a header written by MarshalJSON or
+// HeaderEncoder, configured by o
+func UnmarshalHeaderJSON(data []byte, o JSONOptions) (Header, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if o.Strict {
+		dec.DisallowUnknownFields()
+	}
+	var doc jsonHeader
+	if err := dec.Decode(&doc); err != nil {
+		return Header{}, err
+	}
+	if dec.More() {
+		return Header{}, errors.New("unexpected data after header JSON")
+	}
+	return fromJSON(doc)
+}
+
+// HeaderEncoder writes headers to a stream as newline delimited JSON,
+// one document per header, such as
+//
+//	{"headers":[{"key":"From","value":"alice@example.com"},{"key":"Subject","value":"hello"}]}
+//
+// with the fields in order. Raw bytes, where present, are included
+// base64 encoded as "raw", and a field name that was read in a different
+// case as "rawKey". Truncated headers are marked "truncated".
+type HeaderEncoder struct {
+	enc     *json.Encoder
+	version int
+}
+
+// NewHeaderEncoder returns a HeaderEncoder that writes to w
+func NewHeaderEncoder(w io.Writer) *HeaderEncoder {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &HeaderEncoder{enc: enc}
+}
+
+// IncludeVersion adds the JSONVersion to each document, as MarshalJSON
+// does, for archives that need to outlive the current format
+func (e *HeaderEncoder) IncludeVersion() {
+	e.version = JSONVersion
+}
+
+// Encode writes h to the stream, followed by a newline
+func (e *HeaderEncoder) Encode(h Header) error {
+	return e.enc.Encode(h.toJSON(e.version))
+}
+
+// HeaderDecoder reads headers written by HeaderEncoder
+type HeaderDecoder struct {
+	dec *json.Decoder
+}
+
+// NewHeaderDecoder returns a HeaderDecoder that reads from r
+func NewHeaderDecoder(r io.Reader) *HeaderDecoder {
+	return &HeaderDecoder{dec: json.NewDecoder(r)}
+}
+
+// DisallowUnknownFields makes Decode return an error for documents with
+// fields that aren't part of the JSON form of a header, as
+// JSONOptions.Strict does
+func (d *HeaderDecoder) DisallowUnknownFields() {
+	d.dec.DisallowUnknownFields()
+}
+
+// Decode reads the next header from the stream. It returns io.EOF when
+// there are no more.
+func (d *HeaderDecoder) Decode() (Header, error) {
+	var doc jsonHeader
+	if err := d.dec.Decode(&doc); err != nil {
+		return Header{}, err
+	}
+	return fromJSON(doc)
+}
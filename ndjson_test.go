@@ -0,0 +1,138 @@
+package orderedheaders
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestHeaderEncoder(t *testing.T) {
+	first, err := ReadHeaderWithOptions(reader("From: Alice <alice@example.com>\r\nSubject: hello\r\n\r\n"), ReadOptions{KeepRaw: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := Header{Headers: []KV{
+		{Key: "Received", Value: "from a"},
+		{Key: "Received", Value: "from b"},
+	}}
+	var buf bytes.Buffer
+	enc := NewHeaderEncoder(&buf)
+	for _, h := range []Header{first, second} {
+		if err := enc.Encode(h); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", buf.String())
+	}
+	if want := `{"headers":[{"key":"Received","value":"from a"},{"key":"Received","value":"from b"}]}`; lines[1] != want {
+		t.Errorf("got %s, want %s", lines[1], want)
+	}
+
+	dec := NewHeaderDecoder(&buf)
+	for _, want := range []Header{first, second} {
+		got, err := dec.Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("header mismatch (-want +got):\n%s", diff)
+		}
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Errorf("expected EOF, got %v", err)
+	}
+}
+
+func TestHeaderDecoderCanonical(t *testing.T) {
+	dec := NewHeaderDecoder(strings.NewReader(`{"headers":[{"key":"content-type","value":"text/plain"}]}`))
+	h, err := dec.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := h.Get(HdrContentType); got != "text/plain" {
+		t.Errorf("unexpected Content-Type %q", got)
+	}
+}
+
+func TestHeaderJSON(t *testing.T) {
+	h, err := ReadHeaderWithOptions(reader("from: Alice <alice@example.com>\r\nSubject: hello\r\n\r\n"), ReadOptions{KeepRaw: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Truncated = true
+	b, err := json.Marshal(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"version":1,"headers":[{"key":"From","rawKey":"from","value":"Alice \u003calice@example.com\u003e","raw":"ZnJvbTogQWxpY2UgPGFsaWNlQGV4YW1wbGUuY29tPg0K"},{"key":"Subject","value":"hello","raw":"U3ViamVjdDogaGVsbG8NCg=="}],"truncated":true}`
+	if string(b) != want {
+		t.Errorf("got %s, want %s", b, want)
+	}
+	var got Header
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(h, got); diff != "" {
+		t.Errorf("header mismatch (-want +got):\n%s", diff)
+	}
+
+	lazy := Header{Headers: []KV{{Key: "x-mailer", Value: "m"}}, RawKeys: true}
+	var buf bytes.Buffer
+	enc := NewHeaderEncoder(&buf)
+	enc.IncludeVersion()
+	if err := enc.Encode(lazy); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"version":1,"headers":[{"key":"X-Mailer","rawKey":"x-mailer","value":"m"}]}` + "\n"; buf.String() != want {
+		t.Errorf("got %s, want %s", buf.String(), want)
+	}
+}
+
+func TestUnmarshalHeaderJSON(t *testing.T) {
+	tests := map[string]struct {
+		In        string
+		Strict    bool
+		WantError bool
+		Want      []KV
+	}{
+		"unversioned":    {In: `{"headers":[{"key":"subject","value":"hi"}]}`, Want: []KV{{Key: "Subject", Value: "hi"}}},
+		"versioned":      {In: `{"version":1,"headers":[{"key":"Subject","value":"hi"}]}`, Want: []KV{{Key: "Subject", Value: "hi"}}},
+		"strict":         {In: `{"version":1,"headers":[{"key":"Subject","rawKey":"SUBJECT","value":"hi"}]}`, Strict: true, Want: []KV{{Key: "Subject", Value: "hi"}}},
+		"future":         {In: `{"version":2,"headers":[]}`, WantError: true},
+		"unknown":        {In: `{"headers":[{"key":"Subject","value":"hi","charset":"utf-8"}],"source":"mx1"}`, Want: []KV{{Key: "Subject", Value: "hi"}}},
+		"strict unknown": {In: `{"headers":[{"key":"Subject","value":"hi","charset":"utf-8"}]}`, Strict: true, WantError: true},
+		"strict top":     {In: `{"headers":[],"source":"mx1"}`, Strict: true, WantError: true},
+		"trailing":       {In: `{"headers":[]} {"headers":[]}`, WantError: true},
+		"bad raw":        {In: `{"headers":[{"key":"Subject","value":"hi","raw":"!"}]}`, WantError: true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := UnmarshalHeaderJSON([]byte(test.In), JSONOptions{Strict: test.Strict})
+			if test.WantError {
+				if err == nil {
+					t.Errorf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.Want, got.Headers, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("header mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	dec := NewHeaderDecoder(strings.NewReader(`{"headers":[],"extra":1}`))
+	dec.DisallowUnknownFields()
+	if _, err := dec.Decode(); err == nil {
+		t.Errorf("expected error for unknown field")
+	}
+}
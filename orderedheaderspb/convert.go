@@ -0,0 +1,76 @@
+// Package orderedheaderspb is a protobuf encoding of headers and
+// messages that keeps field order and, optionally, the raw bytes of each
+// field, for services that exchange parsed mail over gRPC.
+//
+// It's a separate module so that orderedheaders itself doesn't depend on
+// protobuf.
+package orderedheaderspb
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/wttw/orderedheaders"
+)
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative orderedheaders.proto
+
+// HeaderToProto converts h to its protobuf form
+func HeaderToProto(h orderedheaders.Header) *Header {
+	h.CanonicalizeKeys()
+	p := &Header{Fields: make([]*KV, 0, len(h.Headers))}
+	for _, kv := range h.Headers {
+		p.Fields = append(p.Fields, &KV{Key: kv.Key, Value: kv.Value, Raw: []byte(kv.Raw)})
+	}
+	return p
+}
+
+// HeaderFromProto converts a protobuf header to a Header
+func HeaderFromProto(p *Header) orderedheaders.Header {
+	var h orderedheaders.Header
+	for _, f := range p.GetFields() {
+		kv := orderedheaders.KV{Key: f.GetKey(), Value: f.GetValue()}
+		if len(f.GetRaw()) > 0 {
+			kv.Raw = string(f.GetRaw())
+		}
+		h.Headers = append(h.Headers, kv)
+	}
+	return h
+}
+
+// MessageToProto converts m to its protobuf form, reading the whole body
+// into BodyData. To send a reference to a body stored elsewhere instead,
+// use HeaderToProto and set Body to a Message_BodyRef.
+func MessageToProto(m *orderedheaders.Message) (*Message, error) {
+	p := &Message{Header: HeaderToProto(m.Header)}
+	if m.Body != nil {
+		body, err := io.ReadAll(m.Body)
+		if err != nil {
+			return nil, err
+		}
+		p.Body = &Message_BodyData{BodyData: body}
+	}
+	return p, nil
+}
+
+// MessageFromProto converts a protobuf message to a Message. A body
+// reference is passed to resolve, which returns the body; it can be nil
+// if references aren't expected.
+func MessageFromProto(p *Message, resolve func(ref string) (io.Reader, error)) (*orderedheaders.Message, error) {
+	m := &orderedheaders.Message{Header: HeaderFromProto(p.GetHeader())}
+	switch body := p.GetBody().(type) {
+	case *Message_BodyData:
+		m.Body = bytes.NewReader(body.BodyData)
+	case *Message_BodyRef:
+		if resolve == nil {
+			return nil, errors.New("message body is a reference, but there's no resolver")
+		}
+		r, err := resolve(body.BodyRef)
+		if err != nil {
+			return nil, err
+		}
+		m.Body = r
+	}
+	return m, nil
+}
@@ -0,0 +1,75 @@
+package orderedheaderspb
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wttw/orderedheaders"
+	"google.golang.org/protobuf/proto"
+)
+
+const testMessage = "Received: from a by b\r\n" +
+	"Subject: hello\r\n" +
+	"  there\r\n" +
+	"Received: from c by d\r\n" +
+	"\r\n" +
+	"body\r\n"
+
+func TestMessageRoundTrip(t *testing.T) {
+	m, err := orderedheaders.ReadMessageWithOptions(strings.NewReader(testMessage), orderedheaders.MessageOptions{ReadOptions: orderedheaders.ReadOptions{KeepRaw: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := m.Header
+	p, err := MessageToProto(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wire, err := proto.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Message
+	if err := proto.Unmarshal(wire, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	back, err := MessageFromProto(&decoded, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, back.Header); diff != "" {
+		t.Errorf("header mismatch (-want +got):\n%s", diff)
+	}
+	body, err := io.ReadAll(back.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "body\r\n" {
+		t.Errorf("unexpected body %q", body)
+	}
+}
+
+func TestMessageBodyRef(t *testing.T) {
+	p := &Message{
+		Header: HeaderToProto(orderedheaders.Header{Headers: []orderedheaders.KV{{Key: "Subject", Value: "hello"}}}),
+		Body:   &Message_BodyRef{BodyRef: "blob/1"},
+	}
+	if _, err := MessageFromProto(p, nil); err == nil {
+		t.Errorf("expected error without resolver")
+	}
+	m, err := MessageFromProto(p, func(ref string) (io.Reader, error) {
+		return strings.NewReader("stored " + ref), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(m.Body)
+	if string(body) != "stored blob/1" {
+		t.Errorf("unexpected body %q", body)
+	}
+	if m.Header.Headers[0].Raw != "" {
+		t.Errorf("empty raw should be nil")
+	}
+}
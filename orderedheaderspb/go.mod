@@ -0,0 +1,11 @@
+module github.com/wttw/orderedheaders/orderedheaderspb
+
+go 1.16
+
+require (
+	github.com/google/go-cmp v0.5.9
+	github.com/wttw/orderedheaders v0.0.0-00010101000000-000000000000
+	google.golang.org/protobuf v1.28.1
+)
+
+replace github.com/wttw/orderedheaders => ../
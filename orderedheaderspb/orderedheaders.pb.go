@@ -0,0 +1,347 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.28.1
+// 	protoc        (unknown)
+// source: orderedheaders.proto
+
+package orderedheaderspb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// KV is a single header field
+type KV struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Key   string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value string `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	// raw is the field exactly as read, including folding and the line
+	// ending, if it was kept
+	Raw []byte `protobuf:"bytes,3,opt,name=raw,proto3" json:"raw,omitempty"`
+}
+
+func (x *KV) Reset() {
+	*x = KV{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_orderedheaders_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *KV) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KV) ProtoMessage() {}
+
+func (x *KV) ProtoReflect() protoreflect.Message {
+	mi := &file_orderedheaders_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KV.ProtoReflect.Descriptor instead.
+func (*KV) Descriptor() ([]byte, []int) {
+	return file_orderedheaders_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *KV) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *KV) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *KV) GetRaw() []byte {
+	if x != nil {
+		return x.Raw
+	}
+	return nil
+}
+
+// Header is a message header, with the fields in order
+type Header struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Fields []*KV `protobuf:"bytes,1,rep,name=fields,proto3" json:"fields,omitempty"`
+}
+
+func (x *Header) Reset() {
+	*x = Header{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_orderedheaders_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Header) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Header) ProtoMessage() {}
+
+func (x *Header) ProtoReflect() protoreflect.Message {
+	mi := &file_orderedheaders_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Header.ProtoReflect.Descriptor instead.
+func (*Header) Descriptor() ([]byte, []int) {
+	return file_orderedheaders_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Header) GetFields() []*KV {
+	if x != nil {
+		return x.Fields
+	}
+	return nil
+}
+
+// Message is a header and a body, which is either included or a
+// reference to somewhere the body is stored
+type Message struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Header *Header `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	// Types that are assignable to Body:
+	//	*Message_BodyData
+	//	*Message_BodyRef
+	Body isMessage_Body `protobuf_oneof:"body"`
+}
+
+func (x *Message) Reset() {
+	*x = Message{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_orderedheaders_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Message) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Message) ProtoMessage() {}
+
+func (x *Message) ProtoReflect() protoreflect.Message {
+	mi := &file_orderedheaders_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Message.ProtoReflect.Descriptor instead.
+func (*Message) Descriptor() ([]byte, []int) {
+	return file_orderedheaders_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Message) GetHeader() *Header {
+	if x != nil {
+		return x.Header
+	}
+	return nil
+}
+
+func (m *Message) GetBody() isMessage_Body {
+	if m != nil {
+		return m.Body
+	}
+	return nil
+}
+
+func (x *Message) GetBodyData() []byte {
+	if x, ok := x.GetBody().(*Message_BodyData); ok {
+		return x.BodyData
+	}
+	return nil
+}
+
+func (x *Message) GetBodyRef() string {
+	if x, ok := x.GetBody().(*Message_BodyRef); ok {
+		return x.BodyRef
+	}
+	return ""
+}
+
+type isMessage_Body interface {
+	isMessage_Body()
+}
+
+type Message_BodyData struct {
+	BodyData []byte `protobuf:"bytes,2,opt,name=body_data,json=bodyData,proto3,oneof"`
+}
+
+type Message_BodyRef struct {
+	BodyRef string `protobuf:"bytes,3,opt,name=body_ref,json=bodyRef,proto3,oneof"`
+}
+
+func (*Message_BodyData) isMessage_Body() {}
+
+func (*Message_BodyRef) isMessage_Body() {}
+
+var File_orderedheaders_proto protoreflect.FileDescriptor
+
+var file_orderedheaders_proto_rawDesc = []byte{
+	0x0a, 0x14, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x65, 0x64, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0e, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x65, 0x64, 0x68,
+	0x65, 0x61, 0x64, 0x65, 0x72, 0x73, 0x22, 0x3e, 0x0a, 0x02, 0x4b, 0x56, 0x12, 0x10, 0x0a, 0x03,
+	0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14,
+	0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76,
+	0x61, 0x6c, 0x75, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x72, 0x61, 0x77, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x0c, 0x52, 0x03, 0x72, 0x61, 0x77, 0x22, 0x34, 0x0a, 0x06, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72,
+	0x12, 0x2a, 0x0a, 0x06, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x12, 0x2e, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x65, 0x64, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72,
+	0x73, 0x2e, 0x4b, 0x56, 0x52, 0x06, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x73, 0x22, 0x7d, 0x0a, 0x07,
+	0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x2e, 0x0a, 0x06, 0x68, 0x65, 0x61, 0x64, 0x65,
+	0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x65,
+	0x64, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73, 0x2e, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x52,
+	0x06, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x12, 0x1d, 0x0a, 0x09, 0x62, 0x6f, 0x64, 0x79, 0x5f,
+	0x64, 0x61, 0x74, 0x61, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x48, 0x00, 0x52, 0x08, 0x62, 0x6f,
+	0x64, 0x79, 0x44, 0x61, 0x74, 0x61, 0x12, 0x1b, 0x0a, 0x08, 0x62, 0x6f, 0x64, 0x79, 0x5f, 0x72,
+	0x65, 0x66, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x07, 0x62, 0x6f, 0x64, 0x79,
+	0x52, 0x65, 0x66, 0x42, 0x06, 0x0a, 0x04, 0x62, 0x6f, 0x64, 0x79, 0x42, 0x31, 0x5a, 0x2f, 0x67,
+	0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x77, 0x74, 0x74, 0x77, 0x2f, 0x6f,
+	0x72, 0x64, 0x65, 0x72, 0x65, 0x64, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73, 0x2f, 0x6f, 0x72,
+	0x64, 0x65, 0x72, 0x65, 0x64, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73, 0x70, 0x62, 0x62, 0x06,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_orderedheaders_proto_rawDescOnce sync.Once
+	file_orderedheaders_proto_rawDescData = file_orderedheaders_proto_rawDesc
+)
+
+func file_orderedheaders_proto_rawDescGZIP() []byte {
+	file_orderedheaders_proto_rawDescOnce.Do(func() {
+		file_orderedheaders_proto_rawDescData = protoimpl.X.CompressGZIP(file_orderedheaders_proto_rawDescData)
+	})
+	return file_orderedheaders_proto_rawDescData
+}
+
+var file_orderedheaders_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_orderedheaders_proto_goTypes = []interface{}{
+	(*KV)(nil),      // 0: orderedheaders.KV
+	(*Header)(nil),  // 1: orderedheaders.Header
+	(*Message)(nil), // 2: orderedheaders.Message
+}
+var file_orderedheaders_proto_depIdxs = []int32{
+	0, // 0: orderedheaders.Header.fields:type_name -> orderedheaders.KV
+	1, // 1: orderedheaders.Message.header:type_name -> orderedheaders.Header
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_orderedheaders_proto_init() }
+func file_orderedheaders_proto_init() {
+	if File_orderedheaders_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_orderedheaders_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*KV); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_orderedheaders_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Header); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_orderedheaders_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Message); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_orderedheaders_proto_msgTypes[2].OneofWrappers = []interface{}{
+		(*Message_BodyData)(nil),
+		(*Message_BodyRef)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_orderedheaders_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_orderedheaders_proto_goTypes,
+		DependencyIndexes: file_orderedheaders_proto_depIdxs,
+		MessageInfos:      file_orderedheaders_proto_msgTypes,
+	}.Build()
+	File_orderedheaders_proto = out.File
+	file_orderedheaders_proto_rawDesc = nil
+	file_orderedheaders_proto_goTypes = nil
+	file_orderedheaders_proto_depIdxs = nil
+}
@@ -0,0 +1,91 @@
+package orderedheaders
+
+import (
+	"errors"
+	"fmt"
+	"net/textproto"
+	"sort"
+)
+
+// ErrPatchConflict is returned by ApplyPatch when the header doesn't
+// match the one the changes were made against
+var ErrPatchConflict = errors.New("patch does not match header")
+
+// ApplyPatch applies changes produced by Diff, possibly after a round
+// trip through JSON, so that a header equal to the first argument of
+// Diff becomes equal to the second. Every removed, modified or moved
+// field is checked against its OldIndex, key and OldValue first, and if
+// any don't match the header is left unchanged and ErrPatchConflict
+// returned. Fields placed by the changes go to their NewIndex, with
+// untouched fields filling the gaps in their existing order. If the diff
+// ignored some fields, positions are relative to the fields that are
+// present.
+func (h *Header) ApplyPatch(changes []Change) error {
+	h.CanonicalizeKeys()
+	touched := map[int]struct{}{}
+	var placed []KV
+	var positions []int
+	for _, c := range changes {
+		key := textproto.CanonicalMIMEHeaderKey(c.Key)
+		switch c.Type {
+		case ChangeAdded:
+			if c.OldIndex != -1 || c.NewIndex < 0 {
+				return fmt.Errorf("invalid indices for added %s", key)
+			}
+		case ChangeRemoved:
+			if c.OldIndex < 0 || c.NewIndex != -1 {
+				return fmt.Errorf("invalid indices for removed %s", key)
+			}
+		case ChangeModified, ChangeMoved:
+			if c.OldIndex < 0 || c.NewIndex < 0 {
+				return fmt.Errorf("invalid indices for %v %s", c.Type, key)
+			}
+		default:
+			return fmt.Errorf("invalid change type %v", c.Type)
+		}
+		if c.OldIndex >= 0 {
+			if c.OldIndex >= len(h.Headers) {
+				return fmt.Errorf("%w: no field %d", ErrPatchConflict, c.OldIndex)
+			}
+			kv := h.Headers[c.OldIndex]
+			if kv.Key != key || kv.Value != c.OldValue {
+				return fmt.Errorf("%w: field %d is %s, not %s", ErrPatchConflict, c.OldIndex, kv.Key, key)
+			}
+			if _, ok := touched[c.OldIndex]; ok {
+				return fmt.Errorf("field %d changed more than once", c.OldIndex)
+			}
+			touched[c.OldIndex] = struct{}{}
+		}
+		if c.NewIndex >= 0 {
+			value := c.NewValue
+			if c.Type == ChangeMoved {
+				value = c.OldValue
+			}
+			placed = append(placed, KV{Key: key, Value: value})
+			positions = append(positions, c.NewIndex)
+		}
+	}
+
+	order := make([]int, len(placed))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(x, y int) bool { return positions[order[x]] < positions[order[y]] })
+
+	var kept []KV
+	for i, kv := range h.Headers {
+		if _, ok := touched[i]; !ok {
+			kept = append(kept, kv)
+		}
+	}
+	result := make([]KV, 0, len(kept)+len(placed))
+	for _, p := range order {
+		for len(result) < positions[p] && len(kept) > 0 {
+			result = append(result, kept[0])
+			kept = kept[1:]
+		}
+		result = append(result, placed[p])
+	}
+	h.Headers = append(result, kept...)
+	return nil
+}
@@ -0,0 +1,92 @@
+package orderedheaders
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestApplyPatch(t *testing.T) {
+	old := []KV{
+		{Key: "Received", Value: "from a"},
+		{Key: "From", Value: "a@example.com"},
+		{Key: "To", Value: "b@example.com"},
+		{Key: "Subject", Value: "hello"},
+		{Key: "X-Spam", Value: "no"},
+		{Key: "X-Old", Value: "gone"},
+	}
+	tests := map[string][]KV{
+		"same":    old,
+		"empty":   nil,
+		"prepend": append([]KV{{Key: "Received", Value: "from b"}}, old...),
+		"modify":  {old[0], old[1], old[2], {Key: "Subject", Value: "[list] hello"}, old[4], old[5]},
+		"reorder": {old[5], old[3], old[0], old[2], old[1], old[4]},
+		"modifymove": {
+			{Key: "X-Spam", Value: "yes"},
+			old[0],
+			{Key: "X-New", Value: "1"},
+			old[1], old[3],
+			{Key: "X-New", Value: "2"},
+		},
+	}
+	for name, want := range tests {
+		t.Run(name, func(t *testing.T) {
+			changes := Diff(Header{Headers: old}, Header{Headers: want})
+			encoded, err := json.Marshal(changes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var decoded []Change
+			if err := json.Unmarshal(encoded, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			h := Header{Headers: append([]KV(nil), old...)}
+			if err := h.ApplyPatch(decoded); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want, h.Headers, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("patched header mismatch (-want +got):\n%s\n%s", diff, encoded)
+			}
+		})
+	}
+}
+
+func TestChangeJSON(t *testing.T) {
+	changes := []Change{{Type: ChangeAdded, Key: "X-New", OldIndex: -1, NewIndex: 0, NewValue: "1"}}
+	encoded, err := json.Marshal(changes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"type":"added","key":"X-New","oldIndex":-1,"newIndex":0,"newValue":"1"}]`
+	if string(encoded) != want {
+		t.Errorf("want %s, got %s", want, encoded)
+	}
+}
+
+func TestApplyPatchConflict(t *testing.T) {
+	h := Header{Headers: []KV{{Key: "Subject", Value: "hello"}, {Key: "X-Spam", Value: "no"}}}
+	tests := map[string]Change{
+		"value":  {Type: ChangeModified, Key: "Subject", OldIndex: 0, NewIndex: 0, OldValue: "goodbye", NewValue: "x"},
+		"key":    {Type: ChangeRemoved, Key: "From", OldIndex: 0, NewIndex: -1, OldValue: "hello"},
+		"bounds": {Type: ChangeRemoved, Key: "Subject", OldIndex: 5, NewIndex: -1, OldValue: "hello"},
+	}
+	for name, c := range tests {
+		t.Run(name, func(t *testing.T) {
+			patched := Header{Headers: append([]KV(nil), h.Headers...)}
+			ok := Change{Type: ChangeRemoved, Key: "X-Spam", OldIndex: 1, NewIndex: -1, OldValue: "no"}
+			if err := patched.ApplyPatch([]Change{ok, c}); !errors.Is(err, ErrPatchConflict) {
+				t.Errorf("expected ErrPatchConflict, got %v", err)
+			}
+			if diff := cmp.Diff(h.Headers, patched.Headers); diff != "" {
+				t.Errorf("header changed on conflict:\n%s", diff)
+			}
+		})
+	}
+	var bad Change
+	if err := json.Unmarshal([]byte(`{"type":"renamed"}`), &bad); err == nil {
+		t.Errorf("expected error decoding unknown change type")
+	}
+}
@@ -0,0 +1,51 @@
+package orderedheaders
+
+import (
+	"net/mail"
+	"net/textproto"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// RecipientPlaceholder is replaced by the recipient's address, URL
+// encoded, in List-Unsubscribe by Personalize, for per-recipient
+// unsubscribe links such as
+// <mailto:unsubscribe@example.com?subject={{recipient}}>
+const RecipientPlaceholder = "{{recipient}}"
+
+// Personalize returns a copy of the header for sending to one recipient
+// of a bulk mailing. To is set to the recipient, a Return-Path becomes a
+// VERP address for the recipient, RecipientPlaceholder is replaced in
+// List-Unsubscribe, and then each of overrides replaces any existing
+// fields with that key, is added at the end in order of key, or if it's
+// empty removes them. Overrides aren't validated, so that custom merge
+// fields can be set. The original header is unchanged, so it can be
+// reused as a template.
+func (h *Header) Personalize(recipient *mail.Address, overrides map[string]string) *Header {
+	h.CanonicalizeKeys()
+	ret := &Header{Headers: make([]KV, len(h.Headers), len(h.Headers)+len(overrides)+1)}
+	copy(ret.Headers, h.Headers)
+
+	ret.replaceAll(HdrTo, recipient.String())
+	if bounce, isNull, err := h.ReturnPath(); err == nil && !isNull {
+		if verp, err := EncodeVERP(bounce, recipient.Address); err == nil {
+			ret.replaceAll(HdrReturnPath, "<"+verp+">")
+		}
+	}
+	escaped := url.QueryEscape(recipient.Address)
+	for i, kv := range ret.Headers {
+		if kv.Key == HdrListUnsubscribe && strings.Contains(kv.Value, RecipientPlaceholder) {
+			ret.Headers[i] = KV{Key: kv.Key, Value: strings.Replace(kv.Value, RecipientPlaceholder, escaped, -1)}
+		}
+	}
+	keys := make([]string, 0, len(overrides))
+	for key := range overrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		ret.replaceAll(textproto.CanonicalMIMEHeaderKey(key), overrides[key])
+	}
+	return ret
+}
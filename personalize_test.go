@@ -0,0 +1,52 @@
+package orderedheaders
+
+import (
+	"net/mail"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPersonalize(t *testing.T) {
+	template := Header{Headers: []KV{
+		{Key: "Return-Path", Value: "<bounce@lists.example.com>"},
+		{Key: "From", Value: "news@example.com"},
+		{Key: "To", Value: "placeholder@example.com"},
+		{Key: "Subject", Value: "News"},
+		{Key: "List-Unsubscribe", Value: "<mailto:unsub@example.com?subject={{recipient}}>, <https://example.com/u?e={{recipient}}>"},
+		{Key: "X-Campaign", Value: "template"},
+		{Key: "X-Remove", Value: "me"},
+	}}
+	orig := append([]KV(nil), template.Headers...)
+	got := template.Personalize(&mail.Address{Name: "Bob", Address: "bob+x@example.org"}, map[string]string{
+		"x-campaign":  "c1",
+		"X-Recipient": "42",
+		"X-Merge":     "hello",
+		"X-Remove":    "",
+	})
+	want := []KV{
+		{Key: "Return-Path", Value: "<bounce-bob+x=example.org@lists.example.com>"},
+		{Key: "From", Value: "news@example.com"},
+		{Key: "To", Value: `"Bob" <bob+x@example.org>`},
+		{Key: "Subject", Value: "News"},
+		{Key: "List-Unsubscribe", Value: "<mailto:unsub@example.com?subject=bob%2Bx%40example.org>, <https://example.com/u?e=bob%2Bx%40example.org>"},
+		{Key: "X-Campaign", Value: "c1"},
+		{Key: "X-Merge", Value: "hello"},
+		{Key: "X-Recipient", Value: "42"},
+	}
+	if diff := cmp.Diff(want, got.Headers); diff != "" {
+		t.Errorf("personalized header mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(orig, template.Headers); diff != "" {
+		t.Errorf("template changed (-want +got):\n%s", diff)
+	}
+}
+
+func TestPersonalizeNullReturnPath(t *testing.T) {
+	template := Header{Headers: []KV{{Key: "Return-Path", Value: "<>"}}}
+	got := template.Personalize(&mail.Address{Address: "bob@example.org"}, nil)
+	want := []KV{{Key: "Return-Path", Value: "<>"}, {Key: "To", Value: "<bob@example.org>"}}
+	if diff := cmp.Diff(want, got.Headers); diff != "" {
+		t.Errorf("personalized header mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,72 @@
+package orderedheaders
+
+import (
+	"sync"
+)
+
+// maxPooledScratch is the largest scratch buffer kept for reuse, so that
+// one huge header doesn't pin memory
+const maxPooledScratch = 64 * 1024
+
+// readScratch holds the buffers used while reading a header
+type readScratch struct {
+	// buf holds unfolded fields, and spans the offsets of the key and
+	// value of each field in it
+	buf   []byte
+	spans []int
+}
+
+var scratchPool = sync.Pool{
+	New: func() interface{} {
+		return &readScratch{buf: make([]byte, 0, 4096), spans: make([]int, 0, 4*64)}
+	},
+}
+
+func getScratch() *readScratch {
+	return scratchPool.Get().(*readScratch)
+}
+
+func putScratch(s *readScratch) {
+	if cap(s.buf) > maxPooledScratch {
+		return
+	}
+	s.buf, s.spans = s.buf[:0], s.spans[:0]
+	scratchPool.Put(s)
+}
+
+// fieldPool holds field slices released by Header.Release
+var fieldPool = sync.Pool{
+	New: func() interface{} {
+		kvs := make([]KV, 0, 32)
+		return &kvs
+	},
+}
+
+// newFields returns an empty slice of fields, reusing a released one if
+// there is one
+func newFields() []KV {
+	return (*fieldPool.Get().(*[]KV))[:0]
+}
+
+// Reset removes all the fields from h, keeping the storage to be reused
+// as fields are added.
+func (h *Header) Reset() {
+	for i := range h.Headers {
+		h.Headers[i] = KV{}
+	}
+	h.Headers = h.Headers[:0]
+}
+
+// Release empties h and returns its storage to be reused by a later
+// ReadHeader or ReadMessage, for servers that parse many messages. The
+// fields mustn't be used afterwards, including through any copy of h or
+// slice of h.Headers; the header itself can be reused.
+func (h *Header) Release() {
+	if cap(h.Headers) == 0 {
+		return
+	}
+	h.Reset()
+	kvs := h.Headers
+	h.Headers = nil
+	fieldPool.Put(&kvs)
+}
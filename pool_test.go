@@ -0,0 +1,69 @@
+package orderedheaders
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestHeaderReset(t *testing.T) {
+	h := Header{}
+	h.Add("Subject", "one")
+	h.Add("From", "alice@example.com")
+	c := cap(h.Headers)
+	h.Reset()
+	if len(h.Headers) != 0 || cap(h.Headers) != c {
+		t.Errorf("Reset left len %d cap %d", len(h.Headers), cap(h.Headers))
+	}
+	if !reflect.DeepEqual(h.Headers[:1][0], KV{}) {
+		t.Errorf("Reset kept a reference to a field")
+	}
+	h.Add("Subject", "two")
+	if got := h.Get(HdrSubject); got != "two" {
+		t.Errorf("unexpected Subject %q", got)
+	}
+}
+
+func TestHeaderRelease(t *testing.T) {
+	const input = "Subject: hello\r\nFrom: alice@example.com\r\n\r\n"
+	want := Header{Headers: []KV{
+		{Key: "Subject", Value: "hello"},
+		{Key: "From", Value: "alice@example.com"},
+	}}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				h, err := ReadHeader(reader(input))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if !reflect.DeepEqual(h, want) {
+					t.Errorf("ReadHeader = %v, want %v", h, want)
+				}
+				h.Release()
+				if h.Headers != nil {
+					t.Errorf("Release left fields")
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	var empty Header
+	empty.Release()
+}
+
+func BenchmarkReadHeaderRelease(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		h, err := ReadHeader(reader(benchmarkHeader))
+		if err != nil {
+			b.Fatal(err)
+		}
+		h.Release()
+	}
+}
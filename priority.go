@@ -0,0 +1,103 @@
+package orderedheaders
+
+import (
+	"fmt"
+	"net/mail"
+	"strconv"
+	"strings"
+)
+
+//go:generate enumer -json -trimprefix=Priority -transform=kebab -type Priority
+
+// https://tools.wordtothewise.com/rfc2156#section-5.3
+
+const (
+	HdrXPriority  = "X-Priority"
+	HdrImportance = "Importance"
+	HdrPriority   = "Priority"
+)
+
+// Priority is a normalized message priority, combining the scales used
+// by X-Priority, Importance and Priority headers
+type Priority int
+
+const (
+	PriorityHighest Priority = iota
+	PriorityHigh
+	PriorityNormal
+	PriorityLow
+	PriorityLowest
+)
+
+// Priority returns the message priority, taken from the first of the
+// X-Priority, Importance or Priority headers that is present. If none
+// are, it returns PriorityNormal and mail.ErrHeaderNotPresent.
+func (h *Header) Priority() (Priority, error) {
+	if v := strings.TrimSpace(h.Get(HdrXPriority)); v != "" {
+		// X-Priority is a digit, often followed by a description
+		// such as "1 (Highest)"
+		end := 0
+		for end < len(v) && v[end] >= '0' && v[end] <= '9' {
+			end++
+		}
+		n, err := strconv.Atoi(v[:end])
+		if err != nil || n < 1 || n > 5 {
+			return PriorityNormal, fmt.Errorf("'%s' is not a valid X-Priority", v)
+		}
+		return Priority(n - 1), nil
+	}
+	if v := strings.TrimSpace(h.Get(HdrImportance)); v != "" {
+		switch strings.ToLower(v) {
+		case "high":
+			return PriorityHigh, nil
+		case "normal":
+			return PriorityNormal, nil
+		case "low":
+			return PriorityLow, nil
+		}
+		return PriorityNormal, fmt.Errorf("'%s' is not a valid Importance", v)
+	}
+	if v := strings.TrimSpace(h.Get(HdrPriority)); v != "" {
+		switch strings.ToLower(v) {
+		case "urgent":
+			return PriorityHigh, nil
+		case "normal":
+			return PriorityNormal, nil
+		case "non-urgent":
+			return PriorityLow, nil
+		}
+		return PriorityNormal, fmt.Errorf("'%s' is not a valid Priority", v)
+	}
+	return PriorityNormal, mail.ErrHeaderNotPresent
+}
+
+var xPriorityValues = map[Priority]string{
+	PriorityHighest: "1 (Highest)",
+	PriorityHigh:    "2 (High)",
+	PriorityNormal:  "3 (Normal)",
+	PriorityLow:     "4 (Low)",
+	PriorityLowest:  "5 (Lowest)",
+}
+
+// SetPriority sets the X-Priority, Importance and Priority headers
+// consistently. Setting PriorityNormal removes them, as that is the
+// default.
+func (h *Header) SetPriority(p Priority) error {
+	if !p.IsAPriority() {
+		return fmt.Errorf("invalid priority: %v", p)
+	}
+	if p == PriorityNormal {
+		h.RemoveAll(HdrXPriority)
+		h.RemoveAll(HdrImportance)
+		h.RemoveAll(HdrPriority)
+		return nil
+	}
+	importance, priority := "high", "urgent"
+	if p > PriorityNormal {
+		importance, priority = "low", "non-urgent"
+	}
+	h.replaceAll(HdrXPriority, xPriorityValues[p])
+	h.replaceAll(HdrImportance, importance)
+	h.replaceAll(HdrPriority, priority)
+	return nil
+}
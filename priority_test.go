@@ -0,0 +1,67 @@
+package orderedheaders
+
+import (
+	"errors"
+	"net/mail"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPriority(t *testing.T) {
+	tests := map[string]struct {
+		Headers   []KV
+		Want      Priority
+		WantError bool
+	}{
+		"xpriority":  {[]KV{{Key: "X-Priority", Value: "1 (Highest)"}, {Key: "Importance", Value: "low"}}, PriorityHighest, false},
+		"bare":       {[]KV{{Key: "X-Priority", Value: "4"}}, PriorityLow, false},
+		"importance": {[]KV{{Key: "Importance", Value: "High"}}, PriorityHigh, false},
+		"priority":   {[]KV{{Key: "Priority", Value: "non-urgent"}}, PriorityLow, false},
+		"invalid":    {[]KV{{Key: "X-Priority", Value: "9"}}, PriorityNormal, true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := &Header{Headers: test.Headers}
+			got, err := h.Priority()
+			if test.WantError != (err != nil) {
+				t.Errorf("want error %v, got %v", test.WantError, err)
+			}
+			if got != test.Want {
+				t.Errorf("want %v, got %v", test.Want, got)
+			}
+		})
+	}
+	h := &Header{}
+	if _, err := h.Priority(); !errors.Is(err, mail.ErrHeaderNotPresent) {
+		t.Errorf("want ErrHeaderNotPresent, got %v", err)
+	}
+}
+
+func TestSetPriority(t *testing.T) {
+	h := &Header{}
+	h.Add("Subject", "hello")
+	h.Add("Priority", "urgent")
+	h.Add("Priority", "normal")
+	if err := h.SetPriority(PriorityLowest); err != nil {
+		t.Fatal(err)
+	}
+	want := []KV{
+		{Key: "Subject", Value: "hello"},
+		{Key: "Priority", Value: "non-urgent"},
+		{Key: "X-Priority", Value: "5 (Lowest)"},
+		{Key: "Importance", Value: "low"},
+	}
+	if diff := cmp.Diff(want, h.Headers); diff != "" {
+		t.Errorf("SetPriority mismatch (-want +got):\n%s", diff)
+	}
+	if p, err := h.Priority(); err != nil || p != PriorityLowest {
+		t.Errorf("want lowest, got %v, %v", p, err)
+	}
+	if err := h.SetPriority(PriorityNormal); err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Headers) != 1 {
+		t.Errorf("expected priority headers to be removed, got %v", h.Headers)
+	}
+}
@@ -0,0 +1,70 @@
+package orderedheaders
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+// PseudonymDomain is the domain of pseudonymized addresses if the
+// Pseudonymizer doesn't give one. The .invalid TLD can never resolve.
+// https://tools.wordtothewise.com/rfc2606#section-2
+const PseudonymDomain = "anon.invalid"
+
+// pseudonymLength is the number of bytes of HMAC in a pseudonym
+const pseudonymLength = 12
+
+// addrAtom and addrLabel match a dot-atom component and a domain label,
+// allowing UTF-8 as in SMTPUTF8 mail
+// https://tools.wordtothewise.com/rfc6532#section-3.2
+const (
+	addrAtom  = "[A-Za-z0-9!#$%&'*+/=?^_`{|}~\\x{80}-\\x{10FFFF}-]+"
+	addrLabel = `[A-Za-z0-9\x{80}-\x{10FFFF}](?:[A-Za-z0-9\x{80}-\x{10FFFF}-]*[A-Za-z0-9\x{80}-\x{10FFFF}])?`
+)
+
+// addrSpecRe matches addr-specs in free text, with either a dot-atom or
+// a quoted-string local part
+var addrSpecRe = regexp.MustCompile(
+	`(?:"(?:[^"\\\r\n]|\\.)*"|` + addrAtom + `(?:\.` + addrAtom + `)*)@` + addrLabel + `(?:\.` + addrLabel + `)+`)
+
+// Pseudonymizer replaces email addresses with stable pseudonyms, so that
+// header data can be analyzed without handling personal data. The same
+// address, ignoring case, always gives the same pseudonym for a given
+// key, and the key is needed to test whether an address matches a
+// pseudonym.
+type Pseudonymizer struct {
+	Key []byte
+	// Domain is the domain of pseudonyms, PseudonymDomain if empty
+	Domain string
+}
+
+// Address returns the pseudonym for an address
+func (p Pseudonymizer) Address(addr string) string {
+	domain := p.Domain
+	if domain == "" {
+		domain = PseudonymDomain
+	}
+	mac := hmac.New(sha256.New, p.Key)
+	mac.Write([]byte(strings.ToLower(addr)))
+	return hex.EncodeToString(mac.Sum(nil)[:pseudonymLength]) + "@" + domain
+}
+
+// Header returns a copy of h with every address in every field, such as
+// those in From, Received and Message-Id, replaced by its pseudonym.
+// Display names and other text are left alone, so use Redact as well to
+// remove those.
+func (p Pseudonymizer) Header(h *Header) *Header {
+	h.CanonicalizeKeys()
+	ret := &Header{Headers: make([]KV, len(h.Headers))}
+	for i, kv := range h.Headers {
+		value := addrSpecRe.ReplaceAllStringFunc(kv.Value, p.Address)
+		if value == kv.Value {
+			ret.Headers[i] = kv
+			continue
+		}
+		ret.Headers[i] = KV{Key: kv.Key, Value: value}
+	}
+	return ret
+}
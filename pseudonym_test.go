@@ -0,0 +1,53 @@
+package orderedheaders
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPseudonymizer(t *testing.T) {
+	p := Pseudonymizer{Key: []byte("secret")}
+	alice := p.Address("alice@example.com")
+	if !strings.HasSuffix(alice, "@"+PseudonymDomain) || len(alice) != 2*pseudonymLength+1+len(PseudonymDomain) {
+		t.Errorf("unexpected pseudonym '%s'", alice)
+	}
+	if p.Address("Alice@Example.COM") != alice {
+		t.Errorf("pseudonyms should ignore case")
+	}
+	if p.Address("bob@example.com") == alice {
+		t.Errorf("different addresses have the same pseudonym")
+	}
+	if (Pseudonymizer{Key: []byte("other")}).Address("alice@example.com") == alice {
+		t.Errorf("pseudonym doesn't depend on key")
+	}
+	if got := (Pseudonymizer{Key: []byte("secret"), Domain: "example.invalid"}).Address("alice@example.com"); !strings.HasSuffix(got, "@example.invalid") {
+		t.Errorf("domain not used: '%s'", got)
+	}
+
+	h := Header{Headers: []KV{
+		{Key: "Received", Value: "from mx.example.net by mx.example.com for <alice@example.com>; Mon, 22 May 2023 10:00:00 +0000"},
+		{Key: "From", Value: `"Bob" <bob@example.net>`, Raw: "From: \"Bob\" <bob@example.net>\r\n"},
+		{Key: "To", Value: "alice@example.com, Carol <carol.smith+tag@mail.example.org>"},
+		{Key: "Subject", Value: "hello"},
+		{Key: "Cc", Value: `"john doe"@example.com, <josé@exämple.com>`},
+	}}
+	got := p.Header(&h)
+	want := []string{
+		"from mx.example.net by mx.example.com for <" + alice + ">; Mon, 22 May 2023 10:00:00 +0000",
+		`"Bob" <` + p.Address("bob@example.net") + ">",
+		alice + ", Carol <" + p.Address("carol.smith+tag@mail.example.org") + ">",
+		"hello",
+		p.Address(`"john doe"@example.com`) + ", <" + p.Address("josé@exämple.com") + ">",
+	}
+	for i, kv := range got.Headers {
+		if kv.Value != want[i] {
+			t.Errorf("%s: want '%s', got '%s'", kv.Key, want[i], kv.Value)
+		}
+	}
+	if got.Headers[1].Raw != "" {
+		t.Errorf("Raw not cleared")
+	}
+	if h.Get("To") != "alice@example.com, Carol <carol.smith+tag@mail.example.org>" {
+		t.Errorf("original header changed")
+	}
+}
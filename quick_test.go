@@ -0,0 +1,76 @@
+package orderedheaders
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+)
+
+// roundTrip writes h and reads it back
+func roundTrip(h Header) (Header, error) {
+	b, err := h.Bytes(Options{RenderBCC: true, RenderBlank: true})
+	if err != nil {
+		return Header{}, err
+	}
+	return ReadHeader(reader(string(b) + "\r\n"))
+}
+
+func TestQuickRoundTrip(t *testing.T) {
+	f := func(h Header) bool {
+		got, err := roundTrip(h)
+		if err != nil {
+			t.Log(err)
+			return false
+		}
+		if len(h.Headers) == 0 {
+			return len(got.Headers) == 0
+		}
+		return reflect.DeepEqual(got.Headers, h.Headers)
+	}
+	if err := quick.Check(f, &quick.Config{MaxCount: 500}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestQuickField(t *testing.T) {
+	f := func(kv KV) bool {
+		got, err := roundTrip(Header{Headers: []KV{kv}})
+		if err != nil {
+			t.Log(err)
+			return false
+		}
+		return reflect.DeepEqual(got.Headers, []KV{kv})
+	}
+	if err := quick.Check(f, &quick.Config{MaxCount: 1000}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	a := Header{}.Generate(rand.New(rand.NewSource(1)), 50).Interface().(Header)
+	b := Header{}.Generate(rand.New(rand.NewSource(1)), 50).Interface().(Header)
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("the same seed gave different headers")
+	}
+	if len(a.Headers) == 0 || len(a.Headers) > 50 {
+		t.Errorf("generated %d fields", len(a.Headers))
+	}
+	seen := map[string]int{}
+	for _, kv := range a.Headers {
+		seen[kv.Key]++
+		if !validFieldName(kv.Key) || kv.Value == "" || !isAscii(kv.Value) {
+			t.Errorf("invalid field %q", kv)
+		}
+		if s, ok := HeaderSyntax[kv.Key]; ok && s.Type != HeaderTypeOpaque {
+			if err := checkHeader(s.Type, kv.Value); err != nil {
+				t.Errorf("%s: %v", kv.Key, err)
+			}
+		}
+	}
+	for key, n := range seen {
+		if HeaderSyntax[key].Unique && n > 1 {
+			t.Errorf("%s appears %d times", key, n)
+		}
+	}
+}
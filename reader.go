@@ -3,33 +3,158 @@
 package orderedheaders
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
+	"io"
 	"net/textproto"
 )
 
+// ReadOptions configures how headers are read.
+type ReadOptions struct {
+	// KeepRaw retains the exact bytes of each header field in KV.Raw
+	KeepRaw bool
+	// Keys, if set, shares key strings between headers read with it.
+	// Common keys are always shared.
+	Keys *KeyCache
+	// LazyKeys leaves keys as they were read, setting Header.RawKeys,
+	// and canonicalizes them only when a field is first looked up, for
+	// code that mostly iterates over fields and writes them out again
+	LazyKeys bool
+	// FieldsHint is the number of fields expected, so that room for them
+	// can be allocated up front
+	FieldsHint int
+	// MaxFields limits the number of fields kept. Any more are read and
+	// discarded, and Header.Truncated set. Zero means no limit.
+	MaxFields int
+	// MaxFieldBytes limits the length of each field, including its name.
+	// The rest of a longer unfolded field is read and discarded without
+	// being buffered, and Header.Truncated set. Raw bytes longer than
+	// this, apart from the final line ending, are dropped in the same way.
+	// Zero means no limit.
+	MaxFieldBytes int
+}
+
 // ReadHeader reads a MIME-style header from r, much like
 // textproto.ReadMIMEHeader.
 // The returned value is a list of key, value pairs
 func ReadHeader(r *textproto.Reader) (Header, error) {
-	m := Header{Headers: []KV{}}
+	return ReadHeaderWithOptions(r, ReadOptions{})
+}
+
+// ReadHeaderWithOptions reads a MIME-style header from r, as ReadHeader,
+// configured by o.
+func ReadHeaderWithOptions(r *textproto.Reader, o ReadOptions) (Header, error) {
+	m, _, err := readHeader(r, o)
+	return m, err
+}
+
+// ParseHTTPHead reads the head of an HTTP/1.x request or response from
+// r: the start line, such as "GET / HTTP/1.1" or "HTTP/1.1 200 OK",
+// followed by the header fields up to the blank line. Unlike net/http,
+// fields are kept in the order they were sent, including repeated fields
+// such as Set-Cookie, which mustn't be combined. r is left positioned at
+// the start of the body. Empty lines before the start line are skipped.
+// https://tools.wordtothewise.com/rfc7230#section-3
+func ParseHTTPHead(r *bufio.Reader) (string, Header, error) {
+	tp := textproto.NewReader(r)
+	var startLine string
+	for startLine == "" {
+		line, err := tp.ReadLine()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return "", Header{}, err
+		}
+		startLine = line
+	}
+	if startLine[0] == ' ' || startLine[0] == '\t' {
+		return "", Header{}, errors.New("malformed HTTP start line: " + startLine)
+	}
+	h, err := ReadHeader(tp)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return startLine, h, err
+}
+
+// readHeader reads a header as ReadHeaderWithOptions, also returning the
+// raw bytes of the blank line that terminated it
+func readHeader(r *textproto.Reader, o ReadOptions) (Header, []byte, error) {
+	m := Header{Headers: newFields()}
+	m.Grow(o.FieldsHint)
+	scratch := getScratch()
+	defer putScratch(scratch)
+	// skipped holds raw bytes not yet attached to a field
+	var skipped []byte
 	for {
-		kv, err := r.ReadContinuedLineBytes()
+		max := -1
+		switch {
+		case o.MaxFields > 0 && len(m.Headers) >= o.MaxFields:
+			max = 0
+		case o.MaxFieldBytes > 0:
+			max = o.MaxFieldBytes
+		}
+		if !o.KeepRaw {
+			if separator := peekBlankLine(r.R); separator != nil {
+				return m, separator, nil
+			}
+		}
+		var kv, raw []byte
+		var truncated bool
+		var err error
+		if o.KeepRaw {
+			raw, kv, truncated, err = readRawField(r.R, max)
+		} else {
+			scratch.buf, truncated, err = appendContinuedLine(r.R, scratch.buf[:0], max)
+			kv = scratch.buf
+		}
+		if truncated {
+			m.Truncated = true
+			raw = nil
+			if max == 0 {
+				if err != nil {
+					return m, nil, err
+				}
+				continue
+			}
+		}
 		if len(kv) == 0 {
-			return m, err
+			return m, append(skipped, raw...), err
 		}
 		i := bytes.IndexByte(kv, ':')
 		if i < 0 {
-			return m, textproto.ProtocolError("malformed MIME header line: " + string(kv))
+			return m, nil, textproto.ProtocolError("malformed MIME header line: " + string(kv))
 		}
 
 		endKey := i
 		for endKey > 0 && kv[endKey-1] == ' ' {
 			endKey--
 		}
-		key := textproto.CanonicalMIMEHeaderKey(string(kv[:endKey]))
-		if key == "" {
+		if endKey == 0 {
+			// keep the raw bytes of a field with no name with those
+			// of a neighbouring one, so that none are lost
+			switch n := len(m.Headers); {
+			case raw == nil:
+			case n > 0 && m.Headers[n-1].Raw != "":
+				m.Headers[n-1].Raw += string(raw)
+			default:
+				skipped = append(skipped, raw...)
+			}
 			continue
 		}
+		if skipped != nil {
+			raw = append(skipped, raw...)
+			skipped = nil
+		}
+		var key string
+		if o.LazyKeys {
+			key = string(kv[:endKey])
+			m.RawKeys = true
+		} else {
+			key = o.Keys.canonicalKey(kv[:endKey])
+		}
 
 		i++ // colon
 		for i < len(kv) && (kv[i] == ' ' || kv[i] == '\t') {
@@ -37,9 +162,246 @@ func ReadHeader(r *textproto.Reader) (Header, error) {
 		}
 
 		value := string(kv[i:])
-		m.Add(key, value)
+		m.Headers = append(m.Headers, KV{Key: key, Value: value, Raw: string(raw)})
 		if err != nil {
-			return m, err
+			return m, nil, err
+		}
+	}
+}
+
+// ReadHeaderInto reads a header as ReadHeader does, into h, reusing the
+// storage of h.Headers. The values all share a single string allocated
+// once per header, rather than two per field, and common keys are shared
+// by every header, for servers that parse a large volume of mail.
+// Holding on to any one value keeps the whole header in memory. Raw bytes
+// aren't kept.
+func ReadHeaderInto(r *textproto.Reader, h *Header) error {
+	pooled := getScratch()
+	defer putScratch(pooled)
+	scratch, spans := pooled.buf, pooled.spans
+	defer func() {
+		pooled.buf, pooled.spans = scratch, spans
+	}()
+
+	var err error
+	for {
+		start := len(scratch)
+		scratch, _, err = appendContinuedLine(r.R, scratch, -1)
+		line := scratch[start:]
+		if len(line) == 0 {
+			break
+		}
+		i := bytes.IndexByte(line, ':')
+		if i < 0 {
+			err = textproto.ProtocolError("malformed MIME header line: " + string(line))
+			break
+		}
+		endKey := i
+		for endKey > 0 && line[endKey-1] == ' ' {
+			endKey--
+		}
+		if endKey == 0 {
+			scratch = scratch[:start]
+			continue
+		}
+		i++ // colon
+		for i < len(line) && (line[i] == ' ' || line[i] == '\t') {
+			i++
+		}
+		spans = append(spans, start, start+endKey, start+i, len(scratch))
+		if err != nil {
+			break
+		}
+	}
+
+	s := string(scratch)
+	h.Headers = h.Headers[:0]
+	for i := 0; i < len(spans); i += 4 {
+		key, ok := lookupKey(scratch[spans[i]:spans[i+1]])
+		if !ok {
+			key = textproto.CanonicalMIMEHeaderKey(s[spans[i]:spans[i+1]])
+		}
+		h.Headers = append(h.Headers, KV{Key: key, Value: s[spans[i+2]:spans[i+3]]})
+	}
+	return err
+}
+
+// appendContinuedLine appends a single, possibly folded, header field
+// from r to buf, unfolded and trimmed as by
+// textproto.Reader.ReadContinuedLineBytes. If max isn't negative buf
+// grows to no more than max bytes, the rest of the field being read and
+// discarded, and truncated is set if anything was.
+func appendContinuedLine(r *bufio.Reader, buf []byte, max int) ([]byte, bool, error) {
+	start := len(buf)
+	buf, truncated, err := appendLine(r, buf, max)
+	if err != nil || (len(buf) == start && !truncated) {
+		return buf, truncated, err
+	}
+	buf = append(buf[:start], bytes.Trim(buf[start:], " \t")...)
+	for {
+		skipped := 0
+		for {
+			c, err := r.ReadByte()
+			if err != nil {
+				break
+			}
+			if c != ' ' && c != '\t' {
+				_ = r.UnreadByte()
+				break
+			}
+			skipped++
+		}
+		if skipped == 0 {
+			return buf, truncated, nil
+		}
+		space := len(buf)
+		if max < 0 || len(buf) < max {
+			buf = append(buf, ' ')
+		} else {
+			truncated = true
+		}
+		cont := len(buf)
+		var more bool
+		buf, more, err = appendLine(r, buf, max)
+		if err != nil {
+			// as textproto, the error is returned by the next read
+			return buf[:space], truncated, nil
+		}
+		truncated = truncated || more
+		buf = append(buf[:cont], bytes.TrimRight(buf[cont:], " \t")...)
+	}
+}
+
+// blank lines are returned by peekBlankLine, and must not be modified
+var (
+	blankCRLF = []byte("\r\n")
+	blankLF   = []byte("\n")
+)
+
+// peekBlankLine consumes and returns the line ending if the next line in
+// r is blank, or returns nil without consuming anything
+func peekBlankLine(r *bufio.Reader) []byte {
+	b, _ := r.Peek(2)
+	switch {
+	case len(b) > 0 && b[0] == '\n':
+		_, _ = r.Discard(1)
+		return blankLF
+	case len(b) > 1 && b[0] == '\r' && b[1] == '\n':
+		_, _ = r.Discard(2)
+		return blankCRLF
+	}
+	return nil
+}
+
+// appendLine appends a line from r to buf, without the line ending. If
+// max isn't negative buf grows to no more than max bytes, and truncated
+// is set if any of the line was discarded.
+func appendLine(r *bufio.Reader, buf []byte, max int) ([]byte, bool, error) {
+	truncated := false
+	for {
+		l, more, err := r.ReadLine()
+		if err != nil {
+			return buf, truncated, err
+		}
+		buf, truncated = appendLimited(buf, l, max, truncated)
+		if !more {
+			return buf, truncated, nil
+		}
+	}
+}
+
+// appendLimited appends as much of b to buf as fits in max bytes, if max
+// isn't negative, setting truncated if any of b doesn't fit
+func appendLimited(buf, b []byte, max int, truncated bool) ([]byte, bool) {
+	if max >= 0 && len(buf)+len(b) > max {
+		if len(buf) < max {
+			buf = append(buf, b[:max-len(buf)]...)
+		}
+		return buf, truncated || len(b) > 0
+	}
+	return append(buf, b...), truncated
+}
+
+// readRawField reads a single, possibly folded, header field from r. It
+// returns the exact bytes read and the unfolded line, trimmed the same
+// way textproto.Reader.ReadContinuedLineBytes does. If max isn't
+// negative the line grows to no more than max bytes, raw is dropped if
+// it's longer than max apart from the final line ending, and truncated is
+// set if either was cut short.
+func readRawField(r *bufio.Reader, max int) ([]byte, []byte, bool, error) {
+	var raw, line []byte
+	truncated, dropped := false, false
+	for first := true; ; first = false {
+		start, lineMax := len(raw), -1
+		if max >= 0 {
+			lineMax = start + max
+		}
+		var more bool
+		var err error
+		raw, more, err = readRawLine(r, raw, lineMax)
+		text := trimEnding(raw[start:])
+		if first && len(text) == 0 && !more {
+			return raw, nil, false, err
+		}
+		content := bytes.Trim(text, " \t")
+		eof := err == io.EOF && len(raw) > start
+		if eof {
+			// as bufio.Reader.ReadLine, EOF is returned by the next read
+			err = nil
+		}
+		truncated = truncated || more
+		switch {
+		case first:
+			line, truncated = appendLimited(line, content, max, truncated)
+		case len(content) > 0 || !eof:
+			// textproto ignores spaces at the end of the input
+			line, truncated = appendLimited(line, []byte(" "), max, truncated)
+			line, truncated = appendLimited(line, content, max, truncated)
+		}
+		if max >= 0 && len(bytes.TrimRight(raw, "\r\n")) > max {
+			// keep reading into the same space, to find the end
+			raw, dropped, truncated = raw[:0], true, true
+		}
+		peek, _ := r.Peek(1)
+		if err != nil || len(peek) == 0 || (peek[0] != ' ' && peek[0] != '\t') {
+			if dropped {
+				raw = nil
+			}
+			return raw, line, truncated, err
+		}
+	}
+}
+
+// readRawLine appends a line from r to raw, including its line ending.
+// If max isn't negative raw grows to no more than max bytes, apart from
+// the line ending, and more is set if anything was discarded.
+func readRawLine(r *bufio.Reader, raw []byte, max int) ([]byte, bool, error) {
+	more := false
+	for {
+		chunk, err := r.ReadSlice('\n')
+		var ending []byte
+		if err == nil {
+			n := len(chunk) - 1
+			if n > 0 && chunk[n-1] == '\r' {
+				n--
+			}
+			chunk, ending = chunk[:n], chunk[n:]
+		}
+		raw, more = appendLimited(raw, chunk, max, more)
+		raw = append(raw, ending...)
+		if err != bufio.ErrBufferFull {
+			return raw, more, err
+		}
+	}
+}
+
+// trimEnding removes the line ending, as bufio.Reader.ReadLine does
+func trimEnding(s []byte) []byte {
+	if n := len(s); n > 0 && s[n-1] == '\n' {
+		s = s[:n-1]
+		if n > 1 && s[n-2] == '\r' {
+			s = s[:n-2]
 		}
 	}
+	return s
 }
@@ -2,8 +2,13 @@ package orderedheaders
 
 import (
 	"bufio"
+	"io"
+	"io/ioutil"
+	"net/mail"
 	"net/textproto"
 	"reflect"
+	"regexp"
+	"runtime"
 	"strings"
 	"testing"
 )
@@ -161,7 +166,7 @@ func TestReadMIMEHeaderTrimContinued(t *testing.T) {
 	}
 
 	if !reflect.DeepEqual(m, want) {
-		t.Fatalf("ReadMIMEHeader mismatch.\n got: %q\nwant: %q", m, want)
+		t.Fatalf("ReadMIMEHeader mismatch.\n got: %q\nwant: %q", m.Headers, want.Headers)
 	}
 
 	wantMap := textproto.MIMEHeader{
@@ -175,3 +180,437 @@ func TestReadMIMEHeaderTrimContinued(t *testing.T) {
 		t.Fatalf("ReadMIMEHeader: %v, %v; want %v", tpm, err, wantMap)
 	}
 }
+
+func TestReadHeaderKeepRaw(t *testing.T) {
+	r := reader("a:\n 0 \r\nb:1 \t\r\nc: 2\r\n 3\t\n  \t 4  \r\n\nbody")
+	m, err := ReadHeaderWithOptions(r, ReadOptions{KeepRaw: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Header{
+		Headers: []KV{
+			{Key: "A", Value: "0", Raw: "a:\n 0 \r\n"},
+			{Key: "B", Value: "1", Raw: "b:1 \t\r\n"},
+			{Key: "C", Value: "2 3 4", Raw: "c: 2\r\n 3\t\n  \t 4  \r\n"},
+		},
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Fatalf("ReadHeaderWithOptions mismatch.\n got: %q\nwant: %q", m.Headers, want.Headers)
+	}
+}
+
+func TestReadHeaderKeepRawMatches(t *testing.T) {
+	tests := []struct {
+		input string
+		raw   []string
+	}{
+		{":\n0:\n\n", []string{":\n0:\n"}},
+		{"a: 1\n: skipped\nb: 2\n\n", []string{"a: 1\n: skipped\n", "b: 2\n"}},
+		{"a: 1\n ", []string{"a: 1\n "}},
+		{" ", nil},
+		{"\r", nil},
+	}
+	for _, tt := range tests {
+		h, err := ReadHeader(reader(tt.input))
+		m, rawErr := ReadHeaderWithOptions(reader(tt.input), ReadOptions{KeepRaw: true})
+		if (err == nil) != (rawErr == nil) {
+			t.Errorf("%q: ReadHeader returned %v, with KeepRaw %v", tt.input, err, rawErr)
+			continue
+		}
+		if len(h.Headers) != len(m.Headers) {
+			t.Errorf("%q: ReadHeader read %q, with KeepRaw %q", tt.input, h.Headers, m.Headers)
+			continue
+		}
+		var raw []string
+		for i, kv := range m.Headers {
+			raw = append(raw, kv.Raw)
+			if kv.Key != h.Headers[i].Key || kv.Value != h.Headers[i].Value {
+				t.Errorf("%q: ReadHeader read %q, with KeepRaw %q", tt.input, h.Headers, m.Headers)
+			}
+		}
+		if err == nil && !reflect.DeepEqual(raw, tt.raw) {
+			t.Errorf("%q: raw %q, want %q", tt.input, raw, tt.raw)
+		}
+	}
+}
+
+func TestParseHTTPHead(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("\r\nHTTP/1.1 200 OK\r\n" +
+		"Set-Cookie: a=1\r\n" +
+		"content-type: text/html\r\n" +
+		"Set-Cookie: b=2\r\n" +
+		"\r\n" +
+		"<html>"))
+	startLine, h, err := ParseHTTPHead(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if startLine != "HTTP/1.1 200 OK" {
+		t.Errorf("unexpected start line %q", startLine)
+	}
+	want := Header{Headers: []KV{
+		{Key: "Set-Cookie", Value: "a=1"},
+		{Key: "Content-Type", Value: "text/html"},
+		{Key: "Set-Cookie", Value: "b=2"},
+	}}
+	if !reflect.DeepEqual(h, want) {
+		t.Errorf("ParseHTTPHead: %v; want %v", h, want)
+	}
+	body, _ := r.ReadString('\n')
+	if body != "<html>" {
+		t.Errorf("unexpected body %q", body)
+	}
+
+	for _, s := range []string{"", "GET / HTTP/1.1\r\nHost: example.com\r\n", " GET / HTTP/1.1\r\n\r\n"} {
+		if _, _, err := ParseHTTPHead(bufio.NewReader(strings.NewReader(s))); err == nil {
+			t.Errorf("ParseHTTPHead(%q): expected error", s)
+		}
+	}
+}
+
+func TestReadHeaderInto(t *testing.T) {
+	inputs := []string{
+		"my-key: Value 1  \r\nLong-key: Even \n Longer Value\r\nmy-Key: Value 2\r\n\n",
+		": bar\ntest-1: 1\n\n",
+		"Foo: bar\r\nSID : 0\r\nAudio Mode : None\r\n\r\n",
+		"a:\n 0 \r\nb:1 \t\r\nc: 2\r\n 3\t\n  \t 4  \r\n\nbody",
+		"Cookie: " + strings.Repeat("x", 16*1024) + "\r\n\n",
+		"Subject: no blank line\r\n",
+		"Subject: ok\r\nno colon\r\n\r\n",
+		"",
+	}
+	h := Header{Headers: make([]KV, 0, 8)}
+	for _, s := range inputs {
+		want, wantErr := ReadHeader(reader(s))
+		err := ReadHeaderInto(reader(s), &h)
+		if !reflect.DeepEqual(err, wantErr) {
+			t.Errorf("ReadHeaderInto(%.20q): error %v, want %v", s, err, wantErr)
+		}
+		if len(h.Headers) != len(want.Headers) || len(h.Headers) > 0 && !reflect.DeepEqual(h.Headers, want.Headers) {
+			t.Errorf("ReadHeaderInto(%.20q) = %q, want %q", s, h.Headers, want.Headers)
+		}
+	}
+}
+
+func TestReadHeaderIntoAllocs(t *testing.T) {
+	const input = "Received: from a by b\r\nFrom: alice@example.com\r\nTo: bob@example.com\r\n" +
+		"Subject: hello\r\n there\r\nMessage-Id: <1@example.com>\r\n\r\n"
+	sr := strings.NewReader(input)
+	br := bufio.NewReader(sr)
+	tp := textproto.NewReader(br)
+	h := Header{Headers: make([]KV, 0, 8)}
+	allocs := testing.AllocsPerRun(100, func() {
+		sr.Reset(input)
+		br.Reset(sr)
+		if err := ReadHeaderInto(tp, &h); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs > 1 {
+		t.Errorf("ReadHeaderInto made %v allocations, want 1", allocs)
+	}
+}
+
+func TestReadHeaderLazyKeys(t *testing.T) {
+	const input = "MIME-version: 1.0\r\nsubject: hello\r\nDKIM-Signature: v=1\r\n\r\n"
+	h, err := ReadHeaderWithOptions(reader(input), ReadOptions{LazyKeys: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !h.RawKeys || h.Headers[0].Key != "MIME-version" || h.Headers[2].Key != "DKIM-Signature" {
+		t.Fatalf("keys were canonicalized: %v", h.Headers)
+	}
+	if got := h.Get("Subject"); got != "hello" {
+		t.Errorf("Get(Subject) = %q", got)
+	}
+	want, err := ReadHeader(reader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.RawKeys || !reflect.DeepEqual(h.Headers, want.Headers) {
+		t.Errorf("Get didn't canonicalize: %v", h.Headers)
+	}
+
+	h, _ = ReadHeaderWithOptions(reader(input), ReadOptions{LazyKeys: true})
+	got, err := h.Bytes(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBytes, _ := want.Bytes(Options{})
+	if string(got) != string(wantBytes) {
+		t.Errorf("WriteTo wrote %q, want %q", got, wantBytes)
+	}
+}
+
+func TestLazyKeysAccessors(t *testing.T) {
+	const input = "received: from helo.example.com (mx.example.com [192.0.2.1]) by mx.example.org; Mon, 1 Jan 2024 00:00:00 +0000\r\n" +
+		"received-spf: pass (mx.example.org: domain of a@example.com designates 192.0.2.1) client-ip=192.0.2.1\r\n" +
+		"arc-seal: i=1; a=rsa-sha256; cv=none; d=example.org; s=sel; b=c2lnbmF0dXJl\r\n" +
+		"arc-message-signature: i=1; a=rsa-sha256; c=relaxed/relaxed; d=example.org; s=sel; h=from:to; bh=aGFzaA==; b=c2ln\r\n" +
+		"arc-authentication-results: i=1; mx.example.org; spf=pass smtp.mailfrom=example.com\r\n" +
+		"dkim-signature: v=1; a=rsa-sha256; d=example.com; s=sel; h=from:list-unsubscribe:list-unsubscribe-post; bh=aGFzaA==; b=c2ln\r\n" +
+		"resent-date: Tue, 2 Jan 2024 00:00:00 +0000\r\n" +
+		"resent-from: bob@example.com\r\n" +
+		"from: alice@example.com\r\n" +
+		"date: Mon, 1 Jan 2024 00:00:00 +0000\r\n" +
+		"keywords: one, two\r\n" +
+		"list-unsubscribe: <https://example.com/unsub>\r\n" +
+		"list-unsubscribe-post: List-Unsubscribe=One-Click\r\n" +
+		"x-mailer: mutt\r\n\r\n"
+	tests := map[string]func(h *Header) bool{
+		"DKIMSignatures": func(h *Header) bool {
+			sigs, err := h.DKIMSignatures()
+			return err == nil && len(sigs) == 1
+		},
+		"Keywords": func(h *Header) bool {
+			return reflect.DeepEqual(h.Keywords(), []string{"one", "two"})
+		},
+		"ARCSets": func(h *Header) bool {
+			sets, err := h.ARCSets()
+			return err == nil && len(sets) == 1
+		},
+		"ReceivedSPF": func(h *Header) bool {
+			spf := h.ReceivedSPF()
+			return len(spf) == 1 && spf[0].Result == "pass"
+		},
+		"ResentBlocks": func(h *Header) bool {
+			blocks := h.ResentBlocks()
+			return len(blocks) == 1 && len(blocks[0].From) == 1
+		},
+		"CheckOneClickUnsubscribe": func(h *Header) bool {
+			return h.CheckOneClickUnsubscribe() == nil
+		},
+		"SignedHeaders": func(h *Header) bool {
+			names, kvs := h.SignedHeaders(SigningPolicy{Fields: []string{"From"}, Oversign: []string{"Keywords"}})
+			return reflect.DeepEqual(names, []string{"keywords", "keywords", "from"}) && len(kvs) == 2
+		},
+		"StripHeaders": func(h *Header) bool {
+			return len(h.StripHeaders(StripPolicy{Names: []string{"X-Mailer"}})) == 1 && !h.Has("X-Mailer")
+		},
+		"ApplyRules": func(h *Header) bool {
+			n, err := h.ApplyRules([]Rule{{Key: regexp.MustCompile("^X-Mailer$"), Action: RuleDelete}})
+			return err == nil && n == 1
+		},
+		"ApplyPatch": func(h *Header) bool {
+			err := h.ApplyPatch([]Change{{Type: ChangeRemoved, Key: "X-Mailer", OldIndex: 13, NewIndex: -1, OldValue: "mutt"}})
+			return err == nil && !h.Has("X-Mailer")
+		},
+		"AnonymizeReceived": func(h *Header) bool {
+			return h.AnonymizeReceived(ReceivedPrivacy{DropFrom: true}) == 1
+		},
+		"Lint": func(h *Header) bool {
+			for _, issue := range h.Lint() {
+				if issue.Rule == "syntax" || issue.Rule == "required" {
+					return false
+				}
+			}
+			return true
+		},
+		"Personalize": func(h *Header) bool {
+			p := h.Personalize(&mail.Address{Address: "carol@example.com"}, map[string]string{"X-Mailer": "pine"})
+			return p.Get("X-Mailer") == "pine" && p.Get("To") == "<carol@example.com>" && len(p.Headers) == len(h.Headers)+1
+		},
+		"Pipeline": func(h *Header) bool {
+			p := &Pipeline{}
+			p.Add("strip", StripTransform(StripPolicy{Names: []string{"X-Mailer"}}))
+			return p.Apply(h) == nil && !h.Has("X-Mailer") && !h.RawKeys
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			h, err := ReadHeaderWithOptions(reader(input), ReadOptions{LazyKeys: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !test(&h) {
+				t.Errorf("%s doesn't handle lazy keys: %v", name, h.Headers)
+			}
+		})
+	}
+}
+
+func TestReadHeaderLimits(t *testing.T) {
+	const input = "A: 1\r\nSubject: a long\r\n  folded subject\r\nB: 2\r\nC: 3\r\n\r\n"
+	tests := []struct {
+		name      string
+		o         ReadOptions
+		want      []KV
+		truncated bool
+	}{
+		{
+			name:      "raw",
+			o:         ReadOptions{MaxFieldBytes: 30},
+			want:      []KV{{Key: "A", Value: "1"}, {Key: "Subject", Value: "a long folded subject"}, {Key: "B", Value: "2"}, {Key: "C", Value: "3"}},
+			truncated: true,
+		},
+		{
+			name: "none",
+			want: []KV{{Key: "A", Value: "1"}, {Key: "Subject", Value: "a long folded subject"}, {Key: "B", Value: "2"}, {Key: "C", Value: "3"}},
+		},
+		{
+			name:      "fields",
+			o:         ReadOptions{MaxFields: 2},
+			want:      []KV{{Key: "A", Value: "1"}, {Key: "Subject", Value: "a long folded subject"}},
+			truncated: true,
+		},
+		{
+			name:      "bytes",
+			o:         ReadOptions{MaxFieldBytes: 18},
+			want:      []KV{{Key: "A", Value: "1"}, {Key: "Subject", Value: "a long fo"}, {Key: "B", Value: "2"}, {Key: "C", Value: "3"}},
+			truncated: true,
+		},
+		{
+			name:      "fold",
+			o:         ReadOptions{MaxFieldBytes: 16},
+			want:      []KV{{Key: "A", Value: "1"}, {Key: "Subject", Value: "a long "}, {Key: "B", Value: "2"}, {Key: "C", Value: "3"}},
+			truncated: true,
+		},
+		{
+			name: "fits",
+			o:    ReadOptions{MaxFields: 4, MaxFieldBytes: 40},
+			want: []KV{{Key: "A", Value: "1"}, {Key: "Subject", Value: "a long folded subject"}, {Key: "B", Value: "2"}, {Key: "C", Value: "3"}},
+		},
+	}
+	for _, tt := range tests {
+		for _, keepRaw := range []bool{false, true} {
+			r := reader(input + "body")
+			o := tt.o
+			o.KeepRaw = keepRaw
+			h, err := ReadHeaderWithOptions(r, o)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			for i := range h.Headers {
+				h.Headers[i].Raw = ""
+			}
+			truncated := tt.truncated && (keepRaw || tt.name != "raw")
+			if !reflect.DeepEqual(h.Headers, tt.want) || h.Truncated != truncated {
+				t.Errorf("%s, KeepRaw %v: got %q truncated %v, want %q truncated %v", tt.name, keepRaw, h.Headers, h.Truncated, tt.want, truncated)
+			}
+			if rest, _ := ioutil.ReadAll(r.R); string(rest) != "body" {
+				t.Errorf("%s, KeepRaw %v: body is %q", tt.name, keepRaw, rest)
+			}
+		}
+	}
+}
+
+// repeatReader returns s n times without holding more than one copy
+type repeatReader struct {
+	s   string
+	n   int
+	off int
+}
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	read := 0
+	for read < len(p) && r.n > 0 {
+		c := copy(p[read:], r.s[r.off:])
+		read += c
+		r.off += c
+		if r.off == len(r.s) {
+			r.off = 0
+			r.n--
+		}
+	}
+	if read == 0 {
+		return 0, io.EOF
+	}
+	return read, nil
+}
+
+func TestReadMessagePathological(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  func() io.Reader
+		o      ReadOptions
+		fields int
+	}{
+		{
+			name: "many fields",
+			input: func() io.Reader {
+				return &repeatReader{s: "X-Field: some value\r\n", n: 100000}
+			},
+			o:      ReadOptions{MaxFields: 1000},
+			fields: 999,
+		},
+		{
+			name: "huge field",
+			input: func() io.Reader {
+				return io.MultiReader(strings.NewReader("Subject: start"), &repeatReader{s: "\r\n and more words in the folded line", n: 50 << 20 / 40}, strings.NewReader("\r\n"))
+			},
+			o:      ReadOptions{MaxFieldBytes: 1000},
+			fields: 1,
+		},
+		{
+			name: "huge line",
+			input: func() io.Reader {
+				return io.MultiReader(strings.NewReader("Subject: start"), &repeatReader{s: "xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx", n: 50 << 20 / 40}, strings.NewReader("\r\n"))
+			},
+			o:      ReadOptions{MaxFieldBytes: 1000},
+			fields: 1,
+		},
+	}
+	for _, tt := range tests {
+		for _, keepRaw := range []bool{false, true} {
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			o := tt.o
+			o.KeepRaw = keepRaw
+			m, err := ReadMessageWithOptions(io.MultiReader(strings.NewReader("From: a@example.com\r\n"), tt.input(), strings.NewReader("\r\nbody")), MessageOptions{ReadOptions: o})
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			runtime.ReadMemStats(&after)
+			if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 4<<20 {
+				t.Errorf("%s, KeepRaw %v: allocated %d bytes", tt.name, keepRaw, alloc)
+			}
+			h := m.Header
+			if !h.Truncated || len(h.Headers) != tt.fields+1 || m.RawHeaderBlock() != nil {
+				t.Errorf("%s, KeepRaw %v: truncated %v, %d fields", tt.name, keepRaw, h.Truncated, len(h.Headers))
+			}
+			for _, kv := range h.Headers {
+				if len(kv.Key)+len(kv.Value) > 1000 || len(kv.Raw) > 1002 {
+					t.Errorf("%s, KeepRaw %v: %s is %d bytes", tt.name, keepRaw, kv.Key, len(kv.Value))
+				}
+			}
+			if body, _ := ioutil.ReadAll(m.Body); string(body) != "body" {
+				t.Errorf("%s, KeepRaw %v: body is %q", tt.name, keepRaw, body)
+			}
+		}
+	}
+}
+
+// benchmarkHeaders are headers of different shapes, for comparing how
+// reading them scales
+var benchmarkHeaders = []struct {
+	name   string
+	header string
+}{
+	{"small", "From: alice@example.com\r\nTo: bob@example.com\r\nSubject: hello\r\n\r\n"},
+	{"medium", benchmarkHeader},
+	{"large", strings.Repeat("Received: from a.example (a.example [192.0.2.1]) by b.example with ESMTPS id 1A2B3C; Mon, 1 Jan 2024 00:00:00 +0000\r\n", 40) +
+		strings.Repeat("X-Custom-Field: a value that is of fairly typical length\r\n", 60) + benchmarkHeader},
+	{"foldy", strings.Repeat("Received: from a.example\r\n\t(a.example [192.0.2.1])\r\n\tby b.example\r\n\twith ESMTPS id 1A2B3C\r\n\tfor <bob@example.com>;\r\n\tMon, 1 Jan 2024 00:00:00 +0000\r\n", 10) +
+		"DKIM-Signature: v=1; a=rsa-sha256;\r\n" + strings.Repeat(" b=AbCdEfGhIjKlMnOpQrStUvWxYz0123456789+/AbCdEfGhIjKlMnOpQrStUvWxYz\r\n", 20) + benchmarkHeader},
+}
+
+func BenchmarkReadHeaderShapes(b *testing.B) {
+	for _, bh := range benchmarkHeaders {
+		for _, keepRaw := range []bool{false, true} {
+			name := bh.name
+			if keepRaw {
+				name += "/raw"
+			}
+			b.Run(name, func(b *testing.B) {
+				b.ReportAllocs()
+				b.SetBytes(int64(len(bh.header)))
+				o := ReadOptions{KeepRaw: keepRaw}
+				for i := 0; i < b.N; i++ {
+					if _, err := ReadHeaderWithOptions(reader(bh.header), o); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
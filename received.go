@@ -0,0 +1,184 @@
+package orderedheaders
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// https://tools.wordtothewise.com/rfc5321#section-4.4
+
+// AnonymizeMode is how AnonymizeReceived treats an IP address or
+// hostname
+type AnonymizeMode int
+
+const (
+	// AnonymizeKeep leaves the value unchanged
+	AnonymizeKeep AnonymizeMode = iota
+	// AnonymizeMask zeroes the last octet of an IPv4 address, or all
+	// but the first 48 bits of an IPv6 address, and reduces a hostname
+	// to its last two labels, or "unknown" if it has only one
+	AnonymizeMask
+	// AnonymizeHash replaces the value with a keyed hash, so that
+	// values can be correlated without being revealed
+	AnonymizeHash
+)
+
+// ReceivedPrivacy configures AnonymizeReceived
+type ReceivedPrivacy struct {
+	// DropFrom removes the from clause, with the HELO name and client
+	// address, entirely
+	DropFrom bool
+	// IP is how client IP addresses in the from clause are treated
+	IP AnonymizeMode
+	// Hosts is how the HELO name and reverse DNS name in the from
+	// clause are treated
+	Hosts AnonymizeMode
+	// Key is the HMAC key used by AnonymizeHash
+	Key []byte
+}
+
+// receivedClauses are the keywords that can follow the from clause of a
+// Received field
+var receivedClauses = map[string]struct{}{
+	"by":   {},
+	"via":  {},
+	"with": {},
+	"id":   {},
+	"for":  {},
+}
+
+// receivedTokenRe matches the words in a from clause that could be an
+// address or hostname
+var receivedTokenRe = regexp.MustCompile(`[A-Za-z0-9_:.\-]+`)
+
+// AnonymizeReceived rewrites the from clause of each Received field,
+// which describes the client that sent the message, to remove or mask
+// client IP addresses and hostnames, such as before archiving mail. The
+// by, with, id and for clauses and the date are left unchanged. It
+// returns the number of fields changed.
+func (h *Header) AnonymizeReceived(p ReceivedPrivacy) int {
+	h.CanonicalizeKeys()
+	changed := 0
+	for i, kv := range h.Headers {
+		if kv.Key != HdrReceived {
+			continue
+		}
+		value := anonymizeReceived(kv.Value, p)
+		if value != kv.Value {
+			h.Headers[i] = KV{Key: kv.Key, Value: value}
+			changed++
+		}
+	}
+	return changed
+}
+
+func anonymizeReceived(value string, p ReceivedPrivacy) string {
+	start, end, ok := receivedFromClause(value)
+	if !ok {
+		return value
+	}
+	if p.DropFrom {
+		return value[:start] + value[end:]
+	}
+	clause := value[start+len("from") : end]
+	var b strings.Builder
+	last := 0
+	for n, loc := range receivedTokenRe.FindAllStringIndex(clause, -1) {
+		tok := clause[loc[0]:loc[1]]
+		b.WriteString(clause[last:loc[0]])
+		last = loc[1]
+		// the HELO name is the first word, or given as helo= in a
+		// comment by some MTAs
+		helo := n == 0 || strings.HasSuffix(strings.ToLower(clause[:loc[0]]), "helo=")
+		literal, prefix := tok, ""
+		if len(tok) > 5 && strings.EqualFold(tok[:5], "ipv6:") {
+			prefix, literal = tok[:5], tok[5:]
+		}
+		switch ip := net.ParseIP(literal); {
+		case ip != nil && p.IP != AnonymizeKeep:
+			b.WriteString(prefix + anonymizeIP(ip, p))
+		case ip == nil && (helo || strings.Contains(tok, ".") && strings.IndexFunc(tok, isLetter) >= 0):
+			b.WriteString(anonymizeHost(tok, p))
+		default:
+			b.WriteString(tok)
+		}
+	}
+	b.WriteString(clause[last:])
+	return value[:start] + "from" + b.String() + value[end:]
+}
+
+// isLetter checks whether r is an ASCII letter
+func isLetter(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
+
+// receivedFromClause finds the from clause at the start of a Received
+// value, returning its extent including trailing whitespace
+func receivedFromClause(value string) (int, int, bool) {
+	start := len(value) - len(strings.TrimLeft(value, " \t\r\n"))
+	if len(value) < start+5 || !strings.EqualFold(value[start:start+4], "from") || !isWSP(value[start+4]) {
+		return 0, 0, false
+	}
+	i := start + 4
+	for i < len(value) {
+		switch c := value[i]; {
+		case isWSP(c):
+			i++
+		case c == '(':
+			_, next, err := readComment(value, i)
+			if err != nil {
+				return start, len(value), true
+			}
+			i = next
+		case c == ';':
+			return start, i, true
+		default:
+			j := i
+			for j < len(value) && !isWSP(value[j]) && value[j] != '(' && value[j] != ';' {
+				j++
+			}
+			if _, ok := receivedClauses[strings.ToLower(value[i:j])]; ok {
+				return start, i, true
+			}
+			i = j
+		}
+	}
+	return start, i, true
+}
+
+// anonymizeIP masks or hashes an IP address
+func anonymizeIP(ip net.IP, p ReceivedPrivacy) string {
+	if p.IP == AnonymizeHash {
+		return anonymousHash(p.Key, ip.String())
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(48, 128)).String()
+}
+
+// anonymizeHost masks or hashes a hostname
+func anonymizeHost(host string, p ReceivedPrivacy) string {
+	switch p.Hosts {
+	case AnonymizeMask:
+		labels := strings.Split(strings.TrimSuffix(host, "."), ".")
+		if len(labels) < 2 {
+			return "unknown"
+		}
+		return strings.Join(labels[len(labels)-2:], ".")
+	case AnonymizeHash:
+		return anonymousHash(p.Key, strings.ToLower(host))
+	}
+	return host
+}
+
+// anonymousHash returns a short keyed hash of s
+func anonymousHash(key []byte, s string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(s))
+	return "anon-" + hex.EncodeToString(mac.Sum(nil)[:8])
+}
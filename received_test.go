@@ -0,0 +1,50 @@
+package orderedheaders
+
+import (
+	"testing"
+)
+
+func TestAnonymizeReceived(t *testing.T) {
+	key := []byte("secret")
+	postfix := "from laptop.home.example (c-198-51-100-23.example.net [198.51.100.23]) by mx.example.com (Postfix) with ESMTPS id 4ABC for <bob@example.com>; Mon, 22 May 2023 10:00:00 +0000"
+	exim := "from [2001:db8:1234:5678::1] (helo=laptop) by mx.example.com with esmtp (Exim 4.96) id 1q1; Mon, 22 May 2023 10:00:00 +0000"
+	local := "by mx.example.com (Postfix, from userid 1000) id 4ABC; Mon, 22 May 2023 10:00:00 +0000"
+	tests := map[string]struct {
+		In      string
+		Privacy ReceivedPrivacy
+		Want    string
+	}{
+		"keep": {In: postfix, Want: postfix},
+		"drop": {In: postfix, Privacy: ReceivedPrivacy{DropFrom: true},
+			Want: "by mx.example.com (Postfix) with ESMTPS id 4ABC for <bob@example.com>; Mon, 22 May 2023 10:00:00 +0000"},
+		"maskip": {In: postfix, Privacy: ReceivedPrivacy{IP: AnonymizeMask},
+			Want: "from laptop.home.example (c-198-51-100-23.example.net [198.51.100.0]) by mx.example.com (Postfix) with ESMTPS id 4ABC for <bob@example.com>; Mon, 22 May 2023 10:00:00 +0000"},
+		"maskall": {In: postfix, Privacy: ReceivedPrivacy{IP: AnonymizeMask, Hosts: AnonymizeMask},
+			Want: "from home.example (example.net [198.51.100.0]) by mx.example.com (Postfix) with ESMTPS id 4ABC for <bob@example.com>; Mon, 22 May 2023 10:00:00 +0000"},
+		"hash": {In: postfix, Privacy: ReceivedPrivacy{IP: AnonymizeHash, Hosts: AnonymizeHash, Key: key},
+			Want: "from " + anonymousHash(key, "laptop.home.example") + " (" + anonymousHash(key, "c-198-51-100-23.example.net") +
+				" [" + anonymousHash(key, "198.51.100.23") + "]) by mx.example.com (Postfix) with ESMTPS id 4ABC for <bob@example.com>; Mon, 22 May 2023 10:00:00 +0000"},
+		"eximmask": {In: exim, Privacy: ReceivedPrivacy{IP: AnonymizeMask, Hosts: AnonymizeMask},
+			Want: "from [2001:db8:1234::] (helo=unknown) by mx.example.com with esmtp (Exim 4.96) id 1q1; Mon, 22 May 2023 10:00:00 +0000"},
+		"eximdrop": {In: exim, Privacy: ReceivedPrivacy{DropFrom: true},
+			Want: "by mx.example.com with esmtp (Exim 4.96) id 1q1; Mon, 22 May 2023 10:00:00 +0000"},
+		"ipv6literal": {In: "from helo.example ([IPv6:2001:db8::1]) by mx.example.com; Mon, 22 May 2023 10:00:00 +0000", Privacy: ReceivedPrivacy{IP: AnonymizeMask},
+			Want: "from helo.example ([IPv6:2001:db8::]) by mx.example.com; Mon, 22 May 2023 10:00:00 +0000"},
+		"local": {In: local, Privacy: ReceivedPrivacy{DropFrom: true, IP: AnonymizeMask}, Want: local},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := Header{Headers: []KV{{Key: "Received", Value: test.In, Raw: "Received: " + test.In + "\r\n"}}}
+			n := h.AnonymizeReceived(test.Privacy)
+			if got := h.Get(HdrReceived); got != test.Want {
+				t.Errorf("want\n%s\ngot\n%s", test.Want, got)
+			}
+			if (n == 1) != (test.In != test.Want) {
+				t.Errorf("unexpected change count %d", n)
+			}
+			if n == 1 && h.Headers[0].Raw != "" {
+				t.Errorf("Raw not cleared")
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package orderedheaders
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/textproto"
+)
+
+// DefaultRedactKeys are the fields Redact replaces if the policy doesn't
+// list any
+var DefaultRedactKeys = []string{HdrTo, HdrCc, HdrBcc, HdrSubject}
+
+// DefaultRedactMarker replaces redacted values if the policy doesn't
+// give a marker or HMAC key
+const DefaultRedactMarker = "[redacted]"
+
+// redactDigestLength is the number of bytes of HMAC kept in a redacted
+// value, enough to correlate values without making them long
+const redactDigestLength = 16
+
+// RedactPolicy configures Redact
+type RedactPolicy struct {
+	// Keys lists the fields to redact. If it's nil DefaultRedactKeys is
+	// used.
+	Keys []string
+	// Marker replaces the value of each redacted field. If it's empty
+	// DefaultRedactMarker is used.
+	Marker string
+	// HMACKey, if set, replaces each value with an HMAC-SHA256 digest
+	// of it instead of the marker, so that equal values can still be
+	// recognised without being revealed
+	HMACKey []byte
+}
+
+// Redact returns a copy of the header with the values of sensitive
+// fields replaced, for logging or storage. Keys and field order are
+// unchanged, so the result is still useful for debugging.
+func (h *Header) Redact(policy RedactPolicy) *Header {
+	h.CanonicalizeKeys()
+	keys := policy.Keys
+	if keys == nil {
+		keys = DefaultRedactKeys
+	}
+	redact := map[string]struct{}{}
+	for _, key := range keys {
+		redact[textproto.CanonicalMIMEHeaderKey(key)] = struct{}{}
+	}
+	marker := policy.Marker
+	if marker == "" {
+		marker = DefaultRedactMarker
+	}
+
+	ret := &Header{Headers: make([]KV, len(h.Headers))}
+	for i, kv := range h.Headers {
+		if _, ok := redact[kv.Key]; !ok {
+			ret.Headers[i] = kv
+			continue
+		}
+		value := marker
+		if policy.HMACKey != nil {
+			mac := hmac.New(sha256.New, policy.HMACKey)
+			mac.Write([]byte(kv.Value))
+			value = "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil)[:redactDigestLength])
+		}
+		ret.Headers[i] = KV{Key: kv.Key, Value: value}
+	}
+	return ret
+}
@@ -0,0 +1,51 @@
+package orderedheaders
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRedact(t *testing.T) {
+	h := Header{Headers: []KV{
+		{Key: "From", Value: "a@example.com"},
+		{Key: "To", Value: "b@example.com", Raw: "To: b@example.com\r\n"},
+		{Key: "Subject", Value: "private"},
+		{Key: "X-Account", Value: "12345"},
+		{Key: "Cc", Value: "b@example.com"},
+	}}
+	orig := append([]KV(nil), h.Headers...)
+
+	got := h.Redact(RedactPolicy{})
+	want := []KV{
+		{Key: "From", Value: "a@example.com"},
+		{Key: "To", Value: DefaultRedactMarker},
+		{Key: "Subject", Value: DefaultRedactMarker},
+		{Key: "X-Account", Value: "12345"},
+		{Key: "Cc", Value: DefaultRedactMarker},
+	}
+	if diff := cmp.Diff(want, got.Headers); diff != "" {
+		t.Errorf("default policy mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(orig, h.Headers); diff != "" {
+		t.Errorf("original header changed:\n%s", diff)
+	}
+
+	got = h.Redact(RedactPolicy{Keys: []string{"x-account"}, Marker: "***"})
+	if got.Get("X-Account") != "***" || got.Get("To") != "b@example.com" {
+		t.Errorf("custom policy not applied: %+v", got.Headers)
+	}
+
+	got = h.Redact(RedactPolicy{HMACKey: []byte("secret")})
+	to, cc, subject := got.Get("To"), got.Get("Cc"), got.Get("Subject")
+	if !strings.HasPrefix(to, "hmac-sha256:") || len(to) != len("hmac-sha256:")+2*redactDigestLength {
+		t.Errorf("unexpected digest '%s'", to)
+	}
+	if to != cc || to == subject {
+		t.Errorf("digests should match only for equal values")
+	}
+	if other := h.Redact(RedactPolicy{HMACKey: []byte("other")}); other.Get("To") == to {
+		t.Errorf("digest doesn't depend on key")
+	}
+}
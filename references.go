@@ -0,0 +1,101 @@
+package orderedheaders
+
+import (
+	"fmt"
+	"strings"
+)
+
+// https://tools.wordtothewise.com/rfc5322#section-3.6.4
+
+// References returns the message IDs from the References header, in
+// order, including their angle brackets
+func (h *Header) References() []string {
+	return h.MessageIDs(HdrReferences)
+}
+
+// MessageIDs returns the message IDs from the first header with the
+// given key, such as In-Reply-To, in order and including their angle
+// brackets
+func (h *Header) MessageIDs(key string) []string {
+	return parseMessageIDs(h.Get(key))
+}
+
+// SetReferences sets the References header to the given message IDs,
+// dropping any duplicates. Angle brackets are added if missing.
+func (h *Header) SetReferences(ids []string) error {
+	var refs []string
+	seen := map[string]struct{}{}
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if !strings.HasPrefix(id, "<") {
+			id = "<" + id + ">"
+		}
+		if err := validMessageId(id); err != nil {
+			return err
+		}
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		refs = append(refs, id)
+	}
+	if len(refs) == 0 {
+		h.RemoveAll(HdrReferences)
+		return nil
+	}
+	return h.Set(HdrReferences, strings.Join(refs, " "))
+}
+
+// AddReference appends a message ID to the References header, unless
+// it's already present
+func (h *Header) AddReference(id string) error {
+	return h.SetReferences(append(h.References(), id))
+}
+
+// TrimReferences shortens the References header to no more than maxBytes
+// by removing message IDs from the middle of the list. The first message
+// ID, identifying the start of the thread, is always retained, along
+// with as many of the most recent as will fit, and at least one.
+func (h *Header) TrimReferences(maxBytes int) error {
+	refs := h.References()
+	if len(strings.Join(refs, " ")) <= maxBytes || len(refs) <= 2 {
+		return nil
+	}
+	size := len(refs[0]) + 1 + len(refs[len(refs)-1])
+	start := len(refs) - 1
+	for start > 1 && size+1+len(refs[start-1]) <= maxBytes {
+		start--
+		size += 1 + len(refs[start])
+	}
+	trimmed := append([]string{refs[0]}, refs[start:]...)
+	if err := h.SetReferences(trimmed); err != nil {
+		return fmt.Errorf("trimming References: %w", err)
+	}
+	return nil
+}
+
+// parseMessageIDs extracts the angle-bracketed message IDs from a list,
+// ignoring whitespace, commas and comments between them
+func parseMessageIDs(s string) []string {
+	var ret []string
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case '<':
+			end := strings.IndexByte(s[i:], '>')
+			if end < 0 {
+				return ret
+			}
+			ret = append(ret, stripFWS(s[i:i+end+1]))
+			i += end + 1
+		case '(':
+			_, next, err := readComment(s, i)
+			if err != nil {
+				return ret
+			}
+			i = next
+		default:
+			i++
+		}
+	}
+	return ret
+}
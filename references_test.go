@@ -0,0 +1,55 @@
+package orderedheaders
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReferences(t *testing.T) {
+	h := &Header{}
+	h.Add("References", "<1@example.com> (first)\r\n <2@example.com>,<3@example.com>")
+	if err := h.AddReference("2@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.AddReference("4@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.AddReference("not a message id"); err == nil {
+		t.Errorf("expected error for invalid message id")
+	}
+	want := []string{"<1@example.com>", "<2@example.com>", "<3@example.com>", "<4@example.com>"}
+	if diff := cmp.Diff(want, h.References()); diff != "" {
+		t.Errorf("References mismatch (-want +got):\n%s", diff)
+	}
+	if got := h.Get("References"); got != strings.Join(want, " ") {
+		t.Errorf("unexpected References header '%s'", got)
+	}
+}
+
+func TestTrimReferences(t *testing.T) {
+	h := &Header{}
+	var ids []string
+	for _, c := range "abcdefgh" {
+		ids = append(ids, "<"+string(c)+"@example.com>")
+	}
+	if err := h.SetReferences(ids); err != nil {
+		t.Fatal(err)
+	}
+	// each id is 15 bytes, so 50 bytes holds three of them
+	if err := h.TrimReferences(50); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"<a@example.com>", "<g@example.com>", "<h@example.com>"}
+	if diff := cmp.Diff(want, h.References()); diff != "" {
+		t.Errorf("TrimReferences mismatch (-want +got):\n%s", diff)
+	}
+	if err := h.TrimReferences(10); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"<a@example.com>", "<h@example.com>"}
+	if diff := cmp.Diff(want, h.References()); diff != "" {
+		t.Errorf("TrimReferences mismatch (-want +got):\n%s", diff)
+	}
+}
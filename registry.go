@@ -0,0 +1,93 @@
+package orderedheaders
+
+import (
+	"fmt"
+	"net/mail"
+	"net/textproto"
+)
+
+// A Parser converts the value of a structured header into a typed value
+type Parser func(value string) (interface{}, error)
+
+// A Serializer renders a typed value as a header value. Any line breaks
+// it includes are unfolded, and long values are folded again when the
+// header is written.
+type Serializer func(v interface{}) (string, error)
+
+// StructuredHeader is a pair of handlers for a structured header
+type StructuredHeader struct {
+	Parse     Parser
+	Serialize Serializer
+}
+
+// StructuredHeaders maps header names to handlers for their values,
+// letting callers add support for structured headers this package
+// doesn't know about. Keys must be canonical; RegisterStructured takes
+// care of that.
+var StructuredHeaders = map[string]StructuredHeader{}
+
+// RegisterSyntax adds a non-standard header to HeaderSyntax, so that Set
+// accepts it and checks its value, WriteTo renders it by its type, and
+// Lint checks it
+func RegisterSyntax(key string, syntax Syntax) {
+	HeaderSyntax[textproto.CanonicalMIMEHeaderKey(key)] = syntax
+}
+
+// RegisterStructured adds handlers for a structured header. Value and
+// SetValue use them to convert between the header and typed values, and
+// WriteTo renders the header by parsing and reserializing it. Unless a
+// validator is already registered for the header, the parser is also
+// used as one, so that Set will accept it.
+func RegisterStructured(key string, parse Parser, serialize Serializer) {
+	key = textproto.CanonicalMIMEHeaderKey(key)
+	StructuredHeaders[key] = StructuredHeader{
+		Parse:     parse,
+		Serialize: serialize,
+	}
+	if _, ok := HeaderValidators[key]; !ok {
+		HeaderValidators[key] = func(value string) error {
+			_, err := parse(value)
+			return err
+		}
+	}
+}
+
+// Value parses the first header with the given key using its registered
+// Parser
+func (h *Header) Value(key string) (interface{}, error) {
+	key = textproto.CanonicalMIMEHeaderKey(key)
+	handler, ok := StructuredHeaders[key]
+	if !ok {
+		return nil, fmt.Errorf("no parser registered for %s", key)
+	}
+	if !h.Has(key) {
+		return nil, mail.ErrHeaderNotPresent
+	}
+	return handler.Parse(h.Get(key))
+}
+
+// SetValue renders v using the Serializer registered for key, and sets
+// the header to the result, replacing any existing one
+func (h *Header) SetValue(key string, v interface{}) error {
+	key = textproto.CanonicalMIMEHeaderKey(key)
+	handler, ok := StructuredHeaders[key]
+	if !ok {
+		return fmt.Errorf("no serializer registered for %s", key)
+	}
+	value, err := handler.Serialize(v)
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	h.replace(key, value)
+	return nil
+}
+
+// reserialize parses and reserializes a header value with its registered
+// handlers
+func reserialize(handler StructuredHeader, value string) (string, error) {
+	v, err := handler.Parse(value)
+	if err != nil {
+		return "", err
+	}
+	return handler.Serialize(v)
+}
@@ -0,0 +1,140 @@
+package orderedheaders
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+type point struct {
+	X, Y int
+}
+
+func registerPoint(t *testing.T) {
+	RegisterStructured("x-point", func(value string) (interface{}, error) {
+		var p point
+		if _, err := fmt.Sscanf(strings.TrimSpace(value), "%d,%d", &p.X, &p.Y); err != nil {
+			return nil, fmt.Errorf("'%s' is not a valid point", value)
+		}
+		return p, nil
+	}, func(v interface{}) (string, error) {
+		p, ok := v.(point)
+		if !ok {
+			return "", fmt.Errorf("%T is not a point", v)
+		}
+		return fmt.Sprintf("%d, %d", p.X, p.Y), nil
+	})
+	t.Cleanup(func() {
+		delete(StructuredHeaders, "X-Point")
+		delete(HeaderValidators, "X-Point")
+	})
+}
+
+func TestStructuredHeaders(t *testing.T) {
+	registerPoint(t)
+	h := &Header{}
+	if _, err := h.Value("X-Point"); !errors.Is(err, mail.ErrHeaderNotPresent) {
+		t.Errorf("expected ErrHeaderNotPresent, got %v", err)
+	}
+	if err := h.SetValue("X-Point", point{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if got := h.Get("X-Point"); got != "1, 2" {
+		t.Errorf("unexpected value '%s'", got)
+	}
+	v, err := h.Value("x-point")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != (point{1, 2}) {
+		t.Errorf("unexpected value %v", v)
+	}
+	if err := h.SetValue("X-Point", "1,2"); err == nil {
+		t.Errorf("expected error serializing the wrong type")
+	}
+	if err := h.Set("X-Point", "nowhere"); err == nil {
+		t.Errorf("expected Set to validate with the parser")
+	}
+	if err := h.Set("X-Point", "3,4"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := h.Bytes(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "X-Point: 3, 4\r\n" {
+		t.Errorf("unexpected rendering %q", b)
+	}
+
+	if _, err := h.Value("X-Unknown"); err == nil {
+		t.Errorf("expected error for unregistered header")
+	}
+	if err := h.SetValue("X-Unknown", 1); err == nil {
+		t.Errorf("expected error for unregistered header")
+	}
+
+	h.Add("X-Point", "broken")
+	if b, err := h.Bytes(Options{}); err != nil || string(b) != "X-Point: 3, 4\r\nX-Point: broken\r\n" {
+		t.Errorf("invalid header not written unchanged, got %q, %v", b, err)
+	}
+}
+
+func TestRegisterSyntax(t *testing.T) {
+	h := &Header{}
+	if err := h.Set("X-Original-To", "bob@example.com"); err == nil {
+		t.Errorf("expected error for unregistered header")
+	}
+	RegisterSyntax("x-original-to", Syntax{Unique: true, Type: HeaderTypeMailbox})
+	defer delete(HeaderSyntax, "X-Original-To")
+	if err := h.Set("x-original-to", "not an address"); err == nil {
+		t.Errorf("expected Set to check the registered syntax")
+	}
+	if err := h.Set("x-original-to", "bob@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	h.Add("X-Original-To", "carol@example.com")
+	b, err := h.Bytes(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "X-Original-To: <bob@example.com>\r\n"; string(b) != want {
+		t.Errorf("want %q, got %q", want, b)
+	}
+}
+
+func TestReserializeOnRepair(t *testing.T) {
+	registerPoint(t)
+	h := &Header{Headers: []KV{{Key: "X-Point", Value: "1,2"}}}
+	var got []string
+	_, err := h.Bytes(Options{OnRepair: func(key, before, after, reason string) {
+		got = append(got, key+" "+before+" -> "+after+" "+reason)
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != "X-Point 1,2 -> 1, 2 reserialized" {
+		t.Errorf("unexpected repairs %q", got)
+	}
+}
+
+func TestReserializeUnique(t *testing.T) {
+	registerPoint(t)
+	RegisterSyntax("X-Point", Syntax{Unique: true, Type: HeaderTypeOpaque})
+	defer delete(HeaderSyntax, "X-Point")
+	h := &Header{Headers: []KV{{Key: "X-Point", Value: "nowhere"}, {Key: "X-Point", Value: "3,4"}}}
+	var got []string
+	b, err := h.Bytes(Options{OnRepair: func(key, before, after, reason string) {
+		got = append(got, key+" "+before+" -> "+after+" "+reason)
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "X-Point: nowhere\r\n" {
+		t.Errorf("unexpected rendering %q", b)
+	}
+	if len(got) != 1 || got[0] != "X-Point nowhere -> nowhere written unchanged, 'nowhere' is not a valid point" {
+		t.Errorf("unexpected repairs %q", got)
+	}
+}
@@ -0,0 +1,139 @@
+package orderedheaders
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// https://tools.wordtothewise.com/rfc5322#section-3.6.3
+// https://tools.wordtothewise.com/rfc5322#section-3.6.4
+
+// ReplyOptions configures BuildReply
+type ReplyOptions struct {
+	// From is the author of the reply. It is set as From, and excluded
+	// from the recipients of a reply to all.
+	From *mail.Address
+	// ReplyAll copies the reply to the other recipients of the original
+	ReplyAll bool
+}
+
+// BuildReply creates the header for a reply to a message. To is taken
+// from the original Reply-To, or From if there isn't one, or if that's
+// the author of the reply from the original To, or Cc if there's no To.
+// When replying to all the other original To and Cc recipients are added
+// as Cc. Subject gets a single "Re: " prefix in place of any reply or
+// forward prefixes. In-Reply-To and References link the reply to the
+// original if its Message-Id is valid, as does Thread-Index if the
+// original has one.
+func BuildReply(orig *Header, opts ReplyOptions) (*Header, error) {
+	reply := &Header{}
+	if opts.From != nil {
+		if err := reply.Set(HdrFrom, opts.From.String()); err != nil {
+			return nil, err
+		}
+	}
+
+	to, err := orig.AddressList(HdrReplyTo)
+	if errors.Is(err, mail.ErrHeaderNotPresent) {
+		to, err = orig.AddressList(HdrFrom)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("no address to reply to: %w", err)
+	}
+	seen := map[string]struct{}{}
+	if opts.From != nil {
+		seen[strings.ToLower(opts.From.Address)] = struct{}{}
+	}
+	to = uniqueAddresses(to, seen)
+	for _, key := range []string{HdrTo, HdrCc} {
+		if len(to) > 0 {
+			break
+		}
+		// replying to our own message goes to its recipients
+		addrs, err := orig.AddressList(key)
+		if err != nil && !errors.Is(err, mail.ErrHeaderNotPresent) {
+			return nil, err
+		}
+		to = uniqueAddresses(addrs, seen)
+	}
+	if len(to) == 0 {
+		return nil, errors.New("no address to reply to")
+	}
+	if err := reply.Set(HdrTo, formatAddressList(to)); err != nil {
+		return nil, err
+	}
+
+	if opts.ReplyAll {
+		var cc []*mail.Address
+		for _, key := range []string{HdrTo, HdrCc} {
+			addrs, err := orig.AddressList(key)
+			if err != nil && !errors.Is(err, mail.ErrHeaderNotPresent) {
+				return nil, err
+			}
+			cc = append(cc, uniqueAddresses(addrs, seen)...)
+		}
+		if len(cc) > 0 {
+			if err := reply.Set(HdrCc, formatAddressList(cc)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	_, subject := SplitSubject(orig.decodedSubject())
+	if err := reply.Set(HdrSubject, "Re: "+subject); err != nil {
+		return nil, err
+	}
+
+	if id := strings.TrimSpace(orig.Get(HdrMessageId)); ValidateMessageID(id) == nil {
+		if err := reply.Set(HdrInReplyTo, id); err != nil {
+			return nil, err
+		}
+		refs := orig.References()
+		if len(refs) == 0 {
+			refs = orig.MessageIDs(HdrInReplyTo)
+			if len(refs) > 1 {
+				refs = nil
+			}
+		}
+		if err := reply.SetReferences(append(refs, id)); err != nil {
+			return nil, err
+		}
+	}
+
+	// Outlook threads on Thread-Index rather than References
+	if ti, err := orig.ThreadIndex(); err == nil {
+		if err := reply.SetThreadIndex(ti.Reply(time.Now())); err != nil {
+			return nil, err
+		}
+		if err := reply.SetThreadTopic(orig.ThreadTopic()); err != nil {
+			return nil, err
+		}
+	}
+	return reply, nil
+}
+
+// uniqueAddresses filters out addresses already in seen, and adds the
+// remainder to it
+func uniqueAddresses(addrs []*mail.Address, seen map[string]struct{}) []*mail.Address {
+	var ret []*mail.Address
+	for _, a := range addrs {
+		key := strings.ToLower(a.Address)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		ret = append(ret, a)
+	}
+	return ret
+}
+
+func formatAddressList(addrs []*mail.Address) string {
+	formatted := make([]string, len(addrs))
+	for i, a := range addrs {
+		formatted[i] = a.String()
+	}
+	return strings.Join(formatted, ", ")
+}
@@ -0,0 +1,150 @@
+package orderedheaders
+
+import (
+	"net/mail"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBuildReply(t *testing.T) {
+	orig := &Header{}
+	orig.Add("From", "Alice <alice@example.com>")
+	orig.Add("Reply-To", "list@example.com")
+	orig.Add("To", "bob@example.com, Carol <carol@example.com>")
+	orig.Add("Cc", "list@example.com, dave@example.com")
+	orig.Add("Subject", "RE: Re:  =?utf-8?q?caf=C3=A9?=")
+	orig.Add("Message-Id", "<3@example.com>")
+	orig.Add("In-Reply-To", "<2@example.com>")
+	orig.Add("References", "<1@example.com> <2@example.com>")
+
+	tests := map[string]struct {
+		Opts ReplyOptions
+		Want []KV
+	}{
+		"reply": {
+			ReplyOptions{From: &mail.Address{Address: "bob@example.com"}},
+			[]KV{
+				{Key: "From", Value: "<bob@example.com>"},
+				{Key: "To", Value: "<list@example.com>"},
+				{Key: "Subject", Value: "Re: café"},
+				{Key: "In-Reply-To", Value: "<3@example.com>"},
+				{Key: "References", Value: "<1@example.com> <2@example.com> <3@example.com>"},
+			},
+		},
+		"replyall": {
+			ReplyOptions{From: &mail.Address{Address: "BOB@example.com"}, ReplyAll: true},
+			[]KV{
+				{Key: "From", Value: "<BOB@example.com>"},
+				{Key: "To", Value: "<list@example.com>"},
+				{Key: "Cc", Value: `"Carol" <carol@example.com>, <dave@example.com>`},
+				{Key: "Subject", Value: "Re: café"},
+				{Key: "In-Reply-To", Value: "<3@example.com>"},
+				{Key: "References", Value: "<1@example.com> <2@example.com> <3@example.com>"},
+			},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			reply, err := BuildReply(orig, test.Opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.Want, reply.Headers); diff != "" {
+				t.Errorf("BuildReply mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestBuildReplyOwn(t *testing.T) {
+	orig := &Header{}
+	orig.Add("From", "Alice <alice@example.com>")
+	orig.Add("To", "bob@example.com")
+	orig.Add("Cc", "carol@example.com, alice@example.com")
+	orig.Add("Subject", "hello")
+	alice := &mail.Address{Address: "alice@example.com"}
+	tests := map[string]struct {
+		Opts ReplyOptions
+		To   string
+		Cc   string
+	}{
+		"reply":    {ReplyOptions{From: alice}, "<bob@example.com>", ""},
+		"replyall": {ReplyOptions{From: alice, ReplyAll: true}, "<bob@example.com>", "<carol@example.com>"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			reply, err := BuildReply(orig, test.Opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := reply.Get("To"); got != test.To {
+				t.Errorf("want To %q, got %q", test.To, got)
+			}
+			if got := reply.Get("Cc"); got != test.Cc {
+				t.Errorf("want Cc %q, got %q", test.Cc, got)
+			}
+		})
+	}
+
+	ccOnly := &Header{}
+	ccOnly.Add("From", "alice@example.com")
+	ccOnly.Add("Cc", "carol@example.com")
+	reply, err := BuildReply(ccOnly, ReplyOptions{From: alice})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reply.Get("To"); got != "<carol@example.com>" {
+		t.Errorf("want To from Cc, got %q", got)
+	}
+
+	alone := &Header{}
+	alone.Add("From", "alice@example.com")
+	alone.Add("To", "alice@example.com")
+	if _, err := BuildReply(alone, ReplyOptions{From: alice}); err == nil {
+		t.Errorf("expected error replying only to ourselves")
+	}
+}
+
+func TestBuildReplyThreadIndex(t *testing.T) {
+	orig := &Header{}
+	orig.Add("From", "alice@example.com")
+	orig.Add("Subject", "hello")
+	orig.Add("Thread-Topic", "hello")
+	root := NewThreadIndex(time.Now().Add(-time.Hour))
+	orig.Add("Thread-Index", root.String())
+	reply, err := BuildReply(orig, ReplyOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ti, err := reply.ThreadIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent, ok := ti.Parent()
+	if !ok || parent.String() != root.String() {
+		t.Errorf("reply Thread-Index %s is not a child of %s", ti, root)
+	}
+	if reply.ThreadTopic() != "hello" {
+		t.Errorf("ThreadTopic() = %q", reply.ThreadTopic())
+	}
+}
+
+func TestBuildReplyInvalidMessageID(t *testing.T) {
+	orig := &Header{}
+	orig.Add("From", "alice@example.com")
+	orig.Add("Subject", "Fwd: Re: hello")
+	orig.Add("Message-Id", "not-a-msgid")
+	orig.Add("References", "<1@example.com>")
+	reply, err := BuildReply(orig, ReplyOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reply.Get("Subject"); got != "Re: hello" {
+		t.Errorf("want Subject %q, got %q", "Re: hello", got)
+	}
+	if reply.Has("In-Reply-To") || reply.Has("References") {
+		t.Errorf("reply links to invalid Message-Id: %v", reply.Headers)
+	}
+}
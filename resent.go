@@ -0,0 +1,119 @@
+package orderedheaders
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// https://tools.wordtothewise.com/rfc5322#section-3.6.6
+
+// ResentBlock is one set of Resent-* fields, describing a single occasion
+// on which a message was reintroduced into the transport system
+type ResentBlock struct {
+	Date      time.Time
+	From      []*mail.Address
+	Sender    *mail.Address
+	To        []*mail.Address
+	Cc        []*mail.Address
+	Bcc       []*mail.Address
+	MessageID string
+}
+
+// isResent reports whether a canonical header key is a resent field
+func isResent(key string) bool {
+	return strings.HasPrefix(key, "Resent-")
+}
+
+// ResentBlocks returns the resent blocks in the header, most recent
+// first. Each run of consecutive Resent-* fields is one block, and a run
+// is split into several if a field is repeated within it. Fields that
+// can't be parsed are left empty in the block.
+func (h *Header) ResentBlocks() []ResentBlock {
+	h.CanonicalizeKeys()
+	var blocks []ResentBlock
+	var seen map[string]struct{}
+	inBlock := false
+	for _, kv := range h.Headers {
+		if !isResent(kv.Key) {
+			inBlock = false
+			continue
+		}
+		if _, ok := seen[kv.Key]; !inBlock || ok {
+			blocks = append(blocks, ResentBlock{})
+			seen = map[string]struct{}{}
+			inBlock = true
+		}
+		seen[kv.Key] = struct{}{}
+		b := &blocks[len(blocks)-1]
+		switch kv.Key {
+		case HdrResentDate:
+			b.Date, _ = mail.ParseDate(kv.Value)
+		case HdrResentFrom:
+			b.From, _ = mail.ParseAddressList(kv.Value)
+		case HdrResentSender:
+			b.Sender, _ = mail.ParseAddress(kv.Value)
+		case HdrResentTo:
+			b.To, _ = mail.ParseAddressList(kv.Value)
+		case HdrResentCc:
+			b.Cc, _ = mail.ParseAddressList(kv.Value)
+		case HdrResentBcc:
+			b.Bcc, _ = mail.ParseAddressList(kv.Value)
+		case HdrResentMessageId:
+			if ids := parseMessageIDs(kv.Value); len(ids) == 1 {
+				b.MessageID = ids[0]
+			}
+		}
+	}
+	return blocks
+}
+
+// AddResentBlock adds a resent block at the top of the header, above any
+// earlier ones. Resent-From is required, as is Resent-Sender if there is
+// more than one Resent-From address. A zero Date is set to the current
+// time.
+func (h *Header) AddResentBlock(b ResentBlock) error {
+	if len(b.From) == 0 {
+		return errors.New("resent block requires a Resent-From address")
+	}
+	if len(b.From) > 1 && b.Sender == nil {
+		return errors.New("resent block with more than one Resent-From address requires a Resent-Sender")
+	}
+	date := b.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	kvs := []KV{
+		{Key: HdrResentDate, Value: FormatDate(date, true)},
+		{Key: HdrResentFrom, Value: formatAddressList(b.From)},
+	}
+	if b.Sender != nil {
+		kvs = append(kvs, KV{Key: HdrResentSender, Value: b.Sender.String()})
+	}
+	for _, f := range []struct {
+		key   string
+		addrs []*mail.Address
+	}{
+		{HdrResentTo, b.To},
+		{HdrResentCc, b.Cc},
+		{HdrResentBcc, b.Bcc},
+	} {
+		if len(f.addrs) > 0 {
+			kvs = append(kvs, KV{Key: f.key, Value: formatAddressList(f.addrs)})
+		}
+	}
+	if b.MessageID != "" {
+		id := strings.TrimSpace(b.MessageID)
+		if !strings.HasPrefix(id, "<") {
+			id = "<" + id + ">"
+		}
+		if err := validMessageId(id); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", HdrResentMessageId, err)
+		}
+		kvs = append(kvs, KV{Key: HdrResentMessageId, Value: id})
+	}
+	h.insert(0, kvs...)
+	return nil
+}
@@ -0,0 +1,123 @@
+package orderedheaders
+
+import (
+	"net/mail"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestResentBlocks(t *testing.T) {
+	tests := map[string]struct {
+		In   string
+		Want []ResentBlock
+	}{
+		"none": {
+			In: "From: a@example.com\r\nSubject: test\r\n\r\n",
+		},
+		"two": {
+			In: "Received: from b\r\n" +
+				"Resent-From: b@example.com\r\n" +
+				"Resent-Date: 2 Jan 2023 10:00:00 +0000\r\n" +
+				"Resent-To: c@example.com\r\n" +
+				"Resent-Message-Id: <2@example.com>\r\n" +
+				"Received: from a\r\n" +
+				"Resent-From: a@example.com\r\n" +
+				"Resent-Date: 1 Jan 2023 10:00:00 +0000\r\n" +
+				"Resent-To: b@example.com\r\n" +
+				"From: x@example.com\r\n\r\n",
+			Want: []ResentBlock{
+				{
+					Date:      time.Date(2023, 1, 2, 10, 0, 0, 0, time.UTC),
+					From:      []*mail.Address{{Address: "b@example.com"}},
+					To:        []*mail.Address{{Address: "c@example.com"}},
+					MessageID: "<2@example.com>",
+				},
+				{
+					Date: time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC),
+					From: []*mail.Address{{Address: "a@example.com"}},
+					To:   []*mail.Address{{Address: "b@example.com"}},
+				},
+			},
+		},
+		"adjacent": {
+			In: "Resent-Date: 2 Jan 2023 10:00:00 +0000\r\n" +
+				"Resent-From: b@example.com\r\n" +
+				"Resent-Date: 1 Jan 2023 10:00:00 +0000\r\n" +
+				"Resent-From: a@example.com\r\n" +
+				"Resent-Sender: s@example.com\r\n\r\n",
+			Want: []ResentBlock{
+				{
+					Date: time.Date(2023, 1, 2, 10, 0, 0, 0, time.UTC),
+					From: []*mail.Address{{Address: "b@example.com"}},
+				},
+				{
+					Date:   time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC),
+					From:   []*mail.Address{{Address: "a@example.com"}},
+					Sender: &mail.Address{Address: "s@example.com"},
+				},
+			},
+		},
+		"invalid": {
+			In: "Resent-Date: yesterday\r\nResent-From: a@example.com\r\n\r\n",
+			Want: []ResentBlock{
+				{From: []*mail.Address{{Address: "a@example.com"}}},
+			},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			h, err := ReadHeader(reader(test.In))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := h.ResentBlocks()
+			if diff := cmp.Diff(test.Want, got); diff != "" {
+				t.Errorf("ResentBlocks() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAddResentBlock(t *testing.T) {
+	h := &Header{}
+	h.Add("Resent-Date", "1 Jan 2023 10:00:00 +0000")
+	h.Add("Resent-From", "a@example.com")
+	h.Add("From", "x@example.com")
+	block := ResentBlock{
+		Date:      time.Date(2023, 1, 2, 10, 0, 0, 0, time.UTC),
+		From:      []*mail.Address{{Name: "Bee", Address: "b@example.com"}},
+		To:        []*mail.Address{{Address: "c@example.com"}, {Address: "d@example.com"}},
+		MessageID: "2@example.com",
+	}
+	if err := h.AddResentBlock(block); err != nil {
+		t.Fatal(err)
+	}
+	want := []KV{
+		{Key: "Resent-Date", Value: "Mon, 2 Jan 2023 10:00:00 +0000"},
+		{Key: "Resent-From", Value: `"Bee" <b@example.com>`},
+		{Key: "Resent-To", Value: "<c@example.com>, <d@example.com>"},
+		{Key: "Resent-Message-Id", Value: "<2@example.com>"},
+		{Key: "Resent-Date", Value: "1 Jan 2023 10:00:00 +0000"},
+		{Key: "Resent-From", Value: "a@example.com"},
+		{Key: "From", Value: "x@example.com"},
+	}
+	if diff := cmp.Diff(want, h.Headers); diff != "" {
+		t.Errorf("AddResentBlock() mismatch (-want +got):\n%s", diff)
+	}
+	if got := len(h.ResentBlocks()); got != 2 {
+		t.Errorf("expected 2 resent blocks, got %d", got)
+	}
+
+	errs := []ResentBlock{
+		{},
+		{From: []*mail.Address{{Address: "a@example.com"}, {Address: "b@example.com"}}},
+		{From: []*mail.Address{{Address: "a@example.com"}}, MessageID: "<not an id>"},
+	}
+	for i, b := range errs {
+		if err := h.AddResentBlock(b); err == nil {
+			t.Errorf("%d: expected error", i)
+		}
+	}
+}
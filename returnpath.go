@@ -0,0 +1,70 @@
+package orderedheaders
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+)
+
+// https://tools.wordtothewise.com/rfc5322#section-3.6.7
+
+// ReturnPath parses the Return-Path header, returning the address without
+// angle brackets or quoting, as net/mail does. A null return path, "<>",
+// as used on bounces, gives an empty address and isNull true. Comments
+// and any obsolete source route are removed.
+func (h *Header) ReturnPath() (addr string, isNull bool, err error) {
+	if !h.Has(HdrReturnPath) {
+		return "", false, mail.ErrHeaderNotPresent
+	}
+	return parseReturnPath(h.Get(HdrReturnPath))
+}
+
+// SetReturnPath sets the Return-Path header to addr, always in angle
+// brackets, replacing any existing ones. An empty address, or "<>", sets
+// the null return path.
+func (h *Header) SetReturnPath(addr string) error {
+	addr = strings.TrimSpace(addr)
+	if addr == "" || addr == "<>" {
+		h.replaceAll(HdrReturnPath, "<>")
+		return nil
+	}
+	parsed, isNull, err := parseReturnPath(addr)
+	if err != nil {
+		return err
+	}
+	if isNull {
+		h.replaceAll(HdrReturnPath, "<>")
+		return nil
+	}
+	h.replaceAll(HdrReturnPath, (&mail.Address{Address: parsed}).String())
+	return nil
+}
+
+// parseReturnPath parses path = angle-addr / ( [CFWS] "<" [CFWS] ">" [CFWS] )
+// and also accepts a bare addr-spec, which is common in practice
+func parseReturnPath(value string) (string, bool, error) {
+	s, err := stripComments(value)
+	if err != nil {
+		return "", false, fmt.Errorf("'%s' is not a valid return path: %w", value, err)
+	}
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "<") && strings.HasSuffix(s, ">") {
+		s = strings.TrimSpace(s[1 : len(s)-1])
+		if s == "" {
+			return "", true, nil
+		}
+		// obs-route, e.g. <@relay.example,@relay2.example:user@example.com>
+		if strings.HasPrefix(s, "@") {
+			colon := strings.IndexByte(s, ':')
+			if colon < 0 {
+				return "", false, fmt.Errorf("'%s' is not a valid return path", value)
+			}
+			s = strings.TrimSpace(s[colon+1:])
+		}
+	}
+	addr, err := mail.ParseAddress("<" + s + ">")
+	if err != nil {
+		return "", false, fmt.Errorf("'%s' is not a valid return path: %w", value, err)
+	}
+	return addr.Address, false, nil
+}
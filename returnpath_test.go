@@ -0,0 +1,95 @@
+package orderedheaders
+
+import (
+	"errors"
+	"net/mail"
+	"testing"
+)
+
+func TestReturnPath(t *testing.T) {
+	tests := map[string]struct {
+		In        string
+		Want      string
+		WantNull  bool
+		WantError bool
+	}{
+		"simple":    {In: "<bounce@example.com>", Want: "bounce@example.com"},
+		"null":      {In: "<>", WantNull: true},
+		"nullspace": {In: " < > ", WantNull: true},
+		"comment":   {In: "(bounces) <bounce@example.com> (via relay)", Want: "bounce@example.com"},
+		"nullcmt":   {In: "<(nothing)>", WantNull: true},
+		"bare":      {In: "bounce@example.com", Want: "bounce@example.com"},
+		"route":     {In: "<@relay.example,@relay2.example:bounce@example.com>", Want: "bounce@example.com"},
+		"quoted":    {In: `<"a (not a comment)"@example.com>`, Want: "a (not a comment)@example.com"},
+		"name":      {In: "Bounce <bounce@example.com>", WantError: true},
+		"garbage":   {In: "<not an address>", WantError: true},
+		"unclosed":  {In: "<bounce@example.com> (oops", WantError: true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := &Header{}
+			h.Add("Return-Path", test.In)
+			got, isNull, err := h.ReturnPath()
+			if test.WantError {
+				if err == nil {
+					t.Errorf("expected error, got '%s'", got)
+				}
+				if h.Set("Return-Path", test.In) == nil {
+					t.Errorf("expected Set to reject '%s'", test.In)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.Want || isNull != test.WantNull {
+				t.Errorf("want '%s', %v; got '%s', %v", test.Want, test.WantNull, got, isNull)
+			}
+			if err := h.Set("Return-Path", test.In); err != nil {
+				t.Errorf("Set rejected '%s': %v", test.In, err)
+			}
+		})
+	}
+	if _, _, err := (&Header{}).ReturnPath(); !errors.Is(err, mail.ErrHeaderNotPresent) {
+		t.Errorf("expected ErrHeaderNotPresent, got %v", err)
+	}
+}
+
+func TestSetReturnPath(t *testing.T) {
+	tests := map[string]struct {
+		In        string
+		Want      string
+		WantError bool
+	}{
+		"bare":     {In: "bounce@example.com", Want: "<bounce@example.com>"},
+		"brackets": {In: "<bounce@example.com>", Want: "<bounce@example.com>"},
+		"empty":    {In: "", Want: "<>"},
+		"null":     {In: "<>", Want: "<>"},
+		"quoted":   {In: `"a b"@example.com`, Want: `<"a b"@example.com>`},
+		"invalid":  {In: "not an address", WantError: true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := &Header{Headers: []KV{
+				{Key: "Return-Path", Value: "<old@example.com>"},
+				{Key: "Return-Path", Value: "<older@example.com>"},
+			}}
+			err := h.SetReturnPath(test.In)
+			if test.WantError {
+				if err == nil {
+					t.Errorf("expected error, got '%s'", h.Get("Return-Path"))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := h.Get("Return-Path"); got != test.Want {
+				t.Errorf("want '%s', got '%s'", test.Want, got)
+			}
+			if len(h.Headers) != 1 {
+				t.Errorf("existing Return-Path fields weren't replaced: %v", h.Headers)
+			}
+		})
+	}
+}
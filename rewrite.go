@@ -0,0 +1,139 @@
+package orderedheaders
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// Rewrite writes the message to w, reproducing the input byte for byte
+// except for header fields that have been changed. It requires the
+// message to have been read with KeepRaw. Fields that still have their
+// Raw bytes are written unchanged, in order, and fields that were added
+// or modified through this package, which clears Raw, are rendered as
+// WriteTo would. Anyone modifying a KV's Value directly must also clear
+// its Raw. Rendered fields use the same line ending as the blank line
+// that ended the original header. The body is copied from Body.
+//
+// This lets relays add trace or authentication fields, such as ARC sets,
+// without invalidating existing signatures.
+//
+// If nothing has been changed, the output is guaranteed to be the same
+// as the input, byte for byte, whatever its line endings, folding or
+// malformations, as long as it could be read at all and the header wasn't
+// truncated by ReadOptions.MaxFields or MaxFieldBytes. VerifyRoundTrip
+// checks this for a given message.
+func (m *Message) Rewrite(w io.Writer, o Options) error {
+	m.Header.CanonicalizeKeys()
+	lf := bytes.Equal(m.separator, []byte("\n"))
+	for _, kv := range m.Header.Headers {
+		if kv.Raw != "" {
+			if err := o.written(w, kv.Key, []byte(kv.Raw)); err != nil {
+				return err
+			}
+			continue
+		}
+		if !o.RenderBlank && kv.Value == "" {
+			o.suppressed(kv.Key)
+			continue
+		}
+		if kv.Key == HdrBcc && !o.RenderBCC {
+			o.suppressed(kv.Key)
+			continue
+		}
+		headerType := HeaderTypeOpaque
+		if syn, ok := HeaderSyntax[kv.Key]; ok {
+			headerType = syn.Type
+		}
+		var buf bytes.Buffer
+		if err := writeHeader(&buf, headerType, kv.Key, kv.Value, o); err != nil {
+			return fmt.Errorf("%s: %w", kv.Key, err)
+		}
+		rendered := buf.Bytes()
+		if lf {
+			rendered = bytes.Replace(rendered, []byte("\r\n"), []byte("\n"), -1)
+		}
+		if err := o.written(w, kv.Key, rendered); err != nil {
+			return err
+		}
+	}
+	separator := m.separator
+	if separator == nil {
+		separator = []byte("\r\n")
+	}
+	if _, err := w.Write(separator); err != nil {
+		return err
+	}
+	if m.Body == nil {
+		return nil
+	}
+	_, err := io.Copy(w, m.Body)
+	return err
+}
+
+// RoundTripError describes where a message written by Rewrite first
+// differs from the message that was read
+type RoundTripError struct {
+	// Offset is the offset of the first byte that differs, and Line the
+	// line it's in, counting from 1
+	Offset int
+	Line   int
+	// Want and Got are that line in the input and in the output
+	Want string
+	Got  string
+}
+
+func (e *RoundTripError) Error() string {
+	return fmt.Sprintf("round trip differs at line %d, offset %d: want %q, got %q", e.Line, e.Offset, e.Want, e.Got)
+}
+
+// VerifyRoundTrip reads raw as a message, keeping raw bytes, and writes
+// it back with Rewrite without changing it, returning a *RoundTripError
+// if the output differs from raw. It returns any other error if the
+// message can't be read or written.
+func VerifyRoundTrip(raw []byte) error {
+	m, err := ReadMessageWithOptions(bytes.NewReader(raw), MessageOptions{ReadOptions: ReadOptions{KeepRaw: true}})
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := m.Rewrite(&buf, Options{}); err != nil {
+		return err
+	}
+	if e := firstDifference(raw, buf.Bytes()); e != nil {
+		return e
+	}
+	return nil
+}
+
+// firstDifference compares want and got, describing the first place
+// they differ, or returning nil if they're the same
+func firstDifference(want, got []byte) *RoundTripError {
+	i := 0
+	for i < len(want) && i < len(got) && want[i] == got[i] {
+		i++
+	}
+	if i == len(want) && i == len(got) {
+		return nil
+	}
+	start := bytes.LastIndexByte(want[:i], '\n') + 1
+	return &RoundTripError{
+		Offset: i,
+		Line:   bytes.Count(want[:i], []byte("\n")) + 1,
+		Want:   lineAt(want, start),
+		Got:    lineAt(got, start),
+	}
+}
+
+// lineAt returns the line of b starting at start, including its line
+// ending
+func lineAt(b []byte, start int) string {
+	if start >= len(b) {
+		return ""
+	}
+	b = b[start:]
+	if i := bytes.IndexByte(b, '\n'); i >= 0 {
+		b = b[:i+1]
+	}
+	return string(b)
+}
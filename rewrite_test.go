@@ -0,0 +1,137 @@
+package orderedheaders
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRewrite(t *testing.T) {
+	in := "Received: from a\r\n  by b; Mon, 22 May 2023 10:00:00 +0000\r\n" +
+		"DKIM-Signature: v=1; a=rsa-sha256; d=example.com; s=s;\r\n\th=from:subject; bh=abc=; b=def=\r\n" +
+		"From:a@example.com\r\n" +
+		"subject:   Hello  \r\n" +
+		"\r\n" +
+		"Body line one\r\nline two\r\n"
+	tests := map[string]struct {
+		In     string
+		Modify func(h *Header)
+		Want   string
+	}{
+		"unchanged": {In: in, Modify: func(h *Header) {}, Want: in},
+		"prepend": {In: in, Modify: func(h *Header) {
+			h.insert(0, KV{Key: "Authentication-Results", Value: "mx.example.com; dkim=pass"})
+		}, Want: "Authentication-Results: mx.example.com; dkim=pass\r\n" + in},
+		"set": {In: in, Modify: func(h *Header) {
+			_ = h.Set("Subject", "Changed")
+		}, Want: strings.Replace(in, "subject:   Hello  \r\n", "Subject: Changed\r\n", 1)},
+		"remove": {In: in, Modify: func(h *Header) {
+			h.RemoveAll("Received")
+		}, Want: in[strings.Index(in, "DKIM"):]},
+		"lf": {In: "From: a@example.com\n\nbody\n", Modify: func(h *Header) {
+			h.Add("X-Added", "yes")
+			h.Add("Bcc", "hidden@example.com")
+		}, Want: "From: a@example.com\nX-Added: yes\n\nbody\n"},
+		"noseparator": {In: "From: a@example.com", Modify: func(h *Header) {}, Want: "From: a@example.com"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			msg, err := ReadMessageWithOptions(strings.NewReader(test.In), MessageOptions{ReadOptions: ReadOptions{KeepRaw: true}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			test.Modify(&msg.Header)
+			var buf bytes.Buffer
+			if err := msg.Rewrite(&buf, Options{}); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != test.Want {
+				t.Errorf("want %q\ngot  %q", test.Want, buf.String())
+			}
+		})
+	}
+}
+
+// goldenMessages are inputs that Rewrite must reproduce exactly
+var goldenMessages = map[string]string{
+	"crlf":          "From: a@example.com\r\nSubject: hello\r\n\r\nbody\r\n",
+	"lf":            "From: a@example.com\nSubject: hello\n\nbody\n",
+	"mixed endings": "From: a@example.com\nSubject: hello\r\n\nbody\r\nmore\n",
+	"folded":        "Received: from a\r\n\tby b;\r\n    Mon, 22 May 2023 10:00:00 +0000\r\nSubject:  spaced  \t\r\n\r\n",
+	"no body":       "From: a@example.com\r\n\r\n",
+	"no separator":  "From: a@example.com\r\nSubject: hello",
+	"no newline":    "From: a@example.com\r\nSubject: hello\r\n",
+	"space line":    " \r\nbody\r\n",
+	"no name":       ": nameless\r\nFrom: a@example.com\r\n: another\r\n\r\nbody",
+	"raw utf-8":     "Subject: caf\xc3\xa9\r\nFrom: J\xc3\xbcrgen <j@example.de>\r\n\r\nbody",
+	"bare cr":       "Subject: bare\rcarriage return\r\n\r\nbody\r",
+	"long line":     "X-Long: " + strings.Repeat("x", 2000) + "\r\n\r\n" + strings.Repeat("y", 5000),
+	"odd colons":    "Subject : space before colon\r\nX-Empty:\r\nX-Tab:\tvalue\r\n\r\n",
+	"empty":         "",
+}
+
+func TestRewriteOnHeaderWritten(t *testing.T) {
+	in := "Subject: folded\r\n subject\r\nTo: bob@example.com\r\nBcc: secret@example.com\r\n\r\nbody\r\n"
+	msg, err := ReadMessageWithOptions(strings.NewReader(in), MessageOptions{ReadOptions: ReadOptions{KeepRaw: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg.Header.Headers[1] = KV{Key: "To", Value: "carol@example.com"}
+	msg.Header.Headers[2].Raw = ""
+	// a raw field with no line ending isn't folded
+	msg.Header.Headers = append(msg.Header.Headers, KV{Key: "X-Raw", Value: "v", Raw: "X-Raw: v"})
+	var got []string
+	o := Options{OnHeaderWritten: func(key string, bytes int, folded bool) {
+		got = append(got, fmt.Sprintf("%s %d %v", key, bytes, folded))
+	}}
+	var out bytes.Buffer
+	if err := msg.Rewrite(&out, o); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Subject 27 true", "To 25 false", "Bcc 0 false", "X-Raw 8 false"}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestVerifyRoundTrip(t *testing.T) {
+	for name, in := range goldenMessages {
+		if err := VerifyRoundTrip([]byte(in)); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	for seed := int64(0); seed < 100; seed++ {
+		h := Header{}.Generate(rand.New(rand.NewSource(seed)), 20).Interface().(Header)
+		b, err := h.Bytes(Options{RenderBCC: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := VerifyRoundTrip(append(b, "\r\nbody\r\n"...)); err != nil {
+			t.Errorf("seed %d: %v", seed, err)
+		}
+	}
+	if err := VerifyRoundTrip([]byte("not a header\r\n\r\n")); err == nil {
+		t.Errorf("expected an error reading a malformed message")
+	}
+}
+
+func TestFirstDifference(t *testing.T) {
+	tests := []struct {
+		want, got string
+		diff      *RoundTripError
+	}{
+		{"a\r\nb\r\n", "a\r\nb\r\n", nil},
+		{"a\r\nb\r\n", "a\r\nc\r\n", &RoundTripError{Offset: 3, Line: 2, Want: "b\r\n", Got: "c\r\n"}},
+		{"a\r\nbc", "a\r\nb", &RoundTripError{Offset: 4, Line: 2, Want: "bc", Got: "b"}},
+		{"a\n", "a\nextra\n", &RoundTripError{Offset: 2, Line: 2, Want: "", Got: "extra\n"}},
+	}
+	for _, tt := range tests {
+		got := firstDifference([]byte(tt.want), []byte(tt.got))
+		if !reflect.DeepEqual(got, tt.diff) {
+			t.Errorf("firstDifference(%q, %q) = %v, want %v", tt.want, tt.got, got, tt.diff)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package orderedheaders
+
+import (
+	"fmt"
+	"net/textproto"
+	"regexp"
+)
+
+// RuleAction is what a Rule does to a matching header field
+type RuleAction int
+
+const (
+	// RuleDelete removes the field
+	RuleDelete RuleAction = iota
+	// RuleRename changes the field name to NewKey, keeping its value
+	RuleRename
+	// RuleReplace replaces the field value with Template
+	RuleReplace
+	// RuleAdd inserts a new field, NewKey with the value Template,
+	// before the matching field
+	RuleAdd
+)
+
+// Rule is a header rewriting rule, similar to a Postfix header_checks
+// entry. A field matches if both Key and Value, where set, match it.
+//
+// Template is expanded as by regexp.Expand, so $1 or ${name} refer to
+// submatches of Value, or of Key if Value isn't set.
+type Rule struct {
+	// Key is matched against the canonical field name, such as
+	// "X-Mailer"; use (?i) for a case insensitive match
+	Key *regexp.Regexp
+	// Value is matched against the unfolded field value
+	Value    *regexp.Regexp
+	Action   RuleAction
+	NewKey   string
+	Template string
+}
+
+// ApplyRules rewrites the header using rules. Each field is checked
+// against the rules in order, and only the first that matches is
+// applied, so a later rule never sees the result of an earlier one.
+// Fields added by RuleAdd aren't checked. It returns the number of
+// fields that matched a rule.
+func (h *Header) ApplyRules(rules []Rule) (int, error) {
+	h.CanonicalizeKeys()
+	for i, r := range rules {
+		switch r.Action {
+		case RuleDelete, RuleReplace:
+		case RuleRename, RuleAdd:
+			if !validFieldName(r.NewKey) {
+				return 0, fmt.Errorf("rule %d: '%s' is not a valid header field name", i, r.NewKey)
+			}
+		default:
+			return 0, fmt.Errorf("rule %d: invalid action %d", i, r.Action)
+		}
+	}
+
+	matched := 0
+	var result []KV
+	for _, kv := range h.Headers {
+		r, expand, ok := matchRule(rules, kv)
+		if !ok {
+			result = append(result, kv)
+			continue
+		}
+		matched++
+		switch r.Action {
+		case RuleDelete:
+		case RuleRename:
+			result = append(result, KV{Key: textproto.CanonicalMIMEHeaderKey(r.NewKey), Value: kv.Value})
+		case RuleReplace:
+			result = append(result, KV{Key: kv.Key, Value: expand(r.Template)})
+		case RuleAdd:
+			result = append(result, KV{Key: textproto.CanonicalMIMEHeaderKey(r.NewKey), Value: expand(r.Template)}, kv)
+		}
+	}
+	h.Headers = result
+	return matched, nil
+}
+
+// matchRule returns the first rule that matches a field, and a function
+// to expand a template using its submatches
+func matchRule(rules []Rule, kv KV) (Rule, func(string) string, bool) {
+	for _, r := range rules {
+		var keyMatch, valueMatch []int
+		if r.Key != nil {
+			if keyMatch = r.Key.FindStringSubmatchIndex(kv.Key); keyMatch == nil {
+				continue
+			}
+		}
+		if r.Value != nil {
+			if valueMatch = r.Value.FindStringSubmatchIndex(kv.Value); valueMatch == nil {
+				continue
+			}
+		}
+		expand := func(template string) string {
+			switch {
+			case r.Value != nil:
+				return string(r.Value.ExpandString(nil, template, kv.Value, valueMatch))
+			case r.Key != nil:
+				return string(r.Key.ExpandString(nil, template, kv.Key, keyMatch))
+			}
+			return template
+		}
+		return r, expand, true
+	}
+	return Rule{}, nil, false
+}
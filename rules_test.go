@@ -0,0 +1,92 @@
+package orderedheaders
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestApplyRules(t *testing.T) {
+	in := []KV{
+		{Key: "Received", Value: "from client (192.0.2.1)"},
+		{Key: "X-Mailer", Value: "Mailer 1.0"},
+		{Key: "Subject", Value: "cheap pills"},
+		{Key: "X-Originating-Ip", Value: "[192.0.2.1]"},
+	}
+	tests := map[string]struct {
+		Rules   []Rule
+		Want    []KV
+		Matched int
+	}{
+		"delete": {
+			Rules:   []Rule{{Key: regexp.MustCompile(`^X-`), Action: RuleDelete}},
+			Want:    []KV{in[0], in[2]},
+			Matched: 2,
+		},
+		"rename": {
+			Rules:   []Rule{{Key: regexp.MustCompile(`^X-Mailer$`), Action: RuleRename, NewKey: "x-original-mailer"}},
+			Want:    []KV{in[0], {Key: "X-Original-Mailer", Value: "Mailer 1.0"}, in[2], in[3]},
+			Matched: 1,
+		},
+		"replace": {
+			Rules:   []Rule{{Key: regexp.MustCompile(`^Subject$`), Value: regexp.MustCompile(`(?i)^(.*pills.*)$`), Action: RuleReplace, Template: "[SPAM] $1"}},
+			Want:    []KV{in[0], in[1], {Key: "Subject", Value: "[SPAM] cheap pills"}, in[3]},
+			Matched: 1,
+		},
+		"add": {
+			Rules:   []Rule{{Key: regexp.MustCompile(`^X-Originating-Ip$`), Value: regexp.MustCompile(`\[(?P<ip>[0-9.]+)\]`), Action: RuleAdd, NewKey: "X-Client", Template: "ip=${ip}"}},
+			Want:    []KV{in[0], in[1], in[2], {Key: "X-Client", Value: "ip=192.0.2.1"}, in[3]},
+			Matched: 1,
+		},
+		"firstmatch": {
+			Rules: []Rule{
+				{Key: regexp.MustCompile(`^X-Mailer$`), Action: RuleReplace, Template: "hidden"},
+				{Key: regexp.MustCompile(`^X-`), Action: RuleDelete},
+			},
+			Want:    []KV{in[0], {Key: "X-Mailer", Value: "hidden"}, in[2]},
+			Matched: 2,
+		},
+		"value": {
+			Rules:   []Rule{{Value: regexp.MustCompile(`192\.0\.2\.1`), Action: RuleDelete}},
+			Want:    []KV{in[1], in[2]},
+			Matched: 2,
+		},
+		"nomatch": {
+			Rules: []Rule{{Key: regexp.MustCompile(`^Bcc$`), Action: RuleDelete}},
+			Want:  in,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := Header{Headers: append([]KV(nil), in...)}
+			matched, err := h.ApplyRules(test.Rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.Want, h.Headers, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("header mismatch (-want +got):\n%s", diff)
+			}
+			if matched != test.Matched {
+				t.Errorf("want %d matched, got %d", test.Matched, matched)
+			}
+		})
+	}
+}
+
+func TestApplyRulesInvalid(t *testing.T) {
+	h := Header{Headers: []KV{{Key: "Subject", Value: "hello"}}}
+	for _, r := range []Rule{
+		{Action: RuleRename},
+		{Action: RuleAdd, NewKey: "Bad Key"},
+		{Action: RuleAction(99)},
+	} {
+		if _, err := h.ApplyRules([]Rule{r}); err == nil {
+			t.Errorf("expected error for %+v", r)
+		}
+	}
+	if len(h.Headers) != 1 {
+		t.Errorf("header changed by invalid rules")
+	}
+}
@@ -0,0 +1,84 @@
+package orderedheaders
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// https://tools.wordtothewise.com/rfc1847#section-2.1
+
+// A Signer creates a detached signature over the exact bytes of the
+// signed part, returning the media type of the signature, such as
+// application/pgp-signature or application/pkcs7-signature, and the
+// signature itself. Binary signatures are base64 encoded by Sign.
+type Signer func(signed []byte) (contentType string, signature []byte, err error)
+
+// Sign wraps a message as multipart/signed. The MIME header fields of
+// the message, those starting Content-, and its body become the first
+// part, and the signature created by signer the second. The message's
+// other header fields are kept in the outer header.
+//
+// The first part is rendered once, and exactly the bytes passed to
+// signer are written to the new body, so nothing is refolded or
+// re-encoded between signing and sending. Fields that still have their
+// Raw bytes, from reading with KeepRaw, are reproduced as they were.
+// The protocol parameter is taken from the media type signer returns,
+// and micalg names the hash algorithm it used, such as pgp-sha256 or
+// sha-256.
+func Sign(m *Message, micalg string, signer Signer) (*Message, error) {
+	m.Header.CanonicalizeKeys()
+	var outer, inner Header
+	for _, kv := range m.Header.Headers {
+		if strings.HasPrefix(kv.Key, "Content-") {
+			inner.Headers = append(inner.Headers, kv)
+			continue
+		}
+		outer.Headers = append(outer.Headers, kv)
+	}
+
+	var buf bytes.Buffer
+	part := &Message{Header: inner, Body: m.Body, separator: []byte("\r\n")}
+	if err := part.Rewrite(&buf, Options{}); err != nil {
+		return nil, err
+	}
+	signed := buf.Bytes()
+
+	contentType, signature, err := signer(signed)
+	if err != nil {
+		return nil, err
+	}
+	protocol, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("'%s' is not a valid signature content type: %w", contentType, err)
+	}
+	if protocol == "" {
+		return nil, errors.New("signer returned no content type")
+	}
+	var sigPart builderPart
+	sigPart.header.Add(HdrContentType, contentType)
+	if is7bit(string(signature)) {
+		sigPart.header.Add(HdrContentTransferEncoding, "7bit")
+		sigPart.body = signature
+	} else {
+		sigPart.header.Add(HdrContentTransferEncoding, "base64")
+		sigPart.body = encodeBase64Lines(signature)
+	}
+
+	parts := []builderPart{{body: signed, raw: true}, sigPart}
+	params := map[string]string{"protocol": protocol, "micalg": strings.ToLower(micalg)}
+	body, err := multipartPart("signed", params, parts)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &Message{Header: outer}
+	if !msg.Header.Has(HdrMimeVersion) {
+		msg.Header.Add(HdrMimeVersion, "1.0")
+	}
+	msg.Header.Headers = append(msg.Header.Headers, body.header.Headers...)
+	msg.Body = bytes.NewReader(body.body)
+	return msg, nil
+}
@@ -0,0 +1,103 @@
+package orderedheaders
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSign(t *testing.T) {
+	in := "From: a@example.com\r\n" +
+		"Subject: signed\r\n" +
+		"Content-Type: text/plain;\r\n" +
+		"   charset=us-ascii\r\n" +
+		"Content-Transfer-Encoding:7bit\r\n" +
+		"\r\n" +
+		"Hello  \r\n" +
+		"From here\r\n"
+	wantSigned := "Content-Type: text/plain;\r\n" +
+		"   charset=us-ascii\r\n" +
+		"Content-Transfer-Encoding:7bit\r\n" +
+		"\r\n" +
+		"Hello  \r\n" +
+		"From here\r\n"
+
+	tests := map[string]struct {
+		ContentType string
+		Signature   []byte
+		WantCTE     string
+	}{
+		"pgp":   {ContentType: "application/pgp-signature", Signature: []byte("-----BEGIN PGP SIGNATURE-----\r\n-----END PGP SIGNATURE-----\r\n"), WantCTE: "7bit"},
+		"smime": {ContentType: "application/pkcs7-signature; name=smime.p7s", Signature: []byte{0x30, 0x82, 0x00, 0xff}, WantCTE: "base64"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			msg, err := ReadMessageWithOptions(strings.NewReader(in), MessageOptions{ReadOptions: ReadOptions{KeepRaw: true}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var signed []byte
+			out, err := Sign(msg, "SHA-256", func(b []byte) (string, []byte, error) {
+				signed = append([]byte(nil), b...)
+				return test.ContentType, test.Signature, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(wantSigned, string(signed)); diff != "" {
+				t.Errorf("signed bytes (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff([]string{HdrFrom, HdrSubject, HdrMimeVersion, HdrContentType}, keys(out.Header)); diff != "" {
+				t.Errorf("outer header (-want +got):\n%s", diff)
+			}
+			mt, params := out.mediaType()
+			protocol := strings.SplitN(test.ContentType, ";", 2)[0]
+			if mt != "multipart/signed" || params["protocol"] != protocol || params["micalg"] != "sha-256" {
+				t.Errorf("unexpected Content-Type %s", out.Header.Get(HdrContentType))
+			}
+
+			parts, err := out.Parts()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(parts) != 2 {
+				t.Fatalf("expected 2 parts, got %d", len(parts))
+			}
+			body, err := io.ReadAll(parts[0].Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(parts[0].RawHeaderBlock()) + string(body); got != wantSigned {
+				t.Errorf("first part not byte-exact:\n%q", got)
+			}
+			if cte := parts[1].Header.Get(HdrContentTransferEncoding); cte != test.WantCTE {
+				t.Errorf("want %s signature, got %s", test.WantCTE, cte)
+			}
+			sig, err := io.ReadAll(decodeTransferEncoding(test.WantCTE, parts[1].Body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(bytes.TrimRight(sig, "\r\n"), bytes.TrimRight(test.Signature, "\r\n")) {
+				t.Errorf("signature mismatch: %q", sig)
+			}
+		})
+	}
+}
+
+func TestSignError(t *testing.T) {
+	msg, err := ReadMessage(strings.NewReader("From: a@example.com\r\n\r\nbody\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signErr := errors.New("no key")
+	_, err = Sign(msg, "sha-256", func([]byte) (string, []byte, error) {
+		return "", nil, signErr
+	})
+	if !errors.Is(err, signErr) {
+		t.Errorf("expected signer error, got %v", err)
+	}
+}
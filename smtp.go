@@ -0,0 +1,178 @@
+package orderedheaders
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/mail"
+	"net/smtp"
+)
+
+// https://tools.wordtothewise.com/rfc5322#section-3.6.6
+
+// Recipients returns the addresses a message should be delivered to,
+// from the To, Cc and Bcc fields, or if the message has been resent
+// from the Resent-To, Resent-Cc and Resent-Bcc fields of the most recent
+// resent block. Every field with each of those keys is used, and empty
+// ones are skipped. Duplicate addresses are removed, comparing them case
+// insensitively.
+func (h *Header) Recipients() ([]string, error) {
+	if blocks := h.ResentBlocks(); len(blocks) > 0 {
+		b := blocks[0]
+		return addressStrings(b.To, b.Cc, b.Bcc), nil
+	}
+	h.CanonicalizeKeys()
+	var lists [][]*mail.Address
+	for _, key := range []string{HdrTo, HdrCc, HdrBcc} {
+		for _, kv := range h.Headers {
+			// an empty field, such as a Bcc with the recipients
+			// removed, is allowed
+			if kv.Key != key || StripComments(kv.Value) == "" {
+				continue
+			}
+			addrs, err := mail.ParseAddressList(kv.Value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			lists = append(lists, addrs)
+		}
+	}
+	return addressStrings(lists...), nil
+}
+
+// addressStrings returns the addresses in lists, without duplicates
+func addressStrings(lists ...[]*mail.Address) []string {
+	var ret []string
+	seen := map[string]struct{}{}
+	for _, list := range lists {
+		for _, a := range uniqueAddresses(list, seen) {
+			ret = append(ret, a.Address)
+		}
+	}
+	return ret
+}
+
+// envelopeSender returns the SMTP MAIL FROM address for a message: the
+// Return-Path if there is one, empty for a null return path, otherwise
+// the Sender or first From address, or their Resent- equivalents if the
+// message has been resent
+func (h *Header) envelopeSender() (string, error) {
+	addr, _, err := h.ReturnPath()
+	if err != mail.ErrHeaderNotPresent {
+		return addr, err
+	}
+	if blocks := h.ResentBlocks(); len(blocks) > 0 {
+		b := blocks[0]
+		switch {
+		case b.Sender != nil:
+			return b.Sender.Address, nil
+		case len(b.From) > 0:
+			return b.From[0].Address, nil
+		}
+		return "", errors.New("no Resent-Sender or Resent-From address")
+	}
+	for _, key := range []string{HdrSender, HdrFrom} {
+		if !h.Has(key) {
+			continue
+		}
+		addrs, err := h.AddressList(key)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", key, err)
+		}
+		if len(addrs) > 0 {
+			return addrs[0].Address, nil
+		}
+	}
+	return "", errors.New("no Return-Path, Sender or From address")
+}
+
+// writeSubmission writes the message as it should be submitted, without
+// Return-Path, Bcc or Resent-Bcc fields
+func (m *Message) writeSubmission(w io.Writer, o Options) error {
+	m.Header.CanonicalizeKeys()
+	h := &Header{}
+	for _, kv := range m.Header.Headers {
+		switch kv.Key {
+		case HdrReturnPath, HdrBcc, HdrResentBcc:
+			continue
+		}
+		h.Headers = append(h.Headers, kv)
+	}
+	if err := h.WriteTo(w, o); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, "\r\n"); err != nil {
+		return err
+	}
+	if m.Body == nil {
+		return nil
+	}
+	_, err := io.Copy(w, m.Body)
+	return err
+}
+
+// SendMessage submits a message over SMTP, as smtp.SendMail does, using
+// STARTTLS if the server supports it and auth if it isn't nil. The
+// envelope sender is taken from Return-Path, Sender or From, and the
+// recipients from Recipients. Return-Path, Bcc and Resent-Bcc fields
+// aren't sent. The body is copied from Body, dot-stuffed and with line
+// endings converted to CRLF as it's sent.
+func SendMessage(addr string, auth smtp.Auth, m *Message, o Options) error {
+	from, err := m.Header.envelopeSender()
+	if err != nil {
+		return err
+	}
+	to, err := m.Header.Recipients()
+	if err != nil {
+		return err
+	}
+	if len(to) == 0 {
+		return errors.New("message has no recipients")
+	}
+
+	c, err := smtp.Dial(addr)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return err
+		}
+		if err = c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("smtp: server doesn't support AUTH")
+		}
+		if err = c.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err = c.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err = c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	o.RenderBCC = false
+	if err = m.writeSubmission(w, o); err != nil {
+		w.Close()
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,154 @@
+package orderedheaders
+
+import (
+	"bufio"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRecipients(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []KV
+		want    []string
+	}{
+		{
+			name: "original",
+			headers: []KV{
+				{Key: "To", Value: "Bob <bob@example.com>, carol@example.com"},
+				{Key: "Cc", Value: "BOB@example.com"},
+				{Key: "Bcc", Value: "dave@example.com"},
+			},
+			want: []string{"bob@example.com", "carol@example.com", "dave@example.com"},
+		},
+		{
+			name: "empty bcc",
+			headers: []KV{
+				{Key: "To", Value: "bob@example.com"},
+				{Key: "Cc", Value: " (none) "},
+				{Key: "Bcc", Value: ""},
+				{Key: "To", Value: "carol@example.com"},
+			},
+			want: []string{"bob@example.com", "carol@example.com"},
+		},
+		{
+			name: "resent",
+			headers: []KV{
+				{Key: "Resent-From", Value: "eve@example.com"},
+				{Key: "Resent-To", Value: "frank@example.com"},
+				{Key: "To", Value: "bob@example.com"},
+			},
+			want: []string{"frank@example.com"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := Header{Headers: tt.headers}
+			got, err := h.Recipients()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("recipients mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// fakeSMTPServer accepts one connection, answering every command with
+// success, and returns the commands and message data it received
+func fakeSMTPServer(t *testing.T) (string, <-chan []string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := make(chan []string, 1)
+	go func() {
+		defer l.Close()
+		var lines []string
+		defer func() { received <- lines }()
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+		tp.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			lines = append(lines, line)
+			switch verb := strings.ToUpper(strings.Fields(line)[0]); verb {
+			case "EHLO":
+				tp.PrintfLine("250 localhost")
+			case "DATA":
+				tp.PrintfLine("354 go ahead")
+				for {
+					line, err := tp.R.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					lines = append(lines, "> "+line)
+				}
+				tp.PrintfLine("250 ok")
+			case "QUIT":
+				tp.PrintfLine("221 bye")
+				return
+			default:
+				tp.PrintfLine("250 ok")
+			}
+		}
+	}()
+	return l.Addr().String(), received
+}
+
+func TestSendMessage(t *testing.T) {
+	addr, received := fakeSMTPServer(t)
+	m, err := ReadMessage(bufio.NewReader(strings.NewReader("Return-Path: <bounces@example.com>\r\n" +
+		"From: alice@example.com\r\n" +
+		"To: bob@example.com\r\n" +
+		"Bcc: carol@example.com\r\n" +
+		"Subject: hello\r\n" +
+		"\r\n" +
+		"line one\n" +
+		".line two\r\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SendMessage(addr, nil, m, Options{RenderBCC: true}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"EHLO localhost",
+		"MAIL FROM:<bounces@example.com>",
+		"RCPT TO:<bob@example.com>",
+		"RCPT TO:<carol@example.com>",
+		"DATA",
+		"> From: <alice@example.com>\r\n",
+		"> To: <bob@example.com>\r\n",
+		"> Subject: hello\r\n",
+		"> \r\n",
+		"> line one\r\n",
+		"> ..line two\r\n",
+		"QUIT",
+	}
+	if diff := cmp.Diff(want, <-received); diff != "" {
+		t.Errorf("session mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSendMessageNoRecipients(t *testing.T) {
+	m := &Message{Header: Header{Headers: []KV{{Key: "From", Value: "alice@example.com"}}}}
+	if err := SendMessage("127.0.0.1:1", nil, m, Options{}); err == nil {
+		t.Errorf("expected error")
+	}
+}
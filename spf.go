@@ -0,0 +1,173 @@
+package orderedheaders
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// https://tools.wordtothewise.com/rfc7208#section-9.1
+
+const HdrReceivedSPF = "Received-Spf"
+
+// ReceivedSPF is a parsed Received-SPF header
+type ReceivedSPF struct {
+	// Result is the SPF result, e.g. pass or softfail, in lower case
+	Result string
+	// Comment is the free text comment following the result, if any
+	Comment string
+	// Identity is the identity checked, e.g. mailfrom or helo
+	Identity string
+	// ClientIP is the address of the SMTP client, if given
+	ClientIP net.IP
+	// Helo is the HELO or EHLO domain given by the client
+	Helo string
+	// EnvelopeFrom is the envelope sender mailbox
+	EnvelopeFrom string
+	// Receiver is the host performing the check
+	Receiver string
+	// Params is every key=value pair, in the order they appear
+	Params []Tag
+}
+
+// Param returns the value of the named key=value pair, and whether it
+// was present
+func (spf ReceivedSPF) Param(key string) (string, bool) {
+	return lookupTag(spf.Params, strings.ToLower(key))
+}
+
+var spfResults = map[string]struct{}{
+	"pass":      {},
+	"fail":      {},
+	"softfail":  {},
+	"neutral":   {},
+	"none":      {},
+	"temperror": {},
+	"permerror": {},
+}
+
+// ParseReceivedSPF parses the value of a Received-SPF header
+func ParseReceivedSPF(value string) (ReceivedSPF, error) {
+	var spf ReceivedSPF
+	i, err := skipCFWS(value, 0)
+	if err != nil {
+		return ReceivedSPF{}, fmt.Errorf("invalid Received-SPF: %w", err)
+	}
+	start := i
+	for i < len(value) && !isWSP(value[i]) && value[i] != '(' && value[i] != ';' {
+		i++
+	}
+	spf.Result = strings.ToLower(value[start:i])
+	if _, ok := spfResults[spf.Result]; !ok {
+		return ReceivedSPF{}, fmt.Errorf("invalid Received-SPF: '%s' is not an SPF result", value[start:i])
+	}
+	for i < len(value) && isWSP(value[i]) {
+		i++
+	}
+	if i < len(value) && value[i] == '(' {
+		spf.Comment, i, err = readComment(value, i)
+		if err != nil {
+			return ReceivedSPF{}, fmt.Errorf("invalid Received-SPF: %w", err)
+		}
+	}
+	spf.Params, err = parseKeyValueList(value, i)
+	if err != nil {
+		return ReceivedSPF{}, fmt.Errorf("invalid Received-SPF: %w", err)
+	}
+	for _, p := range spf.Params {
+		switch p.Name {
+		case "identity":
+			spf.Identity = p.Value
+		case "client-ip":
+			spf.ClientIP = net.ParseIP(p.Value)
+			if spf.ClientIP == nil {
+				return ReceivedSPF{}, fmt.Errorf("invalid Received-SPF: '%s' is not a valid IP address", p.Value)
+			}
+		case "helo":
+			spf.Helo = p.Value
+		case "envelope-from":
+			spf.EnvelopeFrom = p.Value
+		case "receiver":
+			spf.Receiver = p.Value
+		}
+	}
+	return spf, nil
+}
+
+// ReceivedSPF parses every Received-SPF header, most recent first.
+// Headers that cannot be parsed are skipped.
+func (h *Header) ReceivedSPF() []ReceivedSPF {
+	h.CanonicalizeKeys()
+	var ret []ReceivedSPF
+	for _, kv := range h.Headers {
+		if kv.Key != HdrReceivedSPF {
+			continue
+		}
+		spf, err := ParseReceivedSPF(kv.Value)
+		if err != nil {
+			continue
+		}
+		ret = append(ret, spf)
+	}
+	return ret
+}
+
+// parseKeyValueList parses a semicolon separated list of key=value pairs,
+// where values are dot-atoms or quoted-strings, and comments may appear
+// between tokens. Keys are returned in lower case.
+func parseKeyValueList(s string, i int) ([]Tag, error) {
+	var ret []Tag
+	for {
+		var err error
+		i, err = skipCFWS(s, i)
+		if err != nil {
+			return nil, err
+		}
+		if i >= len(s) {
+			return ret, nil
+		}
+		start := i
+		for i < len(s) && s[i] != '=' && s[i] != ';' && !isWSP(s[i]) && s[i] != '(' {
+			i++
+		}
+		key := strings.ToLower(s[start:i])
+		if key == "" {
+			return nil, fmt.Errorf("missing key at offset %d", start)
+		}
+		i, err = skipCFWS(s, i)
+		if err != nil {
+			return nil, err
+		}
+		if i >= len(s) || s[i] != '=' {
+			return nil, fmt.Errorf("missing '=' after %s", key)
+		}
+		i, err = skipCFWS(s, i+1)
+		if err != nil {
+			return nil, err
+		}
+		var value string
+		if i < len(s) && s[i] == '"' {
+			value, i, err = readQuotedString(s, i)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			start = i
+			for i < len(s) && s[i] != ';' && !isWSP(s[i]) && s[i] != '(' {
+				i++
+			}
+			value = s[start:i]
+		}
+		ret = append(ret, Tag{Name: key, Value: value})
+		i, err = skipCFWS(s, i)
+		if err != nil {
+			return nil, err
+		}
+		if i < len(s) {
+			if s[i] != ';' {
+				return nil, fmt.Errorf("expected ';' after %s=%s", key, value)
+			}
+			i++
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package orderedheaders
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseReceivedSPF(t *testing.T) {
+	tests := map[string]struct {
+		In        string
+		WantError bool
+		Want      ReceivedSPF
+	}{
+		"rfc7208": {
+			In: "pass (mybox.example.org: domain of myname@example.com designates 192.0.2.1 as permitted sender)\r\n" +
+				" receiver=mybox.example.org; client-ip=192.0.2.1;\r\n" +
+				" envelope-from=\"myname@example.com\"; helo=foo.example.com;",
+			Want: ReceivedSPF{
+				Result:       "pass",
+				Comment:      "mybox.example.org: domain of myname@example.com designates 192.0.2.1 as permitted sender",
+				ClientIP:     net.ParseIP("192.0.2.1"),
+				Helo:         "foo.example.com",
+				EnvelopeFrom: "myname@example.com",
+				Receiver:     "mybox.example.org",
+				Params: []Tag{
+					{Name: "receiver", Value: "mybox.example.org"},
+					{Name: "client-ip", Value: "192.0.2.1"},
+					{Name: "envelope-from", Value: "myname@example.com"},
+					{Name: "helo", Value: "foo.example.com"},
+				},
+			},
+		},
+		"bare": {
+			In:   "SoftFail",
+			Want: ReceivedSPF{Result: "softfail"},
+		},
+		"identity": {
+			In: "fail (nested (comment)) identity=mailfrom; client-ip=2001:db8::1",
+			Want: ReceivedSPF{
+				Result:   "fail",
+				Comment:  "nested (comment)",
+				Identity: "mailfrom",
+				ClientIP: net.ParseIP("2001:db8::1"),
+				Params: []Tag{
+					{Name: "identity", Value: "mailfrom"},
+					{Name: "client-ip", Value: "2001:db8::1"},
+				},
+			},
+		},
+		"result":  {In: "maybe (who knows)", WantError: true},
+		"ip":      {In: "pass client-ip=banana", WantError: true},
+		"comment": {In: "pass (unterminated", WantError: true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseReceivedSPF(test.In)
+			if test.WantError {
+				if err == nil {
+					t.Errorf("expected error, didn't get one")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.Want, got); diff != "" {
+				t.Errorf("ReceivedSPF mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestHeaderReceivedSPF(t *testing.T) {
+	h := &Header{}
+	h.Add("Received-SPF", "pass identity=helo")
+	h.Add("Received", "from somewhere")
+	h.Add("Received-SPF", "unknown")
+	h.Add("Received-SPF", "fail identity=mailfrom")
+	got := h.ReceivedSPF()
+	if len(got) != 2 || got[0].Result != "pass" || got[1].Identity != "mailfrom" {
+		t.Errorf("unexpected Received-SPF results %#v", got)
+	}
+}
@@ -0,0 +1,79 @@
+package orderedheaders
+
+import (
+	"bytes"
+)
+
+// HeaderStats describes the size and shape of a header
+type HeaderStats struct {
+	// Bytes is the total size of the fields
+	Bytes int
+	// Fields is the number of fields
+	Fields int
+	// Counts is the number of fields with each key
+	Counts map[string]int
+	// LongestLine is the length of the longest line, excluding the line
+	// ending
+	LongestLine int
+	// NonASCII is true if any field contains a byte outside US-ASCII
+	NonASCII bool
+	// Folds is the number of continuation lines
+	Folds int
+}
+
+// Stats returns statistics about the header, for monitoring. Fields read
+// with KeepRaw are measured as they were read; others are measured as a
+// single unfolded "Key: Value" line with a CRLF line ending.
+func (h *Header) Stats() HeaderStats {
+	h.CanonicalizeKeys()
+	s := HeaderStats{Fields: len(h.Headers), Counts: map[string]int{}}
+	for _, kv := range h.Headers {
+		s.Counts[kv.Key]++
+		raw := []byte(kv.Raw)
+		if kv.Raw == "" {
+			raw = []byte(kv.Key + ": " + kv.Value + "\r\n")
+		}
+		s.Bytes += len(raw)
+		if !s.NonASCII && !isAscii(string(raw)) {
+			s.NonASCII = true
+		}
+		lines := bytes.SplitAfter(raw, []byte("\n"))
+		if len(lines[len(lines)-1]) == 0 {
+			lines = lines[:len(lines)-1]
+		}
+		if len(lines) > 1 {
+			s.Folds += len(lines) - 1
+		}
+		for _, line := range lines {
+			if n := len(bytes.TrimRight(line, "\r\n")); n > s.LongestLine {
+				s.LongestLine = n
+			}
+		}
+	}
+	return s
+}
+
+// WireSize returns the size in bytes of the header as WriteTo would
+// render it with o, without keeping the output
+func (h *Header) WireSize(o Options) (int, error) {
+	var c countWriter
+	if err := h.WriteTo(&c, o); err != nil {
+		return 0, err
+	}
+	return c.n, nil
+}
+
+// countWriter counts the bytes written to it, and discards them
+type countWriter struct {
+	n int
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	c.n += len(p)
+	return len(p), nil
+}
+
+func (c *countWriter) WriteString(s string) (int, error) {
+	c.n += len(s)
+	return len(s), nil
+}
@@ -0,0 +1,73 @@
+package orderedheaders
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestStats(t *testing.T) {
+	h, err := ReadHeaderWithOptions(reader("Received: from a\r\n\tby b\r\n"+
+		"Received: from c by d\r\n"+
+		"Subject: café\r\n"+
+		"\r\n"), ReadOptions{KeepRaw: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Add("X-Test", "yes")
+	want := HeaderStats{
+		Bytes:       len("Received: from a\r\n\tby b\r\nReceived: from c by d\r\nSubject: café\r\nX-Test: yes\r\n"),
+		Fields:      4,
+		Counts:      map[string]int{"Received": 2, "Subject": 1, "X-Test": 1},
+		LongestLine: len("Received: from c by d"),
+		NonASCII:    true,
+		Folds:       1,
+	}
+	if diff := cmp.Diff(want, h.Stats()); diff != "" {
+		t.Errorf("stats mismatch (-want +got):\n%s", diff)
+	}
+
+	empty := Header{}
+	if diff := cmp.Diff(HeaderStats{Counts: map[string]int{}}, empty.Stats()); diff != "" {
+		t.Errorf("empty stats mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWireSize(t *testing.T) {
+	h := &Header{Headers: []KV{
+		{Key: "Subject", Value: "café " + strings.Repeat("word ", 30)},
+		{Key: "To", Value: "Bob <bob@example.com>"},
+		{Key: "Bcc", Value: "secret@example.com"},
+	}}
+	for _, o := range []Options{{}, {RenderBCC: true}, {NoEscape: true}, {WrapColumn: 40}} {
+		b, err := h.Bytes(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := h.WireSize(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != len(b) {
+			t.Errorf("%+v: want %d, got %d", o, len(b), got)
+		}
+	}
+	bad := &Header{Headers: []KV{{Key: "Bad Key", Value: "x"}}}
+	if _, err := bad.WireSize(Options{}); err == nil {
+		t.Errorf("expected error for unwritable header")
+	}
+}
+
+func BenchmarkWireSize(b *testing.B) {
+	h := &Header{}
+	for i := 0; i < 50; i++ {
+		h.Add("Received", "from a.example.com by b.example.com with ESMTPS id abc123; Mon, 1 Jan 2024 00:00:00 +0000")
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := h.WireSize(Options{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
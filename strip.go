@@ -0,0 +1,68 @@
+package orderedheaders
+
+import (
+	"net/textproto"
+	"regexp"
+	"strings"
+)
+
+// StripPolicy describes header fields to remove, such as at an outbound
+// gateway. A field is removed if it matches any of Prefixes, Names or
+// Patterns, unless it's in Allow.
+type StripPolicy struct {
+	// Prefixes match the start of field names, case insensitively, e.g.
+	// "X-" for all experimental fields
+	Prefixes []string
+	// Names match field names exactly, case insensitively
+	Names []string
+	// Patterns are matched against canonical field names, such as
+	// "X-Originating-Ip"
+	Patterns []*regexp.Regexp
+	// Allow lists fields that are kept even if they match
+	Allow []string
+}
+
+// StripHeaders removes the fields matched by the policy, returning the
+// removed fields in order
+func (h *Header) StripHeaders(p StripPolicy) []KV {
+	h.CanonicalizeKeys()
+	canonical := func(keys []string) map[string]struct{} {
+		m := make(map[string]struct{}, len(keys))
+		for _, k := range keys {
+			m[textproto.CanonicalMIMEHeaderKey(k)] = struct{}{}
+		}
+		return m
+	}
+	names := canonical(p.Names)
+	allow := canonical(p.Allow)
+
+	var removed []KV
+	filtered := h.Headers[:0]
+	for _, kv := range h.Headers {
+		if _, ok := allow[kv.Key]; !ok && p.matches(kv.Key, names) {
+			removed = append(removed, kv)
+			continue
+		}
+		filtered = append(filtered, kv)
+	}
+	h.Headers = filtered
+	return removed
+}
+
+// matches checks whether the policy removes a field
+func (p StripPolicy) matches(key string, names map[string]struct{}) bool {
+	if _, ok := names[key]; ok {
+		return true
+	}
+	for _, prefix := range p.Prefixes {
+		if len(key) >= len(prefix) && strings.EqualFold(key[:len(prefix)], prefix) {
+			return true
+		}
+	}
+	for _, re := range p.Patterns {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package orderedheaders
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestStripHeaders(t *testing.T) {
+	in := []KV{
+		{Key: "Received", Value: "from a"},
+		{Key: "X-Originating-Ip", Value: "[192.0.2.1]"},
+		{Key: "X-Mailer", Value: "Mailer 1.0"},
+		{Key: "Subject", Value: "hello"},
+		{Key: "X-Spam-Status", Value: "No"},
+		{Key: "User-Agent", Value: "Client 2.0"},
+		{Key: "X-Entity-Id", Value: "abc"},
+	}
+	tests := map[string]struct {
+		Policy      StripPolicy
+		WantKept    []string
+		WantRemoved []string
+	}{
+		"none": {
+			WantKept: []string{"Received", "X-Originating-Ip", "X-Mailer", "Subject", "X-Spam-Status", "User-Agent", "X-Entity-Id"},
+		},
+		"prefix": {
+			Policy:      StripPolicy{Prefixes: []string{"x-"}, Allow: []string{"x-entity-id"}},
+			WantKept:    []string{"Received", "Subject", "User-Agent", "X-Entity-Id"},
+			WantRemoved: []string{"X-Originating-Ip", "X-Mailer", "X-Spam-Status"},
+		},
+		"names": {
+			Policy:      StripPolicy{Names: []string{"user-agent", "X-MAILER"}},
+			WantKept:    []string{"Received", "X-Originating-Ip", "Subject", "X-Spam-Status", "X-Entity-Id"},
+			WantRemoved: []string{"X-Mailer", "User-Agent"},
+		},
+		"patterns": {
+			Policy:      StripPolicy{Patterns: []*regexp.Regexp{regexp.MustCompile(`^X-Spam-`), regexp.MustCompile(`-Ip$`)}},
+			WantKept:    []string{"Received", "X-Mailer", "Subject", "User-Agent", "X-Entity-Id"},
+			WantRemoved: []string{"X-Originating-Ip", "X-Spam-Status"},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := Header{Headers: append([]KV(nil), in...)}
+			removed := h.StripHeaders(test.Policy)
+			if diff := cmp.Diff(test.WantKept, keys(h), cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("kept mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(test.WantRemoved, keys(Header{Headers: removed}), cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("removed mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
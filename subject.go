@@ -0,0 +1,156 @@
+package orderedheaders
+
+import (
+	"mime"
+	"strings"
+)
+
+// SubjectPrefixKind is the kind of a reply or forward subject prefix
+type SubjectPrefixKind int
+
+const (
+	// SubjectPrefixNone is a subject with no prefix
+	SubjectPrefixNone SubjectPrefixKind = iota
+	// SubjectPrefixReply is a reply prefix, canonically "Re: "
+	SubjectPrefixReply
+	// SubjectPrefixForward is a forward prefix, canonically "Fwd: "
+	SubjectPrefixForward
+)
+
+// subjectPrefixes maps the words used as reply and forward prefixes by
+// common clients in various languages to their kind
+var subjectPrefixes = map[string]SubjectPrefixKind{
+	"re":     SubjectPrefixReply,
+	"aw":     SubjectPrefixReply, // German
+	"antw":   SubjectPrefixReply, // Dutch
+	"sv":     SubjectPrefixReply, // Scandinavian
+	"vs":     SubjectPrefixReply, // Finnish
+	"rif":    SubjectPrefixReply, // Italian
+	"res":    SubjectPrefixReply, // Portuguese
+	"odp":    SubjectPrefixReply, // Polish
+	"ynt":    SubjectPrefixReply, // Turkish
+	"fwd":    SubjectPrefixForward,
+	"fw":     SubjectPrefixForward,
+	"wg":     SubjectPrefixForward, // German
+	"doorst": SubjectPrefixForward, // Dutch
+	"vb":     SubjectPrefixForward, // Swedish
+	"tr":     SubjectPrefixForward, // French
+	"rv":     SubjectPrefixForward, // Spanish
+	"enc":    SubjectPrefixForward, // Portuguese
+	"pd":     SubjectPrefixForward, // Polish
+}
+
+// readSubjectPrefix reads a single prefix, such as "Re:", "AW :" or
+// "Re[2]:", at the start of s, returning its kind and length, or
+// SubjectPrefixNone if there isn't one
+func readSubjectPrefix(s string) (SubjectPrefixKind, int) {
+	i := 0
+	for i < len(s) && isLetter(rune(s[i])) {
+		i++
+	}
+	kind, ok := subjectPrefixes[strings.ToLower(s[:i])]
+	if !ok {
+		return SubjectPrefixNone, 0
+	}
+	// some clients count replies, as Re[2]: or Re(2):
+	if i < len(s) && (s[i] == '[' || s[i] == '(') {
+		closing := byte(']')
+		if s[i] == '(' {
+			closing = ')'
+		}
+		j := i + 1
+		for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+			j++
+		}
+		if j == i+1 || j == len(s) || s[j] != closing {
+			return SubjectPrefixNone, 0
+		}
+		i = j + 1
+	}
+	for i < len(s) && s[i] == ' ' {
+		i++
+	}
+	if i < len(s) && s[i] == ':' {
+		return kind, i + 1
+	}
+	return SubjectPrefixNone, 0
+}
+
+// splitSubjectPrefix splits a subject into any leading reply and forward
+// prefixes, including the whitespace after them, and the rest. It also
+// returns the kind of the first prefix.
+func splitSubjectPrefix(s string) (string, string, SubjectPrefixKind) {
+	i := 0
+	first := SubjectPrefixNone
+	for {
+		rest := strings.TrimLeft(s[i:], " \t")
+		i = len(s) - len(rest)
+		kind, n := readSubjectPrefix(rest)
+		if kind == SubjectPrefixNone {
+			return s[:i], s[i:], first
+		}
+		if first == SubjectPrefixNone {
+			first = kind
+		}
+		i += n
+	}
+}
+
+// SplitSubject separates a subject into the kind of its reply or
+// forward prefix and the bare subject, with every stacked or localized
+// prefix such as "Re: AW: Fwd:" removed. The kind is that of the first,
+// most recent, prefix. Bare subjects can be compared to find messages
+// in the same conversation.
+func SplitSubject(s string) (SubjectPrefixKind, string) {
+	_, bare, kind := splitSubjectPrefix(s)
+	return kind, strings.TrimSpace(bare)
+}
+
+// decodedSubject returns the Subject with any RFC 2047 encoded words
+// decoded
+func (h *Header) decodedSubject() string {
+	dec := new(mime.WordDecoder)
+	subject, err := dec.DecodeHeader(h.Get(HdrSubject))
+	if err != nil {
+		return h.Get(HdrSubject)
+	}
+	return subject
+}
+
+// TagSubject adds "[tag] " to the Subject, as mailing lists do, after
+// any Re: or Fwd: prefixes. If the tag is already present anywhere in
+// the decoded subject it's left unchanged, so replies aren't tagged
+// twice.
+func (h *Header) TagSubject(tag string) error {
+	tag = strings.TrimSpace(tag)
+	if !strings.HasPrefix(tag, "[") {
+		tag = "[" + tag + "]"
+	}
+	subject := h.decodedSubject()
+	if strings.Contains(strings.ToLower(subject), strings.ToLower(tag)) {
+		return nil
+	}
+	prefix, rest, _ := splitSubjectPrefix(subject)
+	return h.Set(HdrSubject, strings.TrimSpace(prefix+tag+" "+rest))
+}
+
+// NormalizeSubjectPrefix replaces any stacked or localized reply and
+// forward prefixes on the Subject with a single "Re: " or "Fwd: ",
+// following the first of them, and returns the bare subject
+func (h *Header) NormalizeSubjectPrefix() (string, error) {
+	if !h.Has(HdrSubject) {
+		return "", nil
+	}
+	kind, bare := SplitSubject(h.decodedSubject())
+	subject := bare
+	switch kind {
+	case SubjectPrefixReply:
+		subject = "Re: " + bare
+	case SubjectPrefixForward:
+		subject = "Fwd: " + bare
+	}
+	if subject == h.Get(HdrSubject) {
+		return bare, nil
+	}
+	return bare, h.Set(HdrSubject, subject)
+}
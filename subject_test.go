@@ -0,0 +1,91 @@
+package orderedheaders
+
+import (
+	"testing"
+)
+
+func TestTagSubject(t *testing.T) {
+	tests := map[string]struct {
+		Subject string
+		Tag     string
+		Want    string
+	}{
+		"plain":     {Subject: "hello", Tag: "list", Want: "[list] hello"},
+		"bracketed": {Subject: "hello", Tag: "[list]", Want: "[list] hello"},
+		"reply":     {Subject: "Re: hello", Tag: "list", Want: "Re: [list] hello"},
+		"stacked":   {Subject: "RE: Fwd:  hello", Tag: "list", Want: "RE: Fwd:  [list] hello"},
+		"present":   {Subject: "Re: [list] hello", Tag: "list", Want: "Re: [list] hello"},
+		"case":      {Subject: "Re: [LIST] hello", Tag: "list", Want: "Re: [LIST] hello"},
+		"encoded":   {Subject: "=?utf-8?q?=5Blist=5D_caf=C3=A9?=", Tag: "list", Want: "=?utf-8?q?=5Blist=5D_caf=C3=A9?="},
+		"decoded":   {Subject: "=?utf-8?q?Re=3A_caf=C3=A9?=", Tag: "list", Want: "Re: [list] café"},
+		"empty":     {Subject: "", Tag: "list", Want: "[list]"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var h Header
+			if test.Subject != "" {
+				h.Add(HdrSubject, test.Subject)
+			}
+			if err := h.TagSubject(test.Tag); err != nil {
+				t.Fatal(err)
+			}
+			if got := h.Get(HdrSubject); got != test.Want {
+				t.Errorf("want '%s', got '%s'", test.Want, got)
+			}
+		})
+	}
+}
+
+func TestSplitSubject(t *testing.T) {
+	tests := map[string]struct {
+		Kind SubjectPrefixKind
+		Bare string
+	}{
+		"hello":                    {SubjectPrefixNone, "hello"},
+		"Re: hello":                {SubjectPrefixReply, "hello"},
+		"RE: re:Re : hello":        {SubjectPrefixReply, "hello"},
+		"AW: Antw: Sv: hello":      {SubjectPrefixReply, "hello"},
+		"Fwd: Re: hello":           {SubjectPrefixForward, "hello"},
+		"WG: hello":                {SubjectPrefixForward, "hello"},
+		"Re[2]: Re(3): hello":      {SubjectPrefixReply, "hello"},
+		"Regarding: hello":         {SubjectPrefixNone, "Regarding: hello"},
+		"Re hello":                 {SubjectPrefixNone, "Re hello"},
+		"Re[x]: hello":             {SubjectPrefixNone, "Re[x]: hello"},
+		"  Re:   [list] Re: hello": {SubjectPrefixReply, "[list] Re: hello"},
+		"":                         {SubjectPrefixNone, ""},
+	}
+	for in, test := range tests {
+		t.Run(in, func(t *testing.T) {
+			kind, bare := SplitSubject(in)
+			if kind != test.Kind || bare != test.Bare {
+				t.Errorf("want %d '%s', got %d '%s'", test.Kind, test.Bare, kind, bare)
+			}
+		})
+	}
+}
+
+func TestNormalizeSubjectPrefix(t *testing.T) {
+	tests := map[string]struct {
+		Subject string
+		Want    string
+		Bare    string
+	}{
+		"none":    {Subject: "hello", Want: "hello", Bare: "hello"},
+		"stacked": {Subject: "RE: AW: Re[2]: hello", Want: "Re: hello", Bare: "hello"},
+		"forward": {Subject: "WG: Re: hello", Want: "Fwd: hello", Bare: "hello"},
+		"encoded": {Subject: "=?utf-8?q?AW=3A_caf=C3=A9?=", Want: "Re: café", Bare: "café"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var h Header
+			h.Add(HdrSubject, test.Subject)
+			bare, err := h.NormalizeSubjectPrefix()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := h.Get(HdrSubject); got != test.Want || bare != test.Bare {
+				t.Errorf("want '%s' '%s', got '%s' '%s'", test.Want, test.Bare, got, bare)
+			}
+		})
+	}
+}
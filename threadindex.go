@@ -0,0 +1,199 @@
+package orderedheaders
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// https://learn.microsoft.com/en-us/openspecs/exchange_server_protocols/ms-oxomsg/9e994fbb-b839-495f-84e3-2c8c02c7dd9b
+
+const (
+	HdrThreadIndex = "Thread-Index"
+	HdrThreadTopic = "Thread-Topic"
+)
+
+const (
+	threadIndexHeaderLen = 22
+	threadIndexChildLen  = 5
+	// fileTimeEpoch is the Unix epoch as a Windows FILETIME, in 100ns
+	// intervals since 1601
+	fileTimeEpoch = 116444736000000000
+)
+
+// ThreadIndex is an Outlook / Exchange Thread-Index, identifying a
+// conversation and the position of a message within it. The header block
+// holds the time the conversation started and a GUID, and each reply
+// adds a child block recording the time since its parent.
+type ThreadIndex struct {
+	Time     time.Time
+	GUID     [16]byte
+	Children []ThreadIndexChild
+}
+
+// ThreadIndexChild is one child block of a Thread-Index
+type ThreadIndexChild struct {
+	// Delta is the time since the parent message, with a resolution of
+	// a few milliseconds for short delays and about a second for long ones
+	Delta time.Duration
+	// Random is four random bits
+	Random uint8
+	// Sequence is a four bit sequence count
+	Sequence uint8
+	// Coarse is set if Delta is stored at the lower resolution, with
+	// the top bit of the block set. Deltas of more than about 650 days
+	// always are, but shorter ones may be too.
+	Coarse bool
+}
+
+func toFileTime(t time.Time) uint64 {
+	return uint64(t.UnixNano()/100 + fileTimeEpoch)
+}
+
+func fromFileTime(ft uint64) time.Time {
+	ns := (int64(ft) - fileTimeEpoch) * 100
+	return time.Unix(0, ns).UTC()
+}
+
+// NewThreadIndex starts a new conversation at time t, with a random GUID
+func NewThreadIndex(t time.Time) ThreadIndex {
+	ti := ThreadIndex{Time: fromFileTime(toFileTime(t) &^ 0xffff)}
+	if _, err := rand.Read(ti.GUID[:]); err != nil {
+		// crypto/rand can't fail on supported platforms
+		panic(fmt.Sprintf("reading random bytes: %v", err))
+	}
+	return ti
+}
+
+// ParseThreadIndex parses the base64 value of a Thread-Index header
+func ParseThreadIndex(value string) (ThreadIndex, error) {
+	b, err := base64.StdEncoding.DecodeString(stripFWS(value))
+	if err != nil {
+		return ThreadIndex{}, fmt.Errorf("'%s' is not a valid Thread-Index: %w", value, err)
+	}
+	if len(b) < threadIndexHeaderLen || (len(b)-threadIndexHeaderLen)%threadIndexChildLen != 0 {
+		return ThreadIndex{}, fmt.Errorf("'%s' is not a valid Thread-Index: bad length %d", value, len(b))
+	}
+	var ft [8]byte
+	copy(ft[:6], b[:6])
+	ti := ThreadIndex{Time: fromFileTime(binary.BigEndian.Uint64(ft[:]))}
+	copy(ti.GUID[:], b[6:threadIndexHeaderLen])
+	for i := threadIndexHeaderLen; i < len(b); i += threadIndexChildLen {
+		delta, coarse := decodeDelta(binary.BigEndian.Uint32(b[i:]))
+		ti.Children = append(ti.Children, ThreadIndexChild{
+			Delta:    delta,
+			Random:   b[i+4] >> 4,
+			Sequence: b[i+4] & 0x0f,
+			Coarse:   coarse,
+		})
+	}
+	return ti, nil
+}
+
+// encodeDelta packs a duration into the 32 bit delta of a child block.
+// The top bit selects whether it holds bits 18-48 or 23-53 of the delta
+// as a FILETIME; the latter is used if coarse is set or the delta is too
+// large for the former.
+func encodeDelta(d time.Duration, coarse bool) uint32 {
+	ft := uint64(d / 100)
+	if !coarse && ft < 1<<49 {
+		return uint32(ft >> 18)
+	}
+	return 0x80000000 | uint32(ft>>23)&0x7fffffff
+}
+
+// decodeDelta unpacks the delta of a child block, reporting whether it
+// was stored at the coarser resolution
+func decodeDelta(delta uint32) (time.Duration, bool) {
+	if delta&0x80000000 == 0 {
+		return time.Duration(uint64(delta)<<18) * 100, false
+	}
+	return time.Duration(uint64(delta&0x7fffffff)<<23) * 100, true
+}
+
+// String renders the Thread-Index as base64, as used in the header
+func (ti ThreadIndex) String() string {
+	b := make([]byte, 8, threadIndexHeaderLen+threadIndexChildLen*len(ti.Children)+2)
+	binary.BigEndian.PutUint64(b, toFileTime(ti.Time))
+	b = append(b[:6], ti.GUID[:]...)
+	for _, c := range ti.Children {
+		var block [threadIndexChildLen]byte
+		binary.BigEndian.PutUint32(block[:], encodeDelta(c.Delta, c.Coarse))
+		block[4] = c.Random<<4 | c.Sequence&0x0f
+		b = append(b, block[:]...)
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// Last returns the time of the most recent message in the conversation,
+// the conversation start plus the deltas of all the child blocks
+func (ti ThreadIndex) Last() time.Time {
+	t := ti.Time
+	for _, c := range ti.Children {
+		t = t.Add(c.Delta)
+	}
+	return t
+}
+
+// Reply returns the Thread-Index for a reply sent at time t, adding a
+// child block to ti
+func (ti ThreadIndex) Reply(t time.Time) ThreadIndex {
+	delta := t.Sub(ti.Last())
+	if delta < 0 {
+		delta = 0
+	}
+	var random [1]byte
+	if _, err := rand.Read(random[:]); err != nil {
+		// crypto/rand can't fail on supported platforms
+		panic(fmt.Sprintf("reading random bytes: %v", err))
+	}
+	// round the delta to what can be represented, so Last is consistent
+	// with the rendered value
+	rounded, coarse := decodeDelta(encodeDelta(delta, false))
+	child := ThreadIndexChild{Delta: rounded, Random: random[0] >> 4, Coarse: coarse}
+	ret := ThreadIndex{Time: ti.Time, GUID: ti.GUID}
+	ret.Children = append(append([]ThreadIndexChild(nil), ti.Children...), child)
+	return ret
+}
+
+// Parent returns the Thread-Index of the message this one replies to,
+// and false if this message started the conversation
+func (ti ThreadIndex) Parent() (ThreadIndex, bool) {
+	if len(ti.Children) == 0 {
+		return ti, false
+	}
+	return ThreadIndex{Time: ti.Time, GUID: ti.GUID, Children: ti.Children[:len(ti.Children)-1]}, true
+}
+
+// ThreadIndex parses the Thread-Index header
+func (h *Header) ThreadIndex() (ThreadIndex, error) {
+	value := h.Get(HdrThreadIndex)
+	if value == "" {
+		return ThreadIndex{}, mail.ErrHeaderNotPresent
+	}
+	return ParseThreadIndex(value)
+}
+
+// SetThreadIndex sets the Thread-Index header
+func (h *Header) SetThreadIndex(ti ThreadIndex) error {
+	return h.Set(HdrThreadIndex, ti.String())
+}
+
+// ThreadTopic returns the Thread-Topic header, the subject of the
+// conversation without any reply prefixes
+func (h *Header) ThreadTopic() string {
+	return strings.TrimSpace(h.Get(HdrThreadTopic))
+}
+
+// SetThreadTopic sets the Thread-Topic header. An empty topic removes it.
+func (h *Header) SetThreadTopic(topic string) error {
+	if strings.TrimSpace(topic) == "" {
+		h.RemoveAll(HdrThreadTopic)
+		return nil
+	}
+	return h.Set(HdrThreadTopic, topic)
+}
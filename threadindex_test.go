@@ -0,0 +1,129 @@
+package orderedheaders
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseThreadIndex(t *testing.T) {
+	start := time.Date(2023, 5, 22, 10, 0, 0, 0, time.UTC)
+	root := NewThreadIndex(start)
+	if d := start.Sub(root.Time); d < 0 || d > 7*time.Millisecond {
+		t.Errorf("start time %v too far from %v", root.Time, start)
+	}
+	s := root.String()
+	if len(s) != 32 {
+		t.Errorf("unexpected length %d for %s", len(s), s)
+	}
+	reply := root.Reply(start.Add(time.Hour))
+	second := reply.Reply(start.Add(30 * 24 * time.Hour))
+	if len(second.Children) != 2 {
+		t.Fatalf("expected two children, got %d", len(second.Children))
+	}
+	for _, ti := range []ThreadIndex{root, reply, second} {
+		got, err := ParseThreadIndex(ti.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(ti, got); diff != "" {
+			t.Errorf("round trip mismatch (-want +got):\n%s", diff)
+		}
+	}
+	if d := start.Add(time.Hour).Sub(reply.Last()); d < 0 || d > 50*time.Millisecond {
+		t.Errorf("reply time %v too far from start + 1h", reply.Last())
+	}
+	if d := start.Add(30 * 24 * time.Hour).Sub(second.Last()); d < 0 || d > 2*time.Second {
+		t.Errorf("second reply time %v too far from start + 30d", second.Last())
+	}
+	parent, ok := second.Parent()
+	if !ok || parent.String() != reply.String() {
+		t.Errorf("Parent() = %v, %v", parent, ok)
+	}
+	if _, ok := root.Parent(); ok {
+		t.Errorf("root should have no parent")
+	}
+
+	for _, bad := range []string{"", "not base64!", "AQIDBAU=", root.String()[:30] + "AAAAAA=="} {
+		if _, err := ParseThreadIndex(bad); err == nil {
+			t.Errorf("expected error parsing %q", bad)
+		}
+	}
+}
+
+func TestParseThreadIndexKnown(t *testing.T) {
+	// header block with FILETIME 0x01d98c9a1234, a GUID of 0x01..0x10
+	// and one child block with delta code 0
+	in := "AdmMmhI0AQIDBAUGBwgJCgsMDQ4PEAAAAAEh"
+	ti, err := ParseThreadIndex(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ThreadIndex{
+		Time: fromFileTime(0x01d98c9a12340000),
+		GUID: [16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		Children: []ThreadIndexChild{
+			{Delta: time.Duration(1<<18) * 100, Random: 2, Sequence: 1},
+		},
+	}
+	if diff := cmp.Diff(want, ti); diff != "" {
+		t.Errorf("ParseThreadIndex() mismatch (-want +got):\n%s", diff)
+	}
+	if ti.String() != in {
+		t.Errorf("String() = %s, want %s", ti.String(), in)
+	}
+	if ti.Time.Year() != 2023 {
+		t.Errorf("unexpected year in %v", ti.Time)
+	}
+}
+
+func TestThreadIndexCoarse(t *testing.T) {
+	// a child block with the top bit set and a delta of one unit, which
+	// would fit at the finer resolution
+	in := "AdmMmhI0AQIDBAUGBwgJCgsMDQ4PEIAAAAEh"
+	ti, err := ParseThreadIndex(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ThreadIndexChild{Delta: time.Duration(1<<23) * 100, Random: 2, Sequence: 1, Coarse: true}
+	if diff := cmp.Diff([]ThreadIndexChild{want}, ti.Children); diff != "" {
+		t.Errorf("ParseThreadIndex() mismatch (-want +got):\n%s", diff)
+	}
+	if ti.String() != in {
+		t.Errorf("String() = %s, want %s", ti.String(), in)
+	}
+
+	start := time.Date(2023, 5, 22, 10, 0, 0, 0, time.UTC)
+	reply := NewThreadIndex(start).Reply(start.Add(3 * 365 * 24 * time.Hour))
+	if !reply.Children[0].Coarse {
+		t.Errorf("long delta not stored coarsely")
+	}
+}
+
+func TestThreadIndexHeader(t *testing.T) {
+	h := &Header{}
+	ti := NewThreadIndex(time.Now())
+	if err := h.SetThreadIndex(ti); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.SetThreadTopic("hello world"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := h.ThreadIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != ti.String() {
+		t.Errorf("ThreadIndex() = %s, want %s", got, ti)
+	}
+	if h.ThreadTopic() != "hello world" {
+		t.Errorf("ThreadTopic() = %q", h.ThreadTopic())
+	}
+	if err := h.SetThreadTopic(""); err != nil {
+		t.Fatal(err)
+	}
+	if h.Has(HdrThreadTopic) {
+		t.Errorf("expected Thread-Topic to be removed")
+	}
+}
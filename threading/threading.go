@@ -0,0 +1,270 @@
+// Package threading reconstructs conversation threads from a set of
+// message headers, using the algorithm described by Jamie Zawinski at
+// https://www.jwz.org/doc/threading.html
+package threading
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/wttw/orderedheaders"
+)
+
+// A Container is a node in a thread tree. Containers with a nil Header
+// stand in for messages that were referenced by others but not present
+// in the input, or group messages with the same subject.
+type Container struct {
+	// ID is the message ID, including angle brackets
+	ID string
+	// Index is the position of the message in the input, or -1
+	Index int
+	// Header is the message header, or nil
+	Header   *orderedheaders.Header
+	Parent   *Container
+	Children []*Container
+}
+
+// hasDescendant reports whether d is c, or is below c in the tree
+func (c *Container) hasDescendant(d *Container) bool {
+	for ; d != nil; d = d.Parent {
+		if d == c {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Container) addChild(child *Container) {
+	if child.Parent != nil {
+		child.Parent.removeChild(child)
+	}
+	child.Parent = c
+	c.Children = append(c.Children, child)
+}
+
+func (c *Container) removeChild(child *Container) {
+	for i, v := range c.Children {
+		if v == child {
+			c.Children = append(c.Children[:i], c.Children[i+1:]...)
+			break
+		}
+	}
+	child.Parent = nil
+}
+
+// subject returns the decoded subject of the message in this container
+// or, for an empty container, its first child
+func (c *Container) subject() string {
+	h := c.Header
+	if h == nil && len(c.Children) > 0 {
+		h = c.Children[0].Header
+	}
+	if h == nil {
+		return ""
+	}
+	dec := new(mime.WordDecoder)
+	s, err := dec.DecodeHeader(h.Get(orderedheaders.HdrSubject))
+	if err != nil {
+		return h.Get(orderedheaders.HdrSubject)
+	}
+	return s
+}
+
+type threader struct {
+	byID       map[string]*Container
+	containers []*Container
+}
+
+func (t *threader) get(id string) *Container {
+	c, ok := t.byID[id]
+	if !ok {
+		c = &Container{ID: id, Index: -1}
+		t.byID[id] = c
+		t.containers = append(t.containers, c)
+	}
+	return c
+}
+
+// Thread arranges messages into threads, using Message-Id, References
+// and In-Reply-To to link replies to their parents, falling back to
+// Thread-Index for messages with neither, and grouping otherwise
+// unconnected messages with the same subject. It returns the root of
+// each thread, in the order they were first seen.
+func Thread(headers []orderedheaders.Header) []*Container {
+	t := &threader{byID: map[string]*Container{}}
+
+	// Thread-Index links replies to the Thread-Index of their parent,
+	// rather than its Message-Id
+	byIndex := map[string]string{}
+	for i := range headers {
+		ids := headers[i].MessageIDs(orderedheaders.HdrMessageId)
+		if ti, err := headers[i].ThreadIndex(); err == nil && len(ids) > 0 {
+			if _, ok := byIndex[ti.String()]; !ok {
+				byIndex[ti.String()] = ids[0]
+			}
+		}
+	}
+
+	for i := range headers {
+		h := &headers[i]
+		var id string
+		if ids := h.MessageIDs(orderedheaders.HdrMessageId); len(ids) > 0 {
+			id = ids[0]
+		}
+		c := t.get(id)
+		if id == "" || c.Header != nil {
+			// missing or duplicate message IDs get a unique one
+			c = t.get("<" + strconv.Itoa(i) + "@threading.invalid>")
+		}
+		c.Header = h
+		c.Index = i
+
+		refs := h.References()
+		if len(refs) == 0 {
+			if irt := h.MessageIDs(orderedheaders.HdrInReplyTo); len(irt) > 0 {
+				refs = irt[:1]
+			}
+		}
+		if len(refs) == 0 {
+			refs = threadIndexParent(h, byIndex)
+		}
+		var prev *Container
+		for _, ref := range refs {
+			r := t.get(ref)
+			if prev != nil && r.Parent == nil && !r.hasDescendant(prev) {
+				prev.addChild(r)
+			}
+			prev = r
+		}
+		if c.Parent != nil {
+			c.Parent.removeChild(c)
+		}
+		if prev != nil && !c.hasDescendant(prev) {
+			prev.addChild(c)
+		}
+	}
+
+	var roots []*Container
+	for _, c := range t.containers {
+		if c.Parent == nil {
+			roots = append(roots, c)
+		}
+	}
+	return groupBySubject(prune(roots, true))
+}
+
+// threadIndexParent returns the message ID of the parent of a message
+// according to its Thread-Index, or a placeholder ID if the parent isn't
+// present.
+func threadIndexParent(h *orderedheaders.Header, byIndex map[string]string) []string {
+	ti, err := h.ThreadIndex()
+	if err != nil {
+		return nil
+	}
+	parent, ok := ti.Parent()
+	if !ok {
+		return nil
+	}
+	if id, ok := byIndex[parent.String()]; ok {
+		return []string{id}
+	}
+	return []string{"<" + parent.String() + "@thread-index.invalid>"}
+}
+
+// prune removes empty containers with no children, and replaces empty
+// containers with their children, other than at the root where that
+// would split a thread into several.
+func prune(list []*Container, root bool) []*Container {
+	var ret []*Container
+	for _, c := range list {
+		c.Children = prune(c.Children, false)
+		if c.Header == nil {
+			if len(c.Children) == 0 {
+				continue
+			}
+			if !root || len(c.Children) == 1 {
+				for _, child := range c.Children {
+					child.Parent = c.Parent
+				}
+				ret = append(ret, c.Children...)
+				continue
+			}
+		}
+		ret = append(ret, c)
+	}
+	return ret
+}
+
+// groupBySubject merges root containers whose subjects are the same
+// once reply prefixes are removed
+func groupBySubject(roots []*Container) []*Container {
+	table := map[string]*Container{}
+	for _, c := range roots {
+		subject, reply := baseSubject(c.subject())
+		if subject == "" {
+			continue
+		}
+		old, ok := table[subject]
+		if !ok {
+			table[subject] = c
+			continue
+		}
+		_, oldReply := baseSubject(old.subject())
+		if (c.Header == nil && old.Header != nil) || (old.Header != nil && oldReply && !reply) {
+			table[subject] = c
+		}
+	}
+
+	var ret []*Container
+	for _, c := range roots {
+		if c.Parent != nil {
+			// already merged into another thread
+			continue
+		}
+		subject, reply := baseSubject(c.subject())
+		that, ok := table[subject]
+		if subject == "" || !ok || that == c {
+			ret = append(ret, c)
+			continue
+		}
+		_, thatReply := baseSubject(that.subject())
+		switch {
+		case c.Header == nil && that.Header == nil:
+			for len(c.Children) > 0 {
+				that.addChild(c.Children[0])
+			}
+		case that.Header == nil:
+			that.addChild(c)
+		case c.Header == nil:
+			// c has been chosen over that for this subject, so this
+			// can't happen, but handle it for completeness
+			c.addChild(that)
+		case !thatReply && reply:
+			that.addChild(c)
+		default:
+			group := &Container{Index: -1}
+			replaced := false
+			for i, r := range ret {
+				if r == that {
+					ret[i] = group
+					replaced = true
+				}
+			}
+			if !replaced {
+				ret = append(ret, group)
+			}
+			group.addChild(that)
+			group.addChild(c)
+			table[subject] = group
+		}
+	}
+	return ret
+}
+
+// baseSubject removes reply and forward prefixes from a subject, and
+// reports whether there were any
+func baseSubject(s string) (string, bool) {
+	kind, bare := orderedheaders.SplitSubject(strings.Join(strings.Fields(s), " "))
+	return strings.ToLower(bare), kind != orderedheaders.SubjectPrefixNone
+}
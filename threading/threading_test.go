@@ -0,0 +1,141 @@
+package threading
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wttw/orderedheaders"
+)
+
+func message(id, subject, inReplyTo, references string) orderedheaders.Header {
+	h := orderedheaders.Header{}
+	if id != "" {
+		h.Add("Message-Id", id)
+	}
+	h.Add("Subject", subject)
+	if inReplyTo != "" {
+		h.Add("In-Reply-To", inReplyTo)
+	}
+	if references != "" {
+		h.Add("References", references)
+	}
+	return h
+}
+
+// render draws a thread tree as a string, listing message indexes and
+// using "-" for empty containers
+func render(list []*Container) string {
+	var parts []string
+	for _, c := range list {
+		s := "-"
+		if c.Header != nil {
+			s = fmt.Sprint(c.Index)
+		}
+		if len(c.Children) > 0 {
+			s += "(" + render(c.Children) + ")"
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestThread(t *testing.T) {
+	tests := map[string]struct {
+		Headers []orderedheaders.Header
+		Want    string
+	}{
+		"simple": {
+			[]orderedheaders.Header{
+				message("<1@x>", "hello", "", ""),
+				message("<2@x>", "Re: hello", "<1@x>", "<1@x>"),
+				message("<3@x>", "Re: hello", "<2@x>", "<1@x> <2@x>"),
+				message("<4@x>", "other", "", ""),
+			},
+			"0(1(2)) 3",
+		},
+		"missingparent": {
+			[]orderedheaders.Header{
+				message("<2@x>", "Re: hello", "", "<1@x>"),
+				message("<3@x>", "Re: hello", "", "<1@x>"),
+			},
+			"-(0 1)",
+		},
+		"missingchain": {
+			[]orderedheaders.Header{
+				message("<3@x>", "Re: hello", "", "<1@x> <2@x>"),
+			},
+			"0",
+		},
+		"inreplyto": {
+			[]orderedheaders.Header{
+				message("<2@x>", "Re: hello", "<1@x>", ""),
+				message("<1@x>", "hello", "", ""),
+			},
+			"1(0)",
+		},
+		"subject": {
+			[]orderedheaders.Header{
+				message("<1@x>", "hello", "", ""),
+				message("<2@x>", "Re: Hello", "", ""),
+				message("<3@x>", "hello", "", ""),
+			},
+			"-(0(1) 2)",
+		},
+		"subjectreply": {
+			[]orderedheaders.Header{
+				message("<2@x>", "Re: hello", "", ""),
+				message("<1@x>", "hello", "", ""),
+			},
+			"1(0)",
+		},
+		"loop": {
+			[]orderedheaders.Header{
+				message("<1@x>", "a", "", "<2@x>"),
+				message("<2@x>", "b", "", "<1@x>"),
+			},
+			"1(0)",
+		},
+		"duplicate": {
+			[]orderedheaders.Header{
+				message("<1@x>", "a", "", ""),
+				message("<1@x>", "b", "", ""),
+				message("", "c", "", ""),
+			},
+			"0 1 2",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := render(Thread(test.Headers))
+			if diff := cmp.Diff(test.Want, got); diff != "" {
+				t.Errorf("Thread mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestThreadIndex(t *testing.T) {
+	start := time.Date(2023, 5, 22, 10, 0, 0, 0, time.UTC)
+	root := orderedheaders.NewThreadIndex(start)
+	reply := root.Reply(start.Add(time.Hour))
+	second := reply.Reply(start.Add(2 * time.Hour))
+	withIndex := func(id, subject string, ti orderedheaders.ThreadIndex) orderedheaders.Header {
+		h := message(id, subject, "", "")
+		h.Add("Thread-Index", ti.String())
+		return h
+	}
+	headers := []orderedheaders.Header{
+		withIndex("<3@x>", "RE: hello", second),
+		withIndex("<1@x>", "hello", root),
+		withIndex("<2@x>", "different", reply),
+	}
+	if got := render(Thread(headers)); got != "1(2(0))" {
+		t.Errorf("Thread = %s, want 1(2(0))", got)
+	}
+	if got := render(Thread(headers[:1])); got != "0" {
+		t.Errorf("Thread = %s, want 0", got)
+	}
+}
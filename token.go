@@ -0,0 +1,256 @@
+package orderedheaders
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Lexical helpers for the RFC 5322 structured header grammar
+
+// https://tools.wordtothewise.com/rfc5322#section-3.2
+
+func isWSP(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// skipCFWS skips any folding whitespace and comments starting at s[i],
+// returning the index of the next significant character
+func skipCFWS(s string, i int) (int, error) {
+	for i < len(s) {
+		switch {
+		case isWSP(s[i]):
+			i++
+		case s[i] == '(':
+			_, next, err := readComment(s, i)
+			if err != nil {
+				return i, err
+			}
+			i = next
+		default:
+			return i, nil
+		}
+	}
+	return i, nil
+}
+
+// readComment reads a possibly nested comment starting at s[i], which
+// must be '('. It returns the content of the comment with the outer
+// parentheses removed and quoted-pairs unescaped, and the index following
+// the closing parenthesis.
+func readComment(s string, i int) (string, int, error) {
+	var b strings.Builder
+	depth := 0
+	for ; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '(':
+			depth++
+			if depth == 1 {
+				continue
+			}
+		case ')':
+			depth--
+			if depth == 0 {
+				return b.String(), i + 1, nil
+			}
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				c = s[i]
+			}
+		}
+		b.WriteByte(c)
+	}
+	return "", i, errors.New("unterminated comment")
+}
+
+// readQuotedString reads a quoted-string starting at s[i], which must be
+// '"'. It returns the unescaped content and the index following the
+// closing quote.
+func readQuotedString(s string, i int) (string, int, error) {
+	var b strings.Builder
+	for i++; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '"':
+			return b.String(), i + 1, nil
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				c = s[i]
+			}
+		}
+		b.WriteByte(c)
+	}
+	return "", i, errors.New("unterminated quoted-string")
+}
+
+// isAtextChar checks whether c is an RFC 5322 atext character
+func isAtextChar(c byte) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$%&'*+-/=?^_`{|}~", c) >= 0
+}
+
+// isAtext checks whether s is a non-empty run of atext characters
+func isAtext(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isAtextChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isDotAtom checks whether s is a dot-atom, runs of atext separated by
+// single dots
+// https://tools.wordtothewise.com/rfc5322#section-3.2.3
+func isDotAtom(s string) bool {
+	for _, atom := range strings.Split(s, ".") {
+		if !isAtext(atom) {
+			return false
+		}
+	}
+	return true
+}
+
+// QuoteString returns s ready for use as a phrase, such as a display
+// name: unchanged if it's a sequence of atoms separated by single spaces,
+// otherwise as a quoted-string with any '"' or '\\' escaped. It doesn't
+// encode non-ASCII text or remove line breaks, which callers must deal
+// with first.
+// https://tools.wordtothewise.com/rfc5322#section-3.2.4
+func QuoteString(s string) string {
+	words := strings.Split(s, " ")
+	plain := true
+	for _, w := range words {
+		if !isAtext(w) {
+			plain = false
+			break
+		}
+	}
+	if plain {
+		return s
+	}
+	return quoteString(s)
+}
+
+// UnquoteString returns the content of the quoted-string s, with
+// quoted-pairs unescaped. Whitespace around it is ignored, and anything
+// else is an error.
+func UnquoteString(s string) (string, error) {
+	i := skipWhitespace(s, 0)
+	if i >= len(s) || s[i] != '"' {
+		return "", fmt.Errorf("'%s' is not a quoted-string", s)
+	}
+	unquoted, end, err := readQuotedString(s, i)
+	if err != nil {
+		return "", fmt.Errorf("'%s' is not a quoted-string: %w", s, err)
+	}
+	if skipWhitespace(s, end) != len(s) {
+		return "", fmt.Errorf("'%s' is not a quoted-string: unexpected text after the closing quote", s)
+	}
+	return unquoted, nil
+}
+
+// quoteString returns s as a quoted-string, escaping '"' and '\\'
+func quoteString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// isToken checks s is an RFC 2045 token
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c >= 0x7f || strings.IndexByte(`()<>@,;:\"/[]?=`, c) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// StripComments removes comments from a structured header value, such as
+// "(work)" in "a@example.com (work)", replacing each with a space and
+// trimming leading and trailing whitespace. Nested comments are removed
+// whole, and parentheses within quoted strings aren't comments. A value
+// with an unterminated comment or quoted string is returned unchanged,
+// apart from the trimming.
+// https://tools.wordtothewise.com/rfc5322#section-3.2.2
+func StripComments(value string) string {
+	stripped, err := stripComments(value)
+	if err != nil {
+		return strings.TrimSpace(value)
+	}
+	return strings.TrimSpace(stripped)
+}
+
+// ExtractComments returns the text of the comments in a structured header
+// value, in order, without the outer parentheses and with quoted-pairs
+// unescaped. Nested comments are part of the comment containing them. If
+// the value is malformed only the comments before the problem are
+// returned.
+func ExtractComments(value string) []string {
+	var ret []string
+	_, _ = scanComments(value, func(comment string) {
+		ret = append(ret, comment)
+	})
+	return ret
+}
+
+// stripComments removes comments from s, other than within quoted
+// strings, replacing each with a space
+func stripComments(s string) (string, error) {
+	return scanComments(s, nil)
+}
+
+// scanComments removes comments from s, as stripComments, calling found,
+// if it's not nil, with the text of each
+func scanComments(s string, found func(comment string)) (string, error) {
+	var b strings.Builder
+	inQuote := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && inQuote && i+1 < len(s):
+			b.WriteByte(c)
+			i++
+			c = s[i]
+		case c == '"':
+			inQuote = !inQuote
+		case c == '(' && !inQuote:
+			comment, next, err := readComment(s, i)
+			if err != nil {
+				return "", err
+			}
+			if found != nil {
+				found(comment)
+			}
+			b.WriteByte(' ')
+			i = next - 1
+			continue
+		}
+		b.WriteByte(c)
+	}
+	if inQuote {
+		return "", errors.New("unterminated quoted-string")
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,105 @@
+package orderedheaders
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestComments(t *testing.T) {
+	tests := map[string]struct {
+		In       string
+		Stripped string
+		Comments []string
+	}{
+		"none":          {In: "a@example.com", Stripped: "a@example.com"},
+		"trailing":      {In: "a@example.com (work)", Stripped: "a@example.com", Comments: []string{"work"}},
+		"nested":        {In: "(outer (inner) text) 1.0", Stripped: "1.0", Comments: []string{"outer (inner) text"}},
+		"several":       {In: "Mon, 1 Jan 2024 (day) 00:00:00 +0000 (UTC)", Stripped: "Mon, 1 Jan 2024   00:00:00 +0000", Comments: []string{"day", "UTC"}},
+		"quoted":        {In: `"a (not a comment)" <a@example.com> (one)`, Stripped: `"a (not a comment)" <a@example.com>`, Comments: []string{"one"}},
+		"quoted pair":   {In: `a@example.com (a \) paren)`, Stripped: "a@example.com", Comments: []string{"a ) paren"}},
+		"escaped quote": {In: `"a \" (b)" (c)`, Stripped: `"a \" (b)"`, Comments: []string{"c"}},
+		"unterminated":  {In: " a@example.com (work ", Stripped: "a@example.com (work"},
+		"open quote":    {In: `(first) "a (b)`, Stripped: `(first) "a (b)`, Comments: []string{"first"}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := StripComments(test.In); got != test.Stripped {
+				t.Errorf("StripComments: want '%s', got '%s'", test.Stripped, got)
+			}
+			if diff := cmp.Diff(test.Comments, ExtractComments(test.In)); diff != "" {
+				t.Errorf("ExtractComments mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestQuoteString(t *testing.T) {
+	tests := map[string]struct {
+		In   string
+		Want string
+	}{
+		"atom":     {"Alice", "Alice"},
+		"atoms":    {"Alice Smith", "Alice Smith"},
+		"specials": {"Smith, Alice", `"Smith, Alice"`},
+		"dot":      {"A. Smith", `"A. Smith"`},
+		"spaces":   {"Alice  Smith", `"Alice  Smith"`},
+		"escapes":  {`say "hi" \o/`, `"say \"hi\" \\o/"`},
+		"empty":    {"", `""`},
+		"parens":   {"Alice (work)", `"Alice (work)"`},
+		"leading":  {" Alice", `" Alice"`},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := QuoteString(test.In)
+			if got != test.Want {
+				t.Errorf("want '%s', got '%s'", test.Want, got)
+			}
+			if got == test.In {
+				return
+			}
+			unquoted, err := UnquoteString(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if unquoted != test.In {
+				t.Errorf("round trip gave '%s'", unquoted)
+			}
+		})
+	}
+}
+
+func TestUnquoteString(t *testing.T) {
+	tests := map[string]struct {
+		In        string
+		Want      string
+		WantError bool
+	}{
+		"simple":       {In: `"a b"`, Want: "a b"},
+		"whitespace":   {In: " \t\"a\" \r\n", Want: "a"},
+		"pairs":        {In: `"a\"b\\c\d"`, Want: `a"b\cd`},
+		"empty":        {In: `""`, Want: ""},
+		"unquoted":     {In: "a b", WantError: true},
+		"unterminated": {In: `"a b`, WantError: true},
+		"trailing":     {In: `"a" b`, WantError: true},
+		"two":          {In: `"a" "b"`, WantError: true},
+		"nothing":      {In: "", WantError: true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := UnquoteString(test.In)
+			if test.WantError {
+				if err == nil {
+					t.Errorf("expected error, got '%s'", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.Want {
+				t.Errorf("want '%s', got '%s'", test.Want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package orderedheaders
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"mime/quotedprintable"
+	"strings"
+)
+
+// https://tools.wordtothewise.com/rfc2045#section-6
+
+// SuggestTransferEncoding reads content and recommends the
+// Content-Transfer-Encoding to send it with: "7bit" if it's ASCII with
+// no NULs, CRLF line endings and lines no longer than 998 characters,
+// "base64" if it contains NULs or so many 8-bit bytes that quoted-printable
+// would be the larger, and "quoted-printable" otherwise.
+func SuggestTransferEncoding(body io.Reader) (string, error) {
+	r := bufio.NewReader(body)
+	var total, eightBit, lineLength int
+	var bare, long bool
+	var prev byte
+	for {
+		c, err := r.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		total++
+		if prev == '\r' && c != '\n' {
+			bare = true
+		}
+		switch {
+		case c == 0:
+			return "base64", nil
+		case c == '\n':
+			if prev != '\r' {
+				bare = true
+			}
+			lineLength = 0
+		case c == '\r':
+		default:
+			if c > 127 {
+				eightBit++
+			}
+			lineLength++
+			if lineLength > maxLineLength {
+				long = true
+			}
+		}
+		prev = c
+	}
+	if prev == '\r' {
+		bare = true
+	}
+	switch {
+	case eightBit == 0 && !bare && !long:
+		return "7bit", nil
+	case eightBit*6 > total:
+		// quoted-printable triples the size of each 8-bit byte, while
+		// base64 adds a third to every byte
+		return "base64", nil
+	}
+	return "quoted-printable", nil
+}
+
+// encodeBody encodes a body part's content with the transfer encoding
+// SuggestTransferEncoding recommends. Line breaks in text are encoded as
+// line breaks, as text is in canonical form, and in other content
+// byte by byte so that it's unchanged when decoded.
+func encodeBody(mediaType string, content []byte) (string, []byte) {
+	// reading from memory can't fail
+	cte, _ := SuggestTransferEncoding(bytes.NewReader(content))
+	switch cte {
+	case "7bit":
+		return cte, content
+	case "quoted-printable":
+		var buf bytes.Buffer
+		w := quotedprintable.NewWriter(&buf)
+		w.Binary = !strings.HasPrefix(mediaType, "text/")
+		_, _ = w.Write(content)
+		_ = w.Close()
+		return cte, buf.Bytes()
+	}
+	return "base64", encodeBase64Lines(content)
+}
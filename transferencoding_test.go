@@ -0,0 +1,106 @@
+package orderedheaders
+
+import (
+	"bytes"
+	"io"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+func TestSuggestTransferEncoding(t *testing.T) {
+	tests := map[string]struct {
+		In   string
+		Want string
+	}{
+		"empty":        {In: "", Want: "7bit"},
+		"ascii":        {In: "Hello\r\nworld\r\n", Want: "7bit"},
+		"unterminated": {In: "Hello", Want: "7bit"},
+		"iso-2022-jp":  {In: "\x1b$B$3$s$K$A$O\x1b(B\r\n", Want: "7bit"},
+		"bare lf":      {In: "Hello\nworld\n", Want: "quoted-printable"},
+		"bare cr":      {In: "Hello\rworld", Want: "quoted-printable"},
+		"trailing cr":  {In: "Hello\r", Want: "quoted-printable"},
+		"long line":    {In: strings.Repeat("x", 999), Want: "quoted-printable"},
+		"max line":     {In: strings.Repeat("x", 998) + "\r\n" + strings.Repeat("x", 998), Want: "7bit"},
+		"latin":        {In: "Hello, wörld", Want: "quoted-printable"},
+		"cyrillic":     {In: "Привет, мир", Want: "base64"},
+		"nul":          {In: "Hello\x00", Want: "base64"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := SuggestTransferEncoding(strings.NewReader(test.In))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.Want {
+				t.Errorf("want %s, got %s", test.Want, got)
+			}
+		})
+	}
+
+	if _, err := SuggestTransferEncoding(io.MultiReader(strings.NewReader("x"), failingReader{})); err == nil {
+		t.Error("read error wasn't returned")
+	}
+}
+
+func TestEncodeBody(t *testing.T) {
+	tests := map[string]struct {
+		MediaType string
+		In        string
+		Want      string
+		WantCTE   string
+	}{
+		"7bit":   {MediaType: "text/plain", In: "a\r\nb", WantCTE: "7bit", Want: "a\r\nb"},
+		"text":   {MediaType: "text/plain", In: "caf\xc3\xa9 menu\nbar\n", WantCTE: "quoted-printable", Want: "caf\xc3\xa9 menu\r\nbar\r\n"},
+		"binary": {MediaType: "application/json", In: "{\"caf\xc3\xa9\": 1}\n", WantCTE: "quoted-printable", Want: "{\"caf\xc3\xa9\": 1}\n"},
+		"base64": {MediaType: "image/png", In: "\x89PNG\r\n\x1a\n\x00", WantCTE: "base64", Want: "\x89PNG\r\n\x1a\n\x00"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			cte, body := encodeBody(test.MediaType, []byte(test.In))
+			if cte != test.WantCTE {
+				t.Fatalf("want %s, got %s", test.WantCTE, cte)
+			}
+			decoded, err := io.ReadAll(decodeTransferEncoding(cte, bytes.NewReader(body)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(decoded) != test.Want {
+				t.Errorf("want %q, got %q", test.Want, decoded)
+			}
+		})
+	}
+}
+
+func TestBuilderTransferEncoding(t *testing.T) {
+	csv := "name,city,country\nZoë,Zürich,Switzerland\nAmy,London,United Kingdom\n"
+	msg, err := NewBuilder().
+		From(&mail.Address{Address: "alice@example.com"}).
+		Text("Привет, мир").
+		Attach("notes.txt", "text/plain", strings.NewReader("plain notes\r\n")).
+		Attach("cities.csv", "text/csv", strings.NewReader(csv)).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts, err := msg.Parts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range parts {
+		got = append(got, p.Header.Get(HdrContentTransferEncoding))
+	}
+	want := []string{"base64", "7bit", "quoted-printable"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("want %v, got %v", want, got)
+	}
+	body, err := io.ReadAll(quotedprintable.NewReader(parts[2].Body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != strings.Replace(csv, "\n", "\r\n", -1) {
+		t.Errorf("unexpected csv %q", body)
+	}
+}
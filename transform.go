@@ -0,0 +1,171 @@
+package orderedheaders
+
+import (
+	"fmt"
+	"net/textproto"
+	"sort"
+)
+
+// A Transform modifies a header, as one step of a Pipeline
+type Transform interface {
+	Apply(h *Header) error
+}
+
+// TransformFunc adapts a function to a Transform
+type TransformFunc func(h *Header) error
+
+// Apply calls f(h)
+func (f TransformFunc) Apply(h *Header) error {
+	return f(h)
+}
+
+// StepError is returned by Pipeline.Apply when a step fails
+type StepError struct {
+	// Step is the position of the step in the pipeline, from 0
+	Step int
+	Name string
+	Err  error
+}
+
+func (e *StepError) Error() string {
+	return fmt.Sprintf("step %d (%s): %v", e.Step, e.Name, e.Err)
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// Pipeline applies a sequence of named transforms. It's a Transform
+// itself, so pipelines can be nested.
+//
+//	p := NewPipeline().
+//		Add("strip", StripTransform(StripPolicy{Prefixes: []string{"X-"}})).
+//		Add("tag", TagSubjectTransform("list"))
+//	err := p.Apply(&msg.Header)
+type Pipeline struct {
+	steps []pipelineStep
+}
+
+type pipelineStep struct {
+	name      string
+	transform Transform
+}
+
+// NewPipeline returns an empty Pipeline
+func NewPipeline() *Pipeline {
+	return &Pipeline{}
+}
+
+// Add appends a step, with a name used in errors
+func (p *Pipeline) Add(name string, t Transform) *Pipeline {
+	p.steps = append(p.steps, pipelineStep{name: name, transform: t})
+	return p
+}
+
+// Apply runs each step in turn on a copy of h, and updates h only if
+// they all succeed. If a step fails it returns a *StepError.
+func (p *Pipeline) Apply(h *Header) error {
+	work := &Header{Headers: append([]KV(nil), h.Headers...), RawKeys: h.RawKeys}
+	for i, s := range p.steps {
+		if err := s.transform.Apply(work); err != nil {
+			return &StepError{Step: i, Name: s.name, Err: err}
+		}
+	}
+	h.Headers, h.RawKeys = work.Headers, work.RawKeys
+	return nil
+}
+
+// StripTransform removes fields with StripHeaders
+func StripTransform(policy StripPolicy) Transform {
+	return TransformFunc(func(h *Header) error {
+		h.StripHeaders(policy)
+		return nil
+	})
+}
+
+// TagSubjectTransform tags the subject with TagSubject
+func TagSubjectTransform(tag string) Transform {
+	return TransformFunc(func(h *Header) error {
+		return h.TagSubject(tag)
+	})
+}
+
+// RedactTransform replaces sensitive values with Redact
+func RedactTransform(policy RedactPolicy) Transform {
+	return TransformFunc(func(h *Header) error {
+		*h = *h.Redact(policy)
+		return nil
+	})
+}
+
+// RulesTransform rewrites fields with ApplyRules
+func RulesTransform(rules []Rule) Transform {
+	return TransformFunc(func(h *Header) error {
+		_, err := h.ApplyRules(rules)
+		return err
+	})
+}
+
+// NormalizeTransform repairs irregular whitespace with Normalize
+func NormalizeTransform() Transform {
+	return TransformFunc(func(h *Header) error {
+		h.Normalize()
+		return nil
+	})
+}
+
+// CanonicalOrder is a conventional order for header fields, for use with
+// SortTransform: the originator, destination, identification and
+// informational fields, then MIME fields. SortTransform keeps trace and
+// resent fields above them.
+// https://tools.wordtothewise.com/rfc5322#section-3.6
+var CanonicalOrder = []string{
+	HdrDate,
+	HdrFrom,
+	HdrSender,
+	HdrReplyTo,
+	HdrTo,
+	HdrCc,
+	HdrBcc,
+	HdrMessageId,
+	HdrInReplyTo,
+	HdrReferences,
+	HdrSubject,
+	HdrComments,
+	HdrKeywords,
+	HdrMimeVersion,
+	HdrContentType,
+	HdrContentTransferEncoding,
+	HdrContentID,
+	HdrContentDescription,
+	HdrContentDisposition,
+}
+
+// SortTransform reorders fields so that those named in order come
+// first, in that order, followed by the rest. Fields with the same key,
+// and those not named, keep their relative order. Trace and resent
+// fields stay at the top, above all the others, in their original order,
+// since the resent blocks and the Received fields between them record
+// the path the message took.
+func SortTransform(order []string) Transform {
+	rank := make(map[string]int, len(order))
+	for i, key := range order {
+		rank[textproto.CanonicalMIMEHeaderKey(key)] = i
+	}
+	return TransformFunc(func(h *Header) error {
+		h.CanonicalizeKeys()
+		position := func(kv KV) int {
+			if kv.Key == HdrReturnPath || kv.Key == HdrReceived || isResent(kv.Key) {
+				return -1
+			}
+			if r, ok := rank[kv.Key]; ok {
+				return r
+			}
+			return len(rank)
+		}
+		sort.SliceStable(h.Headers, func(i, j int) bool {
+			return position(h.Headers[i]) < position(h.Headers[j])
+		})
+		return nil
+	})
+}
@@ -0,0 +1,129 @@
+package orderedheaders
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPipeline(t *testing.T) {
+	h := Header{Headers: []KV{
+		{Key: "X-Mailer", Value: "Mailer 1.0"},
+		{Key: "Subject", Value: "Re:  hello\tthere"},
+		{Key: "To", Value: "bob@example.com"},
+		{Key: "From", Value: "alice@example.com"},
+		{Key: "Received", Value: "from a"},
+	}}
+	p := NewPipeline().
+		Add("strip", StripTransform(StripPolicy{Prefixes: []string{"X-"}})).
+		Add("normalize", NormalizeTransform()).
+		Add("tag", TagSubjectTransform("list")).
+		Add("redact", RedactTransform(RedactPolicy{Keys: []string{HdrTo}})).
+		Add("sort", SortTransform([]string{HdrReceived, HdrFrom, HdrTo, HdrSubject}))
+	if err := p.Apply(&h); err != nil {
+		t.Fatal(err)
+	}
+	want := []KV{
+		{Key: "Received", Value: "from a"},
+		{Key: "From", Value: "alice@example.com"},
+		{Key: "To", Value: DefaultRedactMarker},
+		{Key: "Subject", Value: "Re: [list] hello there"},
+	}
+	if diff := cmp.Diff(want, h.Headers); diff != "" {
+		t.Errorf("header mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPipelineError(t *testing.T) {
+	h := Header{Headers: []KV{{Key: "Subject", Value: "hello"}}}
+	failure := errors.New("failed")
+	p := NewPipeline().
+		Add("tag", TagSubjectTransform("list")).
+		Add("rules", RulesTransform([]Rule{{Key: regexp.MustCompile(`.`), Action: RuleRename}})).
+		Add("never", TransformFunc(func(*Header) error { return failure }))
+	err := p.Apply(&h)
+	var stepErr *StepError
+	if !errors.As(err, &stepErr) {
+		t.Fatalf("expected StepError, got %v", err)
+	}
+	if stepErr.Step != 1 || stepErr.Name != "rules" {
+		t.Errorf("unexpected step %d %s", stepErr.Step, stepErr.Name)
+	}
+	if h.Get(HdrSubject) != "hello" {
+		t.Errorf("header changed by failed pipeline")
+	}
+
+	nested := NewPipeline().Add("inner", NewPipeline().Add("fail", TransformFunc(func(*Header) error { return failure })))
+	if err := nested.Apply(&h); !errors.Is(err, failure) {
+		t.Errorf("expected wrapped error, got %v", err)
+	}
+}
+
+func TestCanonicalOrder(t *testing.T) {
+	h := Header{Headers: []KV{
+		{Key: "Content-Type", Value: "text/plain"},
+		{Key: "X-Mailer", Value: "Mailer 1.0"},
+		{Key: "Subject", Value: "hello"},
+		{Key: "Received", Value: "from b"},
+		{Key: "From", Value: "alice@example.com"},
+		{Key: "Date", Value: "Mon, 1 Jan 2024 00:00:00 +0000"},
+		{Key: "Received", Value: "from a"},
+		{Key: "Mime-Version", Value: "1.0"},
+	}}
+	if err := SortTransform(CanonicalOrder).Apply(&h); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, kv := range h.Headers {
+		got = append(got, kv.Key+": "+kv.Value)
+	}
+	want := []string{
+		"Received: from b",
+		"Received: from a",
+		"Date: Mon, 1 Jan 2024 00:00:00 +0000",
+		"From: alice@example.com",
+		"Subject: hello",
+		"Mime-Version: 1.0",
+		"Content-Type: text/plain",
+		"X-Mailer: Mailer 1.0",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("order mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSortResentBlocks(t *testing.T) {
+	h := Header{Headers: []KV{
+		{Key: "Received", Value: "from c"},
+		{Key: "Resent-Date", Value: "Wed, 3 Jan 2024 00:00:00 +0000"},
+		{Key: "Resent-From", Value: "carol@example.com"},
+		{Key: "Received", Value: "from b"},
+		{Key: "Resent-From", Value: "bob@example.com"},
+		{Key: "Resent-Date", Value: "Tue, 2 Jan 2024 00:00:00 +0000"},
+		{Key: "Subject", Value: "hello"},
+		{Key: "Received", Value: "from a"},
+		{Key: "From", Value: "alice@example.com"},
+	}}
+	if err := SortTransform(CanonicalOrder).Apply(&h); err != nil {
+		t.Fatal(err)
+	}
+	want := []KV{
+		{Key: "Received", Value: "from c"},
+		{Key: "Resent-Date", Value: "Wed, 3 Jan 2024 00:00:00 +0000"},
+		{Key: "Resent-From", Value: "carol@example.com"},
+		{Key: "Received", Value: "from b"},
+		{Key: "Resent-From", Value: "bob@example.com"},
+		{Key: "Resent-Date", Value: "Tue, 2 Jan 2024 00:00:00 +0000"},
+		{Key: "Received", Value: "from a"},
+		{Key: "From", Value: "alice@example.com"},
+		{Key: "Subject", Value: "hello"},
+	}
+	if diff := cmp.Diff(want, h.Headers); diff != "" {
+		t.Errorf("order mismatch (-want +got):\n%s", diff)
+	}
+	if blocks := h.ResentBlocks(); len(blocks) != 2 {
+		t.Errorf("want 2 resent blocks, got %d", len(blocks))
+	}
+}
@@ -0,0 +1,75 @@
+package orderedheaders
+
+import (
+	"fmt"
+	"strings"
+)
+
+// https://cr.yp.to/proto/verp.txt
+
+// VERPDelimiter separates the bounce address local part from the
+// encoded recipient in a VERP address
+const VERPDelimiter = "-"
+
+// splitAddress splits an address at its last @
+func splitAddress(addr string) (string, string, error) {
+	at := strings.LastIndexByte(addr, '@')
+	if at <= 0 || at == len(addr)-1 {
+		return "", "", fmt.Errorf("'%s' is not a valid address", addr)
+	}
+	return addr[:at], addr[at+1:], nil
+}
+
+// EncodeVERP returns a variable envelope return path for mail from the
+// bounce address to recipient, so that bounces identify the recipient
+// that failed. For bounce@lists.example.com and user@example.org it's
+// bounce-user=example.org@lists.example.com.
+func EncodeVERP(bounce, recipient string) (string, error) {
+	bounceLocal, bounceDomain, err := splitAddress(bounce)
+	if err != nil {
+		return "", err
+	}
+	local, domain, err := splitAddress(recipient)
+	if err != nil {
+		return "", err
+	}
+	verpLocal := bounceLocal + VERPDelimiter + local + "=" + domain
+	if !isDotAtom(verpLocal) {
+		return "", fmt.Errorf("'%s' can't be encoded in a VERP address", recipient)
+	}
+	return verpLocal + "@" + bounceDomain, nil
+}
+
+// DecodeVERP returns the recipient encoded in a VERP address created by
+// EncodeVERP from the bounce address. Local parts are compared case
+// insensitively, as some MTAs lowercase them.
+func DecodeVERP(bounce, verp string) (string, error) {
+	bounceLocal, bounceDomain, err := splitAddress(bounce)
+	if err != nil {
+		return "", err
+	}
+	local, domain, err := splitAddress(verp)
+	if err != nil {
+		return "", err
+	}
+	prefix := bounceLocal + VERPDelimiter
+	if !strings.EqualFold(domain, bounceDomain) || len(local) <= len(prefix) || !strings.EqualFold(local[:len(prefix)], prefix) {
+		return "", fmt.Errorf("'%s' is not a VERP address for %s", verp, bounce)
+	}
+	encoded := local[len(prefix):]
+	eq := strings.LastIndexByte(encoded, '=')
+	if eq <= 0 || eq == len(encoded)-1 {
+		return "", fmt.Errorf("'%s' is not a valid VERP address", verp)
+	}
+	return encoded[:eq] + "@" + encoded[eq+1:], nil
+}
+
+// SetVERPReturnPath sets the Return-Path to the VERP address for mail
+// from the bounce address to recipient
+func (h *Header) SetVERPReturnPath(bounce, recipient string) error {
+	verp, err := EncodeVERP(bounce, recipient)
+	if err != nil {
+		return err
+	}
+	return h.SetReturnPath(verp)
+}
@@ -0,0 +1,87 @@
+package orderedheaders
+
+import (
+	"testing"
+)
+
+func TestVERP(t *testing.T) {
+	tests := map[string]struct {
+		Bounce    string
+		Recipient string
+		Want      string
+		WantError bool
+	}{
+		"simple":   {Bounce: "bounce@lists.example.com", Recipient: "user@example.org", Want: "bounce-user=example.org@lists.example.com"},
+		"plus":     {Bounce: "list-bounces@example.com", Recipient: "a.b+tag@example.org", Want: "list-bounces-a.b+tag=example.org@example.com"},
+		"dashes":   {Bounce: "b@example.com", Recipient: "first-last@sub.example.org", Want: "b-first-last=sub.example.org@example.com"},
+		"quoted":   {Bounce: "b@example.com", Recipient: `"a b"@example.org`, WantError: true},
+		"nodomain": {Bounce: "b@example.com", Recipient: "user", WantError: true},
+		"bounce":   {Bounce: "bounce", Recipient: "user@example.org", WantError: true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			verp, err := EncodeVERP(test.Bounce, test.Recipient)
+			if test.WantError {
+				if err == nil {
+					t.Errorf("expected error, got '%s'", verp)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if verp != test.Want {
+				t.Errorf("want '%s', got '%s'", test.Want, verp)
+			}
+			rcpt, err := DecodeVERP(test.Bounce, verp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rcpt != test.Recipient {
+				t.Errorf("decoded '%s', expected '%s'", rcpt, test.Recipient)
+			}
+		})
+	}
+}
+
+func TestDecodeVERP(t *testing.T) {
+	bounce := "bounce@lists.example.com"
+	tests := map[string]struct {
+		In        string
+		Want      string
+		WantError bool
+	}{
+		"upper":       {In: "BOUNCE-User=Example.org@LISTS.example.com", Want: "User@Example.org"},
+		"otherdomain": {In: "bounce-user=example.org@example.net", WantError: true},
+		"plain":       {In: "bounce@lists.example.com", WantError: true},
+		"noequals":    {In: "bounce-user@lists.example.com", WantError: true},
+		"otherlocal":  {In: "other-user=example.org@lists.example.com", WantError: true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := DecodeVERP(bounce, test.In)
+			if test.WantError {
+				if err == nil {
+					t.Errorf("expected error, got '%s'", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.Want {
+				t.Errorf("want '%s', got '%s'", test.Want, got)
+			}
+		})
+	}
+}
+
+func TestSetVERPReturnPath(t *testing.T) {
+	var h Header
+	if err := h.SetVERPReturnPath("bounce@lists.example.com", "user@example.org"); err != nil {
+		t.Fatal(err)
+	}
+	if got := h.Get(HdrReturnPath); got != "<bounce-user=example.org@lists.example.com>" {
+		t.Errorf("unexpected Return-Path '%s'", got)
+	}
+}